@@ -0,0 +1,249 @@
+// Package client is a Go client for the handbook API, returning the same types the API is built on
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"handbook-scraper/scrapers/area_of_study"
+	"handbook-scraper/scrapers/common"
+	"handbook-scraper/scrapers/courses"
+	"handbook-scraper/scrapers/units"
+)
+
+const (
+	defaultMaxRetries = 3
+	defaultBackoff    = 500 * time.Millisecond
+	maxBackoff        = 30 * time.Second
+)
+
+// Client calls the handbook API
+type Client struct {
+	BaseURL    string        // e.g. http://localhost:8080
+	HTTPClient *http.Client  // http.DefaultClient if nil
+	MaxRetries int           // Retries of requests which failed with a network error, 429, 502, 503, or 504
+	Backoff    time.Duration // Delay before the first retry, doubled for each retry after it
+	APIKey     string        // Sent as a bearer token if set, for routes which require a role
+}
+
+// New creates a client for the API served at baseURL
+func New(baseURL string) *Client {
+	return &Client{
+		BaseURL:    strings.TrimRight(baseURL, "/"),
+		HTTPClient: http.DefaultClient,
+		MaxRetries: defaultMaxRetries,
+		Backoff:    defaultBackoff,
+	}
+}
+
+// APIError is an error response of the API
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("handbook API returned %d: %s", e.StatusCode, e.Message)
+}
+
+// RequisiteCheck is the result of checking whether completed units meet the requisites of a unit
+type RequisiteCheck struct {
+	Met        bool                    `json:"met_requisites"`
+	Unmet      []string                `json:"message"`    // Requisites which are not met
+	Warning    string                  `json:"warning"`    // Enrolment rules, which cannot be checked
+	Advisories []units.HiddenRequisite `json:"advisories"` // Units only mentioned in the synopsis or enrolment rules
+}
+
+// Answer is the answer to a question in natural language
+type Answer struct {
+	Question string          `json:"question"`
+	Intent   string          `json:"intent"` // check, offerings, requisites, unit, or search
+	Unit     string          `json:"unit"`   // Unit the question is about, empty for searches
+	Answer   string          `json:"answer"`
+	Data     json.RawMessage `json:"data"` // Structured data of the capability the question was routed to
+}
+
+// Item is a handbook item of any type
+type Item struct {
+	ItemType string          `json:"item_type"` // units, courses, or aos
+	Data     json.RawMessage `json:"data"`
+}
+
+// Decode decodes the item into its type, e.g. units.UnitData for units
+func (i Item) Decode(out interface{}) error {
+	return json.Unmarshal(i.Data, out)
+}
+
+// GetUnit returns a unit of a handbook year. The year may also be "current" or "next".
+func (c *Client) GetUnit(ctx context.Context, year string, code string) (units.UnitData, error) {
+	var unit units.UnitData
+	err := c.do(ctx, http.MethodGet, itemPath(year, "units", code), nil, &unit)
+	return unit, err
+}
+
+// GetCourse returns a course of a handbook year
+func (c *Client) GetCourse(ctx context.Context, year string, code string) (courses.CourseData, error) {
+	var course courses.CourseData
+	err := c.do(ctx, http.MethodGet, itemPath(year, "courses", code), nil, &course)
+	return course, err
+}
+
+// GetAreaOfStudy returns an area of study of a handbook year
+func (c *Client) GetAreaOfStudy(ctx context.Context, year string, code string) (area_of_study.AosData, error) {
+	var aos area_of_study.AosData
+	err := c.do(ctx, http.MethodGet, itemPath(year, "aos", code), nil, &aos)
+	return aos, err
+}
+
+// CheckRequisites checks whether the completed units meet the requisites of a unit
+func (c *Client) CheckRequisites(ctx context.Context, year string, code string, completed []common.Unit) (RequisiteCheck, error) {
+	return c.CheckRequisitesForCourse(ctx, year, code, completed, "")
+}
+
+// CheckRequisitesForCourse checks the requisites of a unit like CheckRequisites, for a student admitted to a course
+func (c *Client) CheckRequisitesForCourse(ctx context.Context, year string, code string, completed []common.Unit, course string) (RequisiteCheck, error) {
+	if completed == nil {
+		completed = []common.Unit{}
+	}
+	body, err := json.Marshal(completed)
+	if err != nil {
+		return RequisiteCheck{}, err
+	}
+
+	path := itemPath(year, "units", code) + "/check"
+	if course != "" {
+		path += "?course=" + url.QueryEscape(course)
+	}
+
+	var check RequisiteCheck
+	err = c.do(ctx, http.MethodPost, path, body, &check)
+	return check, err
+}
+
+// Ask answers a question in natural language, such as "can I do FIT3152 if I've done FIT1045 and FIT2004?"
+func (c *Client) Ask(ctx context.Context, year string, question string) (Answer, error) {
+	body, err := json.Marshal(map[string]string{"question": question})
+	if err != nil {
+		return Answer{}, err
+	}
+
+	var answer Answer
+	err = c.do(ctx, http.MethodPost, "/v1/"+url.PathEscape(year)+"/answer", body, &answer)
+	return answer, err
+}
+
+// Search finds the unit, course, or area of study with a code, without knowing its type
+func (c *Client) Search(ctx context.Context, year string, code string) (Item, error) {
+	var item Item
+	err := c.do(ctx, http.MethodGet, itemPath(year, "any", code), nil, &item)
+	return item, err
+}
+
+// itemPath is the path of a handbook item
+func itemPath(year string, urlKey string, code string) string {
+	return "/v1/" + url.PathEscape(year) + "/" + urlKey + "/" + url.PathEscape(code)
+}
+
+// do sends a request, retrying with exponential backoff, and decodes the response into out
+func (c *Client) do(ctx context.Context, method string, path string, body []byte, out interface{}) error {
+	backoff := c.Backoff
+	if backoff <= 0 {
+		backoff = defaultBackoff
+	}
+
+	for attempt := 0; ; attempt++ {
+		retryAfter, err := c.send(ctx, method, path, body, out)
+		if err == nil || retryAfter < 0 || attempt >= c.MaxRetries {
+			return err
+		}
+
+		// The server's Retry-After takes precedence over the backoff
+		delay := backoff
+		if retryAfter > 0 {
+			delay = retryAfter
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
+
+// send sends a request once. A failed request is retryable if the returned delay is not negative,
+// and a positive delay is the server's Retry-After.
+func (c *Client) send(ctx context.Context, method string, path string, body []byte, out interface{}) (time.Duration, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, reader)
+	if err != nil {
+		return -1, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.APIKey)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return -1, ctx.Err()
+		}
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		apiErr := &APIError{StatusCode: resp.StatusCode, Message: errorMessage(resp)}
+		switch resp.StatusCode {
+		case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return retryAfter(resp), apiErr
+		}
+		return -1, apiErr
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return -1, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return 0, nil
+}
+
+// errorMessage reads the error of an error response, falling back to the status text
+func errorMessage(resp *http.Response) string {
+	var errResp struct {
+		Error interface{} `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&errResp); err == nil {
+		if message, ok := errResp.Error.(string); ok && message != "" {
+			return message
+		}
+	}
+	return http.StatusText(resp.StatusCode)
+}
+
+// retryAfter parses the Retry-After header in seconds, or returns 0 if there is none
+func retryAfter(resp *http.Response) time.Duration {
+	seconds, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || seconds <= 0 {
+		return 0
+	}
+	return min(time.Duration(seconds)*time.Second, maxBackoff)
+}
@@ -0,0 +1,256 @@
+// Package discord exposes the handbook API as command handlers for Discord bots, so bots can embed unit lookups,
+// requisite checks and searches without their own HTTP plumbing. Responses are plain structs which marshal to the
+// JSON of Discord interaction responses, so they work with any Discord library.
+package discord
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"handbook-scraper/client"
+	"handbook-scraper/scrapers/common"
+	"handbook-scraper/scrapers/units"
+)
+
+const (
+	// embedColour is the colour of the embeds, Monash blue
+	embedColour = 0x006dae
+	// flagEphemeral marks a response as only visible to the user who sent the command
+	flagEphemeral = 1 << 6
+
+	// Limits of Discord on the text of messages and embeds
+	maxContentLength     = 2000
+	maxTitleLength       = 256
+	maxDescriptionLength = 4096
+	maxFieldValueLength  = 1024
+
+	// Types of the options of application commands
+	optionString = 3
+)
+
+// errUnknownCommand is returned for commands which are not among Commands
+var errUnknownCommand = errors.New("unknown command")
+
+// Embed is a Discord embed
+type Embed struct {
+	Title       string       `json:"title,omitempty"`
+	Description string       `json:"description,omitempty"`
+	URL         string       `json:"url,omitempty"`
+	Color       int          `json:"color,omitempty"`
+	Fields      []EmbedField `json:"fields,omitempty"`
+	Footer      *EmbedFooter `json:"footer,omitempty"`
+}
+
+// EmbedField is a field of a Discord embed
+type EmbedField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline,omitempty"`
+}
+
+// EmbedFooter is the footer of a Discord embed
+type EmbedFooter struct {
+	Text string `json:"text"`
+}
+
+// Response is the data of a Discord interaction response
+type Response struct {
+	Content string  `json:"content,omitempty"`
+	Embeds  []Embed `json:"embeds,omitempty"`
+	Flags   int     `json:"flags,omitempty"` // 64 for errors, which only the user who sent the command sees
+}
+
+// Command is the definition of a Discord application command, for registering the commands of Handlers
+type Command struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Options     []CommandOption `json:"options,omitempty"`
+}
+
+// CommandOption is an option of a Discord application command
+type CommandOption struct {
+	Type        int    `json:"type"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Required    bool   `json:"required,omitempty"`
+}
+
+// Commands returns the definitions of the commands Handle answers
+func Commands() []Command {
+	return []Command{
+		{Name: "unit", Description: "Look up a unit in the handbook", Options: []CommandOption{
+			{Type: optionString, Name: "code", Description: "Unit code, e.g. FIT1045", Required: true},
+		}},
+		{Name: "check", Description: "Check whether you meet the requisites of a unit", Options: []CommandOption{
+			{Type: optionString, Name: "code", Description: "Unit code, e.g. FIT2004", Required: true},
+			{Type: optionString, Name: "completed", Description: "Units you have completed, e.g. FIT1045 FIT1058"},
+			{Type: optionString, Name: "course", Description: "Your course code, e.g. C2001"},
+		}},
+		{Name: "search", Description: "Ask about units, e.g. units about machine learning", Options: []CommandOption{
+			{Type: optionString, Name: "question", Description: "Your question", Required: true},
+		}},
+	}
+}
+
+// Handlers answers the commands of a bot with the handbook API
+type Handlers struct {
+	Client *client.Client
+	Year   string // Handbook year of the commands, "current" if empty
+}
+
+// New creates the handlers of the commands for the API served at baseURL
+func New(baseURL string) *Handlers {
+	return &Handlers{Client: client.New(baseURL), Year: "current"}
+}
+
+// Handle answers a command by name with its options. Failures are answered with a message only the user sees.
+func (h *Handlers) Handle(ctx context.Context, name string, options map[string]string) Response {
+	var response Response
+	var err error
+	switch name {
+	case "unit":
+		response, err = h.Unit(ctx, options["code"])
+	case "check":
+		response, err = h.Check(ctx, options["code"], options["completed"], options["course"])
+	case "search":
+		response, err = h.Search(ctx, options["question"])
+	default:
+		err = fmt.Errorf("%w %q", errUnknownCommand, name)
+	}
+	if err != nil {
+		return errorResponse(err)
+	}
+	return response
+}
+
+// Unit answers with an embed of a unit
+func (h *Handlers) Unit(ctx context.Context, code string) (Response, error) {
+	unit, err := h.Client.GetUnit(ctx, h.year(), normaliseCode(code))
+	if err != nil {
+		return Response{}, err
+	}
+	return Response{Embeds: []Embed{UnitEmbed(unit)}}, nil
+}
+
+// Check answers whether the completed units, separated by spaces or commas, meet the requisites of a unit
+func (h *Handlers) Check(ctx context.Context, code string, completed string, course string) (Response, error) {
+	code = normaliseCode(code)
+	var completedUnits []common.Unit
+	for _, completedCode := range strings.FieldsFunc(completed, func(r rune) bool { return r == ',' || r == ' ' }) {
+		completedUnits = append(completedUnits, common.Unit{Code: normaliseCode(completedCode)})
+	}
+
+	check, err := h.Client.CheckRequisitesForCourse(ctx, h.year(), code, completedUnits, normaliseCode(course))
+	if err != nil {
+		return Response{}, err
+	}
+
+	embed := Embed{Color: embedColour}
+	if check.Met {
+		embed.Title = "You meet the requisites for " + code
+	} else {
+		embed.Title = "You don't meet the requisites for " + code + " yet"
+		embed.Description = truncate("- "+strings.Join(check.Unmet, "\n- "), maxDescriptionLength)
+	}
+	if len(check.Advisories) > 0 {
+		mentions := make([]string, 0, len(check.Advisories))
+		for _, advisory := range check.Advisories {
+			mentions = append(mentions, advisory.UnitCode)
+		}
+		embed.Fields = append(embed.Fields, EmbedField{Name: "Also worth checking", Value: truncate(strings.Join(mentions, ", "), maxFieldValueLength)})
+	}
+	if rules := strings.TrimSpace(check.Warning); rules != "" {
+		embed.Fields = append(embed.Fields, EmbedField{Name: "Enrolment rules", Value: truncate(rules, maxFieldValueLength)})
+	}
+	return Response{Embeds: []Embed{embed}}, nil
+}
+
+// Search answers a question in natural language, such as "units about machine learning"
+func (h *Handlers) Search(ctx context.Context, question string) (Response, error) {
+	answer, err := h.Client.Ask(ctx, h.year(), question)
+	if err != nil {
+		return Response{}, err
+	}
+	return Response{Content: truncate(answer.Answer, maxContentLength)}, nil
+}
+
+// UnitEmbed renders a unit as an embed with its synopsis, credit points, offerings and requisites
+func UnitEmbed(unit units.UnitData) Embed {
+	synopsis := unit.ShortSynopsis
+	if synopsis == "" {
+		synopsis = unit.Synopsis
+	}
+	embed := Embed{
+		Title:       truncate(strings.TrimSpace(unit.Code+" "+unit.Title), maxTitleLength),
+		Description: truncate(synopsis, maxDescriptionLength),
+		URL:         unit.Link,
+		Color:       embedColour,
+		Fields: []EmbedField{
+			{Name: "Credit points", Value: fmt.Sprint(unit.CreditPoints), Inline: true},
+		},
+		Footer: &EmbedFooter{Text: fmt.Sprintf("%d handbook", unit.CurrentYear)},
+	}
+	if unit.UnitLevel != "" {
+		embed.Fields = append(embed.Fields, EmbedField{Name: "Level", Value: unit.UnitLevel, Inline: true})
+	}
+	if unit.Faculty != "" {
+		embed.Fields = append(embed.Fields, EmbedField{Name: "Faculty", Value: unit.Faculty, Inline: true})
+	}
+
+	offerings := make([]string, 0, len(unit.UnitOfferings))
+	for _, offering := range unit.UnitOfferings {
+		offerings = append(offerings, strings.TrimSpace(offering.Semester+" "+offering.Location+" "+offering.AttendanceMode))
+	}
+	if len(offerings) == 0 && unit.OfferingStatus != nil {
+		offerings = append(offerings, unit.OfferingStatus.Message)
+	} else if len(offerings) == 0 {
+		offerings = append(offerings, "Not offered this year")
+	}
+	embed.Fields = append(embed.Fields, EmbedField{Name: "Offerings", Value: truncate(strings.Join(offerings, "\n"), maxFieldValueLength)})
+
+	for _, requisite := range units.RenderRequisites(unit) {
+		embed.Fields = append(embed.Fields, EmbedField{Name: requisite.RequisiteType, Value: truncate(requisite.Text, maxFieldValueLength)})
+	}
+	return embed
+}
+
+// errorResponse explains a failed command to the user who sent it
+func errorResponse(err error) Response {
+	message := "The handbook is unavailable right now, try again later."
+	var apiError *client.APIError
+	if errors.As(err, &apiError) {
+		switch apiError.StatusCode {
+		case http.StatusBadRequest, http.StatusNotFound, http.StatusGone, http.StatusMultipleChoices:
+			message = apiError.Message
+		}
+	} else if errors.Is(err, errUnknownCommand) {
+		message = err.Error()
+	}
+	return Response{Content: message, Flags: flagEphemeral}
+}
+
+// year is the handbook year of the commands
+func (h *Handlers) year() string {
+	if h.Year == "" {
+		return "current"
+	}
+	return h.Year
+}
+
+// normaliseCode upper-cases a code typed by a user
+func normaliseCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// truncate shortens text to Discord's limit of a field, ending it with an ellipsis
+func truncate(text string, limit int) string {
+	if utf8.RuneCountInString(text) <= limit {
+		return text
+	}
+	runes := []rune(text)
+	return string(runes[:limit-1]) + "…"
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"handbook-scraper/server"
 	"handbook-scraper/utils"
+	"os"
 )
 
 func main() {
@@ -12,5 +13,23 @@ func main() {
 		fmt.Printf("Warning: %v\n", err)
 	}
 
+	// The dump command renders the stored data to static files instead of serving it
+	if len(os.Args) > 1 && os.Args[1] == "dump" {
+		if err := server.RunDump(os.Args[2:]); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// The loadtest command measures the performance of a running server
+	if len(os.Args) > 1 && os.Args[1] == "loadtest" {
+		if err := server.RunLoadTest(os.Args[2:]); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	server.StartServer()
 }
@@ -0,0 +1,227 @@
+package planner
+
+import (
+	"strings"
+
+	"handbook-scraper/scrapers/common"
+)
+
+// defaultUnitCreditPoints is used for curriculum items which do not list their credit points
+const defaultUnitCreditPoints = 6
+
+// CurriculumAudit holds the result of evaluating completed units against a curriculum
+type CurriculumAudit struct {
+	Code                   string             `json:"code"`
+	Title                  string             `json:"title"`
+	TotalCreditPoints      int                `json:"total_credit_points"`
+	CreditPointsEarned     int                `json:"credit_points_earned"`
+	Satisfied              bool               `json:"satisfied"`
+	RemainingRequiredUnits []string           `json:"remaining_required_units"` // Units every student must still complete
+	Research               *ResearchAudit     `json:"research,omitempty"`       // Honours and research requirements, if the curriculum has any
+	Parts                  []RequirementAudit `json:"parts"`
+}
+
+// ResearchAudit holds the progress of the honours and research requirements of a curriculum,
+// which usually have entry conditions such as a minimum WAM on top of their units
+type ResearchAudit struct {
+	Satisfied      bool     `json:"satisfied"`
+	Requirements   []string `json:"requirements"`    // Titles of the research parts and containers
+	RemainingUnits []string `json:"remaining_units"` // Research units every student must still complete
+}
+
+// RequirementAudit holds the result of evaluating a single part or container
+type RequirementAudit struct {
+	ID                    string             `json:"id"`
+	Title                 string             `json:"title"`
+	Connector             string             `json:"connector"`
+	CreditPointsRequired  int                `json:"credit_points_required"`
+	CreditPointsEarned    int                `json:"credit_points_earned"`
+	CreditPointsRemaining int                `json:"credit_points_remaining"`
+	Satisfied             bool               `json:"satisfied"`
+	Research              bool               `json:"research,omitempty"` // An honours or research requirement
+	CompletedUnits        []string           `json:"completed_units"`
+	RemainingUnits        []string           `json:"remaining_units"` // Units which must all be completed
+	Options               []string           `json:"options"`         // Units to choose from to make up the remaining credit points
+	Containers            []RequirementAudit `json:"containers,omitempty"`
+}
+
+// AuditCurriculum evaluates the completed units against every part of a curriculum.
+// Requirements are identified by the stable IDs of their parts and containers, assigned here if a stored curriculum lacks them.
+func AuditCurriculum(code string, title string, curriculum common.Curriculum, completed []common.Unit) CurriculumAudit {
+	curriculum.AssignIDs()
+	audit := CurriculumAudit{
+		Code:                   code,
+		Title:                  title,
+		TotalCreditPoints:      curriculum.TotalCreditPoints,
+		Satisfied:              true,
+		RemainingRequiredUnits: []string{},
+		Parts:                  []RequirementAudit{},
+	}
+
+	completedCodes := map[string]bool{}
+	for _, unit := range completed {
+		completedCodes[strings.ToUpper(unit.Code)] = true
+	}
+
+	for _, part := range curriculum.Parts {
+		partAudit := auditRequirement(part.ID, part.Title, part.Connector, part.CreditPointsRequired, part.AcademicItems, part.Containers, completedCodes)
+		audit.Parts = append(audit.Parts, partAudit)
+		audit.CreditPointsEarned += partAudit.CreditPointsEarned
+		audit.Satisfied = audit.Satisfied && partAudit.Satisfied
+		audit.RemainingRequiredUnits = append(audit.RemainingRequiredUnits, requiredUnits(partAudit)...)
+	}
+
+	if audit.TotalCreditPoints > 0 && audit.CreditPointsEarned < audit.TotalCreditPoints {
+		audit.Satisfied = false
+	}
+	audit.Research = auditResearch(audit.Parts)
+	return audit
+}
+
+// auditResearch collects the outermost research requirements of an audit, or returns nil if there are none
+func auditResearch(parts []RequirementAudit) *ResearchAudit {
+	research := ResearchAudit{Satisfied: true, Requirements: []string{}, RemainingUnits: []string{}}
+	var collect func(audits []RequirementAudit)
+	collect = func(audits []RequirementAudit) {
+		for _, audit := range audits {
+			if !audit.Research {
+				collect(audit.Containers)
+				continue
+			}
+			research.Requirements = append(research.Requirements, audit.Title)
+			research.Satisfied = research.Satisfied && audit.Satisfied
+			research.RemainingUnits = append(research.RemainingUnits, requiredUnits(audit)...)
+		}
+	}
+	collect(parts)
+
+	if len(research.Requirements) == 0 {
+		return nil
+	}
+	return &research
+}
+
+// auditRequirement evaluates a part or container.
+// A requirement with academic items is met once its credit points are earned, or once every item is completed
+// if the items are all compulsory. A requirement with containers is met once all (AND) or any (OR) of them are met.
+func auditRequirement(id string, title string, connector string, creditPointsRequired int, items []common.AcademicItem, containers []common.Container, completed map[string]bool) RequirementAudit {
+	audit := RequirementAudit{
+		ID:                   id,
+		Title:                title,
+		Connector:            connector,
+		CreditPointsRequired: creditPointsRequired,
+		Research:             common.IsResearchTitle(title),
+		CompletedUnits:       []string{},
+		RemainingUnits:       []string{},
+		Options:              []string{},
+	}
+
+	if len(containers) > 0 {
+		for _, container := range containers {
+			audit.Containers = append(audit.Containers, auditRequirement(container.ID, container.Title, container.Connector, container.CreditPointsRequired, container.AcademicItems, container.Containers, completed))
+		}
+		auditContainers(&audit)
+		return audit
+	}
+
+	totalCreditPoints := 0
+	researchUnit := false
+	for _, item := range items {
+		creditPoints := itemCreditPoints(item)
+		totalCreditPoints += creditPoints
+		researchUnit = researchUnit || common.IsResearchTitle(item.Title)
+
+		if completed[strings.ToUpper(item.Code)] {
+			audit.CompletedUnits = append(audit.CompletedUnits, item.Code)
+			audit.CreditPointsEarned += creditPoints
+		} else {
+			audit.Options = append(audit.Options, item.Code)
+		}
+	}
+
+	// Every item is compulsory when they are joined by AND and make up the required credit points
+	compulsory := connector != "OR" && (audit.CreditPointsRequired == 0 || audit.CreditPointsRequired >= totalCreditPoints)
+	// A compulsory thesis makes the requirement a research one, while an optional one is only an elective
+	audit.Research = audit.Research || (compulsory && researchUnit)
+	if compulsory {
+		audit.RemainingUnits = audit.Options
+		audit.Options = []string{}
+		if audit.CreditPointsRequired == 0 {
+			audit.CreditPointsRequired = totalCreditPoints
+		}
+		audit.Satisfied = len(audit.RemainingUnits) == 0
+	} else {
+		if audit.CreditPointsRequired == 0 && len(items) > 0 {
+			audit.CreditPointsRequired = itemCreditPoints(items[0])
+		}
+		audit.Satisfied = audit.CreditPointsEarned >= audit.CreditPointsRequired
+	}
+
+	audit.CreditPointsRemaining = max(audit.CreditPointsRequired-audit.CreditPointsEarned, 0)
+	if audit.Satisfied {
+		audit.Options = []string{}
+	}
+	return audit
+}
+
+// auditContainers combines the results of child containers into their parent
+func auditContainers(audit *RequirementAudit) {
+	if audit.Connector == "OR" {
+		// The best progressing option counts towards the parent
+		best := audit.Containers[0]
+		for _, child := range audit.Containers[1:] {
+			if child.Satisfied != best.Satisfied {
+				if child.Satisfied {
+					best = child
+				}
+			} else if child.CreditPointsEarned > best.CreditPointsEarned {
+				best = child
+			}
+		}
+		audit.CreditPointsEarned = best.CreditPointsEarned
+		audit.Satisfied = best.Satisfied
+		if audit.CreditPointsRequired == 0 {
+			audit.CreditPointsRequired = best.CreditPointsRequired
+		}
+	} else {
+		requiredSum := 0
+		audit.Satisfied = true
+		for _, child := range audit.Containers {
+			audit.CreditPointsEarned += child.CreditPointsEarned
+			requiredSum += child.CreditPointsRequired
+			audit.Satisfied = audit.Satisfied && child.Satisfied
+		}
+		if audit.CreditPointsRequired == 0 {
+			audit.CreditPointsRequired = requiredSum
+		}
+	}
+
+	if audit.CreditPointsEarned < audit.CreditPointsRequired {
+		audit.Satisfied = false
+	}
+	audit.CreditPointsRemaining = max(audit.CreditPointsRequired-audit.CreditPointsEarned, 0)
+}
+
+// requiredUnits collects the units every student must still complete within a requirement.
+// Units inside alternatives (OR containers) are not required, since another option can be taken instead.
+func requiredUnits(audit RequirementAudit) []string {
+	if audit.Satisfied {
+		return nil
+	}
+
+	units := append([]string{}, audit.RemainingUnits...)
+	if audit.Connector != "OR" {
+		for _, child := range audit.Containers {
+			units = append(units, requiredUnits(child)...)
+		}
+	}
+	return units
+}
+
+// itemCreditPoints returns the credit points of a curriculum item
+func itemCreditPoints(item common.AcademicItem) int {
+	if item.CreditPoints == 0 {
+		return defaultUnitCreditPoints
+	}
+	return item.CreditPoints
+}
@@ -0,0 +1,131 @@
+package planner
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"handbook-scraper/scrapers/units"
+)
+
+// OfferingClash is a pair of units planned in the same teaching period whose offerings make taking both impractical
+type OfferingClash struct {
+	Year           int      `json:"year"`
+	TeachingPeriod string   `json:"teaching_period"`
+	Units          []string `json:"units"`
+	Reason         string   `json:"reason"` // campus or intensive
+	Message        string   `json:"message"`
+}
+
+// remoteAttendance marks attendance modes which don't need a student on campus
+var remoteAttendance = []string{"ONLINE", "FLEXIBLE", "OFF-CAMPUS"}
+
+// CheckClashes finds pairs of units planned in the same teaching period which are only offered at different campuses,
+// or are both only offered as intensives, whose blocks are likely to overlap.
+// Units which cannot be looked up or are not offered in the period are skipped, since Validate already warns about them.
+func CheckClashes(plan Plan, lookup UnitLookup) []OfferingClash {
+	clashes := []OfferingClash{}
+	for i, a := range plan.Entries {
+		aOfferings := entryOfferings(a, lookup)
+		if len(aOfferings) == 0 {
+			continue
+		}
+
+		for _, b := range plan.Entries[i+1:] {
+			if a.Year != b.Year || a.TeachingPeriod != b.TeachingPeriod {
+				continue
+			}
+			bOfferings := entryOfferings(b, lookup)
+			if len(bOfferings) == 0 {
+				continue
+			}
+
+			clash := OfferingClash{Year: a.Year, TeachingPeriod: a.TeachingPeriod, Units: []string{a.Code, b.Code}}
+			aCampuses, bCampuses := attendedCampuses(aOfferings), attendedCampuses(bOfferings)
+			switch {
+			case aCampuses != nil && bCampuses != nil && !sharesCampus(aCampuses, bCampuses):
+				clash.Reason = "campus"
+				clash.Message = fmt.Sprintf("%s is only offered at %s and %s only at %s in %s %d",
+					a.Code, joinCampuses(aCampuses), b.Code, joinCampuses(bCampuses), a.TeachingPeriod, a.Year)
+			case intensiveOnly(aOfferings) && intensiveOnly(bOfferings):
+				clash.Reason = "intensive"
+				clash.Message = fmt.Sprintf("%s and %s are both only offered as intensives in %s %d, which may overlap",
+					a.Code, b.Code, a.TeachingPeriod, a.Year)
+			default:
+				continue
+			}
+			clashes = append(clashes, clash)
+		}
+	}
+	return clashes
+}
+
+// entryOfferings returns the offerings of a planned unit in its teaching period
+func entryOfferings(entry Entry, lookup UnitLookup) []units.UnitOffering {
+	unitData, err := lookup(entry.Code)
+	if err != nil {
+		return nil
+	}
+
+	var offerings []units.UnitOffering
+	for _, offering := range unitData.UnitOfferings {
+		period, _, found := strings.Cut(offering.DisplayName, "-")
+		if found && strings.EqualFold(period, entry.TeachingPeriod) {
+			offerings = append(offerings, offering)
+		}
+	}
+	return offerings
+}
+
+// attendedCampuses returns the campuses a unit can be attended at, keyed by their upper-case name.
+// It returns nil if an offering can be taken without attending a campus, such as an online offering.
+func attendedCampuses(offerings []units.UnitOffering) map[string]string {
+	campuses := map[string]string{}
+	for _, offering := range offerings {
+		mode := strings.ToUpper(offering.AttendanceMode + " " + offering.DisplayName)
+		for _, remote := range remoteAttendance {
+			if strings.Contains(mode, remote) {
+				return nil
+			}
+		}
+
+		campus := strings.TrimSpace(offering.Location)
+		if campus == "" {
+			// Without a location, the unit cannot be shown to clash
+			return nil
+		}
+		campuses[strings.ToUpper(campus)] = campus
+	}
+	return campuses
+}
+
+// sharesCampus reports whether two units can be attended at the same campus
+func sharesCampus(a map[string]string, b map[string]string) bool {
+	for campus := range a {
+		if _, ok := b[campus]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// joinCampuses lists campuses in a message
+func joinCampuses(campuses map[string]string) string {
+	names := make([]string, 0, len(campuses))
+	for _, campus := range campuses {
+		names = append(names, campus)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// intensiveOnly reports whether every offering of a unit is taught intensively, in blocks rather than across the period
+func intensiveOnly(offerings []units.UnitOffering) bool {
+	for _, offering := range offerings {
+		mode := strings.ToUpper(offering.AttendanceMode + " " + offering.DisplayName)
+		if !strings.Contains(mode, "INTENSIVE") && !strings.Contains(mode, "IMMERSIVE") {
+			return false
+		}
+	}
+	return true
+}
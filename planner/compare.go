@@ -0,0 +1,124 @@
+package planner
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"handbook-scraper/scrapers/units"
+)
+
+// PlanSummary summarises a candidate plan for comparison
+type PlanSummary struct {
+	Name            string         `json:"name,omitempty"`
+	Duration        *DurationCheck `json:"duration"`
+	Cost            CostEstimate   `json:"cost"`
+	Loads           []PeriodLoad   `json:"loads"` // Workload of each teaching period
+	CreditPoints    int            `json:"credit_points"`
+	UnmetRequisites []string       `json:"unmet_requisites"` // Planned units whose requisites are not met
+	UniqueUnits     []string       `json:"unique_units"`     // Units planned or completed in this plan only
+}
+
+// PlanComparison compares two candidate plans, such as plans with different majors
+type PlanComparison struct {
+	A                 PlanSummary `json:"a"`
+	B                 PlanSummary `json:"b"`
+	SharedUnits       []string    `json:"shared_units"`
+	EarlierCompletion string      `json:"earlier_completion"` // a, b, or same
+	Cheaper           string      `json:"cheaper"`            // a, b, or same
+	CostDifference    float64     `json:"cost_difference"`    // Cost of b minus the cost of a
+}
+
+// SummarisePlan works out the cost, workload and requisite problems of a plan, with its duration checked by the caller
+// since the maximum duration and completion date depend on the course and calendar.
+// Units completed under a code equivalents looks up count as the unit itself.
+func SummarisePlan(name string, plan Plan, lookup UnitLookup, equivalents units.Equivalents, rules LoadRules, rates ContributionRates, duration *DurationCheck) PlanSummary {
+	summary := PlanSummary{
+		Name:            name,
+		Duration:        duration,
+		Cost:            EstimateCost(plan, lookup, rates),
+		Loads:           CheckLoad(plan, lookup, rules),
+		UnmetRequisites: []string{},
+		UniqueUnits:     []string{},
+	}
+	for _, load := range summary.Loads {
+		summary.CreditPoints += load.CreditPoints
+	}
+	for _, result := range Validate(plan, lookup, equivalents) {
+		if !result.MetRequisites {
+			summary.UnmetRequisites = append(summary.UnmetRequisites, result.Code)
+		}
+	}
+	return summary
+}
+
+// ComparePlans compares the summaries of two plans, listing the units unique to each
+func ComparePlans(planA Plan, a PlanSummary, planB Plan, b PlanSummary) PlanComparison {
+	unitsA, unitsB := planUnits(planA), planUnits(planB)
+	comparison := PlanComparison{SharedUnits: []string{}}
+	for code := range unitsA {
+		if unitsB[code] {
+			comparison.SharedUnits = append(comparison.SharedUnits, code)
+		} else {
+			a.UniqueUnits = append(a.UniqueUnits, code)
+		}
+	}
+	for code := range unitsB {
+		if !unitsA[code] {
+			b.UniqueUnits = append(b.UniqueUnits, code)
+		}
+	}
+	sort.Strings(comparison.SharedUnits)
+	sort.Strings(a.UniqueUnits)
+	sort.Strings(b.UniqueUnits)
+
+	comparison.A, comparison.B = a, b
+	comparison.EarlierCompletion = earlierCompletion(a.Duration, b.Duration)
+	comparison.CostDifference = math.Round((b.Cost.Total-a.Cost.Total)*100) / 100
+	switch {
+	case comparison.CostDifference > 0:
+		comparison.Cheaper = "a"
+	case comparison.CostDifference < 0:
+		comparison.Cheaper = "b"
+	default:
+		comparison.Cheaper = "same"
+	}
+	return comparison
+}
+
+// planUnits returns the codes of the units completed or planned in a plan
+func planUnits(plan Plan) map[string]bool {
+	codes := map[string]bool{}
+	for _, unit := range plan.Completed {
+		codes[strings.ToUpper(unit.Code)] = true
+	}
+	for _, entry := range plan.Entries {
+		codes[strings.ToUpper(entry.Code)] = true
+	}
+	return codes
+}
+
+// earlierCompletion reports which plan is expected to be completed first, where a plan with nothing planned is never first
+func earlierCompletion(a *DurationCheck, b *DurationCheck) string {
+	if a == nil || b == nil {
+		switch {
+		case a != nil:
+			return "a"
+		case b != nil:
+			return "b"
+		default:
+			return "same"
+		}
+	}
+
+	lastA := Entry{TeachingPeriod: a.ExpectedCompletionPeriod, Year: a.ExpectedCompletionYear}
+	lastB := Entry{TeachingPeriod: b.ExpectedCompletionPeriod, Year: b.ExpectedCompletionYear}
+	switch {
+	case before(lastA, lastB):
+		return "a"
+	case before(lastB, lastA):
+		return "b"
+	default:
+		return "same"
+	}
+}
@@ -0,0 +1,96 @@
+package planner
+
+import (
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"handbook-scraper/scrapers/units"
+	"handbook-scraper/utils/log"
+)
+
+// fullTimeCreditPoints are the credit points of a full-time year, one EFTSL
+const fullTimeCreditPoints = 48
+
+// defaultContributionRates are the approximate annual student contributions of Commonwealth supported places
+// in each funding cluster band, used unless STUDENT_CONTRIBUTION_RATES is set
+var defaultContributionRates = map[int]float64{1: 4445, 2: 8948, 3: 11443, 4: 16992}
+
+// bandPattern matches the number of a funding cluster band
+var bandPattern = regexp.MustCompile(`[0-9]+`)
+
+// ContributionRates are the annual student contributions of a full-time load in each band
+type ContributionRates map[int]float64
+
+// ContributionRatesFromEnv reads the contribution rates from STUDENT_CONTRIBUTION_RATES, a comma-separated list of
+// band:amount, falling back to the default rate of each band it does not list
+func ContributionRatesFromEnv() ContributionRates {
+	rates := ContributionRates{}
+	for band, rate := range defaultContributionRates {
+		rates[band] = rate
+	}
+	for _, entry := range strings.Split(os.Getenv("STUDENT_CONTRIBUTION_RATES"), ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		rawBand, rawRate, _ := strings.Cut(strings.TrimSpace(entry), ":")
+		band, errBand := strconv.Atoi(rawBand)
+		rate, errRate := strconv.ParseFloat(rawRate, 64)
+		if errBand != nil || errRate != nil || rate < 0 {
+			log.Warnf("Invalid STUDENT_CONTRIBUTION_RATES entry %q, expected band:amount", entry)
+			continue
+		}
+		rates[band] = rate
+	}
+	return rates
+}
+
+// CostEstimate estimates the student contributions of the units planned in a plan
+type CostEstimate struct {
+	Total    float64  `json:"total"`
+	Uncosted []string `json:"uncosted"` // Units which could not be looked up or have no known band
+}
+
+// EstimateCost sums the student contribution of each planned unit, its EFTSL times the annual rate of its highest band.
+// Completed units are not counted. The estimate ignores indexation and fee-paying places.
+func EstimateCost(plan Plan, lookup UnitLookup, rates ContributionRates) CostEstimate {
+	estimate := CostEstimate{Uncosted: []string{}}
+	for _, entry := range plan.Entries {
+		unitData, err := lookup(entry.Code)
+		if err != nil {
+			estimate.Uncosted = append(estimate.Uncosted, entry.Code)
+			continue
+		}
+		rate, ok := rates[bandNumber(unitData.HighestSCABand)]
+		if !ok {
+			estimate.Uncosted = append(estimate.Uncosted, entry.Code)
+			continue
+		}
+		estimate.Total += unitEFTSL(unitData) * rate
+	}
+
+	estimate.Total = math.Round(estimate.Total*100) / 100
+	sort.Strings(estimate.Uncosted)
+	return estimate
+}
+
+// bandNumber returns the number of a funding cluster band, such as "Band 2", or 0 if it has none
+func bandNumber(band string) int {
+	number, _ := strconv.Atoi(bandPattern.FindString(band))
+	return number
+}
+
+// unitEFTSL returns the equivalent full-time student load of a unit, from its credit points if the handbook omits it
+func unitEFTSL(unitData units.UnitData) float64 {
+	if unitData.EFTSL > 0 {
+		return float64(unitData.EFTSL)
+	}
+	creditPoints := unitData.CreditPoints
+	if creditPoints == 0 {
+		creditPoints = defaultUnitCreditPoints
+	}
+	return float64(creditPoints) / fullTimeCreditPoints
+}
@@ -0,0 +1,196 @@
+package planner
+
+import (
+	"sort"
+	"strings"
+
+	"handbook-scraper/scrapers/common"
+)
+
+// Categories of the parts of a course, used to group credit points
+const (
+	CreditCore          = "core"
+	CreditMajor         = "major"
+	CreditElectives     = "electives"
+	CreditFreeElectives = "free_electives"
+)
+
+// CreditCount holds the credit points required, completed and planned for a part or category
+type CreditCount struct {
+	Required  int `json:"required"`
+	Completed int `json:"completed"`
+	Planned   int `json:"planned"`
+	Remaining int `json:"remaining"` // Still to be completed or planned
+}
+
+// PartCredits holds the credit points attributed to a part of a curriculum
+type PartCredits struct {
+	ID       string `json:"id"`
+	Title    string `json:"title"`
+	Category string `json:"category"`
+	CreditCount
+	CompletedUnits []string `json:"completed_units"`
+	PlannedUnits   []string `json:"planned_units"`
+}
+
+// CreditBreakdown attributes completed and planned units to the parts of a curriculum
+type CreditBreakdown struct {
+	Code  string `json:"code"`
+	Title string `json:"title"`
+	CreditCount
+	Specialisations []Specialisation       `json:"specialisations"`
+	Categories      map[string]CreditCount `json:"categories"`
+	Parts           []PartCredits          `json:"parts"`
+	Unattributed    []string               `json:"unattributed"` // Units which count towards no part with credit points remaining
+}
+
+// CountCredits attributes each unit to the first part listing it with credit points remaining, then to the
+// free elective parts, counting completed units before planned ones. Unlike an audit, no requirement is evaluated.
+// The units of chosen specialisations count towards the parts listing them, see ResolveSpecialisations.
+func CountCredits(code string, title string, curriculum common.Curriculum, chosen map[string]Specialisation, completed []common.Unit, planned []common.Unit) CreditBreakdown {
+	breakdown := CreditBreakdown{
+		Code:            code,
+		Title:           title,
+		Specialisations: []Specialisation{},
+		Categories:      map[string]CreditCount{},
+		Parts:           []PartCredits{},
+		Unattributed:    []string{},
+	}
+
+	for _, specialisation := range chosen {
+		breakdown.Specialisations = append(breakdown.Specialisations, specialisation)
+	}
+	sort.Slice(breakdown.Specialisations, func(i, j int) bool {
+		return breakdown.Specialisations[i].Code < breakdown.Specialisations[j].Code
+	})
+
+	curriculum.AssignIDs()
+	used := map[string]bool{}
+	listed := make([]map[string]int, len(curriculum.Parts))
+	for i, part := range curriculum.Parts {
+		// Parts are classified before specialisations replace the areas of study they list
+		category := partCategory(part)
+		part = resolvePart(part, chosen, used)
+		listed[i] = map[string]int{}
+		listedCreditPoints := 0
+		for _, item := range containerItems(part.AcademicItems, part.Containers) {
+			if isAreaOfStudy(item) {
+				continue
+			}
+			listed[i][strings.ToUpper(item.Code)] = itemCreditPoints(item)
+			listedCreditPoints += itemCreditPoints(item)
+		}
+
+		required := part.CreditPointsRequired
+		if required == 0 {
+			required = listedCreditPoints
+		}
+		breakdown.Parts = append(breakdown.Parts, PartCredits{
+			ID:             part.ID,
+			Title:          part.Title,
+			Category:       category,
+			CreditCount:    CreditCount{Required: required},
+			CompletedUnits: []string{},
+			PlannedUnits:   []string{},
+		})
+	}
+
+	seen := map[string]bool{}
+	attribute := func(unit common.Unit, isPlanned bool) {
+		unitCode := strings.ToUpper(strings.TrimSpace(unit.Code))
+		if unitCode == "" || seen[unitCode] {
+			return
+		}
+		seen[unitCode] = true
+
+		creditPoints := unit.CreditPoints
+		target := -1
+		for i := range breakdown.Parts {
+			if listedPoints, ok := listed[i][unitCode]; ok && partRoom(breakdown.Parts[i]) > 0 {
+				if creditPoints == 0 {
+					creditPoints = listedPoints
+				}
+				target = i
+				break
+			}
+		}
+		if creditPoints == 0 {
+			creditPoints = defaultUnitCreditPoints
+		}
+		if target < 0 {
+			for i, part := range breakdown.Parts {
+				if part.Category == CreditFreeElectives && partRoom(part) > 0 {
+					target = i
+					break
+				}
+			}
+		}
+		if target < 0 {
+			breakdown.Unattributed = append(breakdown.Unattributed, unitCode)
+			return
+		}
+
+		part := &breakdown.Parts[target]
+		if isPlanned {
+			part.Planned += creditPoints
+			part.PlannedUnits = append(part.PlannedUnits, unitCode)
+		} else {
+			part.Completed += creditPoints
+			part.CompletedUnits = append(part.CompletedUnits, unitCode)
+		}
+	}
+	for _, unit := range completed {
+		attribute(unit, false)
+	}
+	for _, unit := range planned {
+		attribute(unit, true)
+	}
+
+	requiredSum := 0
+	for i := range breakdown.Parts {
+		part := &breakdown.Parts[i]
+		part.Remaining = partRoom(*part)
+
+		category := breakdown.Categories[part.Category]
+		category.Required += part.Required
+		category.Completed += part.Completed
+		category.Planned += part.Planned
+		category.Remaining += part.Remaining
+		breakdown.Categories[part.Category] = category
+
+		requiredSum += part.Required
+		breakdown.Completed += part.Completed
+		breakdown.Planned += part.Planned
+	}
+
+	breakdown.Required = curriculum.TotalCreditPoints
+	if breakdown.Required == 0 {
+		breakdown.Required = requiredSum
+	}
+	breakdown.Remaining = max(breakdown.Required-breakdown.Completed-breakdown.Planned, 0)
+	return breakdown
+}
+
+// partRoom returns the credit points a part can still count
+func partRoom(part PartCredits) int {
+	return max(part.Required-part.Completed-part.Planned, 0)
+}
+
+// partCategory classifies a part of a course by its title, or as a major if it lists areas of study
+func partCategory(part common.Part) string {
+	title := strings.ToLower(part.Title)
+	switch {
+	case strings.Contains(title, "free elective"):
+		return CreditFreeElectives
+	case strings.Contains(title, "elective"):
+		return CreditElectives
+	case strings.Contains(title, "major"), strings.Contains(title, "minor"), strings.Contains(title, "speciali"):
+		return CreditMajor
+	}
+	for _, item := range containerItems(part.AcademicItems, part.Containers) {
+		if isAreaOfStudy(item) {
+			return CreditMajor
+		}
+	}
+	return CreditCore
+}
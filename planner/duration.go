@@ -0,0 +1,73 @@
+package planner
+
+import "fmt"
+
+// DurationCheck compares when a plan is expected to be completed with the maximum duration of its course
+type DurationCheck struct {
+	CommencementYear         int            `json:"commencement_year"`
+	ExpectedCompletionYear   int            `json:"expected_completion_year"`
+	ExpectedCompletionPeriod string         `json:"expected_completion_period"`
+	ExpectedCompletionDate   string         `json:"expected_completion_date,omitempty"` // End of the final teaching period, if its calendar is known
+	YearsPlanned             int            `json:"years_planned"`
+	MaximumDuration          int            `json:"maximum_duration"` // Years, 0 if the course has no maximum
+	ExceedsMaximum           bool           `json:"exceeds_maximum"`
+	Intermissions            int            `json:"intermissions"`           // Intermitted teaching periods before completion, which count towards the duration
+	EmptyPeriods             []Intermission `json:"empty_periods,omitempty"` // Main teaching periods left empty without being declared intermissions
+	Warning                  string         `json:"warning,omitempty"`
+	Warnings                 []string       `json:"warnings,omitempty"` // The warning, followed by one for each empty period
+}
+
+// CheckDuration works out when the plan is expected to be completed, from its last planned teaching period,
+// and warns if that is beyond the maximum duration of the course in years.
+// The course is taken to commence in the plan's commencement year, or the year of its first entry.
+// Intermissions and empty teaching periods before completion count towards the duration, and are warned about
+// when they push completion beyond the maximum. It returns nil if nothing is planned.
+func CheckDuration(plan Plan, maximumDuration int) *DurationCheck {
+	if len(plan.Entries) == 0 {
+		return nil
+	}
+
+	first, last := plan.Entries[0], plan.Entries[0]
+	for _, entry := range plan.Entries[1:] {
+		if before(entry, first) {
+			first = entry
+		}
+		if before(last, entry) {
+			last = entry
+		}
+	}
+
+	check := &DurationCheck{
+		CommencementYear:         plan.CommencementYear,
+		ExpectedCompletionYear:   last.Year,
+		ExpectedCompletionPeriod: last.TeachingPeriod,
+		MaximumDuration:          maximumDuration,
+	}
+	if check.CommencementYear == 0 || check.CommencementYear > first.Year {
+		check.CommencementYear = first.Year
+	}
+	check.YearsPlanned = last.Year - check.CommencementYear + 1
+
+	for _, intermission := range plan.Intermissions {
+		if !before(last, Entry{TeachingPeriod: intermission.TeachingPeriod, Year: intermission.Year}) {
+			check.Intermissions++
+		}
+	}
+	check.EmptyPeriods = EmptyPeriods(plan)
+	for _, empty := range check.EmptyPeriods {
+		check.Warnings = append(check.Warnings, fmt.Sprintf("nothing is planned in %s %d, declare it as an intermission if it is intended", empty.TeachingPeriod, empty.Year))
+	}
+
+	if maximumDuration > 0 && check.YearsPlanned > maximumDuration {
+		check.ExceedsMaximum = true
+		check.Warning = fmt.Sprintf("the plan takes %d years to complete in %s %d, beyond the maximum duration of %d years",
+			check.YearsPlanned, last.TeachingPeriod, last.Year, maximumDuration)
+		if gaps := check.Intermissions + len(check.EmptyPeriods); gaps > 0 {
+			check.Warning += fmt.Sprintf(", including %d intermitted or empty teaching periods", gaps)
+		}
+	}
+	if check.Warning != "" {
+		check.Warnings = append([]string{check.Warning}, check.Warnings...)
+	}
+	return check
+}
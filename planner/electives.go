@@ -0,0 +1,148 @@
+package planner
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"handbook-scraper/scrapers/common"
+	"handbook-scraper/scrapers/units"
+)
+
+// ElectiveSuggestion represents an elective unit the student qualifies for
+type ElectiveSuggestion struct {
+	Code            string   `json:"code"`
+	Title           string   `json:"title"`
+	CreditPoints    int      `json:"credit_points"`
+	OfferedNext     bool     `json:"offered_next"`     // Offered in the requested teaching period
+	TeachingPeriods []string `json:"teaching_periods"` // Normalised teaching periods the unit is offered in
+}
+
+// FindPool returns the units of the part or container with the given ID or title.
+// Units in nested containers are included.
+func FindPool(curriculum common.Curriculum, pool string) ([]common.AcademicItem, bool) {
+	curriculum.AssignIDs()
+	matches := func(id string, title string) bool {
+		return id == strings.TrimSpace(pool) || strings.EqualFold(strings.TrimSpace(title), strings.TrimSpace(pool))
+	}
+
+	var search func(containers []common.Container) ([]common.AcademicItem, bool)
+	search = func(containers []common.Container) ([]common.AcademicItem, bool) {
+		for _, container := range containers {
+			if matches(container.ID, container.Title) {
+				return containerItems(container.AcademicItems, container.Containers), true
+			}
+			if items, found := search(container.Containers); found {
+				return items, true
+			}
+		}
+		return nil, false
+	}
+
+	for _, part := range curriculum.Parts {
+		if matches(part.ID, part.Title) {
+			return containerItems(part.AcademicItems, part.Containers), true
+		}
+		if items, found := search(part.Containers); found {
+			return items, true
+		}
+	}
+	return nil, false
+}
+
+// ElectiveOptions returns the elective units of every unsatisfied requirement in a curriculum audit
+func ElectiveOptions(curriculum common.Curriculum, completed []common.Unit) []common.AcademicItem {
+	options := map[string]bool{}
+	var collect func(audit RequirementAudit)
+	collect = func(audit RequirementAudit) {
+		for _, code := range audit.Options {
+			options[code] = true
+		}
+		for _, child := range audit.Containers {
+			collect(child)
+		}
+	}
+	for _, part := range AuditCurriculum("", "", curriculum, completed).Parts {
+		collect(part)
+	}
+
+	var items []common.AcademicItem
+	for _, part := range curriculum.Parts {
+		for _, item := range containerItems(part.AcademicItems, part.Containers) {
+			if options[item.Code] {
+				items = append(items, item)
+				delete(options, item.Code)
+			}
+		}
+	}
+	return items
+}
+
+// SuggestElectives returns the units in the pool that the student has not completed and already
+// meets the requisites for as a student of the course, counting units completed under a code equivalents looks up.
+// Units offered in the next teaching period are listed first.
+func SuggestElectives(pool []common.AcademicItem, completed []common.Unit, nextPeriod string, course string, lookup UnitLookup, equivalents units.Equivalents) []ElectiveSuggestion {
+	completedCodes := map[string]bool{}
+	for _, unit := range completed {
+		completedCodes[strings.ToUpper(unit.Code)] = true
+	}
+
+	suggestions := []ElectiveSuggestion{}
+	seen := map[string]bool{}
+	for _, item := range pool {
+		// Pools can also list areas of study, which are not units
+		if strings.Contains(item.URL, "/aos/") || strings.Contains(item.URL, "/courses/") {
+			continue
+		}
+
+		code := strings.ToUpper(item.Code)
+		if code == "" || completedCodes[code] || seen[code] {
+			continue
+		}
+		seen[code] = true
+
+		unitData, err := lookup(code)
+		if err != nil {
+			continue
+		}
+
+		met, _, err := units.CheckRequisitesForCourse(unitData, completed, course, equivalents)
+		if err != nil || !met {
+			continue
+		}
+
+		periods := OfferingPeriods(unitData)
+		suggestions = append(suggestions, ElectiveSuggestion{
+			Code:            code,
+			Title:           unitData.Title,
+			CreditPoints:    unitData.CreditPoints,
+			OfferedNext:     offeredIn(unitData, nextPeriod),
+			TeachingPeriods: periods,
+		})
+	}
+
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		if suggestions[i].OfferedNext != suggestions[j].OfferedNext {
+			return suggestions[i].OfferedNext
+		}
+		return suggestions[i].Code < suggestions[j].Code
+	})
+	return suggestions
+}
+
+// NextTeachingPeriod returns the next main semester after the given time
+func NextTeachingPeriod(now time.Time) string {
+	if now.Month() < time.July {
+		return "S2"
+	}
+	return "S1"
+}
+
+// containerItems flattens the academic items of a part or container and its nested containers
+func containerItems(items []common.AcademicItem, containers []common.Container) []common.AcademicItem {
+	result := append([]common.AcademicItem{}, items...)
+	for _, container := range containers {
+		result = append(result, containerItems(container.AcademicItems, container.Containers)...)
+	}
+	return result
+}
@@ -0,0 +1,201 @@
+package planner
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"handbook-scraper/scrapers/units"
+)
+
+// mainPeriods are the teaching periods a full-time student is expected to study in each year
+var mainPeriods = []string{"S1", "S2"}
+
+// trimesterPeriods are the main teaching periods of courses run in trimesters
+var trimesterPeriods = []string{"T1", "T2", "T3"}
+
+// Intermit marks a teaching period as an intermission.
+// It returns an error if the period is unknown, already an intermission, or has units planned in it.
+func (p *Plan) Intermit(intermission Intermission) error {
+	intermission.TeachingPeriod = strings.ToUpper(strings.TrimSpace(intermission.TeachingPeriod))
+
+	if intermission.TeachingPeriod == "" || intermission.Year == 0 {
+		return fmt.Errorf("teaching_period and year are required")
+	}
+	if _, ok := periodOrder[intermission.TeachingPeriod]; !ok {
+		return fmt.Errorf("unknown teaching period: %s", intermission.TeachingPeriod)
+	}
+	if p.intermitted(intermission.TeachingPeriod, intermission.Year) {
+		return fmt.Errorf("%s %d is already an intermission", intermission.TeachingPeriod, intermission.Year)
+	}
+	for _, entry := range p.Entries {
+		if entry.TeachingPeriod == intermission.TeachingPeriod && entry.Year == intermission.Year {
+			return fmt.Errorf("%s is planned in %s %d, remove it first", entry.Code, entry.TeachingPeriod, entry.Year)
+		}
+	}
+
+	p.Intermissions = append(p.Intermissions, intermission)
+	return nil
+}
+
+// Resume removes an intermission from the plan, so units can be planned in its teaching period again.
+// It returns false if the period is not an intermission.
+func (p *Plan) Resume(teachingPeriod string, year int) bool {
+	for i, intermission := range p.Intermissions {
+		if strings.EqualFold(intermission.TeachingPeriod, teachingPeriod) && intermission.Year == year {
+			p.Intermissions = append(p.Intermissions[:i], p.Intermissions[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// intermitted reports whether a teaching period is an intermission
+func (p *Plan) intermitted(teachingPeriod string, year int) bool {
+	for _, intermission := range p.Intermissions {
+		if strings.EqualFold(intermission.TeachingPeriod, teachingPeriod) && intermission.Year == year {
+			return true
+		}
+	}
+	return false
+}
+
+// EmptyPeriods returns the main teaching periods between the first and last planned periods which have nothing planned
+// and are not declared as intermissions. Plans using trimesters are checked against trimesters instead of semesters.
+func EmptyPeriods(plan Plan) []Intermission {
+	if len(plan.Entries) == 0 {
+		return nil
+	}
+
+	periods := mainPeriods
+	planned := map[Intermission]bool{}
+	first, last := plan.Entries[0], plan.Entries[0]
+	for _, entry := range plan.Entries {
+		planned[Intermission{TeachingPeriod: entry.TeachingPeriod, Year: entry.Year}] = true
+		if entry.TeachingPeriod == "FY" {
+			// Full year units are studied across both semesters
+			planned[Intermission{TeachingPeriod: "S1", Year: entry.Year}] = true
+			planned[Intermission{TeachingPeriod: "S2", Year: entry.Year}] = true
+		}
+		if strings.HasPrefix(entry.TeachingPeriod, "T") {
+			periods = trimesterPeriods
+		}
+		if before(entry, first) {
+			first = entry
+		}
+		if before(last, entry) {
+			last = entry
+		}
+	}
+
+	var empty []Intermission
+	for year := first.Year; year <= last.Year; year++ {
+		for _, period := range periods {
+			candidate := Intermission{TeachingPeriod: period, Year: year}
+			entry := Entry{TeachingPeriod: period, Year: year}
+			if before(entry, first) || before(last, entry) || planned[candidate] || plan.intermitted(period, year) {
+				continue
+			}
+			empty = append(empty, candidate)
+		}
+	}
+	return empty
+}
+
+// timeLimitPattern matches enrolment rules requiring prerequisites to be completed recently,
+// such as "must have been completed within the last 3 years"
+var timeLimitPattern = regexp.MustCompile(`(?i)within\s+the\s+(?:last|previous|past|preceding)\s+(\d+|one|two|three|four|five|six|seven|eight|nine|ten)\s+years?`)
+
+// numberWords converts the numbers written out in enrolment rules
+var numberWords = map[string]int{
+	"one": 1, "two": 2, "three": 3, "four": 4, "five": 5,
+	"six": 6, "seven": 7, "eight": 8, "nine": 9, "ten": 10,
+}
+
+// prerequisiteTimeLimit returns how many years before a unit its prerequisites must have been completed,
+// or 0 if its enrolment rules and synopsis do not limit it
+func prerequisiteTimeLimit(unitData units.UnitData) int {
+	texts := []string{unitData.Synopsis}
+	for _, rule := range unitData.EnrolmentRules {
+		texts = append(texts, rule.Description)
+	}
+
+	for _, text := range texts {
+		match := timeLimitPattern.FindStringSubmatch(text)
+		if match == nil {
+			continue
+		}
+		if years, err := strconv.Atoi(match[1]); err == nil {
+			return years
+		}
+		return numberWords[strings.ToLower(match[1])]
+	}
+	return 0
+}
+
+// prerequisiteCodes lists the units named in the prerequisites of a unit
+func prerequisiteCodes(unitData units.UnitData) map[string]bool {
+	codes := map[string]bool{}
+	var collect func(containers []units.CompressedContainer)
+	collect = func(containers []units.CompressedContainer) {
+		for _, container := range containers {
+			for _, unit := range container.Units {
+				codes[strings.ToUpper(unit.UnitCode)] = true
+			}
+			collect(container.Containers)
+		}
+	}
+	for _, requisite := range unitData.Requisites {
+		if requisite.RequisiteType == "Prerequisite" {
+			collect(requisite.Containers)
+		}
+	}
+	return codes
+}
+
+// CheckTimeLimits warns about units planned more years after one of their prerequisites than their enrolment rules allow,
+// such as when an intermission separates them. Warnings are keyed by the code of the unit they concern.
+// Completed units have no recorded teaching period, so only prerequisites planned in the plan are checked.
+func CheckTimeLimits(plan Plan, lookup UnitLookup) map[string][]string {
+	warnings := map[string][]string{}
+	for _, entry := range plan.Entries {
+		unitData, err := lookup(entry.Code)
+		if err != nil {
+			continue
+		}
+		limit := prerequisiteTimeLimit(unitData)
+		if limit == 0 {
+			continue
+		}
+
+		prerequisites := prerequisiteCodes(unitData)
+		for _, other := range plan.Entries {
+			if !prerequisites[other.Code] || !before(other, entry) || entry.Year-other.Year <= limit {
+				continue
+			}
+
+			message := fmt.Sprintf("%s requires its prerequisites to be completed within %d years, but %s is planned in %s %d",
+				entry.Code, limit, other.Code, other.TeachingPeriod, other.Year)
+			if intermissions := intermissionsBetween(plan, other, entry); intermissions > 0 {
+				message += fmt.Sprintf(", with %d intermitted teaching periods in between", intermissions)
+			}
+			warnings[entry.Code] = append(warnings[entry.Code], message)
+		}
+		sort.Strings(warnings[entry.Code])
+	}
+	return warnings
+}
+
+// intermissionsBetween counts the intermissions after a and before b
+func intermissionsBetween(plan Plan, a Entry, b Entry) int {
+	count := 0
+	for _, intermission := range plan.Intermissions {
+		period := Entry{TeachingPeriod: intermission.TeachingPeriod, Year: intermission.Year}
+		if before(a, period) && before(period, b) {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,137 @@
+package planner
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+
+	"handbook-scraper/utils/log"
+)
+
+// shortPeriods are the summer and winter teaching periods, which have a lower load limit
+var shortPeriods = map[string]bool{
+	"SSA": true,
+	"WS":  true,
+	"SSB": true,
+}
+
+// LoadRules holds the credit point limits of a teaching period.
+// Loads of at least the full-time limit are full-time study and loads below it part-time.
+// Loads above the standard limit are an overload and need approval, loads above the maximum are not allowed.
+type LoadRules struct {
+	FullTimeCreditPoints       int `json:"full_time_credit_points"`
+	StandardCreditPoints       int `json:"standard_credit_points"`
+	MaxCreditPoints            int `json:"max_credit_points"`
+	ShortPeriodMaxCreditPoints int `json:"short_period_max_credit_points"` // Summer and winter periods
+}
+
+// PeriodLoad holds the credit point load of a single teaching period
+type PeriodLoad struct {
+	Year           int      `json:"year"`
+	TeachingPeriod string   `json:"teaching_period"`
+	CreditPoints   int      `json:"credit_points"`
+	Units          []string `json:"units"`
+	Status         string   `json:"status"`         // ok, overload, exceeds_max, or intermission
+	Load           string   `json:"load,omitempty"` // full_time, part_time, or overload. Summer and winter periods are not classified
+	Message        string   `json:"message,omitempty"`
+}
+
+// LoadRulesFromEnv reads the load rules from environment variables, falling back to the standard Monash load
+func LoadRulesFromEnv() LoadRules {
+	return LoadRules{
+		FullTimeCreditPoints:       envInt("PLANNER_FULL_TIME_CREDIT_POINTS", 18),
+		StandardCreditPoints:       envInt("PLANNER_STANDARD_CREDIT_POINTS", 24),
+		MaxCreditPoints:            envInt("PLANNER_MAX_CREDIT_POINTS", 30),
+		ShortPeriodMaxCreditPoints: envInt("PLANNER_SHORT_PERIOD_MAX_CREDIT_POINTS", 12),
+	}
+}
+
+// CheckLoad sums the credit points planned in each teaching period and flags periods exceeding the load rules.
+// Units that cannot be looked up are counted as 0 credit points. Intermissions are listed as empty periods.
+func CheckLoad(plan Plan, lookup UnitLookup, rules LoadRules) []PeriodLoad {
+	type periodKey struct {
+		year   int
+		period string
+	}
+
+	loads := map[periodKey]*PeriodLoad{}
+	for _, entry := range plan.Entries {
+		key := periodKey{entry.Year, entry.TeachingPeriod}
+		load, ok := loads[key]
+		if !ok {
+			load = &PeriodLoad{Year: entry.Year, TeachingPeriod: entry.TeachingPeriod, Units: []string{}}
+			loads[key] = load
+		}
+
+		load.Units = append(load.Units, entry.Code)
+		if unitData, err := lookup(entry.Code); err == nil {
+			load.CreditPoints += unitData.CreditPoints
+		}
+	}
+
+	result := make([]PeriodLoad, 0, len(loads)+len(plan.Intermissions))
+	for _, load := range loads {
+		load.Status, load.Message = loadStatus(*load, rules)
+		load.Load = loadClassification(*load, rules)
+		result = append(result, *load)
+	}
+	for _, intermission := range plan.Intermissions {
+		result = append(result, PeriodLoad{Year: intermission.Year, TeachingPeriod: intermission.TeachingPeriod, Units: []string{}, Status: "intermission"})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		a := Entry{TeachingPeriod: result[i].TeachingPeriod, Year: result[i].Year}
+		b := Entry{TeachingPeriod: result[j].TeachingPeriod, Year: result[j].Year}
+		return before(a, b)
+	})
+	return result
+}
+
+// loadStatus classifies the load of a teaching period
+func loadStatus(load PeriodLoad, rules LoadRules) (string, string) {
+	if shortPeriods[load.TeachingPeriod] {
+		if load.CreditPoints > rules.ShortPeriodMaxCreditPoints {
+			return "exceeds_max", fmt.Sprintf("%d credit points planned in %s %d, the maximum is %d", load.CreditPoints, load.TeachingPeriod, load.Year, rules.ShortPeriodMaxCreditPoints)
+		}
+		return "ok", ""
+	}
+
+	switch {
+	case load.CreditPoints > rules.MaxCreditPoints:
+		return "exceeds_max", fmt.Sprintf("%d credit points planned in %s %d, the maximum is %d", load.CreditPoints, load.TeachingPeriod, load.Year, rules.MaxCreditPoints)
+	case load.CreditPoints > rules.StandardCreditPoints:
+		return "overload", fmt.Sprintf("%d credit points planned in %s %d is above the standard load of %d and needs approval", load.CreditPoints, load.TeachingPeriod, load.Year, rules.StandardCreditPoints)
+	default:
+		return "ok", ""
+	}
+}
+
+// loadClassification classifies the load of a main teaching period as full-time, part-time, or an overload
+func loadClassification(load PeriodLoad, rules LoadRules) string {
+	switch {
+	case shortPeriods[load.TeachingPeriod]:
+		return ""
+	case load.CreditPoints > rules.StandardCreditPoints:
+		return "overload"
+	case load.CreditPoints >= rules.FullTimeCreditPoints:
+		return "full_time"
+	default:
+		return "part_time"
+	}
+}
+
+// envInt reads an integer environment variable, falling back to the default if unset or invalid
+func envInt(key string, fallback int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		log.Warnf("Invalid %s value %q, using %d", key, value, fallback)
+		return fallback
+	}
+	return parsed
+}
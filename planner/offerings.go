@@ -0,0 +1,37 @@
+package planner
+
+import (
+	"strings"
+
+	"handbook-scraper/scrapers/units"
+)
+
+// OfferingPeriods normalises the offerings of a unit into teaching period codes (S1, S2, SSA, ...).
+// Offerings are matched on their display name prefix, e.g. S1-01-CLAYTON-ON-CAMPUS.
+func OfferingPeriods(unitData units.UnitData) []string {
+	var periods []string
+	seen := map[string]bool{}
+	for _, offering := range unitData.UnitOfferings {
+		period, _, found := strings.Cut(offering.DisplayName, "-")
+		if !found {
+			continue
+		}
+		period = strings.ToUpper(period)
+		if _, known := periodOrder[period]; !known || seen[period] {
+			continue
+		}
+		seen[period] = true
+		periods = append(periods, period)
+	}
+	return periods
+}
+
+// offeredIn reports whether the unit has an offering in the teaching period
+func offeredIn(unitData units.UnitData, period string) bool {
+	for _, offered := range OfferingPeriods(unitData) {
+		if offered == period {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,110 @@
+package planner
+
+import (
+	"sort"
+	"strings"
+
+	"handbook-scraper/scrapers/common"
+)
+
+// Specialisation is a major, minor or extended major a student has chosen, with its curriculum
+type Specialisation struct {
+	Code       string            `json:"code"`
+	Title      string            `json:"title"`
+	Type       string            `json:"type"` // e.g. Major, Minor or Extended major
+	Curriculum common.Curriculum `json:"-"`
+}
+
+// ResolveSpecialisations replaces the areas of study listed in a curriculum with the curricula of the chosen
+// specialisations, keyed by upper-case code. Where a list names a chosen specialisation, the others it names are
+// dropped, so the slot is constrained to the choice. Chosen codes which the curriculum does not list are returned.
+func ResolveSpecialisations(curriculum common.Curriculum, chosen map[string]Specialisation) (common.Curriculum, []string) {
+	used := map[string]bool{}
+	resolved := common.Curriculum{TotalCreditPoints: curriculum.TotalCreditPoints, Parts: make([]common.Part, len(curriculum.Parts))}
+	for i, part := range curriculum.Parts {
+		resolved.Parts[i] = resolvePart(part, chosen, used)
+	}
+
+	var unused []string
+	for code := range chosen {
+		if !used[code] {
+			unused = append(unused, code)
+		}
+	}
+	sort.Strings(unused)
+	return resolved, unused
+}
+
+// resolvePart resolves the chosen specialisations within a part of a curriculum
+func resolvePart(part common.Part, chosen map[string]Specialisation, used map[string]bool) common.Part {
+	container := resolveContainer(common.Container{
+		Title:                part.Title,
+		CreditPointsRequired: part.CreditPointsRequired,
+		Containers:           part.Containers,
+		AcademicItems:        part.AcademicItems,
+		Connector:            part.Connector,
+	}, chosen, used)
+	part.Containers = container.Containers
+	part.AcademicItems = container.AcademicItems
+	return part
+}
+
+// resolveContainer resolves the chosen specialisations within a container and its children.
+// Requirements are evaluated on either items or containers, so remaining units are grouped into a container
+// of their own when specialisations are added alongside them.
+func resolveContainer(container common.Container, chosen map[string]Specialisation, used map[string]bool) common.Container {
+	var children []common.Container
+	for _, child := range container.Containers {
+		children = append(children, resolveContainer(child, chosen, used))
+	}
+
+	var items []common.AcademicItem
+	var specialisations []common.Container
+	for _, item := range container.AcademicItems {
+		if !isAreaOfStudy(item) {
+			items = append(items, item)
+			continue
+		}
+		if specialisation, ok := chosen[strings.ToUpper(item.Code)]; ok {
+			used[specialisation.Code] = true
+			specialisations = append(specialisations, specialisationContainer(specialisation))
+		}
+	}
+
+	if len(specialisations) == 0 {
+		container.Containers = children
+		return container
+	}
+	if len(items) > 0 {
+		children = append(children, common.Container{Title: container.Title, AcademicItems: items, Connector: container.Connector})
+		items = nil
+	}
+	container.Containers = append(children, specialisations...)
+	container.AcademicItems = items
+	return container
+}
+
+// specialisationContainer turns the curriculum of a specialisation into a container of its parts
+func specialisationContainer(specialisation Specialisation) common.Container {
+	container := common.Container{
+		Title:                specialisation.Title,
+		CreditPointsRequired: specialisation.Curriculum.TotalCreditPoints,
+		Connector:            "AND",
+	}
+	for _, part := range specialisation.Curriculum.Parts {
+		container.Containers = append(container.Containers, common.Container{
+			Title:                part.Title,
+			Description:          part.Description,
+			CreditPointsRequired: part.CreditPointsRequired,
+			Containers:           part.Containers,
+			AcademicItems:        part.AcademicItems,
+			Connector:            part.Connector,
+		})
+	}
+	return container
+}
+
+// isAreaOfStudy reports whether a curriculum item is an area of study rather than a unit
+func isAreaOfStudy(item common.AcademicItem) bool {
+	return strings.EqualFold(item.Type, "area_of_study") || strings.Contains(item.URL, "/aos/")
+}
@@ -0,0 +1,43 @@
+package planner
+
+import (
+	"handbook-scraper/scrapers/common"
+	"handbook-scraper/scrapers/units"
+)
+
+// Entry represents a unit planned in a teaching period
+type Entry struct {
+	Code           string `json:"code"`            // FIT2004
+	TeachingPeriod string `json:"teaching_period"` // S1, S2, SSA, SSB, WS, FY
+	Year           int    `json:"year"`            // 2026
+}
+
+// Plan represents a student's study plan.
+// Completed units count towards requisites of every entry, while planned entries
+// only count towards entries in later teaching periods.
+type Plan struct {
+	HandbookYear     string         `json:"handbook_year"`
+	Course           string         `json:"course"`
+	CommencementYear int            `json:"commencement_year,omitempty"` // Year the course was started, if before the first entry
+	Completed        []common.Unit  `json:"completed"`
+	Entries          []Entry        `json:"entries"`
+	Intermissions    []Intermission `json:"intermissions,omitempty"` // Teaching periods deliberately left empty
+}
+
+// Intermission is a teaching period the student takes off, in which no units can be planned
+type Intermission struct {
+	TeachingPeriod string `json:"teaching_period"`
+	Year           int    `json:"year"`
+}
+
+// EntryResult holds the validation result of a single planned entry
+type EntryResult struct {
+	Entry
+	MetRequisites bool     `json:"met_requisites"`
+	Messages      []string `json:"message"`
+	Warnings      []string `json:"warnings"`
+	Error         string   `json:"error,omitempty"`
+}
+
+// UnitLookup retrieves the handbook data of a unit
+type UnitLookup func(code string) (units.UnitData, error)
@@ -0,0 +1,132 @@
+package planner
+
+import (
+	"fmt"
+	"strings"
+
+	"handbook-scraper/scrapers/common"
+	"handbook-scraper/scrapers/units"
+)
+
+// periodOrder orders teaching periods within a calendar year.
+// Periods sharing an order run at the same time.
+var periodOrder = map[string]int{
+	"SSA": 0,
+	"S1":  1,
+	"T1":  1,
+	"WS":  2,
+	"T2":  2,
+	"S2":  3,
+	"T3":  3,
+	"FY":  3,
+	"SSB": 4,
+}
+
+// Add adds an entry to the plan.
+// It returns an error if the unit is already completed or planned.
+func (p *Plan) Add(entry Entry) error {
+	entry.Code = strings.ToUpper(strings.TrimSpace(entry.Code))
+	entry.TeachingPeriod = strings.ToUpper(strings.TrimSpace(entry.TeachingPeriod))
+
+	if entry.Code == "" || entry.TeachingPeriod == "" || entry.Year == 0 {
+		return fmt.Errorf("code, teaching_period and year are required")
+	}
+	if _, ok := periodOrder[entry.TeachingPeriod]; !ok {
+		return fmt.Errorf("unknown teaching period: %s", entry.TeachingPeriod)
+	}
+	for _, unit := range p.Completed {
+		if strings.EqualFold(unit.Code, entry.Code) {
+			return fmt.Errorf("%s is already completed", entry.Code)
+		}
+	}
+	for _, existing := range p.Entries {
+		if existing.Code == entry.Code {
+			return fmt.Errorf("%s is already planned in %s %d", entry.Code, existing.TeachingPeriod, existing.Year)
+		}
+	}
+	if p.intermitted(entry.TeachingPeriod, entry.Year) {
+		return fmt.Errorf("%s %d is an intermission", entry.TeachingPeriod, entry.Year)
+	}
+
+	p.Entries = append(p.Entries, entry)
+	return nil
+}
+
+// Remove removes a planned unit from the plan.
+// It returns false if the unit is not planned.
+func (p *Plan) Remove(code string) bool {
+	for i, entry := range p.Entries {
+		if strings.EqualFold(entry.Code, code) {
+			p.Entries = append(p.Entries[:i], p.Entries[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Validate validates every entry in the plan, warning about units whose offerings clash with another unit in the same period,
+// and units planned too long after the prerequisites they require to be recent.
+// Units completed under a code equivalents looks up count as the unit itself, unless it is nil.
+func Validate(plan Plan, lookup UnitLookup, equivalents units.Equivalents) []EntryResult {
+	results := make([]EntryResult, 0, len(plan.Entries))
+	index := map[string]int{}
+	for _, entry := range plan.Entries {
+		index[entry.Code] = len(results)
+		results = append(results, ValidateEntry(plan, entry, lookup, equivalents))
+	}
+
+	for _, clash := range CheckClashes(plan, lookup) {
+		for _, code := range clash.Units {
+			results[index[code]].Warnings = append(results[index[code]].Warnings, clash.Message)
+		}
+	}
+	for code, warnings := range CheckTimeLimits(plan, lookup) {
+		results[index[code]].Warnings = append(results[index[code]].Warnings, warnings...)
+	}
+	return results
+}
+
+// ValidateEntry checks the requisites of a planned entry against the units
+// completed before it, and warns if the unit is not offered in the planned teaching period.
+func ValidateEntry(plan Plan, entry Entry, lookup UnitLookup, equivalents units.Equivalents) EntryResult {
+	result := EntryResult{Entry: entry, Messages: []string{}, Warnings: []string{}}
+
+	unitData, err := lookup(entry.Code)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	met, messages, err := units.CheckRequisitesForCourse(unitData, completedBefore(plan, entry), plan.Course, equivalents)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.MetRequisites = met
+	result.Messages = messages
+
+	if !offeredIn(unitData, entry.TeachingPeriod) {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("%s is not offered in %s", entry.Code, entry.TeachingPeriod))
+	}
+
+	return result
+}
+
+// completedBefore returns the units completed, or planned in an earlier teaching period than the entry
+func completedBefore(plan Plan, entry Entry) []common.Unit {
+	completed := append([]common.Unit{}, plan.Completed...)
+	for _, other := range plan.Entries {
+		if before(other, entry) {
+			completed = append(completed, common.Unit{Code: other.Code})
+		}
+	}
+	return completed
+}
+
+// before reports whether a finishes before b starts
+func before(a Entry, b Entry) bool {
+	if a.Year != b.Year {
+		return a.Year < b.Year
+	}
+	return periodOrder[a.TeachingPeriod] < periodOrder[b.TeachingPeriod]
+}
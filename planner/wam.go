@@ -0,0 +1,139 @@
+package planner
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"handbook-scraper/scrapers/units"
+)
+
+// gradePoints are the grade points of each Monash grade in a grade point average
+var gradePoints = map[string]float64{
+	"HD": 4,
+	"D":  3,
+	"C":  2,
+	"P":  1,
+	"NP": 0.7,
+	"N":  0.3,
+	"NH": 0.3,
+	"WN": 0,
+}
+
+// UnitResult is the result of a unit a student has completed
+type UnitResult struct {
+	Code         string   `json:"code"`
+	Mark         *float64 `json:"mark"`                    // Out of 100, or empty for units graded without a mark
+	Grade        string   `json:"grade"`                   // Such as HD or WN, derived from the mark if empty
+	CreditPoints int      `json:"credit_points,omitempty"` // Defaults to the credit points of the unit
+}
+
+// WAMUnit is a unit counted in a weighted average mark or grade point average
+type WAMUnit struct {
+	Code         string   `json:"code"`
+	Grade        string   `json:"grade"`
+	Mark         *float64 `json:"mark"`
+	CreditPoints int      `json:"credit_points"`
+	Level        int      `json:"level"`
+	Weight       float64  `json:"weight"`       // WAM weight of the level of the unit
+	GradePoints  *float64 `json:"grade_points"` // Empty for grades which do not count towards a GPA
+	CountsToWAM  bool     `json:"counts_to_wam"`
+}
+
+// WAMResult holds a weighted average mark and grade point average
+type WAMResult struct {
+	WAM          *float64  `json:"wam"` // Empty if no unit has a mark
+	GPA          *float64  `json:"gpa"` // Empty if no unit has a graded result
+	CreditPoints int       `json:"credit_points"`
+	Units        []WAMUnit `json:"units"`
+	Excluded     []string  `json:"excluded"` // Units with neither a mark nor a grade point, such as pass-grade-only units
+}
+
+// CalculateWAM computes a Monash weighted average mark, where each mark is weighted by the credit points of the unit
+// and half as much for first-year units, and a grade point average weighted by credit points.
+// Withdrawn fails (WN) count as a mark of 0. Units the lookup cannot find are weighted by the level in their code.
+func CalculateWAM(results []UnitResult, lookup UnitLookup) WAMResult {
+	result := WAMResult{Units: []WAMUnit{}, Excluded: []string{}}
+
+	var markSum, wamWeightSum, pointSum, gpaCreditPoints float64
+	for _, unitResult := range results {
+		unit := WAMUnit{
+			Code:         strings.ToUpper(strings.TrimSpace(unitResult.Code)),
+			Grade:        strings.ToUpper(strings.TrimSpace(unitResult.Grade)),
+			Mark:         unitResult.Mark,
+			CreditPoints: unitResult.CreditPoints,
+		}
+
+		level := ""
+		if unitData, err := lookup(unit.Code); err == nil {
+			level = unitData.UnitLevel
+			if unit.CreditPoints == 0 {
+				unit.CreditPoints = unitData.CreditPoints
+			}
+		}
+		if unit.CreditPoints == 0 {
+			unit.CreditPoints = defaultUnitCreditPoints
+		}
+		unit.Level = units.LevelNumber(level, unit.Code)
+		unit.Weight = units.WAMWeight(unit.Level)
+
+		if unit.Grade == "" && unit.Mark != nil {
+			unit.Grade = markGrade(*unit.Mark)
+		}
+		mark := unit.Mark
+		if mark == nil && unit.Grade == "WN" {
+			zero := 0.0
+			mark = &zero
+		}
+		if mark != nil {
+			unit.CountsToWAM = true
+			markSum += *mark * float64(unit.CreditPoints) * unit.Weight
+			wamWeightSum += float64(unit.CreditPoints) * unit.Weight
+		}
+		if points, ok := gradePoints[unit.Grade]; ok {
+			unit.GradePoints = &points
+			pointSum += points * float64(unit.CreditPoints)
+			gpaCreditPoints += float64(unit.CreditPoints)
+		}
+
+		if !unit.CountsToWAM && unit.GradePoints == nil {
+			result.Excluded = append(result.Excluded, unit.Code)
+			continue
+		}
+		result.CreditPoints += unit.CreditPoints
+		result.Units = append(result.Units, unit)
+	}
+
+	if wamWeightSum > 0 {
+		wam := roundTo(markSum/wamWeightSum, 3)
+		result.WAM = &wam
+	}
+	if gpaCreditPoints > 0 {
+		gpa := roundTo(pointSum/gpaCreditPoints, 3)
+		result.GPA = &gpa
+	}
+	sort.Strings(result.Excluded)
+	return result
+}
+
+// markGrade returns the grade of a mark
+func markGrade(mark float64) string {
+	switch {
+	case mark >= 80:
+		return "HD"
+	case mark >= 70:
+		return "D"
+	case mark >= 60:
+		return "C"
+	case mark >= 50:
+		return "P"
+	default:
+		return "N"
+	}
+}
+
+// roundTo rounds a value to a number of decimal places
+func roundTo(value float64, places int) float64 {
+	scale := math.Pow(10, float64(places))
+	return math.Round(value*scale) / scale
+}
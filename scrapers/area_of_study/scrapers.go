@@ -13,9 +13,13 @@ func Scrape(rawJSON map[string]interface{}, baseURL string) (AosData, error) {
 
 	curriculum, errCurriculum := common.ParseCurriculum(rawJSON)
 	var curriculumError bool
+	var curriculumParseError string
+	var rawCurriculum map[string]interface{}
 	if errCurriculum != nil {
 		log.Errorf("aos scraper: Error parsing curriculum: %v", errCurriculum)
 		curriculumError = true
+		curriculumParseError = errCurriculum.Error()
+		rawCurriculum = common.RawCurriculum(rawJSON)
 	} else {
 		curriculumError = false
 	}
@@ -23,22 +27,25 @@ func Scrape(rawJSON map[string]interface{}, baseURL string) (AosData, error) {
 	aosScraperData := AosData{
 		CommonScraperData: common.CommonScraperData{
 			Link:             baseURL,
-			Faculty:          utils.GetTypedValue[string](rawJSON, "props.pageProps.pageContent.school.value"),
-			Code:             utils.GetTypedValue[string](rawJSON, "props.pageProps.pageContent.code"),
+			Faculty:          common.ProfileString(rawJSON, "aos", "faculty"),
+			Code:             common.ProfileString(rawJSON, "aos", "code"),
 			Title:            utils.GetTypedValue[string](rawJSON, "props.pageProps.pageContent.title"),
 			SearchTitle:      utils.GetTypedValue[string](rawJSON, "props.pageProps.pageContent.search_title"),
 			CurrentYear:      utils.StringToInt(utils.GetTypedValue[string](rawJSON, "props.pageProps.pageContent.implementation_year")),
 			AcademicItemType: "area_of_study",
 		},
-		SpecificAosType:      utils.GetTypedValue[string](rawJSON, "props.pageProps.pageContent.academic_item_type"),
-		CreditPoints:         utils.GetTypedValue[int](rawJSON, "props.pageProps.pageContent.credit_points"),
-		CurriculumStructure:  curriculum,
-		CurriculumError:      curriculumError,
-		HandbookDescription:  utils.RemoveHTMLTags(utils.GetTypedValue[string](rawJSON, "props.pageProps.pageContent.handbook_description")),
-		InherentRequirements: utils.GetTypedValue[string](rawJSON, "props.pageProps.pageContent.inherent_requirements"),
-		LearningOutcomes:     common.LearningOutcomes(rawJSON, "props.pageProps.pageContent.learning_outcomes"),
-		SpecialStatements:    utils.RemoveHTMLTags(utils.GetTypedValue[string](rawJSON, "props.pageProps.pageContent.special_statements")),
-		UndergradPostgrad:    utils.GetTypedValue[string](rawJSON, "props.pageProps.pageContent.undergrad_postgrad.value"),
+		SpecificAosType:         utils.GetTypedValue[string](rawJSON, "props.pageProps.pageContent.academic_item_type"),
+		CreditPoints:            utils.GetTypedValue[int](rawJSON, "props.pageProps.pageContent.credit_points"),
+		CurriculumStructure:     curriculum,
+		CurriculumError:         curriculumError,
+		CurriculumParseError:    curriculumParseError,
+		RawCurriculumStructure:  rawCurriculum,
+		HandbookDescription:     utils.RemoveHTMLTags(common.FirstString(rawJSON, "props.pageProps.pageContent.handbook_description", "props.pageProps.pageContent.handbook_synopsis")),
+		InherentRequirements:    utils.GetTypedValue[string](rawJSON, "props.pageProps.pageContent.inherent_requirements"),
+		InherentRequirementList: common.ParseInherentRequirements(utils.GetTypedValue[string](rawJSON, "props.pageProps.pageContent.inherent_requirements")),
+		LearningOutcomes:        common.LearningOutcomes(rawJSON, "props.pageProps.pageContent.learning_outcomes"),
+		SpecialStatements:       utils.RemoveHTMLTags(utils.GetTypedValue[string](rawJSON, "props.pageProps.pageContent.special_statements")),
+		UndergradPostgrad:       common.FirstString(rawJSON, "props.pageProps.pageContent.undergrad_postgrad.value", "props.pageProps.pageContent.undergrad_postgrad_both.value"),
 	}
 
 	log.Success("[AOS SCRAPER] Extraction complete.")
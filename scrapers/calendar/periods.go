@@ -0,0 +1,36 @@
+package calendar
+
+import "strings"
+
+// periodNames are the names teaching period codes are listed under on the key dates page, lower-case
+var periodNames = map[string][]string{
+	"S1":  {"first semester", "semester 1", "semester one"},
+	"S2":  {"second semester", "semester 2", "semester two"},
+	"FY":  {"second semester", "semester 2", "semester two"}, // Full-year units finish with the second semester
+	"SSA": {"summer semester a", "summer a"},
+	"SSB": {"summer semester b", "summer b"},
+	"WS":  {"winter semester", "winter"},
+	"T1":  {"trimester 1", "term 1"},
+	"T2":  {"trimester 2", "term 2"},
+	"T3":  {"trimester 3", "term 3"},
+}
+
+// FindTeachingPeriod returns the dates of a teaching period by its code, e.g. S1
+func (data CalendarData) FindTeachingPeriod(code string) (TeachingPeriodDates, bool) {
+	for _, name := range periodNames[strings.ToUpper(code)] {
+		for _, period := range data.TeachingPeriods {
+			if strings.Contains(strings.ToLower(period.Name), name) {
+				return period, true
+			}
+		}
+	}
+	return TeachingPeriodDates{}, false
+}
+
+// EndDate returns the last day of a teaching period, the end of its examination period if it has one
+func (period TeachingPeriodDates) EndDate() string {
+	if period.ExamPeriodTo != "" {
+		return period.ExamPeriodTo
+	}
+	return period.End
+}
@@ -0,0 +1,177 @@
+package calendar
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gocolly/colly/v2"
+	"handbook-scraper/utils/log"
+)
+
+// BaseDomain is the domain hosting the Monash key dates pages
+const BaseDomain = "www.monash.edu"
+
+// keyDatesURL is the principal dates page of an academic year
+const keyDatesURL = "https://www.monash.edu/students/admin/dates/principal/%s"
+
+// dateLayouts are the date formats used on the key dates page, with and without weekday and year
+var dateLayouts = []string{
+	"Monday 2 January 2006",
+	"Monday, 2 January 2006",
+	"2 January 2006",
+	"Monday 2 January",
+	"Monday, 2 January",
+	"2 January",
+	"Mon 2 Jan 2006",
+	"2 Jan 2006",
+	"2 Jan",
+}
+
+// rangeSeparator splits date ranges such as "3 June - 21 June" or "3 June to 21 June"
+var rangeSeparator = regexp.MustCompile(`\s*(?:-|–|—|\bto\b)\s*`)
+
+// URL returns the key dates page URL of a year
+func URL(year string) string {
+	return fmt.Sprintf(keyDatesURL, year)
+}
+
+// Scrape visits the key dates page of a year and extracts the teaching period dates.
+// The collector is cloned so callbacks do not interfere with other scrapes. Clones share the visited URLs of the
+// collector, so revisits are allowed for the page to be scraped again on every refresh.
+func Scrape(year string, collector *colly.Collector) (CalendarData, error) {
+	log.Infof("[CALENDAR SCRAPER] Extracting data...")
+
+	yearInt, err := strconv.Atoi(year)
+	if err != nil {
+		return CalendarData{}, fmt.Errorf("invalid year: %s", year)
+	}
+
+	data := CalendarData{
+		Link:            URL(year),
+		Year:            yearInt,
+		TeachingPeriods: []TeachingPeriodDates{},
+		KeyDates:        []KeyDate{},
+	}
+
+	currentPeriod := ""
+	c := collector.Clone()
+	c.AllowURLRevisit = true
+	c.OnHTML("h2, h3, h4, tr", func(e *colly.HTMLElement) {
+		// Headings name the teaching period the following table rows belong to
+		if e.Name != "tr" {
+			currentPeriod = strings.TrimSpace(e.Text)
+			return
+		}
+
+		cells := e.ChildTexts("td")
+		if len(cells) < 2 {
+			return
+		}
+
+		from, to, ok := parseDateRange(cells[0], yearInt)
+		event := strings.TrimSpace(cells[1])
+		if !ok {
+			// Some tables list the event first and the date second
+			from, to, ok = parseDateRange(cells[1], yearInt)
+			event = strings.TrimSpace(cells[0])
+		}
+		if !ok {
+			return
+		}
+
+		data.KeyDates = append(data.KeyDates, KeyDate{TeachingPeriod: currentPeriod, Event: event, From: from, To: to})
+	})
+
+	if err := c.Visit(data.Link); err != nil {
+		return CalendarData{}, fmt.Errorf("failed to visit URL: %w", err)
+	}
+
+	if len(data.KeyDates) == 0 {
+		return CalendarData{}, fmt.Errorf("failed to find key dates on %s", data.Link)
+	}
+
+	data.TeachingPeriods = teachingPeriods(data.KeyDates)
+
+	log.Successf("[CALENDAR SCRAPER] Extraction complete.")
+	return data, nil
+}
+
+// teachingPeriods groups key dates by teaching period and maps them to the known fields
+func teachingPeriods(keyDates []KeyDate) []TeachingPeriodDates {
+	var periods []TeachingPeriodDates
+	index := map[string]int{}
+
+	for _, keyDate := range keyDates {
+		if keyDate.TeachingPeriod == "" {
+			continue
+		}
+
+		i, ok := index[keyDate.TeachingPeriod]
+		if !ok {
+			i = len(periods)
+			index[keyDate.TeachingPeriod] = i
+			periods = append(periods, TeachingPeriodDates{Name: keyDate.TeachingPeriod})
+		}
+		period := &periods[i]
+
+		event := strings.ToLower(keyDate.Event)
+		switch {
+		case strings.Contains(event, "census"):
+			period.Census = keyDate.From
+		case strings.Contains(event, "exam"):
+			period.ExamPeriodFrom = keyDate.From
+			period.ExamPeriodTo = keyDate.To
+			if period.ExamPeriodTo == "" {
+				period.ExamPeriodTo = keyDate.From
+			}
+		case strings.Contains(event, "last day of classes"), strings.Contains(event, "teaching ends"), strings.Contains(event, "classes end"):
+			period.End = keyDate.From
+		case strings.Contains(event, "first day of classes"), strings.Contains(event, "teaching begins"), strings.Contains(event, "classes begin"):
+			period.Start = keyDate.From
+		}
+	}
+
+	if periods == nil {
+		return []TeachingPeriodDates{}
+	}
+	return periods
+}
+
+// parseDateRange parses a single date or a date range into YYYY-MM-DD strings.
+// Dates without a year are assumed to fall in the given year.
+func parseDateRange(text string, year int) (string, string, bool) {
+	parts := rangeSeparator.Split(strings.TrimSpace(text), 2)
+
+	from, ok := parseDate(parts[0], year)
+	if !ok {
+		return "", "", false
+	}
+	if len(parts) == 1 {
+		return from, "", true
+	}
+
+	to, ok := parseDate(parts[1], year)
+	if !ok {
+		return from, "", true
+	}
+	return from, to, true
+}
+
+// parseDate parses a single date using the known layouts
+func parseDate(text string, year int) (string, bool) {
+	text = strings.Join(strings.Fields(text), " ")
+	for _, layout := range dateLayouts {
+		parsed, err := time.Parse(layout, text)
+		if err != nil {
+			continue
+		}
+		if !strings.Contains(layout, "2006") {
+			parsed = parsed.AddDate(year-parsed.Year(), 0, 0)
+		}
+		return parsed.Format("2006-01-02"), true
+	}
+	return "", false
+}
@@ -0,0 +1,28 @@
+package calendar
+
+// CalendarData holds the key dates of an academic year
+type CalendarData struct {
+	Link            string                `json:"link"`             // https://www.monash.edu/students/admin/dates/principal/2025
+	Year            int                   `json:"year"`             // 2025
+	TeachingPeriods []TeachingPeriodDates `json:"teaching_periods"` //
+	KeyDates        []KeyDate             `json:"key_dates"`        // Every dated row on the page, including those not mapped to a teaching period field
+}
+
+// TeachingPeriodDates holds the important dates of a teaching period.
+// Dates are formatted as YYYY-MM-DD and are empty when not listed on the page.
+type TeachingPeriodDates struct {
+	Name           string `json:"name"`             // First semester
+	Start          string `json:"start"`            // First day of classes
+	End            string `json:"end"`              // Last day of classes
+	Census         string `json:"census"`           // Census date
+	ExamPeriodFrom string `json:"exam_period_from"` // First day of the examination period
+	ExamPeriodTo   string `json:"exam_period_to"`   // Last day of the examination period
+}
+
+// KeyDate represents a single dated event on the key dates page
+type KeyDate struct {
+	TeachingPeriod string `json:"teaching_period"`
+	Event          string `json:"event"`
+	From           string `json:"from"`
+	To             string `json:"to,omitempty"`
+}
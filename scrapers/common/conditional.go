@@ -0,0 +1,105 @@
+package common
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"handbook-scraper/utils/fetchlog"
+)
+
+// PageValidator records what is needed to tell whether a handbook page changed since it was last scraped
+type PageValidator struct {
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+	Hash         string `json:"hash"` // SHA-256 of the page content JSON
+}
+
+var conditionalClient = &http.Client{Timeout: 30 * time.Second, Transport: &fetchlog.Transport{}}
+
+// FetchIfChanged fetches the raw JSON of a handbook page unless it is unchanged since the previous validator.
+// The ETag and Last-Modified headers are used when the handbook provides them, otherwise the page content is hashed.
+// It returns nil data if the page is unchanged, and the URL the page redirected to otherwise.
+func FetchIfChanged(ctx context.Context, pageURL string, previous PageValidator) (map[string]interface{}, string, PageValidator, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+	if err != nil {
+		return nil, "", previous, fmt.Errorf("failed to create request: %w", err)
+	}
+	if previous.ETag != "" {
+		req.Header.Set("If-None-Match", previous.ETag)
+	}
+	if previous.LastModified != "" {
+		req.Header.Set("If-Modified-Since", previous.LastModified)
+	}
+
+	if err := checkThrottle(); err != nil {
+		return nil, "", previous, err
+	}
+
+	resp, err := conditionalClient.Do(req)
+	if err != nil {
+		return nil, "", previous, fmt.Errorf("failed to visit URL: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, "", previous, nil
+	}
+	if isThrottleStatus(resp.StatusCode) {
+		return nil, "", previous, fmt.Errorf("failed to visit URL: %w", &ThrottledError{RetryAfter: noteThrottled(resp.Header.Get("Retry-After"))})
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", previous, fmt.Errorf("failed to visit URL: status %d", resp.StatusCode)
+	}
+
+	doc, err := goquery.NewDocumentFromReader(resp.Body)
+	if err != nil {
+		return nil, "", previous, fmt.Errorf("failed to parse HTML: %w", err)
+	}
+
+	script := doc.Find("script#__NEXT_DATA__")
+	if script.Length() == 0 {
+		return nil, "", previous, fmt.Errorf("failed to find JSON data in the HTML")
+	}
+	parsedData, err := DecodePageContent(strings.NewReader(script.Text()))
+	if err != nil {
+		return nil, "", previous, err
+	}
+	if err := checkPageContent(pageURL, parsedData); err != nil {
+		return nil, "", previous, err
+	}
+
+	validator := PageValidator{
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		Hash:         contentHash(parsedData),
+	}
+	if previous.Hash != "" && validator.Hash == previous.Hash {
+		return nil, "", validator, nil
+	}
+	return parsedData, resp.Request.URL.String(), validator, nil
+}
+
+// contentHash hashes the page content of the raw JSON.
+// The rest of the Next.js payload, such as the build ID, changes between deployments without the content changing.
+func contentHash(data map[string]interface{}) string {
+	var content interface{} = data
+	if props, ok := data["props"].(map[string]interface{}); ok {
+		if pageProps, ok := props["pageProps"].(map[string]interface{}); ok {
+			if pageContent, ok := pageProps["pageContent"]; ok {
+				content = pageContent
+			}
+		}
+	}
+
+	// Map keys are marshalled in sorted order, so the hash is stable
+	marshalled, _ := json.Marshal(content)
+	sum := sha256.Sum256(marshalled)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,47 @@
+package common
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+)
+
+// CurriculumID returns the stable ID of a part or container, a hash of the ID of its parent and its title.
+// Occurrence numbers siblings with the same title, counting from 0, so they get different IDs.
+func CurriculumID(parentID string, title string, occurrence int) string {
+	path := parentID + "/" + strings.ToLower(strings.Join(strings.Fields(title), " "))
+	if occurrence > 0 {
+		path += "#" + strconv.Itoa(occurrence)
+	}
+	sum := sha256.Sum256([]byte(path))
+	return hex.EncodeToString(sum[:6])
+}
+
+// AssignIDs sets the ID of every part and container of the curriculum without one, in place.
+// IDs only depend on the titles along the path to a block, so they are the same each time a page is scraped
+// and can be referenced by clients instead of the titles. IDs already set are kept, such as when a patch changes a title.
+func (c *Curriculum) AssignIDs() {
+	seen := map[string]int{}
+	for i := range c.Parts {
+		part := &c.Parts[i]
+		if part.ID == "" {
+			part.ID = CurriculumID("", part.Title, seen[part.Title])
+		}
+		seen[part.Title]++
+		assignContainerIDs(part.ID, part.Containers)
+	}
+}
+
+// assignContainerIDs sets the IDs of containers and their children without one, under the part or container with parentID
+func assignContainerIDs(parentID string, containers []Container) {
+	seen := map[string]int{}
+	for i := range containers {
+		container := &containers[i]
+		if container.ID == "" {
+			container.ID = CurriculumID(parentID, container.Title, seen[container.Title])
+		}
+		seen[container.Title]++
+		assignContainerIDs(container.ID, container.Containers)
+	}
+}
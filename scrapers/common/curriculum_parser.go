@@ -16,6 +16,7 @@ type Curriculum struct {
 // Part represents a major section of the curriculum (e.g., Part A, Part B).
 // It contains the title, description, credit points required, and a slice of Container structs.
 type Part struct {
+	ID                   string         `json:"id"` // Stable ID, see AssignIDs
 	Title                string         `json:"title"`
 	Description          string         `json:"description"`
 	CreditPointsRequired int            `json:"credit_points_required"`
@@ -30,6 +31,7 @@ type Part struct {
 // The connector string defines the relationship between the academic items in the container.
 // Containers cannot contain both academic items and child containers simultaneously.
 type Container struct {
+	ID                   string         `json:"id"` // Stable ID, see AssignIDs
 	Title                string         `json:"title"`
 	Description          string         `json:"description"`
 	CreditPointsRequired int            `json:"credit_points_required"`
@@ -54,12 +56,19 @@ type AcademicItem struct {
 // It extracts the curriculum structure from the given path, parses the total credit points,
 // and then iterates through each part of the curriculum, extracting its details and nested containers.
 // It returns a Curriculum struct and an error if any parsing fails.
-func ParseCurriculum(data map[string]interface{}) (Curriculum, error) {
-	data = utils.GetTypedValue[map[string]interface{}](data, "props.pageProps.pageContent.curriculumStructure")
+// Unexpected structures which would otherwise panic are returned as errors as well.
+func ParseCurriculum(data map[string]interface{}) (curriculum Curriculum, err error) {
+	data = RawCurriculum(data)
 
-	var curriculum Curriculum
 	curriculum.Parts = []Part{} // Initialize as empty slice
 
+	defer func() {
+		if r := recover(); r != nil {
+			curriculum = Curriculum{Parts: []Part{}}
+			err = fmt.Errorf("unexpected curriculum structure: %v", r)
+		}
+	}()
+
 	// Extract total credit points.
 	totalCreditsStr, ok := data["credit_points"].(string)
 	if !ok {
@@ -152,9 +161,16 @@ func ParseCurriculum(data map[string]interface{}) (Curriculum, error) {
 		curriculum.Parts = append(curriculum.Parts, part)
 	}
 
+	curriculum.AssignIDs()
 	return curriculum, nil
 }
 
+// RawCurriculum returns the unparsed curriculum structure of the raw JSON,
+// which is included in responses when the curriculum cannot be parsed
+func RawCurriculum(data map[string]interface{}) map[string]interface{} {
+	return utils.GetTypedValue[map[string]interface{}](data, "props.pageProps.pageContent.curriculumStructure")
+}
+
 // parseContainers recursively parses containers and their nested containers.
 // It takes an interface as input, which should be a slice of container data.
 // It iterates through each container, extracts its details, and recursively parses nested containers.
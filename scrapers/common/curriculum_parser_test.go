@@ -0,0 +1,61 @@
+package common
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"handbook-scraper/utils"
+)
+
+// loadPages reads the raw pages in testdata, saved as .json or gzipped .json.gz files, such as the page JSON of
+// real handbook pages. large_course.json.gz is a generated course with a larger curriculum than any real double degree.
+func loadPages(b *testing.B) map[string]map[string]interface{} {
+	paths, err := filepath.Glob(filepath.Join("testdata", "*.json*"))
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	pages := map[string]map[string]interface{}{}
+	for _, path := range paths {
+		file, err := os.Open(path)
+		if err != nil {
+			b.Fatal(err)
+		}
+		var reader io.Reader = file
+		if strings.HasSuffix(path, ".gz") {
+			if reader, err = gzip.NewReader(file); err != nil {
+				b.Fatalf("failed to decompress %s: %v", path, err)
+			}
+		}
+		var data map[string]interface{}
+		err = json.NewDecoder(reader).Decode(&data)
+		file.Close()
+		if err != nil {
+			b.Fatalf("failed to decode %s: %v", path, err)
+		}
+		pages[strings.TrimSuffix(strings.TrimSuffix(filepath.Base(path), ".gz"), ".json")] = data
+	}
+	return pages
+}
+
+// BenchmarkParseCurriculum parses the curriculum of each page which has one
+func BenchmarkParseCurriculum(b *testing.B) {
+	for name, page := range loadPages(b) {
+		if _, ok := utils.LookupValue(page, "props.pageProps.pageContent.curriculumStructure"); !ok {
+			continue
+		}
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := ParseCurriculum(page); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package common
+
+import "strings"
+
+// CurriculumChanges are corrections to a part or container of a parsed curriculum, such as a connector the parser
+// inferred wrongly. Fields left empty are not changed.
+type CurriculumChanges struct {
+	Title                *string        `json:"title,omitempty"`
+	Description          *string        `json:"description,omitempty"`
+	CreditPointsRequired *int           `json:"credit_points_required,omitempty"`
+	Connector            *string        `json:"connector,omitempty"` // AND or OR
+	AddItems             []AcademicItem `json:"add_items,omitempty"`
+	RemoveItems          []string       `json:"remove_items,omitempty"` // Codes of the items to remove
+}
+
+// ApplyChanges changes the part or container with the target ID in place, and reports whether it was found
+func (c *Curriculum) ApplyChanges(target string, changes CurriculumChanges) bool {
+	for i := range c.Parts {
+		part := &c.Parts[i]
+		if part.ID == target {
+			changeBlock(&part.Title, &part.Description, &part.CreditPointsRequired, &part.Connector, &part.AcademicItems, changes)
+			return true
+		}
+		if applyContainerChanges(part.Containers, target, changes) {
+			return true
+		}
+	}
+	return false
+}
+
+// FindBlock reports whether the curriculum has a part or container with the ID
+func (c *Curriculum) FindBlock(id string) bool {
+	var search func(containers []Container) bool
+	search = func(containers []Container) bool {
+		for _, container := range containers {
+			if container.ID == id || search(container.Containers) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, part := range c.Parts {
+		if part.ID == id || search(part.Containers) {
+			return true
+		}
+	}
+	return false
+}
+
+// applyContainerChanges changes the container with the target ID among containers and their children
+func applyContainerChanges(containers []Container, target string, changes CurriculumChanges) bool {
+	for i := range containers {
+		container := &containers[i]
+		if container.ID == target {
+			changeBlock(&container.Title, &container.Description, &container.CreditPointsRequired, &container.Connector, &container.AcademicItems, changes)
+			return true
+		}
+		if applyContainerChanges(container.Containers, target, changes) {
+			return true
+		}
+	}
+	return false
+}
+
+// changeBlock applies changes to the fields shared by parts and containers
+func changeBlock(title *string, description *string, creditPoints *int, connector *string, items *[]AcademicItem, changes CurriculumChanges) {
+	if changes.Title != nil {
+		*title = *changes.Title
+	}
+	if changes.Description != nil {
+		*description = *changes.Description
+	}
+	if changes.CreditPointsRequired != nil {
+		*creditPoints = *changes.CreditPointsRequired
+	}
+	if changes.Connector != nil {
+		*connector = strings.ToUpper(*changes.Connector)
+	}
+
+	if len(changes.RemoveItems) > 0 {
+		kept := []AcademicItem{}
+		for _, item := range *items {
+			removed := false
+			for _, code := range changes.RemoveItems {
+				removed = removed || strings.EqualFold(item.Code, code)
+			}
+			if !removed {
+				kept = append(kept, item)
+			}
+		}
+		*items = kept
+	}
+	*items = append(*items, changes.AddItems...)
+}
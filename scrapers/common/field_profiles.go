@@ -0,0 +1,95 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"handbook-scraper/utils"
+	"handbook-scraper/utils/log"
+)
+
+// FieldProfile maps the fields of an academic item type to the JSON paths they are read from, tried in order.
+// Pages of some faculties name fields differently, such as school rather than academic_org for the owning faculty.
+type FieldProfile map[string][]string
+
+// defaultFieldProfiles are the built-in profiles of each item type
+var defaultFieldProfiles = map[string]FieldProfile{
+	"units": {
+		"faculty": {"props.pageProps.pageContent.academic_org.value", "props.pageProps.pageContent.school.value"},
+		"code":    {"props.pageProps.pageContent.unit_code", "props.pageProps.pageContent.code"},
+	},
+	"courses": {
+		"faculty": {"props.pageProps.pageContent.school.value", "props.pageProps.pageContent.academic_org.value"},
+		"code":    {"props.pageProps.pageContent.course_code", "props.pageProps.pageContent.code"},
+	},
+	"aos": {
+		"faculty": {"props.pageProps.pageContent.school.value", "props.pageProps.pageContent.academic_org.value"},
+		"code":    {"props.pageProps.pageContent.code"},
+	},
+}
+
+var (
+	fieldProfilesOnce sync.Once
+	fieldProfiles     map[string]FieldProfile
+)
+
+// loadFieldProfiles returns the built-in profiles, with the paths of the FIELD_PROFILES file tried before them.
+// The file is JSON of paths by field and item type, e.g. {"units": {"faculty": ["props.pageProps.pageContent.owner.value"]}}.
+func loadFieldProfiles() map[string]FieldProfile {
+	fieldProfilesOnce.Do(func() {
+		fieldProfiles = map[string]FieldProfile{}
+		for itemType, profile := range defaultFieldProfiles {
+			fieldProfiles[itemType] = FieldProfile{}
+			for field, paths := range profile {
+				fieldProfiles[itemType][field] = paths
+			}
+		}
+
+		path := os.Getenv("FIELD_PROFILES")
+		if path == "" {
+			return
+		}
+		overrides, err := readFieldProfiles(path)
+		if err != nil {
+			log.Errorf("[FIELD PROFILES] Ignoring %s: %v", path, err)
+			return
+		}
+		for itemType, profile := range overrides {
+			if fieldProfiles[itemType] == nil {
+				fieldProfiles[itemType] = FieldProfile{}
+			}
+			for field, paths := range profile {
+				fieldProfiles[itemType][field] = append(paths, fieldProfiles[itemType][field]...)
+			}
+		}
+		log.Infof("[FIELD PROFILES] Loaded field profiles from %s", path)
+	})
+	return fieldProfiles
+}
+
+// readFieldProfiles reads the profiles of a FIELD_PROFILES file
+func readFieldProfiles(path string) (map[string]FieldProfile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var profiles map[string]FieldProfile
+	if err := json.Unmarshal(data, &profiles); err != nil {
+		return nil, fmt.Errorf("invalid field profiles: %w", err)
+	}
+	return profiles, nil
+}
+
+// ProfileString returns the first non-empty string of a field of an item type, trying each path of its profile in order.
+// A field found at none of them is logged, so it does not go silently empty.
+func ProfileString(rawJSON map[string]interface{}, itemType string, field string) string {
+	return FirstString(rawJSON, loadFieldProfiles()[itemType][field]...)
+}
+
+// FirstString returns the first non-empty string at the paths of a field which moves between handbook revisions
+func FirstString(rawJSON map[string]interface{}, paths ...string) string {
+	value, _ := utils.GetFirstTypedValue[string](rawJSON, paths...)
+	return value
+}
@@ -0,0 +1,136 @@
+package common
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"handbook-scraper/utils/log"
+)
+
+// HTMLTable is a table embedded in the HTML of a handbook field, such as a workload or assessment breakdown
+type HTMLTable struct {
+	Field   string              `json:"field"`             // The field the table was found in, e.g. workload_requirements
+	Caption string              `json:"caption,omitempty"` //
+	Columns []string            `json:"columns"`           // Column headings as shown in the handbook
+	Rows    []map[string]string `json:"rows"`              // Each row keyed by its column's key, e.g. "hours_per_week"
+}
+
+// columnKeyChars matches the characters of a column heading which are left out of its key
+var columnKeyChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// ParseHTMLTables converts the tables in the HTML of a field into rows keyed by their column headings.
+// Headings come from th cells in the first row, or the table's first row if it is all th cells.
+// Tables without headings are keyed column_1, column_2 and so on. It returns nil if there are no tables.
+func ParseHTMLTables(field string, html string) []HTMLTable {
+	if !strings.Contains(strings.ToLower(html), "<table") {
+		return nil
+	}
+
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		log.Errorf("Failed to parse tables of %s: %v", field, err)
+		return nil
+	}
+
+	var tables []HTMLTable
+	doc.Find("table").Each(func(_ int, s *goquery.Selection) {
+		if table, ok := parseHTMLTable(s); ok {
+			table.Field = field
+			tables = append(tables, table)
+		}
+	})
+	return tables
+}
+
+// parseHTMLTable converts a single table, returning false if it has no rows
+func parseHTMLTable(s *goquery.Selection) (HTMLTable, bool) {
+	table := HTMLTable{Caption: cellText(s.ChildrenFiltered("caption"))}
+
+	// Nested tables are parsed on their own, so only this table's rows are read
+	var rows [][]string
+	headerRow := -1
+	s.Find("tr").Each(func(_ int, tr *goquery.Selection) {
+		if tr.ParentsFiltered("table").First().Get(0) != s.Get(0) {
+			return
+		}
+		cells := tr.ChildrenFiltered("th, td")
+		if cells.Length() == 0 {
+			return
+		}
+		if headerRow == -1 && len(rows) == 0 && cells.Length() == cells.Filter("th").Length() {
+			headerRow = 0
+		}
+		rows = append(rows, expandCells(cells))
+	})
+	if len(rows) == 0 {
+		return table, false
+	}
+
+	if headerRow == 0 {
+		table.Columns, rows = rows[0], rows[1:]
+	}
+	keys := columnKeys(table.Columns, rows)
+	if table.Columns == nil {
+		table.Columns = keys
+	}
+
+	table.Rows = make([]map[string]string, 0, len(rows))
+	for _, cells := range rows {
+		row := map[string]string{}
+		for i, cell := range cells {
+			if i < len(keys) && cell != "" {
+				row[keys[i]] = cell
+			}
+		}
+		if len(row) > 0 {
+			table.Rows = append(table.Rows, row)
+		}
+	}
+	return table, len(table.Rows) > 0
+}
+
+// expandCells returns the text of a row's cells, repeating cells spanning several columns so the columns line up
+func expandCells(cells *goquery.Selection) []string {
+	var texts []string
+	cells.Each(func(_ int, cell *goquery.Selection) {
+		text := cellText(cell)
+		span, _ := strconv.Atoi(cell.AttrOr("colspan", "1"))
+		for i := 0; i < max(span, 1); i++ {
+			texts = append(texts, text)
+		}
+	})
+	return texts
+}
+
+// columnKeys returns the row keys of the columns, made unique, with column_N for columns without a heading
+func columnKeys(columns []string, rows [][]string) []string {
+	width := len(columns)
+	for _, row := range rows {
+		width = max(width, len(row))
+	}
+
+	keys := make([]string, width)
+	seen := map[string]int{}
+	for i := range keys {
+		key := ""
+		if i < len(columns) {
+			key = strings.Trim(columnKeyChars.ReplaceAllString(strings.ToLower(columns[i]), "_"), "_")
+		}
+		if key == "" {
+			key = fmt.Sprintf("column_%d", i+1)
+		}
+		if seen[key]++; seen[key] > 1 {
+			key = fmt.Sprintf("%s_%d", key, seen[key])
+		}
+		keys[i] = key
+	}
+	return keys
+}
+
+// cellText returns the text of a cell with its whitespace collapsed
+func cellText(s *goquery.Selection) string {
+	return strings.Join(strings.Fields(s.Text()), " ")
+}
@@ -0,0 +1,109 @@
+package common
+
+import (
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"handbook-scraper/utils/log"
+)
+
+// InherentRequirement is a single requirement from the inherent requirements of a course or area of study
+type InherentRequirement struct {
+	Category    string `json:"category"`          // physical, cognitive, communication, professional_behaviour, or other
+	Heading     string `json:"heading,omitempty"` // The heading the requirement was listed under in the handbook
+	Description string `json:"description"`
+}
+
+// inherentRequirementCategories maps keywords in requirement headings and text to their category.
+// Categories are checked in order, so the first matching category is used.
+var inherentRequirementCategories = []struct {
+	category string
+	keywords []string
+}{
+	{"communication", []string{"communication", "verbal", "written", "language", "listening", "interpersonal"}},
+	{"physical", []string{"physical", "motor", "sensory", "mobility", "sight", "vision", "visual", "hearing", "auditory", "tactile", "touch", "strength", "stamina", "dexterity"}},
+	{"cognitive", []string{"cognitive", "knowledge", "literacy", "numeracy", "reasoning", "intellectual", "problem solving", "critical thinking"}},
+	{"professional_behaviour", []string{"behaviour", "behavioural", "professional", "ethical", "legal", "conduct", "emotional", "resilience", "sustainable performance", "safety"}},
+}
+
+// maxHeadingLength is the longest text treated as a heading when it is only emphasised rather than in a heading element
+const maxHeadingLength = 80
+
+// ParseInherentRequirements splits the inherent requirements HTML of a course or area of study into categorised requirements.
+// Each paragraph or list item is a requirement, categorised by the heading it is listed under,
+// or by its own text if the heading does not name a category. It returns nil if there are no requirements.
+func ParseInherentRequirements(html string) []InherentRequirement {
+	if strings.TrimSpace(html) == "" {
+		return nil
+	}
+
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		log.Errorf("Failed to parse inherent requirements: %v", err)
+		return nil
+	}
+
+	var requirements []InherentRequirement
+	heading, headingCategory := "", ""
+	doc.Find("h1, h2, h3, h4, h5, h6, p, li").Each(func(_ int, s *goquery.Selection) {
+		// Paragraphs holding lists are covered by their list items
+		if s.Find("li").Length() > 0 {
+			return
+		}
+		text := strings.Join(strings.Fields(s.Text()), " ")
+		if text == "" {
+			return
+		}
+
+		if isRequirementHeading(s, text) {
+			heading = strings.TrimSuffix(text, ":")
+			headingCategory = inherentRequirementCategory(heading)
+			return
+		}
+		if isLeadIn(text) {
+			// Lead-ins such as "Students must be able to:" only replace the heading if they name a category
+			if category := inherentRequirementCategory(text); category != "" {
+				heading, headingCategory = strings.TrimSuffix(text, ":"), category
+			}
+			return
+		}
+
+		category := headingCategory
+		if category == "" {
+			category = inherentRequirementCategory(text)
+		}
+		if category == "" {
+			category = "other"
+		}
+		requirements = append(requirements, InherentRequirement{Category: category, Heading: heading, Description: text})
+	})
+	return requirements
+}
+
+// isRequirementHeading reports whether an element heads the requirements after it,
+// either as a heading element or as a short paragraph whose text is all emphasised
+func isRequirementHeading(s *goquery.Selection, text string) bool {
+	if goquery.NodeName(s)[0] == 'h' {
+		return true
+	}
+	emphasised := strings.Join(strings.Fields(s.Find("strong, b").Text()), " ")
+	return len(text) <= maxHeadingLength && emphasised == text
+}
+
+// isLeadIn reports whether a short paragraph introduces a list, such as "Students must be able to:"
+func isLeadIn(text string) bool {
+	return len(text) <= maxHeadingLength && strings.HasSuffix(text, ":")
+}
+
+// inherentRequirementCategory returns the category named by a heading or requirement, or an empty string if none is
+func inherentRequirementCategory(text string) string {
+	lower := strings.ToLower(text)
+	for _, category := range inherentRequirementCategories {
+		for _, keyword := range category.keywords {
+			if strings.Contains(lower, keyword) {
+				return category.category
+			}
+		}
+	}
+	return ""
+}
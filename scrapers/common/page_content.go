@@ -0,0 +1,97 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// pageContentPath is where the handbook item is found in the Next.js data of a handbook page
+var pageContentPath = []string{"props", "pageProps", "pageContent"}
+
+// DecodePageContent decodes only props.pageProps.pageContent of the Next.js data of a handbook page.
+// The rest of the payload, such as the build manifest and navigation, is skipped token by token
+// rather than decoded, which keeps the peak memory of scraping large course pages down.
+// The result keeps the shape of the full payload, so paths such as props.pageProps.pageContent.code still work.
+func DecodePageContent(r io.Reader) (map[string]interface{}, error) {
+	dec := json.NewDecoder(r)
+
+	var content interface{}
+	found, err := decodePath(dec, pageContentPath, &content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode page content: %w", err)
+	}
+
+	pageProps := map[string]interface{}{}
+	if found {
+		pageProps["pageContent"] = content
+	}
+	return map[string]interface{}{"props": map[string]interface{}{"pageProps": pageProps}}, nil
+}
+
+// decodePath decodes the value at path of the next JSON value into out, skipping everything else.
+// It reports whether the path was found.
+func decodePath(dec *json.Decoder, path []string, out interface{}) (bool, error) {
+	if len(path) == 0 {
+		return true, dec.Decode(out)
+	}
+
+	token, err := dec.Token()
+	if err != nil {
+		return false, err
+	}
+	delim, ok := token.(json.Delim)
+	if !ok {
+		return false, nil
+	}
+	if delim != '{' {
+		return false, skipNested(dec)
+	}
+
+	for dec.More() {
+		keyToken, err := dec.Token()
+		if err != nil {
+			return false, err
+		}
+		if key, _ := keyToken.(string); key == path[0] {
+			// Nothing after the path is needed, so the rest of the payload is never read
+			return decodePath(dec, path[1:], out)
+		}
+		if err := skipValue(dec); err != nil {
+			return false, err
+		}
+	}
+
+	// Consume the closing brace
+	_, err = dec.Token()
+	return false, err
+}
+
+// skipValue skips the next JSON value
+func skipValue(dec *json.Decoder) error {
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if delim, ok := token.(json.Delim); ok && (delim == '{' || delim == '[') {
+		return skipNested(dec)
+	}
+	return nil
+}
+
+// skipNested skips the rest of an object or array whose opening delimiter was already read
+func skipNested(dec *json.Decoder) error {
+	for depth := 1; depth > 0; {
+		token, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		switch token {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+	}
+	return nil
+}
@@ -1,56 +1,173 @@
 package common
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/gocolly/colly/v2"
+	"net/http"
+	"strings"
+	"time"
+	"handbook-scraper/utils/fetchlog"
 	"handbook-scraper/utils/log"
 )
 
+// Keys of the colly context used to record fetches
+const (
+	fetchStartedKey   = "fetch_started"
+	fetchRequestIDKey = "fetch_request_id"
+	fetchRecorderKey  = "fetch_recorder"
+)
+
+// ErrPageNotFound is returned when the handbook has no page at a URL, such as for a code which does not exist in a year
+var ErrPageNotFound = errors.New("page not found")
+
 // SetupCollyCollector sets up a colly collector with shared error handling
 func SetupCollyCollector(baseDomain string) *colly.Collector {
 	log.Info("Setting up colly collector for handbook scraping")
 
+	// Pages are visited again whenever they are refreshed, so revisits are allowed
 	collector := colly.NewCollector(
 		colly.AllowedDomains(baseDomain),
+		colly.AllowURLRevisit(),
 	)
 
-	// Set shared error handling
-	collector.OnError(func(r *colly.Response, err error) {
+	registerFetchCallbacks(collector)
+	return collector
+}
+
+// registerFetchCallbacks sets the error handling and fetch recording shared by the collector and each of its clones
+func registerFetchCallbacks(c *colly.Collector) {
+	// Handle failed requests the same way on every collector
+	c.OnError(func(r *colly.Response, err error) {
 		log.Errorf("Request to %s failed with %v", r.Request.URL, err)
+		recordFetch(r, err)
+		if isThrottleStatus(r.StatusCode) && r.Headers != nil {
+			noteThrottled(r.Headers.Get("Retry-After"))
+		}
+	})
+
+	// Record every fetch in the audit trail
+	c.OnRequest(func(r *colly.Request) {
+		r.Ctx.Put(fetchStartedKey, time.Now())
+	})
+	c.OnResponse(func(r *colly.Response) {
+		recordFetch(r, nil)
 	})
-	return collector
+}
+
+// recordFetch records a fetch made by a collector with the recorder in its colly context, attributed to its request ID
+func recordFetch(r *colly.Response, err error) {
+	fetch := fetchlog.Fetch{
+		URL:       r.Request.URL.String(),
+		Method:    r.Request.Method,
+		Status:    r.StatusCode,
+		Bytes:     int64(len(r.Body)),
+		RequestID: r.Ctx.Get(fetchRequestIDKey),
+	}
+	if started, ok := r.Ctx.GetAny(fetchStartedKey).(time.Time); ok {
+		fetch.FetchedAt = started
+		fetch.DurationMs = time.Since(started).Milliseconds()
+	}
+	if err != nil {
+		fetch.Error = err.Error()
+	}
+	record, _ := r.Ctx.GetAny(fetchRecorderKey).(fetchlog.Recorder)
+	record.Record(fetch)
 }
 
 // ExtractRawJSON extracts raw JSON data from a URL
 func ExtractRawJSON(URL string, c *colly.Collector) (map[string]interface{}, error) {
+	parsedData, _, err := ExtractRawJSONWithURL(URL, c)
+	return parsedData, err
+}
+
+// ExtractRawJSONWithURL extracts raw JSON data from a URL, along with the URL of the page
+// it was extracted from, which differs from the requested URL if it was redirected
+func ExtractRawJSONWithURL(URL string, c *colly.Collector) (map[string]interface{}, string, error) {
+	return extractNextData(context.Background(), URL, c, func(text string) (map[string]interface{}, error) {
+		var parsedData map[string]interface{}
+		err := json.Unmarshal([]byte(text), &parsedData)
+		return parsedData, err
+	})
+}
+
+// ExtractPageContent extracts only the page content of a handbook page, as decoded by DecodePageContent,
+// along with the URL of the page it was extracted from. The fetch is attributed to the request ID of ctx.
+// It returns ErrPageNotFound if the handbook has no page at the URL.
+func ExtractPageContent(ctx context.Context, URL string, c *colly.Collector) (map[string]interface{}, string, error) {
+	data, finalURL, err := extractNextData(ctx, URL, c, func(text string) (map[string]interface{}, error) {
+		return DecodePageContent(strings.NewReader(text))
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	if err := checkPageContent(URL, data); err != nil {
+		return nil, "", err
+	}
+	return data, finalURL, nil
+}
+
+// checkPageContent returns ErrPageNotFound if the raw JSON of a page has no page content,
+// as the handbook serves its not found page without it
+func checkPageContent(URL string, data map[string]interface{}) error {
+	props, _ := data["props"].(map[string]interface{})
+	if pageProps, _ := props["pageProps"].(map[string]interface{}); pageProps["pageContent"] == nil {
+		return fmt.Errorf("no page content at %s: %w", URL, ErrPageNotFound)
+	}
+	return nil
+}
+
+// extractNextData visits a URL and decodes its Next.js data script with decode
+func extractNextData(ctx context.Context, URL string, c *colly.Collector, decode func(text string) (map[string]interface{}, error)) (map[string]interface{}, string, error) {
 	var parsedData map[string]interface{}
+	finalURL := URL
 
 	log.Logf("Extracting raw JSON data from URL: %s", URL)
 
-	// Set the new OnHTML callback
-	c.OnHTML("script#__NEXT_DATA__", func(e *colly.HTMLElement) {
-		if err := json.Unmarshal([]byte(e.Text), &parsedData); err != nil {
+	// Requests are not sent while the handbook is throttling
+	if err := checkThrottle(); err != nil {
+		return nil, "", err
+	}
+
+	// Each fetch has a clone of the collector, sharing its HTTP client and limits, so the callback of one fetch
+	// never sees the page of another fetched at the same time
+	fetcher := c.Clone()
+	registerFetchCallbacks(fetcher)
+	fetcher.OnHTML("script#__NEXT_DATA__", func(e *colly.HTMLElement) {
+		finalURL = e.Request.URL.String()
+		var err error
+		if parsedData, err = decode(e.Text); err != nil {
+			parsedData = nil
 			log.Errorf("Failed parsing JSON data: %v", err)
 		}
 	})
 
 	// Start the scrape
-	err := c.Visit(URL)
-
-	// Detach the callback
-	c.OnHTMLDetach("script#__NEXT_DATA__")
+	collyCtx := colly.NewContext()
+	collyCtx.Put(fetchRequestIDKey, fetchlog.RequestID(ctx))
+	collyCtx.Put(fetchRecorderKey, fetchlog.RecorderOf(ctx))
+	err := fetcher.Request("GET", URL, nil, collyCtx, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to visit URL: %w", err)
+		// Colly reports unsuccessful responses with their status text
+		if err.Error() == http.StatusText(http.StatusNotFound) {
+			return nil, "", fmt.Errorf("failed to visit URL: %w", ErrPageNotFound)
+		}
+		if err.Error() == http.StatusText(http.StatusTooManyRequests) || err.Error() == http.StatusText(http.StatusServiceUnavailable) {
+			return nil, "", fmt.Errorf("failed to visit URL: %w", &ThrottledError{RetryAfter: max(ThrottlePause(), time.Second)})
+		}
+		return nil, "", fmt.Errorf("failed to visit URL: %w", err)
 	}
 
 	log.Infof("Successfully visited URL %s", URL)
 	
 	// Check if data is parsed
 	if parsedData == nil {
-		return nil, fmt.Errorf("failed to find JSON data in the HTML")
+		return nil, "", fmt.Errorf("failed to find JSON data in the HTML")
 	}
 
 	log.Log("Successfully extracted raw JSON data")
-	return parsedData, nil
+	return parsedData, finalURL, nil
 }
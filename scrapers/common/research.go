@@ -0,0 +1,12 @@
+package common
+
+import "regexp"
+
+// researchTitlePattern matches the titles of honours components and research units, such as
+// "Part D. Honours research" or "Minor thesis"
+var researchTitlePattern = regexp.MustCompile(`(?i)\b(honours|thesis|dissertation|research project|research component|research methods?)\b`)
+
+// IsResearchTitle reports whether the title of a curriculum part, container or unit names research or honours work
+func IsResearchTitle(title string) bool {
+	return researchTitlePattern.MatchString(title)
+}
@@ -0,0 +1,148 @@
+package common
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"handbook-scraper/utils/fetchlog"
+	"handbook-scraper/utils/log"
+)
+
+// SitemapIndexURL is the root sitemap of the handbook
+const SitemapIndexURL = "https://handbook.monash.edu/sitemap.xml"
+
+var sitemapClient = &http.Client{Transport: &fetchlog.Transport{}}
+
+// sitemapDocument represents either a sitemap index or a URL set
+type sitemapDocument struct {
+	Sitemaps []sitemapEntry `xml:"sitemap"`
+	URLs     []sitemapEntry `xml:"url"`
+}
+
+// sitemapEntry represents a single <sitemap> or <url> element
+type sitemapEntry struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod"`
+}
+
+// HandbookItemURLs returns the handbook page URLs listed in the sitemap for a given year and urlKey.
+// urlKey could be "courses", "aos", or "units".
+func HandbookItemURLs(ctx context.Context, year string, urlKey string) ([]string, error) {
+	entries, err := sitemapEntries(ctx, SitemapIndexURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var urls []string
+	for _, entry := range entries {
+		parsed, err := url.Parse(entry.Loc)
+		if err != nil {
+			continue
+		}
+		parts := strings.Split(strings.Trim(parsed.Path, "/"), "/")
+		if len(parts) == 3 && parts[0] == year && strings.EqualFold(parts[1], urlKey) {
+			urls = append(urls, entry.Loc)
+		}
+	}
+
+	log.Infof("[SITEMAP] Found %d %s pages for %s", len(urls), urlKey, year)
+	return urls, nil
+}
+
+// sitemapYearRegex matches the handbook year in a sitemap or page URL
+var sitemapYearRegex = regexp.MustCompile(`\b(20\d{2})\b`)
+
+// PublishedYears returns the handbook years listed in the sitemap, in ascending order.
+// The sitemap index is checked first, and only walked in full if its entries do not mention years.
+func PublishedYears(ctx context.Context) ([]int, error) {
+	doc, err := fetchSitemap(ctx, SitemapIndexURL)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := append(append([]sitemapEntry{}, doc.Sitemaps...), doc.URLs...)
+	years := sitemapYears(entries)
+	if len(years) == 0 {
+		entries, err = sitemapEntries(ctx, SitemapIndexURL)
+		if err != nil {
+			return nil, err
+		}
+		years = sitemapYears(entries)
+	}
+	return years, nil
+}
+
+// sitemapYears collects the distinct years in the paths of sitemap entries
+func sitemapYears(entries []sitemapEntry) []int {
+	seen := map[int]bool{}
+	var years []int
+	for _, entry := range entries {
+		parsed, err := url.Parse(entry.Loc)
+		if err != nil {
+			continue
+		}
+		for _, match := range sitemapYearRegex.FindAllString(parsed.Path, -1) {
+			year, _ := strconv.Atoi(match)
+			if !seen[year] {
+				seen[year] = true
+				years = append(years, year)
+			}
+		}
+	}
+	sort.Ints(years)
+	return years
+}
+
+// sitemapEntries recursively collects all <url> entries reachable from a sitemap
+func sitemapEntries(ctx context.Context, sitemapURL string) ([]sitemapEntry, error) {
+	doc, err := fetchSitemap(ctx, sitemapURL)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := doc.URLs
+	for _, child := range doc.Sitemaps {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		childEntries, err := sitemapEntries(ctx, child.Loc)
+		if err != nil {
+			log.Errorf("[SITEMAP] Skipping %s: %v", child.Loc, err)
+			continue
+		}
+		entries = append(entries, childEntries...)
+	}
+	return entries, nil
+}
+
+// fetchSitemap downloads and decodes a single sitemap document
+func fetchSitemap(ctx context.Context, sitemapURL string) (sitemapDocument, error) {
+	var doc sitemapDocument
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, sitemapURL, nil)
+	if err != nil {
+		return doc, fmt.Errorf("failed to create sitemap request: %w", err)
+	}
+
+	resp, err := sitemapClient.Do(req)
+	if err != nil {
+		return doc, fmt.Errorf("failed to fetch sitemap: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return doc, fmt.Errorf("failed to fetch sitemap: status %d", resp.StatusCode)
+	}
+
+	if err := xml.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return doc, fmt.Errorf("failed to decode sitemap: %w", err)
+	}
+	return doc, nil
+}
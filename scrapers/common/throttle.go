@@ -0,0 +1,75 @@
+package common
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"handbook-scraper/utils/log"
+)
+
+// defaultThrottlePause is how long requests to the handbook are paused when it throttles without a Retry-After header
+const defaultThrottlePause = time.Minute
+
+// maxThrottlePause caps the pause advised by a Retry-After header, so a bad header cannot stop scraping for long
+const maxThrottlePause = time.Hour
+
+var (
+	throttleMu    sync.Mutex
+	throttleUntil time.Time
+)
+
+// ThrottledError is returned when the handbook throttles requests, or while requests are paused after it did
+type ThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *ThrottledError) Error() string {
+	return fmt.Sprintf("the handbook is throttling requests, retry after %s", e.RetryAfter.Round(time.Second))
+}
+
+// isThrottleStatus reports whether a status code means the handbook is throttling requests
+func isThrottleStatus(status int) bool {
+	return status == http.StatusTooManyRequests || status == http.StatusServiceUnavailable
+}
+
+// noteThrottled pauses requests to the handbook for the period advised by a Retry-After header value
+func noteThrottled(retryAfter string) time.Duration {
+	pause := parseRetryAfter(retryAfter, time.Now())
+	throttleMu.Lock()
+	if until := time.Now().Add(pause); until.After(throttleUntil) {
+		throttleUntil = until
+	}
+	throttleMu.Unlock()
+
+	log.Warnf("[THROTTLE] The handbook is throttling requests, pausing for %s", pause)
+	return pause
+}
+
+// ThrottlePause returns how long requests to the handbook are paused for, or 0 if they are not
+func ThrottlePause() time.Duration {
+	throttleMu.Lock()
+	defer throttleMu.Unlock()
+	return max(time.Until(throttleUntil), 0)
+}
+
+// checkThrottle returns a ThrottledError while requests to the handbook are paused, so it is not sent more requests
+func checkThrottle() error {
+	if pause := ThrottlePause(); pause > 0 {
+		return &ThrottledError{RetryAfter: pause}
+	}
+	return nil
+}
+
+// parseRetryAfter parses a Retry-After header, given either in seconds or as an HTTP date
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	pause := defaultThrottlePause
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		pause = time.Duration(seconds) * time.Second
+	} else if date, err := http.ParseTime(value); err == nil && date.After(now) {
+		pause = date.Sub(now)
+	}
+	return min(pause, maxThrottlePause)
+}
@@ -0,0 +1,104 @@
+package common
+
+import (
+	"fmt"
+	"strings"
+
+	"handbook-scraper/utils"
+)
+
+// variantListFields are the fields of a chooser page which may list its entries
+var variantListFields = []string{"items", "results", "variants", "versions", "entries", "options"}
+
+// Variant is one of the entries of a code which has more than one in a year's handbook, such as a version of a unit
+// for each location it is taught at
+type Variant struct {
+	Code     string `json:"code"`
+	Title    string `json:"title"`
+	Version  string `json:"version,omitempty"`
+	Location string `json:"location,omitempty"`
+	URL      string `json:"url"`
+}
+
+// MultipleEntriesError is returned when a code resolves to a chooser page listing several entries instead of an item
+type MultipleEntriesError struct {
+	URL      string
+	Variants []Variant
+}
+
+func (e *MultipleEntriesError) Error() string {
+	return fmt.Sprintf("%s has %d entries in the handbook, choose one of them", e.URL, len(e.Variants))
+}
+
+// ParseVariants detects a chooser page, which the handbook serves for a code with several entries in a year,
+// and returns the entries it lists. A chooser page has no code of its own, but a list of items which do.
+// It reports false for the page of a single item.
+func ParseVariants(data map[string]interface{}) ([]Variant, bool) {
+	content, ok := utils.LookupValue(data, "props.pageProps.pageContent")
+	if !ok {
+		return nil, false
+	}
+
+	var entries []interface{}
+	switch content := content.(type) {
+	case []interface{}:
+		entries = content
+	case map[string]interface{}:
+		if code, _ := content["code"].(string); code != "" {
+			return nil, false
+		}
+		for _, field := range variantListFields {
+			if list, ok := content[field].([]interface{}); ok && len(list) > 0 {
+				entries = list
+				break
+			}
+		}
+	}
+
+	var variants []Variant
+	for _, entry := range entries {
+		entry, ok := entry.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		variant := Variant{
+			Code:     variantField(entry, "code", "academic_item_code"),
+			Title:    variantField(entry, "title", "academic_item_name", "name"),
+			Version:  variantField(entry, "version_name", "academic_item_version_name", "version"),
+			Location: variantField(entry, "location", "campus", "location_name"),
+			URL:      absoluteHandbookURL(variantField(entry, "url", "academic_item_url", "link", "href")),
+		}
+		if variant.Code == "" || variant.URL == "" {
+			continue
+		}
+		variants = append(variants, variant)
+	}
+	return variants, len(variants) > 0
+}
+
+// variantField returns the first field of an entry with a value, reading the label of fields which are options
+func variantField(entry map[string]interface{}, fields ...string) string {
+	for _, field := range fields {
+		switch value := entry[field].(type) {
+		case string:
+			if value != "" {
+				return strings.TrimSpace(value)
+			}
+		case map[string]interface{}:
+			for _, key := range []string{"label", "value"} {
+				if label, _ := value[key].(string); label != "" {
+					return strings.TrimSpace(label)
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// absoluteHandbookURL resolves a link of a handbook page, which may be relative to the handbook
+func absoluteHandbookURL(link string) string {
+	if strings.HasPrefix(link, "/") {
+		return "https://handbook.monash.edu" + link
+	}
+	return link
+}
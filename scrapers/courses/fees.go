@@ -0,0 +1,113 @@
+package courses
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"handbook-scraper/utils"
+)
+
+// FeeInformation holds the fee and scholarship details published for a course.
+// The handbook only gives an indication of fees, usually as text or links to the fee pages.
+type FeeInformation struct {
+	Domestic      string            `json:"domestic,omitempty"`
+	International string            `json:"international,omitempty"`
+	Scholarships  string            `json:"scholarships,omitempty"`
+	Other         map[string]string `json:"other,omitempty"` // Fee fields not specific to domestic or international students, keyed by their handbook field
+}
+
+// CricosRegistration holds the CRICOS registration of a course, which is needed for it to be offered to student visa holders
+type CricosRegistration struct {
+	Codes                       []string `json:"codes"`
+	OpenToInternationalStudents bool     `json:"open_to_international_students"`
+}
+
+// cricosCodePattern matches CRICOS course codes, such as "085229M"
+var cricosCodePattern = regexp.MustCompile(`\b[0-9]{6}[A-Z]\b`)
+
+// extractFees collects the fee and scholarship fields of the page content, whose names vary between handbook years.
+// It returns nil if the page has none.
+func extractFees(data map[string]interface{}) *FeeInformation {
+	pageContent := utils.GetTypedValue[map[string]interface{}](data, "props.pageProps.pageContent")
+
+	keys := make([]string, 0, len(pageContent))
+	for key := range pageContent {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	fees := FeeInformation{}
+	found := false
+	for _, key := range keys {
+		lower := strings.ToLower(key)
+		if !strings.Contains(lower, "fee") && !strings.Contains(lower, "scholarship") {
+			continue
+		}
+		text := feeText(pageContent[key])
+		if text == "" {
+			continue
+		}
+
+		found = true
+		switch {
+		case strings.Contains(lower, "scholarship"):
+			fees.Scholarships = appendLine(fees.Scholarships, text)
+		case strings.Contains(lower, "international"):
+			fees.International = appendLine(fees.International, text)
+		case strings.Contains(lower, "domestic"), strings.Contains(lower, "csp"), strings.Contains(lower, "commonwealth"):
+			fees.Domestic = appendLine(fees.Domestic, text)
+		default:
+			if fees.Other == nil {
+				fees.Other = map[string]string{}
+			}
+			fees.Other[key] = text
+		}
+	}
+
+	if !found {
+		return nil
+	}
+	return &fees
+}
+
+// feeText returns the text of a fee field, which is either HTML text, a labelled value, or a list of either
+func feeText(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return strings.TrimSpace(utils.RemoveHTMLTags(v))
+	case map[string]interface{}:
+		for _, key := range []string{"label", "value", "description"} {
+			if text, ok := v[key].(string); ok && strings.TrimSpace(text) != "" {
+				return strings.TrimSpace(utils.RemoveHTMLTags(text))
+			}
+		}
+	case []interface{}:
+		var lines []string
+		for _, item := range v {
+			if text := feeText(item); text != "" {
+				lines = append(lines, text)
+			}
+		}
+		return strings.Join(lines, "\n")
+	}
+	return ""
+}
+
+// appendLine joins fee texts found in several fields
+func appendLine(existing string, text string) string {
+	if existing == "" {
+		return text
+	}
+	return existing + "\n" + text
+}
+
+// parseCricosRegistration splits the CRICOS code field of a course into its codes.
+// Courses without a CRICOS code cannot be offered to student visa holders.
+func parseCricosRegistration(cricosCode string) CricosRegistration {
+	codes := cricosCodePattern.FindAllString(strings.ToUpper(cricosCode), -1)
+	if codes == nil {
+		codes = []string{}
+	}
+	return CricosRegistration{Codes: codes, OpenToInternationalStudents: len(codes) > 0}
+}
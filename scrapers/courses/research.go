@@ -0,0 +1,129 @@
+package courses
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"handbook-scraper/scrapers/common"
+	"handbook-scraper/utils"
+)
+
+// ResearchPathway holds the honours and research components of a course found in its curriculum and handbook text
+type ResearchPathway struct {
+	Honours      bool     `json:"honours"`                 // Awards an honours degree or has an honours year
+	HonoursParts []string `json:"honours_parts,omitempty"` // Titles of the curriculum parts making up the honours or research year
+	ThesisUnits  []string `json:"thesis_units,omitempty"`  // Codes of the thesis and research project units
+	MinimumWAM   float64  `json:"minimum_wam,omitempty"`   // Weighted average mark required for entry or progression, if stated
+	EntryNotes   []string `json:"entry_notes,omitempty"`   // Sentences of the handbook text about WAM or honours entry
+}
+
+var (
+	// wamPatterns match a WAM threshold written after or before the WAM, such as "a WAM of at least 70" or "70 WAM"
+	wamPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`(?i)\b(?:WAM|weighted average mark)\b[^.\d]{0,40}?(\d{2}(?:\.\d+)?)`),
+		regexp.MustCompile(`(?i)\b(\d{2}(?:\.\d+)?)\s*(?:%|per ?cent)?\s*(?:WAM|weighted average mark)\b`),
+	}
+	// entryNotePattern matches sentences about WAM or honours entry
+	entryNotePattern = regexp.MustCompile(`(?i)\b(WAM|weighted average mark|honours)\b`)
+	// sentencePattern splits text into sentences
+	sentencePattern = regexp.MustCompile(`[^.!?\n]+[.!?]?`)
+)
+
+// extractResearchPathway finds the honours year, thesis units and WAM entry notes of a course.
+// It returns nil if the course has no research components.
+func extractResearchPathway(data map[string]interface{}, title string, awardTitles []string, curriculum common.Curriculum) *ResearchPathway {
+	pathway := ResearchPathway{Honours: strings.Contains(strings.ToLower(title), "honours")}
+	for _, award := range awardTitles {
+		if strings.Contains(strings.ToLower(award), "honours") {
+			pathway.Honours = true
+		}
+	}
+
+	var walk func(containers []common.Container)
+	walk = func(containers []common.Container) {
+		for _, container := range containers {
+			pathway.ThesisUnits = append(pathway.ThesisUnits, thesisUnits(container.AcademicItems)...)
+			walk(container.Containers)
+		}
+	}
+	for _, part := range curriculum.Parts {
+		if common.IsResearchTitle(part.Title) {
+			pathway.HonoursParts = append(pathway.HonoursParts, part.Title)
+		}
+		pathway.ThesisUnits = append(pathway.ThesisUnits, thesisUnits(part.AcademicItems)...)
+		walk(part.Containers)
+	}
+	pathway.ThesisUnits = uniqueStrings(pathway.ThesisUnits)
+	if len(pathway.HonoursParts) > 0 {
+		pathway.Honours = true
+	}
+
+	pageContent := utils.GetTypedValue[map[string]interface{}](data, "props.pageProps.pageContent")
+	keys := make([]string, 0, len(pageContent))
+	for key := range pageContent {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		text, ok := pageContent[key].(string)
+		if !ok || key == "title" || key == "search_title" {
+			continue
+		}
+		for _, sentence := range sentencePattern.FindAllString(utils.RemoveHTMLTags(text), -1) {
+			sentence = strings.TrimSpace(sentence)
+			if !entryNotePattern.MatchString(sentence) || strings.EqualFold(sentence, title) {
+				continue
+			}
+			pathway.EntryNotes = append(pathway.EntryNotes, sentence)
+			if wam, ok := parseWAM(sentence); ok && pathway.MinimumWAM == 0 {
+				pathway.MinimumWAM = wam
+			}
+		}
+	}
+	pathway.EntryNotes = uniqueStrings(pathway.EntryNotes)
+
+	if !pathway.Honours && len(pathway.ThesisUnits) == 0 && pathway.MinimumWAM == 0 {
+		return nil
+	}
+	return &pathway
+}
+
+// thesisUnits returns the codes of the thesis and research project units among curriculum items
+func thesisUnits(items []common.AcademicItem) []string {
+	var codes []string
+	for _, item := range items {
+		if item.Code != "" && common.IsResearchTitle(item.Title) {
+			codes = append(codes, item.Code)
+		}
+	}
+	return codes
+}
+
+// parseWAM returns the WAM threshold stated in a sentence
+func parseWAM(sentence string) (float64, bool) {
+	for _, pattern := range wamPatterns {
+		match := pattern.FindStringSubmatch(sentence)
+		if match == nil {
+			continue
+		}
+		if wam, err := strconv.ParseFloat(match[1], 64); err == nil && wam > 0 && wam <= 100 {
+			return wam, true
+		}
+	}
+	return 0, false
+}
+
+// uniqueStrings removes repeated strings, keeping the first of each
+func uniqueStrings(values []string) []string {
+	seen := map[string]bool{}
+	unique := values[:0]
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			unique = append(unique, value)
+		}
+	}
+	return unique
+}
@@ -13,19 +13,26 @@ func Scrape(rawJSON map[string]interface{}, baseURL string) (CourseData, error)
 
 	curriculum, errCurriculum := common.ParseCurriculum(rawJSON)
 	var curriculumError bool
+	var curriculumParseError string
+	var rawCurriculum map[string]interface{}
 	if errCurriculum != nil {
 		log.Errorf("[COURSE SCRAPER]: Error parsing curriculum: %v", errCurriculum)
 		curriculumError = true
+		curriculumParseError = errCurriculum.Error()
+		rawCurriculum = common.RawCurriculum(rawJSON)
 	} else {
 		curriculumError = false
 	}
 
+	title := utils.GetTypedValue[string](rawJSON, "props.pageProps.pageContent.title")
+	awardTitles := extractAwardTitles(rawJSON)
+
 	courseScraperData := CourseData{
 		CommonScraperData: common.CommonScraperData{
 			Link:             baseURL,
-			Faculty:          utils.GetTypedValue[string](rawJSON, "props.pageProps.pageContent.school.value"),
-			Code:             utils.GetTypedValue[string](rawJSON, "props.pageProps.pageContent.course_code"),
-			Title:            utils.GetTypedValue[string](rawJSON, "props.pageProps.pageContent.title"),
+			Faculty:          common.ProfileString(rawJSON, "courses", "faculty"),
+			Code:             common.ProfileString(rawJSON, "courses", "code"),
+			Title:            title,
 			SearchTitle:      utils.GetTypedValue[string](rawJSON, "props.pageProps.pageContent.search_title"),
 			CurrentYear:      utils.StringToInt(utils.GetTypedValue[string](rawJSON, "props.pageProps.pageContent.implementation_year")),
 			AcademicItemType: utils.GetTypedValue[string](rawJSON, "props.pageProps.pageContent.academic_item_type"),
@@ -33,10 +40,12 @@ func Scrape(rawJSON map[string]interface{}, baseURL string) (CourseData, error)
 		ProfessionalAccreditation: utils.RemoveHTMLTags(utils.GetTypedValue[string](rawJSON, "props.pageProps.pageContent.professional_accreditation")),
 		AbbreviatedName:           utils.GetTypedValue[string](rawJSON, "props.pageProps.pageContent.abbreviated_name"),
 		Atar:                      utils.GetTypedValue[string](rawJSON, "props.pageProps.pageContent.atar"),
-		AwardTitles:               extractAwardTitles(rawJSON),
+		AwardTitles:               awardTitles,
 		CourseDuration:            utils.RemoveHTMLTags(utils.GetTypedValue[string](rawJSON, "props.pageProps.pageContent.course_duration_notes")),
 		CreditPoints:              utils.StringToInt(utils.GetTypedValue[string](rawJSON, "props.pageProps.pageContent.credit_points")),
 		CricosCode:                utils.GetTypedValue[string](rawJSON, "props.pageProps.pageContent.cricos_code"),
+		Cricos:                    parseCricosRegistration(utils.GetTypedValue[string](rawJSON, "props.pageProps.pageContent.cricos_code")),
+		Fees:                      extractFees(rawJSON),
 		DoubleDegrees:             utils.RemoveHTMLTags(utils.GetTypedValue[string](rawJSON, "props.pageProps.pageContent.double_degrees")),
 		EnglishLanguage:           utils.RemoveHTMLTags(utils.GetTypedValue[string](rawJSON, "props.pageProps.pageContent.english_language")),
 		FullTimeDuration:          extractFullTimeDurations(rawJSON),
@@ -44,8 +53,13 @@ func Scrape(rawJSON map[string]interface{}, baseURL string) (CourseData, error)
 		IBMaths:                   utils.GetTypedValue[string](rawJSON, "props.pageProps.pageContent.ib_maths"),
 		MaximumDuration:           utils.StringToInt(utils.GetTypedValue[string](rawJSON, "props.pageProps.pageContent.maximum_duration")),
 		LearningOutcomes:          common.LearningOutcomes(rawJSON, "props.pageProps.pageContent.learning_outcomes"),
+		InherentRequirements:      utils.GetTypedValue[string](rawJSON, "props.pageProps.pageContent.inherent_requirements"),
+		InherentRequirementList:   common.ParseInherentRequirements(utils.GetTypedValue[string](rawJSON, "props.pageProps.pageContent.inherent_requirements")),
 		CurriculumStructure:       curriculum,
 		CurriculumError:           curriculumError,
+		ResearchPathway:           extractResearchPathway(rawJSON, title, awardTitles, curriculum),
+		CurriculumParseError:      curriculumParseError,
+		RawCurriculumStructure:    rawCurriculum,
 	}
 
 	log.Success("[COURSE SCRAPER] Extraction complete.")
@@ -0,0 +1,232 @@
+package pdf_archive
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ledongthuc/pdf"
+	"handbook-scraper/scrapers/common"
+	"handbook-scraper/scrapers/units"
+	"handbook-scraper/utils"
+	"handbook-scraper/utils/fetchlog"
+	"handbook-scraper/utils/log"
+)
+
+// Source marks unit data extracted from an archived handbook PDF
+const Source = "pdf_archive"
+
+var (
+	// unitHeadingRegex matches the heading of a unit entry, e.g. "FIT2004 - Algorithms and data structures"
+	unitHeadingRegex = regexp.MustCompile(`^([A-Z]{3}\d{4})\s*[-–:]\s*(.+)$`)
+	// unitCodeRegex matches unit codes mentioned in requisite text
+	unitCodeRegex = regexp.MustCompile(`\b[A-Z]{3}\d{4}\b`)
+	// creditPointsRegex matches "6 points" or "Credit points: 6"
+	creditPointsRegex = regexp.MustCompile(`(?i)(\d+)\s*(?:credit\s*)?points|credit points:\s*(\d+)`)
+	// fieldRegex matches a labelled field such as "Prerequisites: FIT1008"
+	fieldRegex = regexp.MustCompile(`(?i)^(faculty|offered|synopsis|prerequisites?|prohibitions?|co-?requisites?|assessment|level)\s*(?:\(s\))?\s*:?\s*(.*)$`)
+)
+
+// defaultAllowedHosts are the hosts archived handbook PDFs are downloaded from, unless PDF_ARCHIVE_HOSTS is set
+const defaultAllowedHosts = "www.monash.edu,handbook.monash.edu"
+
+var client = &http.Client{
+	Timeout:   2 * time.Minute,
+	Transport: &fetchlog.Transport{},
+	// Redirects are held to the same hosts, so an allowed URL cannot forward the download elsewhere
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 10 {
+			return fmt.Errorf("stopped after 10 redirects")
+		}
+		return CheckURL(req.URL.String())
+	},
+}
+
+// allowedHosts returns the hosts of PDF_ARCHIVE_HOSTS, a comma-separated list, or the Monash hosts
+func allowedHosts() []string {
+	raw := os.Getenv("PDF_ARCHIVE_HOSTS")
+	if raw == "" {
+		raw = defaultAllowedHosts
+	}
+	var hosts []string
+	for _, host := range strings.Split(raw, ",") {
+		if host = strings.ToLower(strings.TrimSpace(host)); host != "" {
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts
+}
+
+// CheckURL returns an error unless a URL is an HTTPS URL on one of the allowed hosts
+func CheckURL(pdfURL string) error {
+	parsed, err := url.Parse(pdfURL)
+	if err != nil {
+		return fmt.Errorf("invalid url: %w", err)
+	}
+	if parsed.Scheme != "https" {
+		return fmt.Errorf("url must be https")
+	}
+	if !slices.Contains(allowedHosts(), strings.ToLower(parsed.Hostname())) {
+		return fmt.Errorf("url must be on one of %s", strings.Join(allowedHosts(), ", "))
+	}
+	return nil
+}
+
+// Download fetches an archived handbook PDF from an allowed host and extracts its plain text
+func Download(pdfURL string) (string, error) {
+	if err := CheckURL(pdfURL); err != nil {
+		return "", err
+	}
+	resp, err := client.Get(pdfURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to download PDF: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to download PDF: status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read PDF: %w", err)
+	}
+	return ExtractText(bytes.NewReader(body), int64(len(body)))
+}
+
+// ExtractText extracts the plain text of every page in a PDF
+func ExtractText(r io.ReaderAt, size int64) (string, error) {
+	reader, err := pdf.NewReader(r, size)
+	if err != nil {
+		return "", fmt.Errorf("failed to open PDF: %w", err)
+	}
+
+	text, err := reader.GetPlainText()
+	if err != nil {
+		return "", fmt.Errorf("failed to extract PDF text: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if _, err := buf.ReadFrom(text); err != nil {
+		return "", fmt.Errorf("failed to read PDF text: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// ScrapeUnits splits the text of an archived handbook into unit entries and extracts
+// best-effort UnitData records from them. Fields which cannot be found are left empty.
+func ScrapeUnits(text string, year int, pdfURL string) []units.UnitData {
+	log.Infof("[PDF ARCHIVE SCRAPER] Extracting data...")
+
+	var result []units.UnitData
+	var current *units.UnitData
+	var field string
+	var synopsis, prerequisites, prohibitions []string
+
+	flush := func() {
+		if current == nil {
+			return
+		}
+		current.Synopsis = strings.TrimSpace(strings.Join(synopsis, " "))
+		current.Requisites = archivedRequisites(strings.Join(prerequisites, " "), strings.Join(prohibitions, " "))
+		current.WAMWeight = units.WAMWeight(units.LevelNumber("", current.Code))
+		result = append(result, *current)
+		synopsis, prerequisites, prohibitions = nil, nil, nil
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		if match := unitHeadingRegex.FindStringSubmatch(line); match != nil {
+			flush()
+			current = &units.UnitData{
+				CommonScraperData: common.CommonScraperData{
+					Link:             pdfURL,
+					Code:             match[1],
+					Title:            strings.TrimSpace(match[2]),
+					SearchTitle:      match[1] + " - " + strings.TrimSpace(match[2]),
+					CurrentYear:      year,
+					AcademicItemType: "Unit",
+				},
+				Source: Source,
+			}
+			field = "synopsis"
+			continue
+		}
+		if current == nil {
+			continue
+		}
+
+		if current.CreditPoints == 0 {
+			if match := creditPointsRegex.FindStringSubmatch(line); match != nil {
+				current.CreditPoints = utils.StringToInt(match[1] + match[2])
+			}
+		}
+
+		value := line
+		if match := fieldRegex.FindStringSubmatch(line); match != nil {
+			field = strings.ToLower(match[1])
+			value = match[2]
+		}
+
+		switch {
+		case strings.HasPrefix(field, "faculty"), strings.HasPrefix(field, "offered"):
+			if current.Faculty == "" {
+				current.Faculty = value
+			}
+		case strings.HasPrefix(field, "prerequisite"):
+			prerequisites = append(prerequisites, value)
+		case strings.HasPrefix(field, "prohibition"):
+			prohibitions = append(prohibitions, value)
+		case field == "synopsis":
+			synopsis = append(synopsis, value)
+		}
+	}
+	flush()
+
+	log.Successf("[PDF ARCHIVE SCRAPER] Extracted %d units.", len(result))
+	return result
+}
+
+// archivedRequisites converts requisite text into compressed requisites.
+// Codes are joined by OR if the text mentions "or", otherwise by AND.
+func archivedRequisites(prerequisites string, prohibitions string) []units.CompressedRequisite {
+	var requisites []units.CompressedRequisite
+	for _, requisite := range []struct {
+		requisiteType string
+		text          string
+	}{
+		{"Prerequisite", prerequisites},
+		{"Prohibition", prohibitions},
+	} {
+		codes := unitCodeRegex.FindAllString(requisite.text, -1)
+		if len(codes) == 0 {
+			continue
+		}
+
+		relationship := "AND"
+		if requisite.requisiteType == "Prohibition" || strings.Contains(strings.ToLower(requisite.text), " or ") {
+			relationship = "OR"
+		}
+
+		container := units.CompressedContainer{Relationship: relationship, Units: []units.CompressedUnit{}}
+		for _, code := range codes {
+			container.Units = append(container.Units, units.CompressedUnit{UnitCode: code, UnitNumber: utils.ExtractUnitNumber(code)})
+		}
+		requisites = append(requisites, units.CompressedRequisite{
+			RequisiteType: requisite.requisiteType,
+			Containers:    []units.CompressedContainer{container},
+		})
+	}
+	return requisites
+}
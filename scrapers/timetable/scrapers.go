@@ -0,0 +1,123 @@
+package timetable
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"handbook-scraper/utils/fetchlog"
+	"handbook-scraper/utils/log"
+)
+
+// defaultFeedURL is the public class timetable subject search
+const defaultFeedURL = "https://my-timetable.monash.edu/even/rest/timetable/subjects"
+
+var client = &http.Client{Timeout: 15 * time.Second, Transport: &fetchlog.Transport{}}
+
+// feedURL returns the timetable feed URL, which can be overridden with MYTIMETABLE_URL
+func feedURL() string {
+	if override := os.Getenv("MYTIMETABLE_URL"); override != "" {
+		return override
+	}
+	return defaultFeedURL
+}
+
+// Scrape queries the class timetable for a unit and summarises which activity types still have open classes
+func Scrape(code string, year string) (AvailabilityData, error) {
+	log.Infof("[TIMETABLE SCRAPER] Extracting data...")
+
+	query := url.Values{}
+	query.Set("search-term", code)
+	query.Set("year", year)
+
+	resp, err := client.Get(feedURL() + "?" + query.Encode())
+	if err != nil {
+		return AvailabilityData{}, fmt.Errorf("failed to query timetable: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return AvailabilityData{}, fmt.Errorf("failed to query timetable: status %d", resp.StatusCode)
+	}
+
+	var subjects map[string]subject
+	if err := json.NewDecoder(resp.Body).Decode(&subjects); err != nil {
+		return AvailabilityData{}, fmt.Errorf("failed to decode timetable: %w", err)
+	}
+
+	data := AvailabilityData{
+		Code:        strings.ToUpper(code),
+		Year:        year,
+		RetrievedAt: time.Now(),
+		Offerings:   []OfferingAvailability{},
+	}
+
+	for key, subject := range subjects {
+		// The search matches on prefixes, so other units may be returned as well
+		if !strings.HasPrefix(strings.ToUpper(key), data.Code+"_") {
+			continue
+		}
+		data.Offerings = append(data.Offerings, summariseOffering(key, subject))
+	}
+	sort.Slice(data.Offerings, func(i, j int) bool { return data.Offerings[i].Offering < data.Offerings[j].Offering })
+
+	log.Successf("[TIMETABLE SCRAPER] Extraction complete.")
+	return data, nil
+}
+
+// summariseOffering groups the classes of an offering by activity type
+func summariseOffering(key string, subject subject) OfferingAvailability {
+	offering := OfferingAvailability{
+		Offering:    key,
+		Description: subject.Description,
+		Campus:      subject.Campus,
+		Activities:  []ActivityAvailability{},
+	}
+
+	byType := map[string]*ActivityAvailability{}
+	var order []string
+	for _, class := range subject.Activities {
+		summary, ok := byType[class.ActivityGroupCode]
+		if !ok {
+			summary = &ActivityAvailability{ActivityType: class.ActivityGroupCode}
+			byType[class.ActivityGroupCode] = summary
+			order = append(order, class.ActivityGroupCode)
+		}
+
+		summary.Classes++
+		if isOpen(class) {
+			summary.OpenClasses++
+			summary.HasOpenSlots = true
+		}
+	}
+
+	sort.Strings(order)
+	for _, activityType := range order {
+		offering.Activities = append(offering.Activities, *byType[activityType])
+	}
+	return offering
+}
+
+// isOpen reports whether a class can still be selected and has places remaining
+func isOpen(class activity) bool {
+	if class.Selectable != "" && class.Selectable != "available" {
+		return false
+	}
+
+	switch v := class.Availability.(type) {
+	case float64:
+		return v > 0
+	case string:
+		remaining, err := strconv.Atoi(strings.TrimSpace(v))
+		return err == nil && remaining > 0
+	default:
+		// Availability is not always published, in which case selectable is all we have
+		return class.Selectable == "available"
+	}
+}
@@ -0,0 +1,42 @@
+package timetable
+
+import "time"
+
+// AvailabilityData holds the class availability of a unit from the public class timetable
+type AvailabilityData struct {
+	Code        string                 `json:"code"`         // FIT2004
+	Year        string                 `json:"year"`         // 2025
+	RetrievedAt time.Time              `json:"retrieved_at"` // When the timetable was queried
+	Offerings   []OfferingAvailability `json:"offerings"`    //
+}
+
+// OfferingAvailability holds the availability of each activity type of a unit offering
+type OfferingAvailability struct {
+	Offering    string                 `json:"offering"`    // FIT2004_CL_S2-01_ON-CAMPUS
+	Description string                 `json:"description"` //
+	Campus      string                 `json:"campus"`      //
+	Activities  []ActivityAvailability `json:"activities"`  //
+}
+
+// ActivityAvailability summarises the classes of one activity type, e.g. Workshop or Applied
+type ActivityAvailability struct {
+	ActivityType string `json:"activity_type"`
+	Classes      int    `json:"classes"`      // Number of classes of this activity type
+	OpenClasses  int    `json:"open_classes"` // Classes which can still be selected
+	HasOpenSlots bool   `json:"has_open_slots"`
+}
+
+// subject represents a subject entry in the timetable feed
+type subject struct {
+	SubjectCode string              `json:"subject_code"`
+	Description string              `json:"description"`
+	Campus      string              `json:"campus"`
+	Activities  map[string]activity `json:"activities"`
+}
+
+// activity represents a single class in the timetable feed
+type activity struct {
+	ActivityGroupCode string      `json:"activity_group_code"`
+	Selectable        string      `json:"selectable"`   // "available", "full", ...
+	Availability      interface{} `json:"availability"` // Remaining places, sent as a number or string
+}
@@ -0,0 +1,113 @@
+package units
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Effects of a course requirement on students of its courses
+const (
+	CourseRequired           = "required"             // Only students of one of the courses can take the unit
+	CourseExcluded           = "excluded"             // Students of the courses cannot take the unit
+	CourseWaivesPrerequisite = "waives_prerequisites" // Students of the courses need none of the prerequisites
+)
+
+var (
+	// courseCodeInText matches course codes mentioned in free text, such as "C2001", but not unit codes
+	courseCodeInText = regexp.MustCompile(`\b[A-Z][0-9]{4}\b`)
+	// courseCode matches a course code
+	courseCode = regexp.MustCompile(`^[A-Z][0-9]{4}$`)
+)
+
+// CourseRequirement is a requisite of a unit on the course a student is admitted to, such as
+// "You must be enrolled in course B6002 or B6038", parsed from the enrolment rules of the unit
+type CourseRequirement struct {
+	Effect  string   `json:"effect"`  // required, excluded or waives_prerequisites
+	Courses []string `json:"courses"` // Any of the courses
+	Rule    string   `json:"rule"`    // Sentence of the enrolment rules the requirement was parsed from
+}
+
+// ParseCourseRequirements parses the sentences of enrolment rules which admit or exclude the students of courses.
+// Sentences which make other requisites depend on the course, such as "If you are enrolled in course B6005 you must
+// have completed ACX5903", are left to the text, as are sentences the patterns do not recognise.
+func ParseCourseRequirements(rules []EnrolmentRule) []CourseRequirement {
+	var requirements []CourseRequirement
+	for _, rule := range rules {
+		for _, sentence := range ruleSentences(rule.Description) {
+			courses := courseCodeInText.FindAllString(sentence, -1)
+			if len(courses) == 0 {
+				continue
+			}
+			lower := strings.ToLower(sentence)
+			conditional := strings.HasPrefix(lower, "if ")
+
+			var effect string
+			switch {
+			case strings.Contains(lower, "enrolled in") && (strings.Contains(lower, "cannot undertake") || strings.Contains(lower, "cannot enrol") || strings.Contains(lower, "not eligible")):
+				effect = CourseExcluded
+			case strings.Contains(lower, "enrolled in") && strings.Contains(lower, "no prerequisites"):
+				effect = CourseWaivesPrerequisite
+			case !conditional && (strings.Contains(lower, "must be enrolled in") || strings.Contains(lower, "must be admitted to") ||
+				strings.Contains(lower, "only available to students") || strings.Contains(lower, "restricted to students")):
+				effect = CourseRequired
+			default:
+				continue
+			}
+
+			requirements = append(requirements, CourseRequirement{Effect: effect, Courses: uniqueStrings(courses), Rule: sentence})
+		}
+	}
+	return requirements
+}
+
+// ruleSentences splits the text of an enrolment rule into its sentences
+func ruleSentences(text string) []string {
+	var sentences []string
+	for _, line := range strings.Split(text, "\n") {
+		for _, sentence := range strings.SplitAfter(line, ". ") {
+			if sentence = strings.TrimSpace(sentence); sentence != "" {
+				sentences = append(sentences, sentence)
+			}
+		}
+	}
+	return sentences
+}
+
+// withCourseRequirements adds the course requirements of enrolment rules to the requisites of a unit.
+// Requirements which admit students go to the prerequisites and exclusions to the prohibitions,
+// which are added if the unit has none.
+func withCourseRequirements(requisites []CompressedRequisite, rules []EnrolmentRule) []CompressedRequisite {
+	for _, requirement := range ParseCourseRequirements(rules) {
+		requisiteType := "Prerequisite"
+		if requirement.Effect == CourseExcluded {
+			requisiteType = "Prohibition"
+		}
+
+		i := slices.IndexFunc(requisites, func(requisite CompressedRequisite) bool {
+			return requisite.RequisiteType == requisiteType
+		})
+		if i == -1 {
+			requisites = append(requisites, CompressedRequisite{RequisiteType: requisiteType, Containers: []CompressedContainer{}})
+			i = len(requisites) - 1
+		}
+		requisites[i].CourseRequirements = append(requisites[i].CourseRequirements, requirement)
+	}
+	return requisites
+}
+
+// isCourseRelationship reports whether a relationship of a requisite is the admission to a course rather than a unit
+func isCourseRelationship(rel Relationship) bool {
+	return strings.Contains(strings.ToLower(rel.AcademicItemType.Value), "course") || courseCode.MatchString(rel.AcademicItemCode)
+}
+
+// uniqueStrings removes repeated strings, keeping their order
+func uniqueStrings(values []string) []string {
+	unique := make([]string, 0, len(values))
+	for _, value := range values {
+		if !slices.Contains(unique, value) {
+			unique = append(unique, value)
+		}
+	}
+	return unique
+}
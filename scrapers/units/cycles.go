@@ -0,0 +1,241 @@
+package units
+
+import (
+	"fmt"
+	"sort"
+)
+
+// RequisiteCycle is a group of units whose prerequisites refer to each other
+type RequisiteCycle struct {
+	Units      []string `json:"units"`
+	Impossible bool     `json:"impossible"` // Every unit in the cycle must be completed before another, so none can be taken
+}
+
+// ImpossibleRequisite is a unit whose requisites cannot be satisfied on their own
+type ImpossibleRequisite struct {
+	UnitCode string `json:"unit_code"`
+	Reason   string `json:"reason"`
+}
+
+// RequisiteReport is the result of analysing the requisites of every unit in a year
+type RequisiteReport struct {
+	Units      int                   `json:"units"`
+	Cycles     []RequisiteCycle      `json:"cycles"`
+	Impossible []ImpossibleRequisite `json:"impossible"`
+}
+
+// AnalyseRequisites finds prerequisite cycles and impossible requisite structures across a set of units.
+// A cycle is impossible when it only follows units that are required in every way of meeting the prerequisites,
+// otherwise it runs through an alternative and may only be worth a look.
+func AnalyseRequisites(unitsByCode map[string]UnitData) RequisiteReport {
+	report := RequisiteReport{Units: len(unitsByCode), Cycles: []RequisiteCycle{}, Impossible: []ImpossibleRequisite{}}
+
+	referenced := map[string][]string{}
+	mandatory := map[string][]string{}
+	for code, unitData := range unitsByCode {
+		required := map[string]bool{}
+		anyOf := map[string]bool{}
+		prohibited := map[string]bool{}
+
+		for _, requisite := range unitData.Requisites {
+			switch requisite.RequisiteType {
+			case "Prerequisite":
+				for _, container := range requisite.Containers {
+					for unit := range mandatoryUnits(container) {
+						required[unit] = true
+					}
+					collectUnits(container, anyOf)
+					if isEmptyContainer(container) {
+						report.Impossible = append(report.Impossible, ImpossibleRequisite{UnitCode: code, Reason: "prerequisite has an empty container"})
+					}
+				}
+			case "Prohibition":
+				for _, container := range requisite.Containers {
+					collectUnits(container, prohibited)
+				}
+			}
+		}
+
+		if required[code] {
+			report.Impossible = append(report.Impossible, ImpossibleRequisite{UnitCode: code, Reason: "unit is its own prerequisite"})
+		}
+		for _, unit := range sortedKeys(required) {
+			if prohibited[unit] {
+				report.Impossible = append(report.Impossible, ImpossibleRequisite{UnitCode: code, Reason: fmt.Sprintf("%s is both required and prohibited", unit)})
+			}
+		}
+
+		referenced[code] = sortedKeys(anyOf)
+		mandatory[code] = sortedKeys(required)
+	}
+
+	for _, component := range stronglyConnected(mandatory) {
+		report.Cycles = append(report.Cycles, RequisiteCycle{Units: component, Impossible: true})
+	}
+	for _, component := range stronglyConnected(referenced) {
+		// Components over mandatory edges are also components over all edges, so only report them once
+		if containsCycle(report.Cycles, component) {
+			continue
+		}
+		report.Cycles = append(report.Cycles, RequisiteCycle{Units: component})
+	}
+
+	sort.Slice(report.Impossible, func(i, j int) bool {
+		if report.Impossible[i].UnitCode != report.Impossible[j].UnitCode {
+			return report.Impossible[i].UnitCode < report.Impossible[j].UnitCode
+		}
+		return report.Impossible[i].Reason < report.Impossible[j].Reason
+	})
+	sort.SliceStable(report.Cycles, func(i, j int) bool {
+		if report.Cycles[i].Impossible != report.Cycles[j].Impossible {
+			return report.Cycles[i].Impossible
+		}
+		return report.Cycles[i].Units[0] < report.Cycles[j].Units[0]
+	})
+	return report
+}
+
+// mandatoryUnits returns the units which every way of meeting a container requires
+func mandatoryUnits(container CompressedContainer) map[string]bool {
+	var options []map[string]bool
+	for _, unit := range container.Units {
+		options = append(options, map[string]bool{unit.UnitCode: true})
+	}
+	for _, subContainer := range container.Containers {
+		options = append(options, mandatoryUnits(subContainer))
+	}
+
+	required := map[string]bool{}
+	if len(options) == 0 {
+		return required
+	}
+	if container.Relationship == "OR" {
+		// Only units required by every alternative are mandatory
+		for unit := range options[0] {
+			required[unit] = true
+		}
+		for _, option := range options[1:] {
+			for unit := range required {
+				if !option[unit] {
+					delete(required, unit)
+				}
+			}
+		}
+		return required
+	}
+	for _, option := range options {
+		for unit := range option {
+			required[unit] = true
+		}
+	}
+	return required
+}
+
+// collectUnits adds every unit mentioned in a container to units
+func collectUnits(container CompressedContainer, units map[string]bool) {
+	for _, unit := range container.Units {
+		units[unit.UnitCode] = true
+	}
+	for _, subContainer := range container.Containers {
+		collectUnits(subContainer, units)
+	}
+}
+
+// isEmptyContainer reports whether an OR container, or any of its children, offers no alternative to choose
+func isEmptyContainer(container CompressedContainer) bool {
+	if container.Relationship == "OR" && len(container.Units) == 0 && len(container.Containers) == 0 {
+		return true
+	}
+	for _, subContainer := range container.Containers {
+		if isEmptyContainer(subContainer) {
+			return true
+		}
+	}
+	return false
+}
+
+// stronglyConnected finds the cycles of a graph using Tarjan's algorithm.
+// Each component with more than one unit, or a unit referring to itself, is returned sorted.
+func stronglyConnected(graph map[string][]string) [][]string {
+	index := map[string]int{}
+	lowLink := map[string]int{}
+	onStack := map[string]bool{}
+	var stack []string
+	var components [][]string
+
+	var connect func(node string)
+	connect = func(node string) {
+		index[node] = len(index)
+		lowLink[node] = index[node]
+		stack = append(stack, node)
+		onStack[node] = true
+
+		selfLoop := false
+		for _, next := range graph[node] {
+			if next == node {
+				selfLoop = true
+			}
+			if _, visited := index[next]; !visited {
+				connect(next)
+				lowLink[node] = min(lowLink[node], lowLink[next])
+			} else if onStack[next] {
+				lowLink[node] = min(lowLink[node], index[next])
+			}
+		}
+
+		if lowLink[node] != index[node] {
+			return
+		}
+		var component []string
+		for {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[top] = false
+			component = append(component, top)
+			if top == node {
+				break
+			}
+		}
+		if len(component) > 1 || selfLoop {
+			sort.Strings(component)
+			components = append(components, component)
+		}
+	}
+
+	for _, node := range sortedKeys(graph) {
+		if _, visited := index[node]; !visited {
+			connect(node)
+		}
+	}
+	return components
+}
+
+// containsCycle reports whether a cycle with exactly the units of component was already reported
+func containsCycle(cycles []RequisiteCycle, component []string) bool {
+	for _, cycle := range cycles {
+		if len(cycle.Units) != len(component) {
+			continue
+		}
+		same := true
+		for i := range component {
+			if cycle.Units[i] != component[i] {
+				same = false
+				break
+			}
+		}
+		if same {
+			return true
+		}
+	}
+	return false
+}
+
+// sortedKeys returns the keys of a map in sorted order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,39 @@
+package units
+
+// UnitSummary is the title and credit points of a unit referenced by requisites
+type UnitSummary struct {
+	Title        string
+	CreditPoints int
+}
+
+// EnrichRequisites fills in the title and credit points of every requisite unit found by lookup.
+// It returns the codes of the units lookup could not find.
+func EnrichRequisites(requisites []CompressedRequisite, lookup func(code string) (UnitSummary, bool)) []string {
+	var missing []string
+	seen := map[string]bool{}
+
+	var enrich func(containers []CompressedContainer)
+	enrich = func(containers []CompressedContainer) {
+		for i := range containers {
+			for j := range containers[i].Units {
+				unit := &containers[i].Units[j]
+				summary, ok := lookup(unit.UnitCode)
+				if !ok {
+					if !seen[unit.UnitCode] {
+						seen[unit.UnitCode] = true
+						missing = append(missing, unit.UnitCode)
+					}
+					continue
+				}
+				unit.Title = summary.Title
+				unit.CreditPoints = summary.CreditPoints
+			}
+			enrich(containers[i].Containers)
+		}
+	}
+
+	for i := range requisites {
+		enrich(requisites[i].Containers)
+	}
+	return missing
+}
@@ -0,0 +1,37 @@
+package units
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Equivalents returns the codes of the units equivalent to a unit, such as the codes it had in earlier years
+type Equivalents func(code string) []string
+
+// replacementHint matches the handbook's notes on code changes, such as "This unit replaces FIT1040"
+// or "Replaced by FIT2099", capturing the clause naming the other units
+var replacementHint = regexp.MustCompile(`(?i)\b(replaced by|replaces|formerly(?: coded| known as)?|previously (?:coded|offered|known)(?: as)?)\b([^.;]*)`)
+
+// ReplacementHints returns the units a unit replaces and is replaced by, according to its synopsis and enrolment rules
+func ReplacementHints(unitData UnitData) ([]string, []string) {
+	replaces, replacedBy := map[string]bool{}, map[string]bool{}
+	check := func(text string) {
+		for _, match := range replacementHint.FindAllStringSubmatch(text, -1) {
+			found := replaces
+			if strings.EqualFold(match[1], "replaced by") {
+				found = replacedBy
+			}
+			for _, code := range unitCodeInText.FindAllString(match[2], -1) {
+				if code != unitData.Code {
+					found[code] = true
+				}
+			}
+		}
+	}
+
+	check(unitData.Synopsis)
+	for _, rule := range unitData.EnrolmentRules {
+		check(rule.Description)
+	}
+	return sortedKeys(replaces), sortedKeys(replacedBy)
+}
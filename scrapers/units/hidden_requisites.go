@@ -0,0 +1,64 @@
+package units
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// unitCodeInText matches unit codes mentioned in free text, such as "FIT1045"
+var unitCodeInText = regexp.MustCompile(`\b[A-Z]{3}[0-9]{4}\b`)
+
+// HiddenRequisite is a unit mentioned in the text of a unit page but missing from its structured requisites
+type HiddenRequisite struct {
+	UnitCode string `json:"unit_code"`
+	Source   string `json:"source"` // synopsis or enrolment_rules
+	Message  string `json:"message"`
+}
+
+// FindHiddenRequisites flags units referenced by the synopsis or enrolment rules of a unit
+// which are not listed in its structured requisites.
+// These are advisory only, as the text may mention a unit for reasons other than a requirement.
+func FindHiddenRequisites(unitData UnitData) []HiddenRequisite {
+	listed := map[string]bool{unitData.Code: true}
+	var collect func(containers []CompressedContainer)
+	collect = func(containers []CompressedContainer) {
+		for _, container := range containers {
+			for _, unit := range container.Units {
+				listed[unit.UnitCode] = true
+			}
+			collect(container.Containers)
+		}
+	}
+	for _, requisite := range unitData.Requisites {
+		collect(requisite.Containers)
+	}
+
+	var hidden []HiddenRequisite
+	check := func(text string, source string) {
+		for _, code := range unitCodeInText.FindAllString(text, -1) {
+			if listed[code] {
+				continue
+			}
+			listed[code] = true
+			hidden = append(hidden, HiddenRequisite{
+				UnitCode: code,
+				Source:   source,
+				Message:  fmt.Sprintf("%s is mentioned in the %s but is not a listed requisite", code, sourceName(source)),
+			})
+		}
+	}
+
+	check(unitData.Synopsis, "synopsis")
+	for _, rule := range unitData.EnrolmentRules {
+		check(rule.Description, "enrolment_rules")
+	}
+	return hidden
+}
+
+// sourceName is the readable name of where a hidden requisite was found
+func sourceName(source string) string {
+	if source == "enrolment_rules" {
+		return "enrolment rules"
+	}
+	return source
+}
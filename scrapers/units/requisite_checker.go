@@ -3,13 +3,25 @@ package units
 import (
 	"fmt"
 	"handbook-scraper/scrapers/common"
+	"slices"
+	"strings"
 )
 
 // CheckRequisites checks if a student meets the prerequisites and prohibitions for a given unit.
 // It takes a UnitData struct and a slice of completed units as input.
 // It returns true if all prerequisites are met and no prohibitions are violated, false otherwise,
 // a message explaining why the prereqs are not met or prohibitions are violated, and an error if any occurs.
+// Requisites on the student's course are not checked, see CheckRequisitesForCourse.
 func CheckRequisites(unitData UnitData, completedUnits []common.Unit) (bool, []string, error) {
+	return CheckRequisitesForCourse(unitData, completedUnits, "", nil)
+}
+
+// CheckRequisitesForCourse checks the requisites of a unit like CheckRequisites, for a student admitted to a course.
+// Course admissions among the requisites are met by the course, and the course requirements of the enrolment rules
+// can admit, exclude, or waive the prerequisites of its students. Without a course, course requirements are skipped,
+// and course admissions are never met. Units completed under a code equivalents looks up count as the unit itself,
+// unless equivalents is nil.
+func CheckRequisitesForCourse(unitData UnitData, completedUnits []common.Unit, course string, equivalents Equivalents) (bool, []string, error) {
 	if len(unitData.Requisites) == 0 {
 		// If there are no requisites, the student automatically meets the requirements
 		return true, []string{}, nil
@@ -20,7 +32,12 @@ func CheckRequisites(unitData UnitData, completedUnits []common.Unit) (bool, []s
 	// Iterate through each requisite
 	for _, requisite := range unitData.Requisites {
 		if requisite.RequisiteType == "Prerequisite" {
-			met, messages, err := checkContainer(requisite.Containers, completedUnits, false)
+			waived, messages := checkCourseRequirements(requisite.CourseRequirements, course)
+			unmetRequisites = append(unmetRequisites, messages...)
+			if waived {
+				continue
+			}
+			met, messages, err := checkContainer(requisite.Containers, completedUnits, course, equivalents, false)
 			if err != nil {
 				return false, []string{}, fmt.Errorf("error checking prerequisite container: %w", err)
 			}
@@ -28,7 +45,9 @@ func CheckRequisites(unitData UnitData, completedUnits []common.Unit) (bool, []s
 				unmetRequisites = append(unmetRequisites, messages...)
 			}
 		} else if requisite.RequisiteType == "Prohibition" {
-			met, messages, err := checkContainer(requisite.Containers, completedUnits, true)
+			_, messages := checkCourseRequirements(requisite.CourseRequirements, course)
+			unmetRequisites = append(unmetRequisites, messages...)
+			met, messages, err := checkContainer(requisite.Containers, completedUnits, course, equivalents, true)
 			if err != nil {
 				return false, []string{}, fmt.Errorf("error checking prohibition container: %w", err)
 			}
@@ -46,11 +65,46 @@ func CheckRequisites(unitData UnitData, completedUnits []common.Unit) (bool, []s
 	return true, []string{}, nil
 }
 
+// checkCourseRequirements checks the course requirements of a requisite against the student's course.
+// It returns whether the course waives the prerequisites, and a message for each requirement the course fails.
+func checkCourseRequirements(requirements []CourseRequirement, course string) (bool, []string) {
+	if course == "" {
+		return false, nil
+	}
+
+	waived := false
+	var messages []string
+	for _, requirement := range requirements {
+		admitted := slices.Contains(requirement.Courses, course)
+		switch {
+		case requirement.Effect == CourseWaivesPrerequisite && admitted:
+			waived = true
+		case requirement.Effect == CourseRequired && !admitted:
+			messages = append(messages, "Requires admission to one of: "+strings.Join(requirement.Courses, " or "))
+		case requirement.Effect == CourseExcluded && admitted:
+			messages = append(messages, "Not available to students of "+course)
+		}
+	}
+	return waived, messages
+}
+
+// courseAdmissions returns the courses of a container the student is admitted to, or is not if admitted is false,
+// as "admission to" mentions for messages
+func courseAdmissions(container CompressedContainer, course string, admitted bool) []string {
+	var mentions []string
+	for _, code := range container.Courses {
+		if (course != "" && code == course) == admitted {
+			mentions = append(mentions, "admission to "+code)
+		}
+	}
+	return mentions
+}
+
 // checkContainer recursively checks if a container's requirements are met.
 // It takes a slice of CompressedContainer, a slice of completed units, and a boolean indicating whether to check for prohibitions as input.
 // It returns true if the requirements of all containers are met (or no prohibitions are violated), false otherwise,
 // a message explaining why the prereqs are not met or prohibitions are violated, and an error if any occurs.
-func checkContainer(containers []CompressedContainer, completedUnits []common.Unit, isProhibition bool) (bool, []string, error) {
+func checkContainer(containers []CompressedContainer, completedUnits []common.Unit, course string, equivalents Equivalents, isProhibition bool) (bool, []string, error) {
 	if len(containers) == 0 {
 		return true, []string{}, nil // No containers, consider it met
 	}
@@ -58,7 +112,7 @@ func checkContainer(containers []CompressedContainer, completedUnits []common.Un
 	var unmetRequisites []string
 
 	for _, container := range containers {
-		met, messages, err := checkContainerLogic(container, completedUnits, isProhibition)
+		met, messages, err := checkContainerLogic(container, completedUnits, course, equivalents, isProhibition)
 		if err != nil {
 			return false, []string{}, fmt.Errorf("error checking container logic: %w", err)
 		}
@@ -77,11 +131,11 @@ func checkContainer(containers []CompressedContainer, completedUnits []common.Un
 // It takes a CompressedContainer, a slice of completed units, and a boolean indicating whether to check for prohibitions as input.
 // It returns true if the container's logic is met (or no prohibitions are violated), false otherwise,
 // a message explaining why the prereqs are not met or prohibitions are violated, and an error if any occurs.
-func checkContainerLogic(container CompressedContainer, completedUnits []common.Unit, isProhibition bool) (bool, []string, error) {
+func checkContainerLogic(container CompressedContainer, completedUnits []common.Unit, course string, equivalents Equivalents, isProhibition bool) (bool, []string, error) {
 	if container.Relationship == "AND" {
-		return checkAndLogic(container, completedUnits, isProhibition)
+		return checkAndLogic(container, completedUnits, course, equivalents, isProhibition)
 	} else if container.Relationship == "OR" {
-		return checkOrLogic(container, completedUnits, isProhibition)
+		return checkOrLogic(container, completedUnits, course, equivalents, isProhibition)
 	} else {
 		return false, []string{}, fmt.Errorf("unknown relationship type: %s", container.Relationship)
 	}
@@ -91,8 +145,8 @@ func checkContainerLogic(container CompressedContainer, completedUnits []common.
 // It takes a CompressedContainer, a slice of completed units, and a boolean indicating whether to check for prohibitions as input.
 // It returns true if all units and subcontainers are met (or no prohibitions are violated), false otherwise,
 // a message explaining why the prereqs are not met or prohibitions are violated, and an error if any occurs.
-func checkAndLogic(container CompressedContainer, completedUnits []common.Unit, isProhibition bool) (bool, []string, error) {
-	if len(container.Units) == 0 && len(container.Containers) == 0 {
+func checkAndLogic(container CompressedContainer, completedUnits []common.Unit, course string, equivalents Equivalents, isProhibition bool) (bool, []string, error) {
+	if len(container.Units) == 0 && len(container.Courses) == 0 && len(container.Containers) == 0 {
 		return true, []string{}, nil // No units or containers, consider it met
 	}
 
@@ -101,13 +155,14 @@ func checkAndLogic(container CompressedContainer, completedUnits []common.Unit,
 
 	if !isProhibition {
 		for _, unit := range container.Units {
-			if !isUnitCompleted(unit, completedUnits) {
+			if !isUnitCompleted(unit, completedUnits, equivalents) {
 				mentionedUnits = append(mentionedUnits, unit.UnitCode)
 			}
 		}
+		mentionedUnits = append(mentionedUnits, courseAdmissions(container, course, false)...)
 
 		for _, subContainer := range container.Containers {
-			met, messages, err := checkContainer([]CompressedContainer{subContainer}, completedUnits, isProhibition)
+			met, messages, err := checkContainer([]CompressedContainer{subContainer}, completedUnits, course, equivalents, isProhibition)
 			if err != nil {
 				return false, []string{}, fmt.Errorf("error checking subcontainer: %w", err)
 			}
@@ -129,13 +184,14 @@ func checkAndLogic(container CompressedContainer, completedUnits []common.Unit,
 		}
 	} else {
 		for _, unit := range container.Units {
-			if isUnitCompleted(unit, completedUnits) {
+			if isUnitCompleted(unit, completedUnits, equivalents) {
 				mentionedUnits = append(mentionedUnits, unit.UnitCode)
 			}
 		}
+		mentionedUnits = append(mentionedUnits, courseAdmissions(container, course, true)...)
 
 		for _, subContainer := range container.Containers {
-			met, messages, err := checkContainer([]CompressedContainer{subContainer}, completedUnits, isProhibition)
+			met, messages, err := checkContainer([]CompressedContainer{subContainer}, completedUnits, course, equivalents, isProhibition)
 			if err != nil {
 				return false, []string{}, fmt.Errorf("error checking subcontainer: %w", err)
 			}
@@ -168,8 +224,8 @@ func checkAndLogic(container CompressedContainer, completedUnits []common.Unit,
 // It takes a CompressedContainer, a slice of completed units, and a boolean indicating whether to check for prohibitions as input.
 // It returns true if at least one unit or subcontainer is met (or no prohibitions are violated), false otherwise,
 // a message explaining why the prereqs are not met or prohibitions are violated, and an error if any occurs.
-func checkOrLogic(container CompressedContainer, completedUnits []common.Unit, isProhibition bool) (bool, []string, error) {
-	if len(container.Units) == 0 && len(container.Containers) == 0 {
+func checkOrLogic(container CompressedContainer, completedUnits []common.Unit, course string, equivalents Equivalents, isProhibition bool) (bool, []string, error) {
+	if len(container.Units) == 0 && len(container.Courses) == 0 && len(container.Containers) == 0 {
 		return true, []string{}, nil // No units or containers, consider it met
 	}
 
@@ -177,13 +233,16 @@ func checkOrLogic(container CompressedContainer, completedUnits []common.Unit, i
 
 	if !isProhibition {
 		for _, unit := range container.Units {
-			if isUnitCompleted(unit, completedUnits) {
+			if isUnitCompleted(unit, completedUnits, equivalents) {
 				return true, []string{}, nil // If any unit is completed, return true
 			}
 		}
+		if len(courseAdmissions(container, course, true)) > 0 {
+			return true, []string{}, nil // If the student is admitted to any of the courses, return true
+		}
 
 		for _, subContainer := range container.Containers {
-			met, _, err := checkContainer([]CompressedContainer{subContainer}, completedUnits, isProhibition)
+			met, _, err := checkContainer([]CompressedContainer{subContainer}, completedUnits, course, equivalents, isProhibition)
 			if err != nil {
 				return false, []string{}, fmt.Errorf("error checking subcontainer: %w", err)
 			}
@@ -194,19 +253,21 @@ func checkOrLogic(container CompressedContainer, completedUnits []common.Unit, i
 
 		// Collect unmet units
 		for _, unit := range container.Units {
-			if !isUnitCompleted(unit, completedUnits) {
+			if !isUnitCompleted(unit, completedUnits, equivalents) {
 				mentionedUnits = append(mentionedUnits, unit.UnitCode)
 			}
 		}
+		mentionedUnits = append(mentionedUnits, courseAdmissions(container, course, false)...)
 	} else {
 		for _, unit := range container.Units {
-			if isUnitCompleted(unit, completedUnits) {
+			if isUnitCompleted(unit, completedUnits, equivalents) {
 				mentionedUnits = append(mentionedUnits, unit.UnitCode)
 			}
 		}
+		mentionedUnits = append(mentionedUnits, courseAdmissions(container, course, true)...)
 
 		for _, subContainer := range container.Containers {
-			met, _, err := checkContainer([]CompressedContainer{subContainer}, completedUnits, isProhibition)
+			met, _, err := checkContainer([]CompressedContainer{subContainer}, completedUnits, course, equivalents, isProhibition)
 			if err != nil {
 				return false, []string{}, fmt.Errorf("error checking subcontainer: %w", err)
 			}
@@ -245,7 +306,7 @@ func checkOrLogic(container CompressedContainer, completedUnits []common.Unit, i
 	}
 
 	for _, subContainer := range container.Containers {
-		_, messages, _ := checkContainer([]CompressedContainer{subContainer}, completedUnits, isProhibition)
+		_, messages, _ := checkContainer([]CompressedContainer{subContainer}, completedUnits, course, equivalents, isProhibition)
 		unmetRequisites = append(unmetRequisites, messages...)
 	}
 
@@ -256,14 +317,26 @@ func checkOrLogic(container CompressedContainer, completedUnits []common.Unit, i
 	return isProhibition, []string{}, nil // No units or subcontainers met or all prohibitions violated
 }
 
-// isUnitCompleted checks if a unit is in the list of completed units.
-// It takes a CompressedUnit and a slice of completed units as input.
+// isUnitCompleted checks if a unit, or a unit equivalent to it, is in the list of completed units.
+// It takes a CompressedUnit, a slice of completed units, and how equivalent units are looked up as input.
 // It returns true if the unit is in the list of completed units, false otherwise.
-func isUnitCompleted(unit CompressedUnit, completedUnits []common.Unit) bool {
+func isUnitCompleted(unit CompressedUnit, completedUnits []common.Unit, equivalents Equivalents) bool {
 	for _, completed := range completedUnits {
 		if completed.Code == unit.UnitCode {
 			return true
 		}
 	}
+
+	// Units completed under an earlier or later code count as the unit itself
+	if equivalents == nil {
+		return false
+	}
+	for _, equivalent := range equivalents(unit.UnitCode) {
+		for _, completed := range completedUnits {
+			if completed.Code == equivalent {
+				return true
+			}
+		}
+	}
 	return false
 }
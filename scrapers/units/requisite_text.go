@@ -0,0 +1,127 @@
+package units
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// RenderedRequisite is a requisite of a unit rendered as a sentence
+type RenderedRequisite struct {
+	RequisiteType string `json:"requisite_type"`
+	Text          string `json:"text"`
+}
+
+// RenderRequisites renders the requisites of a unit as sentences in the phrasing of the handbook,
+// such as "Prerequisite: FIT2004 and one of MAT1830 or MTH1030.", followed by its course requirements
+func RenderRequisites(unitData UnitData) []RenderedRequisite {
+	rendered := []RenderedRequisite{}
+	for _, requisite := range unitData.Requisites {
+		if text := renderContainers(requisite.Containers); text != "" {
+			rendered = append(rendered, RenderedRequisite{
+				RequisiteType: requisite.RequisiteType,
+				Text:          requisite.RequisiteType + ": " + capitalise(text) + ".",
+			})
+		}
+		for _, requirement := range requisite.CourseRequirements {
+			rendered = append(rendered, RenderedRequisite{
+				RequisiteType: requisite.RequisiteType,
+				Text:          renderCourseRequirement(requirement),
+			})
+		}
+	}
+	return rendered
+}
+
+// RequisiteParagraph joins the rendered requisites of a unit into a paragraph, for chatbots and voice assistants
+func RequisiteParagraph(unitData UnitData) string {
+	rendered := RenderRequisites(unitData)
+	if len(rendered) == 0 {
+		return unitData.Code + " has no requisites."
+	}
+
+	sentences := make([]string, 0, len(rendered))
+	for _, requisite := range rendered {
+		sentences = append(sentences, requisite.Text)
+	}
+	return strings.Join(sentences, " ")
+}
+
+// renderContainers renders the top-level containers of a requisite, all of which have to be met
+func renderContainers(containers []CompressedContainer) string {
+	var parts []string
+	for _, container := range containers {
+		if text, _ := renderContainer(container); text != "" {
+			parts = append(parts, text)
+		}
+	}
+	return joinList(parts, "and")
+}
+
+// renderContainer renders a container as a phrase, also returning how many items it lists,
+// so containers nested in others are parenthesised when they list more than one
+func renderContainer(container CompressedContainer) (string, int) {
+	var items []string
+	for _, unit := range container.Units {
+		items = append(items, unit.UnitCode)
+	}
+	for _, course := range container.Courses {
+		items = append(items, "admission to "+course)
+	}
+	for _, child := range container.Containers {
+		text, count := renderContainer(child)
+		if text == "" {
+			continue
+		}
+		if count > 1 {
+			text = "(" + text + ")"
+		}
+		items = append(items, text)
+	}
+
+	switch {
+	case len(items) == 0:
+		return "", 0
+	case len(items) == 1:
+		return items[0], 1
+	case container.Relationship == "OR" && len(items) > 2:
+		return "one of " + joinList(items, "or"), len(items)
+	case container.Relationship == "OR":
+		return joinList(items, "or"), len(items)
+	default:
+		return joinList(items, "and"), len(items)
+	}
+}
+
+// renderCourseRequirement renders a course requirement parsed from the enrolment rules
+func renderCourseRequirement(requirement CourseRequirement) string {
+	courses := joinList(requirement.Courses, "or")
+	switch requirement.Effect {
+	case CourseRequired:
+		return "Only available to students enrolled in " + courses + "."
+	case CourseExcluded:
+		return "Not available to students enrolled in " + courses + "."
+	case CourseWaivesPrerequisite:
+		return "Students enrolled in " + courses + " need none of the prerequisites."
+	default:
+		return requirement.Rule
+	}
+}
+
+// joinList joins items as in a sentence, such as "A, B or C"
+func joinList(items []string, conjunction string) string {
+	switch len(items) {
+	case 0:
+		return ""
+	case 1:
+		return items[0]
+	default:
+		return strings.Join(items[:len(items)-1], ", ") + " " + conjunction + " " + items[len(items)-1]
+	}
+}
+
+// capitalise upper-cases the first letter of a sentence
+func capitalise(text string) string {
+	first, size := utf8.DecodeRuneInString(text)
+	return string(unicode.ToUpper(first)) + text[size:]
+}
@@ -0,0 +1,111 @@
+package units
+
+import (
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"handbook-scraper/utils"
+	"handbook-scraper/utils/log"
+)
+
+// resourceHosts maps the hosts of official unit resources, or a host and the path the resources are under,
+// to their resource type
+var resourceHosts = map[string]string{
+	"unitguides.monash.edu":   "unit_guide",
+	"learning.monash.edu":     "moodle",
+	"lms.monash.edu":          "moodle",
+	"readinglists.monash.edu": "reading_list",
+	"monash.rl.talis.com":     "reading_list",
+	"guides.lib.monash.edu":   "library",
+	"www.monash.edu/library":  "library",
+}
+
+// anchorRegex matches an HTML anchor and captures its href and text
+var anchorRegex = regexp.MustCompile(`(?is)<a[^>]+href=["']([^"']+)["'][^>]*>(.*?)</a>`)
+
+// urlRegex matches bare URLs in plain text
+var urlRegex = regexp.MustCompile(`https?://[^\s"'<>]+`)
+
+// resources extracts links to unit guides, Moodle, and reading lists from anywhere in the page content.
+func resources(data map[string]interface{}) []Resource {
+	pageContent := utils.GetTypedValue[map[string]interface{}](data, "props.pageProps.pageContent")
+	if pageContent == nil {
+		return []Resource{}
+	}
+
+	found := map[string]Resource{}
+	collectResources(pageContent, found)
+
+	result := make([]Resource, 0, len(found))
+	for _, resource := range found {
+		result = append(result, resource)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].URL < result[j].URL })
+
+	log.Logf("[UNIT SCRAPER] Found %d resource links", len(result))
+	return result
+}
+
+// collectResources recursively walks the JSON value and collects resource links found in strings
+func collectResources(value interface{}, found map[string]Resource) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, child := range v {
+			collectResources(child, found)
+		}
+	case []interface{}:
+		for _, child := range v {
+			collectResources(child, found)
+		}
+	case string:
+		// Anchors carry a title, so they are collected before bare URLs
+		for _, match := range anchorRegex.FindAllStringSubmatch(v, -1) {
+			addResource(match[1], strings.TrimSpace(utils.RemoveHTMLTags(match[2])), found)
+		}
+		for _, link := range urlRegex.FindAllString(v, -1) {
+			addResource(link, "", found)
+		}
+	}
+}
+
+// addResource adds the link if it points to a known resource host
+func addResource(link string, title string, found map[string]Resource) {
+	link = strings.TrimRight(link, ".,;)")
+	if _, exists := found[link]; exists {
+		return
+	}
+
+	kind := resourceType(link)
+	if kind == "" {
+		return
+	}
+
+	found[link] = Resource{Type: kind, Title: title, URL: link}
+}
+
+// resourceType classifies a link by its host, and path for hosts serving other pages too,
+// returning an empty string for unknown hosts
+func resourceType(link string) string {
+	parsed, err := url.Parse(link)
+	if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") {
+		return ""
+	}
+
+	host := strings.ToLower(parsed.Hostname())
+	for resource, kind := range resourceHosts {
+		resourceHost, resourcePath, hasPath := strings.Cut(resource, "/")
+		if host != resourceHost {
+			continue
+		}
+		if !hasPath {
+			return kind
+		}
+		resourcePath = "/" + resourcePath
+		if parsed.Path == resourcePath || strings.HasPrefix(parsed.Path, resourcePath+"/") {
+			return kind
+		}
+	}
+	return ""
+}
@@ -15,36 +15,60 @@ func Scrape(rawJSON map[string]interface{}, baseURL string) (UnitData, error) {
 	unitScraperData := UnitData{
 		CommonScraperData: common.CommonScraperData{
 			Link:             baseURL,
-			Faculty:          utils.GetTypedValue[string](rawJSON, "props.pageProps.pageContent.academic_org.value"),
-			Code:             utils.GetTypedValue[string](rawJSON, "props.pageProps.pageContent.unit_code"),
+			Faculty:          common.ProfileString(rawJSON, "units", "faculty"),
+			Code:             common.ProfileString(rawJSON, "units", "code"),
 			Title:            utils.GetTypedValue[string](rawJSON, "props.pageProps.pageContent.title"),
 			SearchTitle:      utils.GetTypedValue[string](rawJSON, "props.pageProps.pageContent.search_title"),
 			CurrentYear:      utils.StringToInt(utils.GetTypedValue[string](rawJSON, "props.pageProps.pageContent.implementation_year")),
 			AcademicItemType: utils.GetTypedValue[string](rawJSON, "props.pageProps.pageContent.academic_item_type"),
 		},
-		Synopsis:             utils.RemoveHTMLTags(utils.GetTypedValue[string](rawJSON, "props.pageProps.pageContent.handbook_synopsis")),
-		UnitLevel:            utils.GetTypedValue[string](rawJSON, "props.pageProps.pageContent.level.label"),
+		Synopsis:             utils.RemoveHTMLTags(common.FirstString(rawJSON, "props.pageProps.pageContent.handbook_synopsis", "props.pageProps.pageContent.handbook_description")),
+		UnitLevel:            common.FirstString(rawJSON, "props.pageProps.pageContent.level.label", "props.pageProps.pageContent.level.value"),
 		WorkloadRequirements: utils.RemoveHTMLTags(utils.GetTypedValue[string](rawJSON, "props.pageProps.pageContent.workload_requirements")),
 		Active:               utils.GetTypedValue[string](rawJSON, "props.pageProps.pageContent.status.value") == "Active",
 		CreditPoints:         utils.GetTypedValue[int](rawJSON, "props.pageProps.pageContent.credit_points"),
 		HandbookVersion:      utils.GetTypedValue[string](rawJSON, "props.pageProps.pageContent.version_name"),
 		EFTSL:                utils.GetTypedValue[float32](rawJSON, "props.pageProps.pageContent.eftsl"),
 		HighestSCABand:       utils.GetTypedValue[string](rawJSON, "props.pageProps.pageContent.highest_sca_band"),
-		UndergradPostgrad:    utils.GetTypedValue[string](rawJSON, "props.pageProps.pageContent.undergrad_postgrad_both.value"),
+		UndergradPostgrad:    common.FirstString(rawJSON, "props.pageProps.pageContent.undergrad_postgrad_both.value", "props.pageProps.pageContent.undergrad_postgrad.value"),
 		AreaOfStudy:          utils.StringToArray(utils.RemoveHTMLTags(utils.GetTypedValue[string](rawJSON, "props.pageProps.pageContent.area_of_study_links"))),
 		LearningOutcomes:     common.LearningOutcomes(rawJSON, "props.pageProps.pageContent.unit_learning_outcomes"),
 		Assessments:          assessments(rawJSON),
 		UnitOfferings:        unitOfferings(rawJSON),
 		LearningActivities:   learningActivities(rawJSON),
 		Requisites:           requisites(rawJSON),
+		RawRequisites:        rawRequisites(rawJSON),
 		EnrolmentRules:       enrolmentRules(rawJSON),
+		Resources:            resources(rawJSON),
+		Staff:                staff(rawJSON),
+		Tables:               tables(rawJSON),
+		Source:               "handbook",
 	}
 
+	unitScraperData.Requisites = withCourseRequirements(unitScraperData.Requisites, unitScraperData.EnrolmentRules)
+	unitScraperData.ShortSynopsis = shortSynopsis(unitScraperData.Synopsis)
+	unitScraperData.Tags = Tags(unitScraperData.Synopsis, unitScraperData.LearningOutcomes)
+	unitScraperData.Replaces, unitScraperData.ReplacedBy = ReplacementHints(unitScraperData)
+	unitScraperData.WAMWeight = WAMWeight(LevelNumber(unitScraperData.UnitLevel, unitScraperData.Code))
+
 	log.Successf("[UNIT SCRAPER] Extraction complete.")
 
 	return unitScraperData, nil
 }
 
+// tables extracts the tables embedded in the HTML fields of a unit, which RemoveHTMLTags flattens into plain text
+func tables(data map[string]interface{}) []common.HTMLTable {
+	var found []common.HTMLTable
+	for _, field := range []string{"handbook_synopsis", "workload_requirements"} {
+		found = append(found, common.ParseHTMLTables(field, utils.GetTypedValue[string](data, "props.pageProps.pageContent."+field))...)
+	}
+	for _, assessment := range utils.GetTypedValue[[]map[string]interface{}](data, "props.pageProps.pageContent.assessments") {
+		description, _ := assessment["description"].(string)
+		found = append(found, common.ParseHTMLTables("assessments", description)...)
+	}
+	return found
+}
+
 // requisites extracts and compresses the requisite data from the raw JSON.
 // It navigates to the "requisites" path, extracts the data, and compresses it into a simplified structure.
 func requisites(data map[string]interface{}) []CompressedRequisite {
@@ -69,6 +93,23 @@ func requisites(data map[string]interface{}) []CompressedRequisite {
 	return compressed
 }
 
+// rawRequisites extracts the requisites of the handbook as they are, keeping the titles of containers and the
+// descriptions of requisites that the compressed requisites drop
+func rawRequisites(data map[string]interface{}) []Requisite {
+	marshalled, err := json.Marshal(utils.GetTypedValue[[]map[string]interface{}](data, "props.pageProps.pageContent.requisites"))
+	if err != nil {
+		log.Errorf("Error marshalling array: %v", err)
+		return nil
+	}
+
+	var raw []Requisite
+	if err := json.Unmarshal(marshalled, &raw); err != nil {
+		log.Errorf("Error parsing raw requisites: %v", err)
+		return nil
+	}
+	return raw
+}
+
 // assessments parses the JSON input and extracts assessment data into a slice of Assessment structs.
 // It navigates to the "assessments" path, extracts the data, and unmarshals it into the Assessment struct.
 func assessments(data map[string]interface{}) []Assessment {
@@ -263,8 +304,12 @@ func compressContainer(container Container) CompressedContainer {
 		Containers:   []CompressedContainer{},
 	}
 
-	// Extract units from relationships
+	// Extract units from relationships, and the courses of those which are course admissions
 	for _, rel := range container.Relationships {
+		if isCourseRelationship(rel) {
+			compContainer.Courses = append(compContainer.Courses, rel.AcademicItemCode)
+			continue
+		}
 		unit := CompressedUnit{
 			UnitCode:   rel.AcademicItemCode,
 			UnitNumber: utils.ExtractUnitNumber(rel.AcademicItemCode),
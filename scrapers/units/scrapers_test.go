@@ -0,0 +1,67 @@
+package units
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"handbook-scraper/utils"
+)
+
+// loadPages reads the raw pages in testdata, saved as .json or gzipped .json.gz files, such as the page JSON of
+// real handbook pages. large_unit.json.gz is a generated unit with more deeply nested requisites than any real unit.
+func loadPages(b *testing.B) map[string]map[string]interface{} {
+	paths, err := filepath.Glob(filepath.Join("testdata", "*.json*"))
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	pages := map[string]map[string]interface{}{}
+	for _, path := range paths {
+		file, err := os.Open(path)
+		if err != nil {
+			b.Fatal(err)
+		}
+		var reader io.Reader = file
+		if strings.HasSuffix(path, ".gz") {
+			if reader, err = gzip.NewReader(file); err != nil {
+				b.Fatalf("failed to decompress %s: %v", path, err)
+			}
+		}
+		var data map[string]interface{}
+		err = json.NewDecoder(reader).Decode(&data)
+		file.Close()
+		if err != nil {
+			b.Fatalf("failed to decode %s: %v", path, err)
+		}
+		pages[strings.TrimSuffix(strings.TrimSuffix(filepath.Base(path), ".gz"), ".json")] = data
+	}
+	return pages
+}
+
+// BenchmarkCompressRequisites compresses the requisites of each page, marshalled once up front as requisites does
+// before compressing them
+func BenchmarkCompressRequisites(b *testing.B) {
+	for name, page := range loadPages(b) {
+		if _, ok := utils.LookupValue(page, "props.pageProps.pageContent.requisites"); !ok {
+			continue
+		}
+		marshalled, err := json.Marshal(utils.GetTypedValue[[]map[string]interface{}](page, "props.pageProps.pageContent.requisites"))
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(marshalled)))
+			for i := 0; i < b.N; i++ {
+				if _, err := compressRequisites(marshalled); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package units
+
+import (
+	"strings"
+
+	"handbook-scraper/utils"
+)
+
+// staff extracts the chief examiners and coordinators listed on the unit page.
+// Contacts can appear at the top level of the page content or under each unit offering.
+func staff(data map[string]interface{}) []StaffMember {
+	var result []StaffMember
+	seen := map[string]bool{}
+
+	add := func(contacts interface{}, offering string) {
+		list, ok := contacts.([]interface{})
+		if !ok {
+			return
+		}
+		for _, item := range list {
+			contact, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			member := parseStaffMember(contact)
+			if member.Name == "" {
+				continue
+			}
+			member.Offering = offering
+
+			key := member.Name + "|" + member.Role + "|" + member.Offering
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			result = append(result, member)
+		}
+	}
+
+	pageContent := utils.GetTypedValue[map[string]interface{}](data, "props.pageProps.pageContent")
+	if pageContent == nil {
+		return []StaffMember{}
+	}
+	add(pageContent["contacts"], "")
+
+	offerings, _ := pageContent["unit_offering"].([]interface{})
+	for _, item := range offerings {
+		offering, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		displayName, _ := offering["display_name"].(string)
+		add(offering["contacts"], displayName)
+		add(offering["teaching_staff"], displayName)
+	}
+
+	if result == nil {
+		return []StaffMember{}
+	}
+	return result
+}
+
+// parseStaffMember reads the name and role of a contact, which use different field names across handbook years
+func parseStaffMember(contact map[string]interface{}) StaffMember {
+	return StaffMember{
+		Name: strings.TrimSpace(utils.RemoveHTMLTags(firstString(contact, "contact_name", "name", "full_name", "title"))),
+		Role: strings.TrimSpace(firstString(contact, "contact_role", "role", "type")),
+	}
+}
+
+// firstString returns the first non-empty string among the given keys.
+// Values of the form {"label": ..., "value": ...} are read through their label.
+func firstString(m map[string]interface{}, keys ...string) string {
+	for _, key := range keys {
+		switch v := m[key].(type) {
+		case string:
+			if v != "" {
+				return v
+			}
+		case map[string]interface{}:
+			if label, ok := v["label"].(string); ok && label != "" {
+				return label
+			}
+			if value, ok := v["value"].(string); ok && value != "" {
+				return value
+			}
+		}
+	}
+	return ""
+}
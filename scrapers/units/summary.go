@@ -0,0 +1,101 @@
+package units
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Summariser shortens the synopsis of a unit for list views and search result snippets
+type Summariser interface {
+	Summarise(synopsis string) string
+}
+
+var (
+	summariserMu sync.RWMutex
+	summariser   Summariser = ExtractiveSummariser{MaxSentences: 2, MaxLength: 300}
+)
+
+// SetSummariser sets how short synopses are generated. Setting nil stops short synopses being generated.
+func SetSummariser(s Summariser) {
+	summariserMu.Lock()
+	summariser = s
+	summariserMu.Unlock()
+}
+
+// shortSynopsis summarises a synopsis with the current summariser, returning an empty string if there is none
+func shortSynopsis(synopsis string) string {
+	summariserMu.RLock()
+	s := summariser
+	summariserMu.RUnlock()
+
+	synopsis = strings.Join(strings.Fields(synopsis), " ")
+	if s == nil || synopsis == "" {
+		return ""
+	}
+	return s.Summarise(synopsis)
+}
+
+// sentenceEnd matches the end of a sentence, followed by the start of the next one
+var sentenceEnd = regexp.MustCompile(`[.!?]["')\]]?\s+["'(]?[A-Z0-9]`)
+
+// abbreviations end in a full stop without ending the sentence
+var abbreviations = []string{"e.g.", "i.e.", "etc.", "approx.", "incl.", "vs.", "Dr.", "Prof."}
+
+// ExtractiveSummariser summarises a synopsis by taking its leading sentences, which introduce the unit in the handbook
+type ExtractiveSummariser struct {
+	MaxSentences int // Most sentences kept
+	MaxLength    int // Most characters kept, a longer first sentence is cut at a word boundary
+}
+
+func (e ExtractiveSummariser) Summarise(synopsis string) string {
+	var summary string
+	rest := synopsis
+	for sentences := 0; sentences < e.MaxSentences && rest != ""; sentences++ {
+		sentence := nextSentence(rest)
+		if summary != "" && len(summary)+1+len(sentence) > e.MaxLength {
+			break
+		}
+		summary = strings.TrimSpace(summary + " " + sentence)
+		rest = strings.TrimSpace(rest[len(sentence):])
+	}
+
+	if len(summary) <= e.MaxLength {
+		return summary
+	}
+	cut := summary[:e.MaxLength]
+	if space := strings.LastIndex(cut, " "); space > 0 {
+		cut = cut[:space]
+	}
+	return strings.TrimRight(cut, " ,;:-") + "…"
+}
+
+// nextSentence returns the first sentence of some text, or all of it if it is a single sentence
+func nextSentence(text string) string {
+	offset := 0
+	for {
+		match := sentenceEnd.FindStringIndex(text[offset:])
+		if match == nil {
+			return text
+		}
+		end := offset + match[0] + 1
+		if !endsWithAbbreviation(text[:end]) {
+			// Closing quotes and brackets belong to the sentence they end
+			for end < len(text) && strings.ContainsRune(`"')]`, rune(text[end])) {
+				end++
+			}
+			return text[:end]
+		}
+		offset = end
+	}
+}
+
+// endsWithAbbreviation reports whether text ends with an abbreviation rather than the end of a sentence
+func endsWithAbbreviation(text string) bool {
+	for _, abbreviation := range abbreviations {
+		if strings.HasSuffix(text, " "+abbreviation) || text == abbreviation {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,127 @@
+package units
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"handbook-scraper/scrapers/common"
+)
+
+// maxTags is the most tags kept for a unit
+const maxTags = 8
+
+// maxTagWords is the most words in a tag, since longer phrases rarely name a topic
+const maxTagWords = 3
+
+// tagWord matches the words of a synopsis or learning outcome, keeping hyphenated words and terms such as C++ together
+var tagWord = regexp.MustCompile(`[A-Za-z][A-Za-z0-9+#'-]*|[.,;:!?()/\[\]"]`)
+
+// tagStopWords split candidate tags. Besides common English words, they include the words handbook entries
+// use for every unit, such as "students" and the verbs learning outcomes start with, which never name a topic.
+var tagStopWords = toSet(strings.Fields(`
+	a about above across after again against all also am an and any are around as at be because been before being
+	below between both but by can could did do does doing down during each either etc few for from further had has
+	have having he her here hers how i if in into is it its itself just may me might more most must my no nor not of
+	off on once only or other our out over own per same shall she should so some such than that the their them then
+	there these they this those through to too under until up upon very via was we well were what when where which
+	while who whom why will with within without would you your
+	e.g i.e eg ie including include includes included using use used uses various range wide key new different
+	unit units student students course courses topic topics study studies studying
+	knowledge understanding skills skill ability able introduction introduces introduce introductory overview
+	successful successfully completion complete completing end particular areas area aspects aspect focus focuses
+	well based related relevant appropriate effective effectively within through week weeks semester
+	apply analyse analyze evaluate explain describe demonstrate identify discuss develop design create implement
+	critically critique compare construct formulate interpret justify recognise recognize select synthesise
+	synthesize assess examine investigate communicate utilise utilize employ outline define reflect plan
+	`))
+
+// Tags extracts the keywords of a unit from its synopsis and learning outcomes with RAKE (rapid automatic keyword extraction).
+// Text is split into candidate phrases at stop words and punctuation, and each phrase is scored by how often
+// its words appear and how many other words they appear alongside, favouring specific multi-word topics.
+// Tags are lower-case and ordered by score.
+func Tags(synopsis string, outcomes []common.LearningOutcome) []string {
+	texts := []string{synopsis}
+	for _, outcome := range outcomes {
+		texts = append(texts, outcome.Description)
+	}
+
+	var phrases [][]string
+	for _, text := range texts {
+		var phrase []string
+		flush := func() {
+			if len(phrase) > 0 && len(phrase) <= maxTagWords {
+				phrases = append(phrases, phrase)
+			}
+			phrase = nil
+		}
+		for _, token := range tagWord.FindAllString(strings.ToLower(text), -1) {
+			token = strings.Trim(token, "'-")
+			if len(token) < 3 || tagStopWords[token] {
+				flush()
+				continue
+			}
+			phrase = append(phrase, token)
+		}
+		flush()
+	}
+
+	// A word's degree counts the words it appears with, including itself, so words of longer phrases score higher
+	frequency, degree := map[string]int{}, map[string]int{}
+	for _, phrase := range phrases {
+		for _, word := range phrase {
+			frequency[word]++
+			degree[word] += len(phrase)
+		}
+	}
+
+	scores := map[string]float64{}
+	for _, phrase := range phrases {
+		tag := strings.Join(phrase, " ")
+		if _, ok := scores[tag]; ok {
+			continue
+		}
+		for _, word := range phrase {
+			scores[tag] += float64(degree[word]) / float64(frequency[word])
+		}
+	}
+
+	tags := make([]string, 0, len(scores))
+	for tag := range scores {
+		tags = append(tags, tag)
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		if scores[tags[i]] != scores[tags[j]] {
+			return scores[tags[i]] > scores[tags[j]]
+		}
+		return tags[i] < tags[j]
+	})
+
+	// Tags already covered by a higher scoring tag, such as "networks" after "neural networks", are left out
+	kept := []string{}
+	for _, tag := range tags {
+		covered := false
+		for _, existing := range kept {
+			if strings.Contains(" "+existing+" ", " "+tag+" ") {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			kept = append(kept, tag)
+		}
+		if len(kept) == maxTags {
+			break
+		}
+	}
+	return kept
+}
+
+// toSet converts a list of words to a set
+func toSet(words []string) map[string]bool {
+	set := make(map[string]bool, len(words))
+	for _, word := range words {
+		set[word] = true
+	}
+	return set
+}
@@ -7,34 +7,62 @@ import (
 // UnitData holds the extracted data from the handbook.
 type UnitData struct {
 	common.CommonScraperData `json:"common"`
-	Synopsis                 string                   `json:"synopsis"`              //
-	UnitLevel                string                   `json:"unit_level"`            //
-	WorkloadRequirements     string                   `json:"workload_requirements"` //
-	Active                   bool                     `json:"active"`                //
-	CreditPoints             int                      `json:"credit_points"`         //
-	HandbookVersion          string                   `json:"handbook_version"`      //
-	EFTSL                    float32                  `json:"eftsl"`                 //
-	HighestSCABand           string                   `json:"highest_sca_band"`      //
-	UndergradPostgrad        string                   `json:"undergrad_postgrad"`    //
-	AreaOfStudy              []string                 `json:"area_of_study"`         //
-	LearningOutcomes         []common.LearningOutcome `json:"learning_outcomes"`     //
-	Assessments              []Assessment             `json:"assessments"`           //
-	UnitOfferings            []UnitOffering           `json:"unit_offerings"`        //
-	LearningActivities       []LearningActivity       `json:"learning_activities"`   //
-	Requisites               []CompressedRequisite    `json:"requisites"`            //
-	EnrolmentRules           []EnrolmentRule          `json:"enrolment_rules"`       //
+	Synopsis                 string                   `json:"synopsis"`                  //
+	ShortSynopsis            string                   `json:"short_synopsis,omitempty"`  // One or two sentences of the synopsis, for list views and search snippets
+	Tags                     []string                 `json:"tags,omitempty"`            // Keywords of the synopsis and learning outcomes, for topic-based browsing
+	UnitLevel                string                   `json:"unit_level"`                //
+	WAMWeight                float64                  `json:"wam_weight"`                // Weight of the unit in a weighted average mark, 0.5 for first-year units
+	WorkloadRequirements     string                   `json:"workload_requirements"`     //
+	Active                   bool                     `json:"active"`                    //
+	CreditPoints             int                      `json:"credit_points"`             //
+	HandbookVersion          string                   `json:"handbook_version"`          //
+	EFTSL                    float32                  `json:"eftsl"`                     //
+	HighestSCABand           string                   `json:"highest_sca_band"`          //
+	UndergradPostgrad        string                   `json:"undergrad_postgrad"`        //
+	AreaOfStudy              []string                 `json:"area_of_study"`             //
+	LearningOutcomes         []common.LearningOutcome `json:"learning_outcomes"`         //
+	Assessments              []Assessment             `json:"assessments"`               //
+	UnitOfferings            []UnitOffering           `json:"unit_offerings"`            //
+	LearningActivities       []LearningActivity       `json:"learning_activities"`       //
+	Requisites               []CompressedRequisite    `json:"requisites"`                //
+	RawRequisites            []Requisite              `json:"raw_requisites,omitempty"`  // Requisites as the handbook lists them, only in responses which ask for them
+	EnrolmentRules           []EnrolmentRule          `json:"enrolment_rules"`           //
+	Resources                []Resource               `json:"resources"`                 //
+	Staff                    []StaffMember            `json:"staff"`                     //
+	Replaces                 []string                 `json:"replaces,omitempty"`        // Units the handbook says this unit replaces, such as its earlier codes
+	ReplacedBy               []string                 `json:"replaced_by,omitempty"`     // Units the handbook says replace this unit
+	Tables                   []common.HTMLTable       `json:"tables,omitempty"`          // Tables embedded in the synopsis, workload requirements or assessments
+	Source                   string                   `json:"source"`                    // handbook, or pdf_archive for units extracted from archived PDFs
+	OfferingStatus           *OfferingStatus          `json:"offering_status,omitempty"` // Set in responses when the unit has no offerings in the year
+}
+
+// OfferingStatus explains a unit with no offerings in a year, from the stored offerings of the unit in other years
+type OfferingStatus struct {
+	Offered     bool           `json:"offered"`
+	Message     string         `json:"message"` // Such as "FIT1045 is not offered in 2025, it was last offered in 2024"
+	LastOffered *YearOfferings `json:"last_offered,omitempty"`
+	NextOffered *YearOfferings `json:"next_offered,omitempty"`
+}
+
+// YearOfferings are the offerings of a unit in a year
+type YearOfferings struct {
+	Year      string         `json:"year"`
+	Offerings []UnitOffering `json:"offerings"`
 }
 
 // Assessment represents a single assessment with relevant fields
 type Assessment struct {
-	AssessmentName string `json:"assessment_name"`
-	AssessmentType struct {
-		Label string `json:"label"`
-		Value string `json:"value"`
-	} `json:"assessment_type"`
-	Number      string `json:"number"`
-	Weight      string `json:"weight"`
-	Description string `json:"description,omitempty"`
+	AssessmentName string         `json:"assessment_name"`
+	AssessmentType AssessmentType `json:"assessment_type"`
+	Number         string         `json:"number"`
+	Weight         string         `json:"weight"`
+	Description    string         `json:"description,omitempty"`
+}
+
+// AssessmentType is the kind of an assessment, such as an examination
+type AssessmentType struct {
+	Label string `json:"label"`
+	Value string `json:"value"`
 }
 
 // UnitOffering represents the structured data for each unit offering
@@ -57,6 +85,20 @@ type EnrolmentRule struct {
 	Description string `json:"description"`
 }
 
+// Resource represents a link to an official unit resource found in the page content
+type Resource struct {
+	Type  string `json:"type"` // unit_guide, moodle, reading_list, or library
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+// StaffMember represents a chief examiner or coordinator listed on the unit page
+type StaffMember struct {
+	Name     string `json:"name"`
+	Role     string `json:"role"`               // e.g. Chief examiner(s), Unit coordinator(s)
+	Offering string `json:"offering,omitempty"` // Offering display name, empty if the contact applies to all offerings
+}
+
 // REQUISITE TYPES START HERE
 
 type Requisite struct {
@@ -135,17 +177,21 @@ type AcademicItemType struct {
 
 // Compressed structures
 type CompressedRequisite struct {
-	RequisiteType string                `json:"requisite_type"` // "Prerequisite" or "Prohibition"
-	Containers    []CompressedContainer `json:"containers"`
+	RequisiteType      string                `json:"requisite_type"` // "Prerequisite" or "Prohibition"
+	Containers         []CompressedContainer `json:"containers"`
+	CourseRequirements []CourseRequirement   `json:"course_requirements,omitempty"` // Parsed from the enrolment rules
 }
 
 type CompressedContainer struct {
 	Relationship string                `json:"relationship"` // "AND" or "OR"
 	Units        []CompressedUnit      `json:"units"`
+	Courses      []string              `json:"courses,omitempty"` // Courses the student may be admitted to instead, joined by the relationship
 	Containers   []CompressedContainer `json:"containers,omitempty"`
 }
 
 type CompressedUnit struct {
-	UnitCode   string `json:"unit_code"`
-	UnitNumber string `json:"unit_number"`
+	UnitCode     string `json:"unit_code"`
+	UnitNumber   string `json:"unit_number"`
+	Title        string `json:"title,omitempty"`         // Only set when requisites are enriched
+	CreditPoints int    `json:"credit_points,omitempty"` // Only set when requisites are enriched
 }
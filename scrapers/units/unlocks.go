@@ -0,0 +1,25 @@
+package units
+
+// PrerequisiteUnits returns the units the prerequisites of a unit mention, each with whether every way of meeting
+// the prerequisites requires it, rather than it being one of several alternatives
+func PrerequisiteUnits(unitData UnitData) map[string]bool {
+	mentioned := map[string]bool{}
+	required := map[string]bool{}
+	for _, requisite := range unitData.Requisites {
+		if requisite.RequisiteType != "Prerequisite" {
+			continue
+		}
+		for _, container := range requisite.Containers {
+			collectUnits(container, mentioned)
+			for unit := range mandatoryUnits(container) {
+				required[unit] = true
+			}
+		}
+	}
+
+	prerequisites := make(map[string]bool, len(mentioned))
+	for unit := range mentioned {
+		prerequisites[unit] = required[unit]
+	}
+	return prerequisites
+}
@@ -0,0 +1,30 @@
+package units
+
+import (
+	"regexp"
+	"strconv"
+)
+
+// levelPattern matches the level of a unit, such as "Level 1", or the first digit of its code, such as FIT1045
+var levelPattern = regexp.MustCompile(`[0-9]`)
+
+// LevelNumber returns the year level of a unit from its handbook level, or from its code if the level is missing.
+// It returns 0 if neither names a level.
+func LevelNumber(level string, code string) int {
+	for _, text := range []string{level, code} {
+		if digit := levelPattern.FindString(text); digit != "" {
+			number, _ := strconv.Atoi(digit)
+			return number
+		}
+	}
+	return 0
+}
+
+// WAMWeight returns the weight of a unit of a level in a Monash weighted average mark,
+// where first-year units count half as much as later-year units
+func WAMWeight(level int) float64 {
+	if level <= 1 {
+		return 0.5
+	}
+	return 1
+}
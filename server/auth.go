@@ -0,0 +1,359 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/gin-gonic/gin"
+	"handbook-scraper/server/handlers"
+	"handbook-scraper/utils"
+	"handbook-scraper/utils/log"
+	"handbook-scraper/utils/secrets"
+)
+
+// role grants access to the routes whose policy requires it or any role below it
+type role int
+
+const (
+	rolePublic     role = iota // Anyone, without credentials
+	roleMonitoring             // Metrics scrapers, such as Prometheus
+	roleTrustedApp             // Applications trusted to submit jobs, such as crawls
+	roleAdmin                  // Operators, for the admin and debug endpoints
+)
+
+var roleNames = []string{"public", "monitoring", "trusted-app", "admin"}
+
+func (r role) String() string {
+	return roleNames[r]
+}
+
+// parseRole returns the role of a name, such as trusted-app
+func parseRole(name string) (role, bool) {
+	for i, roleName := range roleNames {
+		if strings.EqualFold(strings.TrimSpace(name), roleName) {
+			return role(i), true
+		}
+	}
+	return rolePublic, false
+}
+
+// principalKey is the context key of the principal of a request
+const principalKey = "principal"
+
+// principal is who made a request, and the role they were granted
+type principal struct {
+	Subject string // The name of the API key, or the subject of the JWT
+	Role    role
+	Tenant  string // The name of the API key, empty for JWTs
+}
+
+// routePolicy requires a role for the routes matching a pattern, for one method or all of them if Method is empty.
+// Patterns ending with a slash match every route below them, and other patterns match one route as it was registered.
+type routePolicy struct {
+	Method  string
+	Pattern string
+	Role    role
+}
+
+// matches reports whether the policy applies to a route
+func (p routePolicy) matches(method string, route string) bool {
+	if p.Method != "" && p.Method != method {
+		return false
+	}
+	if strings.HasSuffix(p.Pattern, "/") {
+		return strings.HasPrefix(route, p.Pattern)
+	}
+	return route == p.Pattern
+}
+
+// defaultRoutePolicies are the policies of routes which are not public
+var defaultRoutePolicies = []routePolicy{
+	{Pattern: "/v1/admin/", Role: roleAdmin},
+	{Pattern: "/debug/", Role: roleAdmin},
+	{Method: http.MethodGet, Pattern: "/metrics", Role: roleMonitoring},
+	{Method: http.MethodPost, Pattern: "/v1/jobs", Role: roleTrustedApp},
+	{Method: http.MethodDelete, Pattern: "/v1/jobs/:id", Role: roleTrustedApp},
+	{Method: http.MethodGet, Pattern: "/v1/jobs/:id/file", Role: roleTrustedApp},
+}
+
+// routePolicies returns the policies of ROUTE_POLICIES followed by the default policies, the first matching policy applying.
+// ROUTE_POLICIES is a comma-separated list of [METHOD ]pattern=role, e.g. "GET /v1/:year/analytics/=trusted-app".
+func routePolicies() []routePolicy {
+	var policies []routePolicy
+	for _, entry := range strings.Split(os.Getenv("ROUTE_POLICIES"), ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		route, roleName, _ := strings.Cut(entry, "=")
+		required, ok := parseRole(roleName)
+		if !ok {
+			log.Warnf("[AUTH] Ignoring route policy %q with an unknown role", entry)
+			continue
+		}
+		policy := routePolicy{Pattern: strings.TrimSpace(route), Role: required}
+		if method, pattern, ok := strings.Cut(policy.Pattern, " "); ok {
+			policy.Method, policy.Pattern = strings.ToUpper(method), strings.TrimSpace(pattern)
+		}
+		policies = append(policies, policy)
+	}
+	return append(policies, defaultRoutePolicies...)
+}
+
+// requiredRole returns the role a route requires under policies
+func requiredRole(policies []routePolicy, method string, route string) role {
+	for _, policy := range policies {
+		if policy.matches(method, route) {
+			return policy.Role
+		}
+	}
+	return rolePublic
+}
+
+// apiKey is a bearer token granting a role
+type apiKey struct {
+	Name string
+	Role role
+	Key  string
+}
+
+// tenantName matches the names of API keys, which name their tenant in the keys of its usage and limits
+var tenantName = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// apiKeySecrets are the secrets the API keys are parsed from
+var apiKeySecrets = []string{"ADMIN_TOKEN", "API_KEYS"}
+
+// keyring holds the parsed API keys, so they are parsed once rather than on every request
+var (
+	keyring     atomic.Pointer[[]apiKey]
+	keyringOnce sync.Once
+)
+
+// apiKeys returns the API keys, parsed on first use and again whenever ADMIN_TOKEN or API_KEYS is rotated
+func apiKeys() []apiKey {
+	keyringOnce.Do(func() {
+		reloadAPIKeys()
+		secrets.OnChange(reloadAPIKeys, apiKeySecrets...)
+	})
+	return *keyring.Load()
+}
+
+// reloadAPIKeys parses the API keys again
+func reloadAPIKeys() {
+	keys := parseAPIKeys()
+	keyring.Store(&keys)
+}
+
+// parseAPIKeys returns the ADMIN_TOKEN as an admin key, and the keys of API_KEYS.
+// API_KEYS is a comma-separated list of name:role:key, e.g. "timetable-app:trusted-app:s3cret".
+// Both are secrets, so rotated keys apply from the next reload of the secrets.
+func parseAPIKeys() []apiKey {
+	var keys []apiKey
+	if token := secrets.Get("ADMIN_TOKEN"); token != "" {
+		keys = append(keys, apiKey{Name: "admin_token", Role: roleAdmin, Key: token})
+	}
+	for _, entry := range strings.Split(secrets.Get("API_KEYS"), ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, ":", 3)
+		if len(parts) != 3 || parts[2] == "" {
+			log.Warnf("[AUTH] Ignoring a malformed entry of API_KEYS, expected name:role:key")
+			continue
+		}
+		if !tenantName.MatchString(parts[0]) {
+			log.Warnf("[AUTH] Ignoring an API key whose name is not up to 64 letters, digits, underscores or hyphens")
+			continue
+		}
+		granted, ok := parseRole(parts[1])
+		if !ok {
+			log.Warnf("[AUTH] Ignoring API key %s with unknown role %q", parts[0], parts[1])
+			continue
+		}
+		keys = append(keys, apiKey{Name: parts[0], Role: granted, Key: parts[2]})
+	}
+	return keys
+}
+
+// errNoCredentials is returned when a request has no bearer token
+var errNoCredentials = errors.New("authentication is required")
+
+// authenticate returns who made a request from its bearer API key, or from its JWT if OIDC is enabled
+func authenticate(c *gin.Context, keys []apiKey) (principal, error) {
+	rawToken, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !ok || rawToken == "" {
+		return principal{}, errNoCredentials
+	}
+
+	for _, key := range keys {
+		if subtle.ConstantTimeCompare([]byte(rawToken), []byte(key.Key)) == 1 {
+			return principal{Subject: key.Name, Role: key.Role, Tenant: key.Name}, nil
+		}
+	}
+
+	if !oidcEnabled() || strings.Count(rawToken, ".") != 2 {
+		return principal{}, errors.New("invalid token")
+	}
+	subject, roles, err := verifyJWT(c.Request.Context(), rawToken)
+	if err != nil {
+		return principal{}, fmt.Errorf("invalid token: %w", err)
+	}
+	return principal{Subject: subject, Role: jwtRole(roles)}, nil
+}
+
+// jwtRole returns the highest role granted by the roles claim of a JWT,
+// whose OIDC_ADMIN_ROLE (default admin) and OIDC_TRUSTED_APP_ROLE (default trusted-app) map to the roles of the API
+func jwtRole(roles []string) role {
+	claimed := map[string]bool{}
+	for _, name := range roles {
+		claimed[name] = true
+	}
+	for _, mapping := range []struct {
+		env      string
+		fallback string
+		role     role
+	}{
+		{"OIDC_ADMIN_ROLE", "admin", roleAdmin},
+		{"OIDC_TRUSTED_APP_ROLE", "trusted-app", roleTrustedApp},
+	} {
+		name := os.Getenv(mapping.env)
+		if name == "" {
+			name = mapping.fallback
+		}
+		if claimed[name] {
+			return mapping.role
+		}
+	}
+	return rolePublic
+}
+
+// authorizationMiddleware enforces the policy of each route. Routes requiring a role are disabled
+// when no API key grants it and OIDC is not enabled, and otherwise require a bearer API key or JWT granting it.
+// Credentials are checked on every route, so that requests to public routes are attributed to their tenant.
+// ROUTE_POLICIES is read once, when the router is created.
+func authorizationMiddleware() gin.HandlerFunc {
+	policies := routePolicies()
+	return func(c *gin.Context) {
+		required := requiredRole(policies, c.Request.Method, c.FullPath())
+		keys := apiKeys()
+
+		if required > rolePublic {
+			enabled := oidcEnabled()
+			for _, key := range keys {
+				enabled = enabled || key.Role >= required
+			}
+			if !enabled {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("%s endpoints are disabled", required)})
+				return
+			}
+		}
+
+		caller, err := authenticate(c, keys)
+		switch {
+		case errors.Is(err, errNoCredentials) && required == rolePublic:
+		case err != nil:
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		default:
+			c.Set(principalKey, caller)
+			c.Set(handlers.SubjectKey, caller.Subject)
+			c.Set(handlers.AdminKey, caller.Role >= roleAdmin)
+		}
+		if caller.Role < required {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("the %s role is required", required)})
+			return
+		}
+
+		if required > rolePublic {
+			log.Infof("[AUTH] %s %s by %s (%s)", c.Request.Method, c.Request.URL.Path, caller.Subject, caller.Role)
+		}
+		c.Next()
+	}
+}
+
+// oidcVerifier verifies JWTs issued by the OIDC_ISSUER_URL for the OIDC_AUDIENCE, such as a university's SSO.
+// The issuer is discovered on first use, and again on the next request if discovery fails.
+var oidcVerifier struct {
+	sync.Mutex
+	issuer   string
+	verifier *oidc.IDTokenVerifier
+}
+
+// oidcEnabled reports whether bearer JWTs are accepted, which needs OIDC_ISSUER_URL
+func oidcEnabled() bool {
+	return os.Getenv("OIDC_ISSUER_URL") != ""
+}
+
+// verifyJWT verifies the signature, issuer, audience and expiry of a JWT, and returns its subject and roles.
+// Roles are read from the OIDC_ROLES_CLAIM (roles by default), which may be a path such as realm_access.roles,
+// and which holds a list of roles or a space-separated string of them.
+func verifyJWT(ctx context.Context, rawToken string) (string, []string, error) {
+	verifier, err := jwtVerifier()
+	if err != nil {
+		return "", nil, err
+	}
+
+	token, err := verifier.Verify(ctx, rawToken)
+	if err != nil {
+		return "", nil, err
+	}
+	var claims map[string]interface{}
+	if err := token.Claims(&claims); err != nil {
+		return "", nil, err
+	}
+
+	claim := os.Getenv("OIDC_ROLES_CLAIM")
+	if claim == "" {
+		claim = "roles"
+	}
+	var roles []string
+	value, _ := utils.LookupValue(claims, claim)
+	switch value := value.(type) {
+	case []interface{}:
+		for _, role := range value {
+			if role, ok := role.(string); ok {
+				roles = append(roles, role)
+			}
+		}
+	case string:
+		roles = strings.Fields(value)
+	}
+	return token.Subject, roles, nil
+}
+
+// jwtVerifier returns the verifier of the configured issuer, discovering it if it has not been yet
+func jwtVerifier() (*oidc.IDTokenVerifier, error) {
+	oidcVerifier.Lock()
+	defer oidcVerifier.Unlock()
+
+	issuer := os.Getenv("OIDC_ISSUER_URL")
+	if oidcVerifier.verifier != nil && oidcVerifier.issuer == issuer {
+		return oidcVerifier.verifier, nil
+	}
+	audience := os.Getenv("OIDC_AUDIENCE")
+	if audience == "" {
+		return nil, errors.New("OIDC_AUDIENCE is not set")
+	}
+
+	// The context is kept by the provider to refresh the issuer's keys, so it must not be cancelled
+	ctx := oidc.ClientContext(context.Background(), &http.Client{Timeout: 10 * time.Second})
+	provider, err := oidc.NewProvider(ctx, issuer)
+	if err != nil {
+		log.Errorf("[AUTH] Failed to discover OIDC issuer %s: %v", issuer, err)
+		return nil, fmt.Errorf("failed to discover OIDC issuer: %w", err)
+	}
+
+	log.Infof("[AUTH] Accepting JWTs issued by %s", issuer)
+	oidcVerifier.issuer = issuer
+	oidcVerifier.verifier = provider.Verifier(&oidc.Config{ClientID: audience})
+	return oidcVerifier.verifier, nil
+}
@@ -0,0 +1,26 @@
+package server
+
+import (
+	"net/http/pprof"
+
+	"github.com/gin-gonic/gin"
+	"handbook-scraper/server/handlers"
+)
+
+// setupDebugRoutes registers net/http/pprof and runtime stats under /debug, which require the admin role,
+// and Prometheus metrics under /metrics, which require the monitoring role
+func setupDebugRoutes(router *gin.Engine) {
+	router.GET("metrics", handlers.MetricsHandler)
+
+	debug := router.Group("debug")
+	debug.GET("runtime", handlers.RuntimeStatsHandler)
+
+	// pprof.Index serves the named profiles, such as heap, goroutine, and allocs, under the same prefix
+	debug.GET("pprof/", gin.WrapF(pprof.Index))
+	debug.GET("pprof/:profile", gin.WrapF(pprof.Index))
+	debug.GET("pprof/cmdline", gin.WrapF(pprof.Cmdline))
+	debug.GET("pprof/profile", gin.WrapF(pprof.Profile))
+	debug.GET("pprof/symbol", gin.WrapF(pprof.Symbol))
+	debug.POST("pprof/symbol", gin.WrapF(pprof.Symbol))
+	debug.GET("pprof/trace", gin.WrapF(pprof.Trace))
+}
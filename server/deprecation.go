@@ -0,0 +1,64 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// routeDeprecation marks a route as deprecated, with when it stops being served and the route replacing it
+type routeDeprecation struct {
+	Deprecated time.Time // When the route was deprecated
+	Sunset     time.Time // When the route stops being served, zero if not decided yet
+	Successor  string    // Path of the route replacing it, which may use the deprecated route's parameters, e.g. /v2/:year/units/:code
+}
+
+// deprecatedRoutes maps "METHOD path" of deprecated routes, as registered in SetupRoutes, to their deprecation.
+// e.g. "GET v1/:year/units/:code": {Deprecated: ..., Sunset: ..., Successor: "/v2/:year/units/:code"}
+var deprecatedRoutes = map[string]routeDeprecation{}
+
+// deprecationMiddleware signals deprecated routes to clients with the Deprecation (RFC 9745) and Sunset (RFC 8594) headers,
+// and a Link to the successor route. Once a route's sunset has passed it responds with 410 Gone instead.
+func deprecationMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		deprecation, ok := deprecatedRoutes[c.Request.Method+" "+strings.TrimPrefix(c.FullPath(), "/")]
+		if !ok {
+			c.Next()
+			return
+		}
+
+		c.Header("Deprecation", fmt.Sprintf("@%d", deprecation.Deprecated.Unix()))
+		successor := ""
+		if deprecation.Successor != "" {
+			successor = successorPath(deprecation.Successor, c.Params)
+			c.Header("Link", fmt.Sprintf(`<%s>; rel="successor-version"`, successor))
+		}
+		if !deprecation.Sunset.IsZero() {
+			c.Header("Sunset", deprecation.Sunset.UTC().Format(http.TimeFormat))
+			if time.Now().After(deprecation.Sunset) {
+				message := "this endpoint is no longer served"
+				if successor != "" {
+					message += ", use " + successor
+				}
+				c.AbortWithStatusJSON(http.StatusGone, gin.H{"error": message})
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
+
+// successorPath fills the parameters of a successor route with the values of the deprecated route's request
+func successorPath(successor string, params gin.Params) string {
+	segments := strings.Split(successor, "/")
+	for i, segment := range segments {
+		if value, ok := params.Get(strings.TrimPrefix(segment, ":")); ok && strings.HasPrefix(segment, ":") {
+			segments[i] = value
+		}
+	}
+	return strings.Join(segments, "/")
+}
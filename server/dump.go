@@ -0,0 +1,47 @@
+package server
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+
+	"handbook-scraper/server/handlers"
+	"handbook-scraper/utils/databases"
+	"handbook-scraper/utils/log"
+)
+
+// RunDump renders the stored handbook data into a static directory of JSON files, for the dump command:
+//
+//	handbook-scraper dump [-out dist] [-years 2024,2025]
+func RunDump(args []string) error {
+	flags := flag.NewFlagSet("dump", flag.ContinueOnError)
+	out := flags.String("out", "dist", "directory to write the dump to, replaced once the dump is complete")
+	years := flags.String("years", "", "comma-separated handbook years to dump, every year with stored data if empty")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if err := databases.Init(); err != nil {
+		return err
+	}
+	defer func() {
+		if err := databases.Shutdown(); err != nil {
+			log.Errorf("Failed to close databases: %v", err)
+		}
+	}()
+
+	var selected []string
+	for _, year := range strings.Split(*years, ",") {
+		if year = strings.TrimSpace(year); year != "" {
+			selected = append(selected, year)
+		}
+	}
+
+	manifest, err := handlers.DumpStatic(context.Background(), *out, selected)
+	if err != nil {
+		return fmt.Errorf("failed to dump: %w", err)
+	}
+	log.Successf("[DUMP] Dumped %d years to %s", len(manifest.Years), *out)
+	return nil
+}
@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"handbook-scraper/scrapers/units"
+	"handbook-scraper/utils/databases"
+	"handbook-scraper/utils/log"
+)
+
+// creditPointsSummary is the average credit points of the units of a faculty at a level
+type creditPointsSummary struct {
+	Faculty             string  `json:"faculty" bson:"faculty"`
+	UnitLevel           string  `json:"unit_level" bson:"unit_level"`
+	Units               int     `json:"units" bson:"units"`
+	AverageCreditPoints float64 `json:"average_credit_points" bson:"average_credit_points"`
+}
+
+// assessmentTypeSummary counts the assessments of a type set by the units of a faculty
+type assessmentTypeSummary struct {
+	Faculty        string  `json:"faculty" bson:"faculty"`
+	AssessmentType string  `json:"assessment_type" bson:"assessment_type"`
+	Assessments    int     `json:"assessments" bson:"assessments"`
+	Share          float64 `json:"share" bson:"-"` // Fraction of the faculty's assessments
+}
+
+// prerequisiteDepthSummary counts the units whose longest chain of prerequisites has a given length
+type prerequisiteDepthSummary struct {
+	Depth int `json:"depth"`
+	Units int `json:"units"`
+}
+
+// CreditPointsAnalyticsHandler returns the average credit points of units per faculty and unit level
+func CreditPointsAnalyticsHandler(c *gin.Context) {
+	year, ok := yearParam(c)
+	if !ok {
+		return
+	}
+
+	pipeline := []bson.M{
+		{"$match": unitsMatch(year, c.Query("faculty"))},
+		{"$group": bson.M{
+			"_id":                   bson.M{"faculty": "$common.faculty", "unit_level": "$unit_level"},
+			"units":                 bson.M{"$sum": 1},
+			"average_credit_points": bson.M{"$avg": "$credit_points"},
+		}},
+		{"$project": bson.M{
+			"_id":                   0,
+			"faculty":               "$_id.faculty",
+			"unit_level":            "$_id.unit_level",
+			"units":                 1,
+			"average_credit_points": 1,
+		}},
+		{"$sort": bson.D{{Key: "faculty", Value: 1}, {Key: "unit_level", Value: 1}}},
+	}
+
+	summaries := []creditPointsSummary{}
+	if err := aggregateHandbook(c.Request.Context(), pipeline, &summaries); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"year": year, "summaries": summaries})
+}
+
+// AssessmentAnalyticsHandler returns the distribution of assessment types per faculty
+func AssessmentAnalyticsHandler(c *gin.Context) {
+	year, ok := yearParam(c)
+	if !ok {
+		return
+	}
+
+	pipeline := []bson.M{
+		{"$match": unitsMatch(year, c.Query("faculty"))},
+		{"$unwind": "$assessments"},
+		{"$group": bson.M{
+			"_id":         bson.M{"faculty": "$common.faculty", "assessment_type": "$assessments.assessment_type.label"},
+			"assessments": bson.M{"$sum": 1},
+		}},
+		{"$project": bson.M{
+			"_id":             0,
+			"faculty":         "$_id.faculty",
+			"assessment_type": "$_id.assessment_type",
+			"assessments":     1,
+		}},
+		{"$sort": bson.D{{Key: "faculty", Value: 1}, {Key: "assessments", Value: -1}}},
+	}
+
+	summaries := []assessmentTypeSummary{}
+	if err := aggregateHandbook(c.Request.Context(), pipeline, &summaries); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	totals := map[string]int{}
+	for _, summary := range summaries {
+		totals[summary.Faculty] += summary.Assessments
+	}
+	for i := range summaries {
+		summaries[i].Share = float64(summaries[i].Assessments) / float64(totals[summaries[i].Faculty])
+	}
+
+	c.JSON(http.StatusOK, gin.H{"year": year, "summaries": summaries})
+}
+
+// PrerequisiteDepthAnalyticsHandler returns the distribution of prerequisite depth, the length of the longest
+// chain of prerequisites leading to a unit. Units without prerequisites have a depth of 0.
+func PrerequisiteDepthAnalyticsHandler(c *gin.Context) {
+	year, ok := yearParam(c)
+	if !ok {
+		return
+	}
+
+	// Chains may pass through units of other faculties, so every unit of the year is loaded
+	pipeline := []bson.M{
+		{"$match": unitsMatch(year, "")},
+		{"$project": bson.M{
+			"_id":     0,
+			"code":    "$common.code",
+			"faculty": "$common.faculty",
+			"requisites": bson.M{"$filter": bson.M{
+				"input": bson.M{"$ifNull": bson.A{"$requisites", bson.A{}}},
+				"as":    "requisite",
+				"cond":  bson.M{"$eq": bson.A{"$$requisite.requisite_type", "Prerequisite"}},
+			}},
+		}},
+	}
+
+	var results []bson.M
+	if err := aggregateHandbook(c.Request.Context(), pipeline, &results); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// The requisite types only have JSON field names, so the results are decoded through JSON
+	var documents []struct {
+		Code       string                      `json:"code"`
+		Faculty    string                      `json:"faculty"`
+		Requisites []units.CompressedRequisite `json:"requisites"`
+	}
+	if err := decodeInto(results, &documents); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	prerequisites := map[string][]string{}
+	for _, document := range documents {
+		codes := map[string]bool{}
+		for _, requisite := range document.Requisites {
+			for _, container := range requisite.Containers {
+				collectRequisiteCodes(container, codes)
+			}
+		}
+		prerequisites[document.Code] = sortedCodes(codes)
+	}
+
+	depthOf := prerequisiteDepths(prerequisites)
+
+	faculty := c.Query("faculty")
+	counts := map[int]int{}
+	maxDepth := 0
+	deepest := []string{}
+	for _, document := range documents {
+		if faculty != "" && document.Faculty != faculty {
+			continue
+		}
+		depth := depthOf(document.Code)
+		counts[depth]++
+		if depth > maxDepth {
+			maxDepth, deepest = depth, []string{}
+		}
+		if depth == maxDepth {
+			deepest = append(deepest, document.Code)
+		}
+	}
+	sort.Strings(deepest)
+
+	distribution := []prerequisiteDepthSummary{}
+	for depth, count := range counts {
+		distribution = append(distribution, prerequisiteDepthSummary{Depth: depth, Units: count})
+	}
+	sort.Slice(distribution, func(i, j int) bool { return distribution[i].Depth < distribution[j].Depth })
+
+	c.JSON(http.StatusOK, gin.H{"year": year, "distribution": distribution, "max_depth": maxDepth, "deepest": deepest})
+}
+
+// prerequisiteDepths returns a function giving the prerequisite depth of a unit, from the prerequisites of each unit.
+// Depths are memoised, and units on a cycle stop the chain rather than recursing forever.
+func prerequisiteDepths(prerequisites map[string][]string) func(code string) int {
+	depths := map[string]int{}
+	visiting := map[string]bool{}
+	var depthOf func(code string) int
+	depthOf = func(code string) int {
+		if depth, ok := depths[code]; ok {
+			return depth
+		}
+		if visiting[code] {
+			return 0
+		}
+		visiting[code] = true
+		depth := 0
+		for _, prerequisite := range prerequisites[code] {
+			depth = max(depth, depthOf(prerequisite)+1)
+		}
+		visiting[code] = false
+		depths[code] = depth
+		return depth
+	}
+	return depthOf
+}
+
+// unitsMatch matches the stored units of a year, optionally of a single faculty
+func unitsMatch(year string, faculty string) bson.M {
+	match := bson.M{"_id": bson.M{"$regex": "^" + regexp.QuoteMeta(handbookURL(year, "units", ""))}}
+	if faculty != "" {
+		match["common.faculty"] = faculty
+	}
+	return match
+}
+
+// aggregateHandbook runs an aggregation over the handbook collection, decoding every result into results
+func aggregateHandbook(ctx context.Context, pipeline []bson.M, results interface{}) error {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	collection := databases.FromContext(ctx).GetMongoDatabase().Collection("handbook")
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		log.Errorf("[ANALYTICS] Aggregation failed: %v", err)
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, results); err != nil {
+		log.Errorf("[ANALYTICS] Decoding failed: %v", err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,239 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"handbook-scraper/scrapers/units"
+	"handbook-scraper/utils/databases"
+	"handbook-scraper/utils/log"
+	_ "modernc.org/sqlite"
+)
+
+// analyticsSchema creates the relational tables of an analytics export
+const analyticsSchema = `
+CREATE TABLE units (
+	year INTEGER NOT NULL,
+	code TEXT NOT NULL,
+	title TEXT NOT NULL,
+	faculty TEXT,
+	level TEXT,
+	credit_points INTEGER,
+	wam_weight REAL,
+	eftsl REAL,
+	sca_band TEXT,
+	undergrad_postgrad TEXT,
+	active INTEGER,
+	synopsis TEXT,
+	source TEXT,
+	PRIMARY KEY (year, code)
+);
+CREATE TABLE offerings (
+	year INTEGER NOT NULL,
+	unit_code TEXT NOT NULL,
+	display_name TEXT,
+	semester TEXT,
+	location TEXT,
+	attendance_mode TEXT
+);
+CREATE TABLE assessments (
+	year INTEGER NOT NULL,
+	unit_code TEXT NOT NULL,
+	name TEXT,
+	type TEXT,
+	weight REAL -- NULL if the weight is not a number
+);
+CREATE TABLE requisite_edges (
+	year INTEGER NOT NULL,
+	unit_code TEXT NOT NULL,
+	requisite_type TEXT NOT NULL, -- e.g. Prerequisite, Corequisite or Prohibition
+	requisite_code TEXT NOT NULL,
+	group_path TEXT NOT NULL,     -- Position of the group listing the requisite, e.g. 0.1 for the second group of the first
+	connector TEXT                -- AND or OR, joining the requisites of the group
+);
+CREATE INDEX offerings_unit ON offerings (year, unit_code);
+CREATE INDEX assessments_unit ON assessments (year, unit_code);
+CREATE INDEX requisite_edges_unit ON requisite_edges (year, unit_code);
+CREATE INDEX requisite_edges_requisite ON requisite_edges (year, requisite_code);
+`
+
+// analyticsExportParams are the parameters of an analytics_export job
+type analyticsExportParams struct {
+	Year string `json:"year"`
+}
+
+// analyticsExportResult summarises an analytics_export job
+type analyticsExportResult struct {
+	File string         `json:"file"`
+	Rows map[string]int `json:"rows"` // Table to row count
+}
+
+// analyticsExportFile is the name of the analytics export of a year in the shared file store
+func analyticsExportFile(year string) string {
+	return "analytics_export/handbook-" + year + ".sqlite"
+}
+
+// analyticsExportJob writes the stored units of a year into a SQLite database, with their offerings, assessments and
+// requisite edges as relational tables, so the handbook can be analysed with SQL or loaded into a dataframe.
+// The database is built in a temporary file, then copied into the shared file store, replacing the previous export.
+func analyticsExportJob(ctx context.Context, raw json.RawMessage) (interface{}, error) {
+	var params analyticsExportParams
+	if err := decodeParams(raw, &params); err != nil {
+		return nil, err
+	}
+	year, err := resolveYear(ctx, params.Year)
+	if err != nil {
+		return nil, err
+	}
+	yearNumber, _ := strconv.Atoi(year)
+
+	// SQLite needs a file it can seek in, which GridFS is not
+	temp, err := os.CreateTemp("", "handbook-"+year+"-*.sqlite")
+	if err != nil {
+		return nil, err
+	}
+	partial := temp.Name()
+	temp.Close()
+	defer os.Remove(partial)
+
+	db, err := sql.Open("sqlite", partial)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	if _, err := db.ExecContext(ctx, analyticsSchema); err != nil {
+		return nil, fmt.Errorf("failed to create the tables: %w", err)
+	}
+
+	keys, err := storedItemKeys(ctx, year, "units")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stored units: %w", err)
+	}
+	base := handbookURL(year, "units", "")
+	codes := make([]string, 0, len(keys))
+	for _, key := range keys {
+		codes = append(codes, strings.TrimPrefix(key, base))
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	result := analyticsExportResult{File: analyticsExportFile(year), Rows: map[string]int{"units": 0, "offerings": 0, "assessments": 0, "requisite_edges": 0}}
+	for start := 0; start < len(codes); start += dumpBatchSize {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		batch := codes[start:min(start+dumpBatchSize, len(codes))]
+		stored := retrieveStoredMany(ctx, year, "units", batch)
+		for _, code := range batch {
+			var unitData units.UnitData
+			if data, ok := stored[code]; !ok || decodeInto(data, &unitData) != nil {
+				log.Warnf("[ANALYTICS EXPORT] Skipping %s, which could not be retrieved", base+code)
+				continue
+			}
+			if err := insertAnalyticsUnit(ctx, tx, yearNumber, unitData, result.Rows); err != nil {
+				return nil, fmt.Errorf("failed to export %s: %w", code, err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	if err := db.Close(); err != nil {
+		return nil, err
+	}
+	err = databases.FromContext(ctx).WriteFile(ctx, result.File, func(w io.Writer) error {
+		exported, err := os.Open(partial)
+		if err != nil {
+			return err
+		}
+		defer exported.Close()
+		_, err = io.Copy(w, exported)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Infof("[ANALYTICS EXPORT] Exported %d units of %s to %s", result.Rows["units"], year, result.File)
+	return result, nil
+}
+
+// insertAnalyticsUnit inserts the rows of a unit, counting them by table
+func insertAnalyticsUnit(ctx context.Context, tx *sql.Tx, year int, unitData units.UnitData, rows map[string]int) error {
+	code := unitData.Code
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO units VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		year, code, unitData.Title, unitData.Faculty, unitData.UnitLevel, unitData.CreditPoints, unitData.WAMWeight,
+		unitData.EFTSL, unitData.HighestSCABand, unitData.UndergradPostgrad, unitData.Active, unitData.Synopsis, unitData.Source,
+	); err != nil {
+		return err
+	}
+	rows["units"]++
+
+	for _, offering := range unitData.UnitOfferings {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO offerings VALUES (?, ?, ?, ?, ?, ?)`,
+			year, code, offering.DisplayName, offering.Semester, offering.Location, offering.AttendanceMode); err != nil {
+			return err
+		}
+		rows["offerings"]++
+	}
+
+	for _, assessment := range unitData.Assessments {
+		var weight interface{}
+		if parsed, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(assessment.Weight), "%"), 64); err == nil {
+			weight = parsed
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT INTO assessments VALUES (?, ?, ?, ?, ?)`,
+			year, code, assessment.AssessmentName, assessment.AssessmentType.Label, weight); err != nil {
+			return err
+		}
+		rows["assessments"]++
+	}
+
+	var insertEdges func(requisiteType string, container units.CompressedContainer, path string) error
+	insertEdges = func(requisiteType string, container units.CompressedContainer, path string) error {
+		for _, unit := range container.Units {
+			if _, err := tx.ExecContext(ctx, `INSERT INTO requisite_edges VALUES (?, ?, ?, ?, ?, ?)`,
+				year, code, requisiteType, unit.UnitCode, path, container.Relationship); err != nil {
+				return err
+			}
+			rows["requisite_edges"]++
+		}
+		for i, child := range container.Containers {
+			if err := insertEdges(requisiteType, child, path+"."+strconv.Itoa(i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, requisite := range unitData.Requisites {
+		for i, container := range requisite.Containers {
+			if err := insertEdges(requisite.RequisiteType, container, strconv.Itoa(i)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// AnalyticsExportHandler downloads the analytics export of a year written by the analytics_export job
+func AnalyticsExportHandler(c *gin.Context) {
+	year, ok := yearParam(c)
+	if !ok {
+		return
+	}
+
+	serveFile(c, analyticsExportFile(year), fmt.Sprintf("the %s handbook has not been exported, submit an analytics_export job first", year))
+}
@@ -0,0 +1,260 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gocolly/colly/v2"
+	"handbook-scraper/scrapers/common"
+	"handbook-scraper/scrapers/units"
+)
+
+// Intents a question can be routed to
+const (
+	intentCheck      = "check"      // Whether the student can take a unit, with the units they completed
+	intentOfferings  = "offerings"  // When and where a unit is offered
+	intentRequisites = "requisites" // What a unit requires
+	intentUnit       = "unit"       // What a unit is about
+	intentSearch     = "search"     // Which units are about a topic
+)
+
+// maxAnswerResults is the most units a search answer lists
+const maxAnswerResults = 5
+
+var (
+	// questionWord matches the words of a question, codes included
+	questionWord = regexp.MustCompile(`[A-Za-z0-9]+`)
+	// checkPhrases, offeringPhrases and requisitePhrases are the phrasings which route a question about a unit
+	checkPhrases     = regexp.MustCompile(`\b(can i|could i|am i (allowed|eligible|able)|eligible|qualify|allowed to)\b`)
+	offeringPhrases  = regexp.MustCompile(`\b(when|offered|offering|offerings|semester|teaching period|trimester|campus|where|online)\b`)
+	requisitePhrases = regexp.MustCompile(`\b(prereq|prereqs|prerequisite|prerequisites|requisite|requisites|prohibition|prohibitions|corequisite|need to (do|have done|complete|take)|required for|requirements?)\b`)
+)
+
+// answerRequest is the request body of the answer endpoint
+type answerRequest struct {
+	Question string `json:"question"`
+	Course   string `json:"course"` // Course of the student, for unit checks
+}
+
+// parsedQuestion is a question with the codes it mentions
+type parsedQuestion struct {
+	Intent    string
+	Unit      string        // First unit mentioned, the one asked about
+	Completed []common.Unit // Other units mentioned, the units completed for checks
+	Course    string        // Course mentioned, if any
+}
+
+// parseQuestion routes a question to an intent by its phrasing and the codes it mentions.
+// Questions which mention no unit are searches.
+func parseQuestion(question string) parsedQuestion {
+	var parsed parsedQuestion
+	for _, word := range questionWord.FindAllString(question, -1) {
+		code := strings.ToUpper(word)
+		switch {
+		case ValidCode("units", code) && parsed.Unit == "":
+			parsed.Unit = code
+		case ValidCode("units", code):
+			parsed.Completed = append(parsed.Completed, common.Unit{Code: code})
+		case ValidCode("courses", code) && parsed.Course == "":
+			parsed.Course = code
+		}
+	}
+
+	lower := strings.ToLower(question)
+	switch {
+	case parsed.Unit == "":
+		parsed.Intent = intentSearch
+	case checkPhrases.MatchString(lower) && !offeringPhrases.MatchString(lower):
+		parsed.Intent = intentCheck
+	case requisitePhrases.MatchString(lower):
+		parsed.Intent = intentRequisites
+	case offeringPhrases.MatchString(lower):
+		parsed.Intent = intentOfferings
+	case checkPhrases.MatchString(lower):
+		parsed.Intent = intentCheck
+	default:
+		parsed.Intent = intentUnit
+	}
+	return parsed
+}
+
+// AnswerHandler answers a question in natural language, such as "can I do FIT3152 if I've done FIT1045 and FIT2004?",
+// by routing it to a unit check, an offerings or requisites lookup, a unit summary, or a search by topic.
+// It responds with the intent, the structured data of the capability, and a templated answer, for chat bots.
+func AnswerHandler(c *gin.Context, collector *colly.Collector) {
+	ctx := c.Request.Context()
+	year, ok := yearParam(c)
+	if !ok {
+		return
+	}
+
+	var req answerRequest
+	if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Question) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "a question is required"})
+		return
+	}
+
+	parsed := parseQuestion(req.Question)
+	if req.Course != "" {
+		parsed.Course = strings.ToUpper(strings.TrimSpace(req.Course))
+		if !ValidCode("courses", parsed.Course) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("malformed course code: %s", parsed.Course)})
+			return
+		}
+	}
+
+	var answer string
+	var data interface{}
+	switch parsed.Intent {
+	case intentCheck:
+		completed := normaliseCompleted(parsed.Completed)
+		result, ok := checkUnit(c, collector, year, parsed.Unit, completed, parsed.Course)
+		if !ok {
+			return
+		}
+		answer, data = checkAnswer(parsed.Unit, result), result
+	case intentSearch:
+		found, err := searchAnswer(c.Request.Context(), year, req.Question)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		answer, data = searchAnswerText(found), found
+	default:
+		final, err := ScrapeAndCache(c.Request.Context(), handbookURL(year, "units", parsed.Unit), collector, "units")
+		if err == nil {
+			final, err = withOfferingStatus(ctx, year, final)
+		}
+		var unitData units.UnitData
+		if err == nil {
+			err = decodeInto(final, &unitData)
+		}
+		if err != nil {
+			respondWithScrapeError(c, err)
+			return
+		}
+
+		switch parsed.Intent {
+		case intentOfferings:
+			answer, data = offeringsAnswer(year, unitData), gin.H{"unit_offerings": unitData.UnitOfferings, "offering_status": unitData.OfferingStatus}
+		case intentRequisites:
+			answer, data = units.RequisiteParagraph(unitData), gin.H{"requisites": units.RenderRequisites(unitData)}
+		default:
+			answer, data = unitAnswer(unitData), summariseItem(parsed.Unit, final)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"question": req.Question,
+		"intent":   parsed.Intent,
+		"unit":     parsed.Unit,
+		"answer":   answer,
+		"data":     data,
+	})
+}
+
+// checkAnswer phrases the result of a unit check
+func checkAnswer(code string, result unitCheckResult) string {
+	if result.Met {
+		return fmt.Sprintf("Yes, you meet the requisites for %s.", code)
+	}
+	if len(result.Message) == 0 {
+		return fmt.Sprintf("Not yet, you do not meet the requisites for %s.", code)
+	}
+	return fmt.Sprintf("Not yet, for %s: %s.", code, strings.Join(result.Message, "; "))
+}
+
+// offeringsAnswer phrases the offerings of a unit in a year
+func offeringsAnswer(year string, unitData units.UnitData) string {
+	if len(unitData.UnitOfferings) == 0 {
+		if unitData.OfferingStatus != nil {
+			return unitData.OfferingStatus.Message + "."
+		}
+		return fmt.Sprintf("%s is not offered in %s.", unitData.Code, year)
+	}
+
+	offerings := make([]string, 0, len(unitData.UnitOfferings))
+	for _, offering := range unitData.UnitOfferings {
+		var where []string
+		for _, detail := range []string{offering.Location, offering.AttendanceMode} {
+			if detail != "" {
+				where = append(where, detail)
+			}
+		}
+		text := offering.Semester
+		if text == "" {
+			text = offering.DisplayName
+		}
+		if len(where) > 0 {
+			text += " (" + strings.Join(where, ", ") + ")"
+		}
+		offerings = append(offerings, text)
+	}
+	return fmt.Sprintf("%s is offered in %s in %s.", unitData.Code, year, strings.Join(offerings, ", "))
+}
+
+// unitAnswer phrases what a unit is
+func unitAnswer(unitData units.UnitData) string {
+	answer := fmt.Sprintf("%s %s is a %d credit point unit.", unitData.Code, unitData.Title, unitData.CreditPoints)
+	if unitData.ShortSynopsis != "" {
+		answer += " " + unitData.ShortSynopsis
+	}
+	return answer
+}
+
+// searchAnswer finds the stored units of a year tagged with the topics a question mentions,
+// the units with the most matching tags first
+func searchAnswer(ctx context.Context, year string, question string) ([]itemSummary, error) {
+	index, err := tagIndex(ctx, year)
+	if err != nil {
+		return nil, err
+	}
+
+	words := " " + strings.Join(questionWord.FindAllString(strings.ToLower(question), -1), " ") + " "
+	scores := map[string]int{}
+	for tag, codes := range index {
+		if !strings.Contains(words, " "+tag+" ") {
+			continue
+		}
+		for _, code := range codes {
+			// Longer tags name a topic more precisely
+			scores[code] += len(strings.Fields(tag))
+		}
+	}
+
+	codes := make([]string, 0, len(scores))
+	for code := range scores {
+		codes = append(codes, code)
+	}
+	sort.Slice(codes, func(i, j int) bool {
+		if scores[codes[i]] != scores[codes[j]] {
+			return scores[codes[i]] > scores[codes[j]]
+		}
+		return codes[i] < codes[j]
+	})
+	codes = codes[:min(len(codes), maxAnswerResults)]
+
+	stored := retrieveStoredMany(ctx, year, "units", codes)
+	found := make([]itemSummary, 0, len(codes))
+	for _, code := range codes {
+		found = append(found, summariseItem(code, stored[code]))
+	}
+	return found, nil
+}
+
+// searchAnswerText phrases the units found by a search
+func searchAnswerText(found []itemSummary) string {
+	if len(found) == 0 {
+		return "I couldn't find any units about that. Try asking about a unit code, such as FIT1045."
+	}
+	names := make([]string, 0, len(found))
+	for _, summary := range found {
+		names = append(names, strings.TrimSpace(summary.Code+" "+summary.Title))
+	}
+	return "These units might help: " + strings.Join(names, ", ") + "."
+}
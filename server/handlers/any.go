@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gocolly/colly/v2"
+	"handbook-scraper/utils/log"
+)
+
+// AnyItemHandler works out whether a code is a unit, course, or area of study and returns its document.
+// Codes are tried against each item type whose format they match, most specific first.
+func AnyItemHandler(c *gin.Context, collector *colly.Collector) {
+	code := c.Param("code")
+
+	year, ok := yearParam(c)
+	if !ok {
+		return
+	}
+	view, ok := requisitesView(c)
+	if !ok {
+		return
+	}
+
+	for _, urlKey := range candidateItemTypes(code) {
+		data, err := ScrapeAndCache(c.Request.Context(), handbookURL(year, urlKey, code), collector, urlKey)
+		if multiple, ok := asMultipleEntries(err); ok {
+			respondWithVariants(c, collector, urlKey, code, multiple)
+			return
+		}
+		if err != nil {
+			log.Infof("[ANY] %s is not in %s: %v", code, urlKey, err)
+			continue
+		}
+
+		if urlKey == "units" {
+			if data, err = withRequisitesView(data, view); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+		}
+		respondWithFields(c, gin.H{"item_type": urlKey, "data": data})
+		return
+	}
+
+	c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("%s was not found in the %s handbook", code, year)})
+}
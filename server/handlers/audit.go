@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gocolly/colly/v2"
+	"handbook-scraper/planner"
+	"handbook-scraper/scrapers/area_of_study"
+	"handbook-scraper/scrapers/common"
+)
+
+// AosAuditHandler evaluates a student's completed units against the curriculum of an area of study,
+// returning the units still required for that major, minor, or specialisation.
+// Specialisations it lists can be chosen with the comma-separated specialisations query parameter.
+func AosAuditHandler(c *gin.Context, collector *colly.Collector) {
+	code := c.Param("code")
+
+	year, ok := yearParam(c)
+	if !ok {
+		return
+	}
+
+	var completedUnits []common.Unit
+	if err := c.BindJSON(&completedUnits); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON format for completed units"})
+		return
+	}
+
+	completedUnits = normaliseCompleted(completedUnits)
+
+	var specialisations []string
+	if raw := c.Query("specialisations"); raw != "" {
+		specialisations = uniqueCodes(strings.Split(raw, ","))
+	}
+	checked := strings.Join(append([]string{code}, specialisations...), "+")
+
+	respondWithCachedCheck(c, "aos_audit", year, checked, completedUnits, func() (interface{}, bool) {
+		data, err := ScrapeAndCache(c.Request.Context(), handbookURL(year, "aos", code), collector, "aos")
+		if err != nil {
+			respondWithScrapeError(c, err)
+			return nil, false
+		}
+
+		var aosData area_of_study.AosData
+		if err := decodeInto(data, &aosData); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to decode area of study data"})
+			return nil, false
+		}
+
+		if aosData.CurriculumError {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "the curriculum of this area of study could not be parsed"})
+			return nil, false
+		}
+
+		curriculum, _, ok := resolveSpecialisations(c, year, collector, aosData.CurriculumStructure, specialisations)
+		if !ok {
+			return nil, false
+		}
+		return planner.AuditCurriculum(aosData.Code, aosData.Title, curriculum, completedUnits), true
+	})
+}
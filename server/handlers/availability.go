@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"handbook-scraper/scrapers/timetable"
+	"handbook-scraper/utils/databases"
+	"handbook-scraper/utils/log"
+)
+
+// availabilityTTL is kept short since class places change constantly during enrolment
+const availabilityTTL = 5 * time.Minute
+
+// AvailabilityHandler reports whether the classes of a unit still have open places, per activity type
+func AvailabilityHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	year, ok := yearParam(c)
+	if !ok {
+		return
+	}
+	code := strings.ToUpper(c.Param("code"))
+
+	dbHandler := databases.FromContext(ctx)
+	key := fmt.Sprintf("availability:%s:%s", year, code)
+
+	// Check cache
+	var cached timetable.AvailabilityData
+	if err := dbHandler.Retrieve(databases.Cache, key, &cached); err == nil {
+		respondWithFields(c, cached)
+		return
+	}
+
+	if ReadOnly(ctx) {
+		respondWithScrapeError(c, errReadOnly)
+		return
+	}
+
+	data, err := timetable.Scrape(code, year)
+	if err != nil {
+		log.Errorf("[ERROR] %v", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := dbHandler.Store(databases.Cache, key, data, availabilityTTL); err != nil {
+		log.Errorf("Error saving to cache: %v", err)
+	}
+
+	respondWithFields(c, data)
+}
@@ -0,0 +1,232 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gocolly/colly/v2"
+	"handbook-scraper/scrapers/common"
+	"handbook-scraper/utils/log"
+)
+
+const (
+	// maxBatchSize is the most codes a batch request can ask for
+	maxBatchSize = 200
+	// defaultBatchConcurrency is how many items of a batch which are not stored are fetched at a time
+	defaultBatchConcurrency = 4
+)
+
+// batchRequest is the request body of the batch endpoints, either {"codes": [...]} or a bare array of codes
+type batchRequest struct {
+	Codes []string `json:"codes"`
+}
+
+// UnmarshalJSON accepts the codes as an object or a bare array
+func (r *batchRequest) UnmarshalJSON(data []byte) error {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		return json.Unmarshal(trimmed, &r.Codes)
+	}
+	type plain batchRequest
+	return json.Unmarshal(data, (*plain)(r))
+}
+
+// batchItem is the result of a single code of a batch.
+// Status is 200 with the item's data, 300 with the choices of a code with several entries, 400 for a malformed code,
+// 404 if the handbook has no such item, 502 if the handbook could not be scraped, or 503 if it is throttling requests
+// or too many pages are being fetched.
+type batchItem struct {
+	Code    string           `json:"code"`
+	Status  int              `json:"status"`
+	Data    interface{}      `json:"data,omitempty"`
+	Error   string           `json:"error,omitempty"`
+	Choices []common.Variant `json:"choices,omitempty"` // Entries of a code with several, when the status is 300
+}
+
+// batchSummary counts the results of a batch by outcome
+type batchSummary struct {
+	Requested int `json:"requested"`
+	Succeeded int `json:"succeeded"`
+	Invalid   int `json:"invalid"`
+	NotFound  int `json:"not_found"`
+	Ambiguous int `json:"ambiguous"` // Codes with several entries, see Choices
+	Failed    int `json:"failed"`
+
+	retryAfter time.Duration // Longest pause advised by the handbook while throttling the batch
+}
+
+// add counts the result of an item
+func (s *batchSummary) add(item batchItem) {
+	switch item.Status {
+	case http.StatusOK:
+		s.Succeeded++
+	case http.StatusBadRequest:
+		s.Invalid++
+	case http.StatusNotFound:
+		s.NotFound++
+	case http.StatusMultipleChoices:
+		s.Ambiguous++
+	default:
+		s.Failed++
+	}
+}
+
+// BatchHandler returns the handbook data of several items of the same type at once.
+// Each code gets its own status, so a malformed or missing code never fails the whole batch,
+// and the batch responds with 200 and a summary as long as the request itself is valid.
+// Clients accepting NDJSON are streamed each item in order as soon as it is fetched, followed by the summary.
+// urlKey could be "courses", "aos", or "units"
+func BatchHandler(c *gin.Context, collector *colly.Collector, urlKey string) {
+	ctx := c.Request.Context()
+	year, ok := yearParam(c)
+	if !ok {
+		return
+	}
+	view := requisitesCompressed
+	if urlKey == "units" {
+		if view, ok = requisitesView(c); !ok {
+			return
+		}
+	}
+
+	var req batchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON format for batch request"})
+		return
+	}
+	codes := uniqueCodes(req.Codes)
+	if len(codes) == 0 || len(codes) > maxBatchSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("between 1 and %d codes are required", maxBatchSize)})
+		return
+	}
+
+	results := fetchBatch(ctx, collector, year, urlKey, codes)
+
+	summary := batchSummary{Requested: len(codes)}
+	fetch := func(i int) batchItem {
+		result := <-results[i]
+		item := result.item
+		summary.retryAfter = max(summary.retryAfter, result.retryAfter)
+		if urlKey == "units" && item.Data != nil {
+			if data, err := withRequisitesView(item.Data, view); err == nil {
+				item.Data = data
+			} else {
+				log.Errorf("[BATCH] Error selecting the requisites of %s: %v", item.Code, err)
+			}
+		}
+		summary.add(item)
+		return item
+	}
+
+	if wantsNDJSON(c) {
+		stream := newNDJSONStream(c)
+		for i := range codes {
+			if !stream.Send(fetch(i)) {
+				return
+			}
+		}
+		stream.Send(gin.H{"summary": summary})
+		return
+	}
+
+	items := make([]batchItem, 0, len(codes))
+	for i := range codes {
+		items = append(items, fetch(i))
+	}
+	if summary.retryAfter > 0 {
+		c.Header("Retry-After", retryAfterSeconds(summary.retryAfter))
+	}
+	c.JSON(http.StatusOK, gin.H{"items": items, "summary": summary})
+}
+
+// batchResult is a fetched item of a batch, with the pause advised by the handbook if it was throttling requests
+type batchResult struct {
+	item       batchItem
+	retryAfter time.Duration
+}
+
+// fetchBatch retrieves the items of a batch, sending the result of each code on the channel at its index.
+// Stored items are retrieved together, so only the items which are not stored cost a round-trip each,
+// and the rest are fetched concurrently, a few at a time.
+func fetchBatch(ctx context.Context, collector *colly.Collector, year string, urlKey string, codes []string) []chan batchResult {
+	valid := make([]string, 0, len(codes))
+	for _, code := range codes {
+		if ValidCode(urlKey, code) {
+			valid = append(valid, code)
+		}
+	}
+	stored := retrieveStoredMany(ctx, year, urlKey, valid)
+
+	results := make([]chan batchResult, len(codes))
+	slots := make(chan struct{}, envInt("BATCH_CONCURRENCY", defaultBatchConcurrency, 1))
+	for i, code := range codes {
+		results[i] = make(chan batchResult, 1)
+		if data, ok := stored[code]; ok {
+			results[i] <- batchResult{item: batchItem{Code: code, Status: http.StatusOK, Data: data}}
+			continue
+		}
+		go func(result chan<- batchResult, code string) {
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			item, retryAfter := fetchBatchItem(ctx, collector, year, urlKey, code)
+			result <- batchResult{item: item, retryAfter: retryAfter}
+		}(results[i], code)
+	}
+	return results
+}
+
+// fetchBatchItem scrapes or retrieves the cached data of a single code of a batch.
+// It also returns the pause advised by the handbook if it is throttling requests.
+func fetchBatchItem(ctx context.Context, collector *colly.Collector, year string, urlKey string, code string) (batchItem, time.Duration) {
+	item := batchItem{Code: code}
+	if !ValidCode(urlKey, code) {
+		item.Status, item.Error = http.StatusBadRequest, fmt.Sprintf("malformed code: %s", code)
+		return item, 0
+	}
+
+	data, err := ScrapeAndCache(ctx, handbookURL(year, urlKey, code), collector, urlKey)
+	var throttled *common.ThrottledError
+	var overloaded *overloadedError
+	var multiple *common.MultipleEntriesError
+	switch {
+	case errors.As(err, &throttled):
+		item.Status, item.Error = http.StatusServiceUnavailable, err.Error()
+		return item, throttled.RetryAfter
+	case errors.As(err, &overloaded):
+		item.Status, item.Error = http.StatusServiceUnavailable, err.Error()
+		return item, overloaded.RetryAfter
+	case errors.Is(err, common.ErrPageNotFound):
+		item.Status, item.Error = http.StatusNotFound, fmt.Sprintf("%s was not found in the %s handbook", code, year)
+	case errors.As(err, &multiple):
+		item.Status, item.Error, item.Choices = http.StatusMultipleChoices, err.Error(), multiple.Variants
+	case errors.Is(err, errReadOnly):
+		item.Status, item.Error = readOnlyYearStatus(year), fmt.Sprintf("%s is %s", code, err)
+	case err != nil:
+		log.Errorf("[BATCH] Error fetching %s %s: %v", urlKey, code, err)
+		item.Status, item.Error = http.StatusBadGateway, err.Error()
+	default:
+		item.Status, item.Data = http.StatusOK, data
+	}
+	return item, 0
+}
+
+// uniqueCodes upper-cases codes and removes duplicates and blanks, keeping their order
+func uniqueCodes(codes []string) []string {
+	seen := map[string]bool{}
+	unique := []string{}
+	for _, code := range codes {
+		code = strings.ToUpper(strings.TrimSpace(code))
+		if code == "" || seen[code] {
+			continue
+		}
+		seen[code] = true
+		unique = append(unique, code)
+	}
+	return unique
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gocolly/colly/v2"
+	"handbook-scraper/scrapers/calendar"
+	"handbook-scraper/utils/databases"
+	"handbook-scraper/utils/log"
+)
+
+// calendarTTL is how long key dates are cached in Redis
+const calendarTTL = time.Hour * 144
+
+// CalendarHandler returns the academic calendar of a year
+func CalendarHandler(c *gin.Context, collector *colly.Collector) {
+	ctx := c.Request.Context()
+	year, ok := yearParam(c)
+	if !ok {
+		return
+	}
+
+	// HandbookCache retrieval
+	var cached calendar.CalendarData
+	if err := databases.FromContext(ctx).Retrieve(databases.Handbook, calendar.URL(year), &cached); err == nil {
+		log.Successf("[CACHE HIT] Success for %s", calendar.URL(year))
+		respondWithFields(c, cached)
+		return
+	}
+
+	data, err := RefreshCalendar(ctx, year, collector)
+	if errors.Is(err, errReadOnly) {
+		respondWithScrapeError(c, err)
+		return
+	}
+	if err != nil {
+		log.Errorf("[ERROR] %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	respondWithFields(c, data)
+}
+
+// RefreshCalendar scrapes the key dates of a year and saves them in the storage of a context, replacing any cached copy
+func RefreshCalendar(ctx context.Context, year string, collector *colly.Collector) (calendar.CalendarData, error) {
+	if ReadOnly(ctx) {
+		return calendar.CalendarData{}, errReadOnly
+	}
+	data, err := calendar.Scrape(year, collector)
+	if err != nil {
+		return calendar.CalendarData{}, fmt.Errorf("failed to scrape calendar: %w", err)
+	}
+
+	if err := databases.FromContext(ctx).Store(databases.Handbook, data.Link, data, calendarTTL); err != nil {
+		log.Errorf("Error saving to cache: %v", err)
+	}
+
+	log.Infof("[CACHE SAVE] %s", data.Link)
+	return data, nil
+}
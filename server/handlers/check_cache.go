@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"handbook-scraper/scrapers/common"
+	"handbook-scraper/utils/databases"
+	"handbook-scraper/utils/log"
+)
+
+// defaultCheckCacheTTL is how long check results are cached unless CHECK_CACHE_TTL is set
+const defaultCheckCacheTTL = 5 * time.Minute
+
+// checkCacheTTL returns how long check results are cached, from CHECK_CACHE_TTL. A TTL of 0 disables the cache.
+func checkCacheTTL() time.Duration {
+	raw := os.Getenv("CHECK_CACHE_TTL")
+	if raw == "" {
+		return defaultCheckCacheTTL
+	}
+	ttl, err := time.ParseDuration(raw)
+	if err != nil || ttl < 0 {
+		log.Warnf("[CHECK CACHE] Invalid CHECK_CACHE_TTL %q, using %s", raw, defaultCheckCacheTTL)
+		return defaultCheckCacheTTL
+	}
+	return ttl
+}
+
+// normaliseCompleted upper-cases the codes of completed units and sorts them, removing repeated units,
+// so the same set of units always gives the same check
+func normaliseCompleted(completedUnits []common.Unit) []common.Unit {
+	normalised := make([]common.Unit, 0, len(completedUnits))
+	for _, unit := range completedUnits {
+		unit.Code = strings.ToUpper(strings.TrimSpace(unit.Code))
+		if !slices.Contains(normalised, unit) {
+			normalised = append(normalised, unit)
+		}
+	}
+	slices.SortStableFunc(normalised, func(a, b common.Unit) int { return strings.Compare(a.Code, b.Code) })
+	return normalised
+}
+
+// checkCacheKey is the cache key of a check of an item against normalised completed units. Checks are cached per tenant,
+// as the curricula they are checked against can be patched for one tenant.
+func checkCacheKey(tenant string, kind string, year string, code string, completedUnits []common.Unit) string {
+	marshalled, _ := json.Marshal(completedUnits)
+	sum := sha256.Sum256(marshalled)
+	return "check:" + tenantName(tenant) + ":" + kind + ":" + year + ":" + code + ":" + hex.EncodeToString(sum[:16])
+}
+
+// respondWithCachedCheck responds with the result of an earlier check of the same item and completed units,
+// or runs the check and caches its result. Planner clients repeat the same checks as students edit their plans.
+// check responds itself and returns false if it fails, and failed checks are not cached.
+func respondWithCachedCheck(c *gin.Context, kind string, year string, code string, completedUnits []common.Unit, check func() (interface{}, bool)) {
+	ttl := checkCacheTTL()
+	key := checkCacheKey(requestTenant(c.Request.Context()), kind, year, code, completedUnits)
+	dbHandler := databases.FromContext(c.Request.Context())
+
+	if ttl > 0 {
+		var cached json.RawMessage
+		if err := dbHandler.Retrieve(databases.Cache, key, &cached); err == nil && len(cached) > 0 {
+			c.Header("X-Cache", "HIT")
+			c.JSON(http.StatusOK, cached)
+			return
+		}
+	}
+
+	result, ok := check()
+	if !ok {
+		return
+	}
+	if ttl > 0 {
+		if err := dbHandler.Store(databases.Cache, key, result, ttl); err != nil {
+			log.Errorf("[CHECK CACHE] Error caching %s: %v", key, err)
+		}
+	}
+	c.Header("X-Cache", "MISS")
+	c.JSON(http.StatusOK, result)
+}
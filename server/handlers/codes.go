@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"regexp"
+)
+
+// itemTypes are the handbook urlKeys in the order ambiguous codes are tried
+var itemTypes = []string{"units", "courses", "aos"}
+
+// codePatterns are the expected formats of academic item codes by urlKey
+var codePatterns = map[string]*regexp.Regexp{
+	"units":   regexp.MustCompile(`^[A-Z]{3,4}[0-9]{4}(-[A-Z]+)?$`), // FIT3138, or campus variants such as FIT5057-MALAYSIA
+	"courses": regexp.MustCompile(`^[A-Z][0-9]{4}$`),                // C2001
+	"aos":     regexp.MustCompile(`^[A-Z][A-Z0-9]{2,15}$`),          // SFTWRDEV08
+}
+
+// ValidCode reports whether an upper-case code matches the format of the urlKey.
+// The "any" urlKey accepts codes matching any format. The area of study format is loose, so codes in the
+// format of a unit or course are never areas of study, and lookups of them never probe the handbook's areas of study.
+func ValidCode(urlKey string, code string) bool {
+	switch urlKey {
+	case "any":
+		return len(candidateItemTypes(code)) > 0
+	case "aos":
+		if ValidCode("units", code) || ValidCode("courses", code) {
+			return false
+		}
+	}
+	pattern, ok := codePatterns[urlKey]
+	return ok && pattern.MatchString(code)
+}
+
+// candidateItemTypes returns the urlKeys whose format an upper-case code matches, most specific first
+func candidateItemTypes(code string) []string {
+	var candidates []string
+	for _, urlKey := range itemTypes {
+		if ValidCode(urlKey, code) {
+			candidates = append(candidates, urlKey)
+		}
+	}
+	return candidates
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"handbook-scraper/utils/databases"
+	"handbook-scraper/utils/log"
+)
+
+const (
+	// consistencyReportKey is the cache key of the latest consistency report
+	consistencyReportKey = "consistency_report"
+	// consistencyLockTTL is how long the consistency lock is held before it must be refreshed
+	consistencyLockTTL = time.Minute
+)
+
+// consistencyParams are the parameters of a check_consistency job
+type consistencyParams struct {
+	Repair bool `json:"repair"`
+}
+
+// checkConsistencyJob compares the handbook documents cached in Redis with MongoDB, optionally repairing the drift,
+// and stores the report for the admin API and the metrics. Only one replica checks at a time.
+func checkConsistencyJob(ctx context.Context, raw json.RawMessage) (interface{}, error) {
+	var params consistencyParams
+	if err := decodeParams(raw, &params); err != nil {
+		return nil, err
+	}
+
+	dbHandler, release := databases.Hold(ctx)
+	defer release()
+	var report databases.ConsistencyReport
+	ran, err := dbHandler.RunExclusive("consistency", consistencyLockTTL, func(lockCtx context.Context) error {
+		// The check stops if the job is cancelled or the lock is lost
+		checkCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		stop := context.AfterFunc(lockCtx, cancel)
+		defer stop()
+
+		var err error
+		report, err = dbHandler.CheckHandbookConsistency(checkCtx, params.Repair)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !ran {
+		return nil, fmt.Errorf("consistency is already being checked by another replica")
+	}
+
+	if err := dbHandler.Store(databases.Cache, consistencyReportKey, report, 0); err != nil {
+		log.Errorf("[CONSISTENCY] Error saving report: %v", err)
+	}
+	log.Infof("[CONSISTENCY] %d matching, %d mismatched, %d only in Redis and %d corrupt documents, %d repaired",
+		report.Matching, len(report.Mismatched), len(report.RedisOnly), len(report.Corrupt), report.Repaired)
+	return report, nil
+}
+
+// latestConsistencyReport returns the report of the latest consistency check, or nil if none has run
+func latestConsistencyReport(ctx context.Context) *databases.ConsistencyReport {
+	var report databases.ConsistencyReport
+	if err := databases.FromContext(ctx).Retrieve(databases.Cache, consistencyReportKey, &report); err != nil {
+		return nil
+	}
+	return &report
+}
+
+// ConsistencyHandler returns the report of the latest consistency check
+func ConsistencyHandler(c *gin.Context) {
+	report := latestConsistencyReport(c.Request.Context())
+	if report == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no consistency report, run one with POST /v1/admin/consistency"})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// CheckConsistencyHandler starts a check_consistency job, which repairs the drift if the repair query parameter is true
+func CheckConsistencyHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	repair, _ := strconv.ParseBool(c.Query("repair"))
+	params, _ := json.Marshal(consistencyParams{Repair: repair})
+	job, err := dependencies(ctx).jobs.Submit("check_consistency", params)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, job)
+}
@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"handbook-scraper/scrapers/area_of_study"
+	"handbook-scraper/scrapers/courses"
+	"handbook-scraper/scrapers/units"
+	"handbook-scraper/utils/databases"
+	"handbook-scraper/utils/log"
+)
+
+// courseGraphSummaryTTL is how long the precomputed graph of a course is kept
+const courseGraphSummaryTTL = 7 * 24 * time.Hour
+
+// precomputeCourseGraphsParams are the parameters of a precompute_course_graphs job
+type precomputeCourseGraphsParams struct {
+	Year string `json:"year"`
+}
+
+// courseGraphSummary is the precomputed curriculum graph of a course
+type courseGraphSummary struct {
+	Year              string         `json:"year"`
+	Code              string         `json:"code"`
+	GeneratedAt       time.Time      `json:"generated_at"`
+	Units             []string       `json:"units"`              // Every unit in the curriculum, including those of its areas of study
+	PrerequisiteDepth map[string]int `json:"prerequisite_depth"` // Length of the longest prerequisite chain leading to each unit
+	Unlocks           map[string]int `json:"unlocks"`            // How many other units of the course list each unit as a prerequisite
+	Missing           []string       `json:"missing"`            // Units and areas of study of the curriculum which are not stored
+}
+
+// precomputeCourseGraphsResult summarises a precompute_course_graphs job
+type precomputeCourseGraphsResult struct {
+	Courses int               `json:"courses"`
+	Failed  map[string]string `json:"failed"`
+}
+
+// courseGraphSummaryKey is the cache key of the precomputed graph of a course
+func courseGraphSummaryKey(year string, code string) string {
+	return "course_graph:" + year + ":" + strings.ToUpper(code)
+}
+
+// precomputeCourseGraphsJob precomputes the curriculum graph of every stored course of a year,
+// so planner clients can get a course's units, prerequisite depths and unlock counts without walking its graph.
+// Only stored units and areas of study are used, nothing is scraped.
+func precomputeCourseGraphsJob(ctx context.Context, raw json.RawMessage) (interface{}, error) {
+	var params precomputeCourseGraphsParams
+	if err := decodeParams(raw, &params); err != nil {
+		return nil, err
+	}
+	if params.Year == "" {
+		return nil, fmt.Errorf("year is required")
+	}
+
+	dbHandler, release := databases.Hold(ctx)
+	defer release()
+	unitKeys, err := storedItemKeys(ctx, params.Year, "units")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stored units: %w", err)
+	}
+
+	// Prerequisite chains may pass through units outside a course, so every unit of the year is loaded
+	prerequisites := make(map[string][]string, len(unitKeys))
+	for _, key := range unitKeys {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		var unitData units.UnitData
+		if err := dbHandler.Retrieve(databases.Handbook, key, &unitData); err != nil {
+			log.Errorf("[COURSE GRAPHS] Error retrieving %s: %v", key, err)
+			continue
+		}
+		prerequisites[unitData.Code] = unitPrerequisites(unitData)
+	}
+	depthOf := prerequisiteDepths(prerequisites)
+
+	courseKeys, err := storedItemKeys(ctx, params.Year, "courses")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stored courses: %w", err)
+	}
+
+	result := precomputeCourseGraphsResult{Failed: map[string]string{}}
+	for _, key := range courseKeys {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		var courseData courses.CourseData
+		if err := dbHandler.Retrieve(databases.Handbook, key, &courseData); err != nil {
+			result.Failed[key] = err.Error()
+			continue
+		}
+		if courseData.CurriculumError {
+			result.Failed[courseData.Code] = "the curriculum could not be parsed"
+			continue
+		}
+
+		summary := summariseCourseGraph(ctx, params.Year, courseData, prerequisites, depthOf)
+		if err := dbHandler.Store(databases.Cache, courseGraphSummaryKey(params.Year, courseData.Code), summary, courseGraphSummaryTTL); err != nil {
+			result.Failed[courseData.Code] = err.Error()
+			continue
+		}
+		result.Courses++
+	}
+
+	log.Infof("[COURSE GRAPHS] %s: precomputed %d courses, %d failed", params.Year, result.Courses, len(result.Failed))
+	return result, nil
+}
+
+// summariseCourseGraph flattens the curriculum of a course, following its stored areas of study,
+// and works out the prerequisite depth and unlock count of each of its units
+func summariseCourseGraph(ctx context.Context, year string, courseData courses.CourseData, prerequisites map[string][]string, depthOf func(string) int) courseGraphSummary {
+	summary := courseGraphSummary{
+		Year:              year,
+		Code:              courseData.Code,
+		GeneratedAt:       time.Now(),
+		PrerequisiteDepth: map[string]int{},
+		Unlocks:           map[string]int{},
+	}
+
+	unitCodes := map[string]bool{}
+	missing := map[string]bool{}
+	expanded := map[string]bool{}
+	// Each level of areas of study is retrieved together
+	level := curriculumItems(courseData.CurriculumStructure)
+	for len(level) > 0 {
+		var aosCodes []string
+		for _, item := range level {
+			switch itemURLKey(item) {
+			case "units":
+				if item.Code != "" {
+					unitCodes[item.Code] = true
+				}
+			case "aos":
+				if item.Code != "" && !expanded[item.Code] {
+					expanded[item.Code] = true
+					aosCodes = append(aosCodes, item.Code)
+				}
+			}
+		}
+
+		stored := retrieveStoredMany(ctx, year, "aos", aosCodes)
+		level = nil
+		for _, code := range aosCodes {
+			var aos area_of_study.AosData
+			data, ok := stored[code]
+			if !ok || decodeInto(data, &aos) != nil {
+				missing[code] = true
+				continue
+			}
+			level = append(level, curriculumItems(aos.CurriculumStructure)...)
+		}
+	}
+
+	for code := range unitCodes {
+		summary.Unlocks[code] = 0
+	}
+	for code := range unitCodes {
+		if _, stored := prerequisites[code]; !stored {
+			missing[code] = true
+		}
+		summary.PrerequisiteDepth[code] = depthOf(code)
+		for _, prerequisite := range prerequisites[code] {
+			if unitCodes[prerequisite] {
+				summary.Unlocks[prerequisite]++
+			}
+		}
+	}
+
+	summary.Units = sortedCodes(unitCodes)
+	summary.Missing = sortedCodes(missing)
+	return summary
+}
+
+// unitPrerequisites lists the units named in the prerequisites of a unit
+func unitPrerequisites(unitData units.UnitData) []string {
+	codes := map[string]bool{}
+	for _, requisite := range unitData.Requisites {
+		if requisite.RequisiteType != "Prerequisite" {
+			continue
+		}
+		for _, container := range requisite.Containers {
+			collectRequisiteCodes(container, codes)
+		}
+	}
+	return sortedCodes(codes)
+}
+
+// PrecomputeCourseGraphsHandler starts a precompute_course_graphs job for a year
+func PrecomputeCourseGraphsHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	year, ok := yearParam(c)
+	if !ok {
+		return
+	}
+
+	params, _ := json.Marshal(precomputeCourseGraphsParams{Year: year})
+	job, err := dependencies(ctx).jobs.Submit("precompute_course_graphs", params)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, job)
+}
+
+// CourseGraphSummaryHandler returns the precomputed curriculum graph of a course
+func CourseGraphSummaryHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	code := c.Param("code")
+	year, ok := yearParam(c)
+	if !ok {
+		return
+	}
+
+	var summary courseGraphSummary
+	if err := databases.FromContext(ctx).Retrieve(databases.Cache, courseGraphSummaryKey(year, code), &summary); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("no precomputed graph for %s in %s, run one with POST /v1/admin/course_graphs/%s", code, year, year)})
+		return
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"handbook-scraper/utils/databases"
+	"handbook-scraper/utils/log"
+)
+
+const (
+	// crawlQuarantineThreshold is how many scheduled crawls in a row a page must fail before it is quarantined
+	crawlQuarantineThreshold = 3
+	// crawlQuarantineRetry is how long scheduled crawls skip a quarantined page before trying it again
+	crawlQuarantineRetry = 7 * 24 * time.Hour
+)
+
+// ScheduledCrawlItemTypes are the item types crawled by the scheduler
+var ScheduledCrawlItemTypes = []string{"units", "courses", "aos"}
+
+// failingPage is a page which failed the latest scheduled crawls
+type failingPage struct {
+	Failures      int        `json:"failures"` // Scheduled crawls in a row which failed to scrape the page
+	LastError     string     `json:"last_error"`
+	QuarantinedAt *time.Time `json:"quarantined_at,omitempty"`
+}
+
+// crawlHealth tracks the scheduled crawls of an item type, so operators can alert on stale handbook data.
+// It is stored rather than kept in memory, since the crawl runs on whichever replica takes its lock.
+type crawlHealth struct {
+	ItemType            string                  `json:"item_type"`
+	ConsecutiveFailures int                     `json:"consecutive_failures"`
+	LastRun             *time.Time              `json:"last_run,omitempty"`
+	LastSuccess         *time.Time              `json:"last_success,omitempty"`
+	Pages               map[string]*failingPage `json:"pages"` // Failing pages by URL
+}
+
+// crawlHealthKey is the cache key of the crawl health of an item type
+func crawlHealthKey(itemType string) string {
+	return "crawl_health:" + itemType
+}
+
+// loadCrawlHealth returns the crawl health of an item type, or an empty one if no scheduled crawl has run
+func loadCrawlHealth(ctx context.Context, itemType string) *crawlHealth {
+	health := &crawlHealth{ItemType: itemType}
+	if err := databases.FromContext(ctx).Retrieve(databases.Cache, crawlHealthKey(itemType), health); err != nil {
+		health = &crawlHealth{ItemType: itemType}
+	}
+	if health.Pages == nil {
+		health.Pages = map[string]*failingPage{}
+	}
+	return health
+}
+
+// save stores the crawl health in the storage of a context, without expiry
+func (h *crawlHealth) save(ctx context.Context) {
+	if err := databases.FromContext(ctx).Store(databases.Cache, crawlHealthKey(h.ItemType), h, 0); err != nil {
+		log.Errorf("[CRAWL] Error saving the crawl health of %s: %v", h.ItemType, err)
+	}
+}
+
+// skip reports whether a page is quarantined and not yet due to be tried again
+func (h *crawlHealth) skip(pageURL string) bool {
+	page, ok := h.Pages[pageURL]
+	return ok && page.QuarantinedAt != nil && time.Since(*page.QuarantinedAt) < crawlQuarantineRetry
+}
+
+// quarantined returns how many pages are quarantined
+func (h *crawlHealth) quarantined() int {
+	count := 0
+	for _, page := range h.Pages {
+		if page.QuarantinedAt != nil {
+			count++
+		}
+	}
+	return count
+}
+
+// record updates the crawl health after a scheduled crawl of the attempted pages.
+// A crawl fails if it stops early or fails every page it attempts.
+func (h *crawlHealth) record(attempted []string, result crawlYearResult, err error) {
+	now := time.Now()
+	h.LastRun = &now
+
+	if err != nil || (len(attempted) > 0 && len(result.Failed) >= len(attempted)) {
+		h.ConsecutiveFailures++
+	} else {
+		h.ConsecutiveFailures = 0
+		h.LastSuccess = &now
+	}
+
+	// Pages are only judged by crawls which attempted every one of them
+	if err != nil {
+		return
+	}
+	for _, pageURL := range attempted {
+		pageErr, failed := result.Failed[pageURL]
+		if !failed {
+			delete(h.Pages, pageURL)
+			continue
+		}
+
+		page, ok := h.Pages[pageURL]
+		if !ok {
+			page = &failingPage{}
+			h.Pages[pageURL] = page
+		}
+		page.Failures++
+		page.LastError = pageErr
+		if page.Failures >= crawlQuarantineThreshold {
+			if page.QuarantinedAt == nil {
+				log.Warnf("[CRAWL] Quarantined %s after %d failed crawls: %s", pageURL, page.Failures, pageErr)
+			}
+			page.QuarantinedAt = &now
+		}
+	}
+}
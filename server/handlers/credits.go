@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gocolly/colly/v2"
+	"handbook-scraper/planner"
+	"handbook-scraper/scrapers/common"
+	"handbook-scraper/scrapers/courses"
+)
+
+// creditsRequest is the request body for the credit point calculator
+type creditsRequest struct {
+	Completed       []common.Unit `json:"completed"`
+	Planned         []common.Unit `json:"planned"`
+	Specialisations []string      `json:"specialisations"` // Chosen majors, minors or extended majors listed by the course
+}
+
+// CourseCreditsHandler attributes completed and planned units to the parts of a course and returns the credit
+// points of each part and category, a lighter alternative to an audit for progress bars
+func CourseCreditsHandler(c *gin.Context, collector *colly.Collector) {
+	code := c.Param("code")
+
+	year, ok := yearParam(c)
+	if !ok {
+		return
+	}
+
+	var req creditsRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON format for credits request"})
+		return
+	}
+
+	data, err := ScrapeAndCache(c.Request.Context(), handbookURL(year, "courses", code), collector, "courses")
+	if err != nil {
+		respondWithScrapeError(c, err)
+		return
+	}
+
+	var courseData courses.CourseData
+	if err := decodeInto(data, &courseData); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to decode course data"})
+		return
+	}
+	if courseData.CurriculumError {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "the curriculum of this course could not be parsed"})
+		return
+	}
+
+	_, chosen, ok := resolveSpecialisations(c, year, collector, courseData.CurriculumStructure, req.Specialisations)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, planner.CountCredits(courseData.Code, courseData.Title, courseData.CurriculumStructure, chosen, req.Completed, req.Planned))
+}
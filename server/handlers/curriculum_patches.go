@@ -0,0 +1,318 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"handbook-scraper/scrapers/common"
+	"handbook-scraper/utils/databases"
+	"handbook-scraper/utils/log"
+)
+
+// patchRefresh is how often the curriculum patches are reloaded, so changes made by other instances are picked up
+const patchRefresh = 5 * time.Minute
+
+// curriculumPatch corrects a part or container of the parsed curriculum of a course or area of study.
+// Patches are kept apart from the scraped documents and applied when they are read, so they survive re-scrapes.
+// Deleted patches are kept, with who deleted them, so every correction can be audited.
+type curriculumPatch struct {
+	ID        string                   `json:"id"`
+	Type      string                   `json:"type"` // courses or aos
+	Code      string                   `json:"code"`
+	Tenant    string                   `json:"tenant,omitempty"` // Tenant the patch applies to, or every tenant if empty
+	Target    string                   `json:"target"`           // ID of the part or container to change
+	Years     []string                 `json:"years,omitempty"`  // Handbook years the patch applies to, or every year if empty
+	Changes   common.CurriculumChanges `json:"changes"`
+	Reason    string                   `json:"reason"`
+	Author    string                   `json:"author"`
+	CreatedAt time.Time                `json:"created_at"`
+	DeletedBy string                   `json:"deleted_by,omitempty"`
+	DeletedAt *time.Time               `json:"deleted_at,omitempty"`
+}
+
+// appliedPatch is the provenance of a patch applied to a document
+type appliedPatch struct {
+	ID        string    `json:"id"`
+	Tenant    string    `json:"tenant,omitempty"`
+	Target    string    `json:"target"`
+	Reason    string    `json:"reason"`
+	Author    string    `json:"author"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// activePatches holds the patches which have not been deleted, keyed by type and code
+type activePatches struct {
+	sync.Mutex
+	patches  map[string][]curriculumPatch
+	loadedAt time.Time
+}
+
+// patchKey is the storage key of a patch, grouped by the item it patches
+func patchKey(urlKey string, code string, id string) string {
+	return urlKey + "/" + code + "/" + id
+}
+
+// itemPatches returns the patches of a course or area of study which have not been deleted, oldest first
+func itemPatches(ctx context.Context, urlKey string, code string) []curriculumPatch {
+	activePatches := &dependencies(ctx).patches
+	activePatches.Lock()
+	defer activePatches.Unlock()
+
+	if activePatches.patches == nil || time.Since(activePatches.loadedAt) > patchRefresh {
+		records, err := loadCurriculumPatches(ctx)
+		if err != nil {
+			log.Errorf("[PATCHES] Failed to load curriculum patches: %v", err)
+			return activePatches.patches[urlKey+"/"+code]
+		}
+		patches := map[string][]curriculumPatch{}
+		for _, record := range records {
+			if record.DeletedAt == nil {
+				patches[record.Type+"/"+record.Code] = append(patches[record.Type+"/"+record.Code], record)
+			}
+		}
+		activePatches.patches, activePatches.loadedAt = patches, time.Now()
+	}
+	return activePatches.patches[urlKey+"/"+code]
+}
+
+// invalidateCurriculumPatches reloads the curriculum patches on the next lookup
+func invalidateCurriculumPatches(ctx context.Context) {
+	activePatches := &dependencies(ctx).patches
+	activePatches.Lock()
+	activePatches.patches = nil
+	activePatches.Unlock()
+}
+
+// loadCurriculumPatches loads every curriculum patch, including deleted ones, oldest first
+func loadCurriculumPatches(ctx context.Context) ([]curriculumPatch, error) {
+	dbHandler := databases.FromContext(ctx)
+	keys, err := dbHandler.ListKeys(databases.Patch, "^")
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]curriculumPatch, 0, len(keys))
+	for _, key := range keys {
+		var record curriculumPatch
+		if err := dbHandler.Retrieve(databases.Patch, key, &record); err != nil {
+			log.Errorf("[PATCHES] Error retrieving %s: %v", key, err)
+			continue
+		}
+		records = append(records, record)
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].CreatedAt.Before(records[j].CreatedAt) })
+	return records, nil
+}
+
+// withCurriculumPatches applies the patches of a course or area of study to its document, and lists the patches
+// applied under curriculum_patches. Patches of every tenant are applied before those of the tenant of ctx, so a tenant
+// can override them. Documents without patches, or whose curriculum could not be parsed, are returned as is.
+func withCurriculumPatches(ctx context.Context, baseURL string, urlKey string, data interface{}) interface{} {
+	if urlKey != "courses" && urlKey != "aos" {
+		return data
+	}
+	parts := strings.Split(strings.TrimPrefix(baseURL, "https://handbook.monash.edu/"), "/")
+	if len(parts) != 3 {
+		return data
+	}
+	year, code := parts[0], strings.ToUpper(parts[2])
+
+	var patches, tenantPatches []curriculumPatch
+	tenant := requestTenant(ctx)
+	for _, patch := range itemPatches(ctx, urlKey, code) {
+		if len(patch.Years) > 0 && !slices.Contains(patch.Years, year) {
+			continue
+		}
+		switch patch.Tenant {
+		case "":
+			patches = append(patches, patch)
+		case tenant:
+			tenantPatches = append(tenantPatches, patch)
+		}
+	}
+	patches = append(patches, tenantPatches...)
+	if len(patches) == 0 {
+		return data
+	}
+
+	// The document is copied, as stored documents may be shared with the in-memory cache
+	var doc map[string]interface{}
+	if err := decodeInto(data, &doc); err != nil || doc == nil {
+		return data
+	}
+	if curriculumError, _ := doc["curriculum_error"].(bool); curriculumError {
+		return data
+	}
+	var curriculum common.Curriculum
+	if err := decodeInto(doc["curriculum_structure"], &curriculum); err != nil {
+		log.Errorf("[PATCHES] Failed to decode the curriculum of %s: %v", baseURL, err)
+		return data
+	}
+
+	curriculum.AssignIDs()
+	applied := []appliedPatch{}
+	for _, patch := range patches {
+		if !curriculum.ApplyChanges(patch.Target, patch.Changes) {
+			log.Warnf("[PATCHES] Patch %s targets %s, which %s no longer has", patch.ID, patch.Target, baseURL)
+			continue
+		}
+		applied = append(applied, appliedPatch{
+			ID:        patch.ID,
+			Tenant:    patch.Tenant,
+			Target:    patch.Target,
+			Reason:    patch.Reason,
+			Author:    patch.Author,
+			CreatedAt: patch.CreatedAt,
+		})
+	}
+	if len(applied) == 0 {
+		return data
+	}
+
+	doc["curriculum_structure"] = curriculum
+	doc["curriculum_patches"] = applied
+	return doc
+}
+
+// newPatchID generates a random patch ID
+func newPatchID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// ListCurriculumPatchesHandler lists every curriculum patch, including deleted ones, oldest first
+func ListCurriculumPatchesHandler(c *gin.Context) {
+	records, err := loadCurriculumPatches(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	respondWithPage(c, records, listFields{Filter: []string{"type", "code", "tenant", "target", "years", "author"}, Sort: []string{"code", "created_at"}})
+}
+
+// CreateCurriculumPatchHandler attaches a patch to a part or container of the curriculum of a course or area of study,
+// for every tenant or for the tenant query parameter
+func CreateCurriculumPatchHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	urlKey := c.Param("type")
+	code := strings.ToUpper(c.Param("code"))
+	if urlKey != "courses" && urlKey != "aos" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "only courses and aos have curricula"})
+		return
+	}
+	if !ValidCode(urlKey, code) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("malformed %s code: %s", urlKey, c.Param("code"))})
+		return
+	}
+
+	var req struct {
+		Target  string                   `json:"target"`
+		Years   []string                 `json:"years"`
+		Changes common.CurriculumChanges `json:"changes"`
+		Reason  string                   `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON format for curriculum patch"})
+		return
+	}
+	if req.Target == "" || strings.TrimSpace(req.Reason) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "target and reason are required"})
+		return
+	}
+	if connector := req.Changes.Connector; connector != nil && !strings.EqualFold(*connector, "AND") && !strings.EqualFold(*connector, "OR") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "connector must be AND or OR"})
+		return
+	}
+	for i, year := range req.Years {
+		resolved, err := resolveYear(ctx, year)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		req.Years[i] = resolved
+	}
+
+	// The target is checked against the stored curriculum when there is one, without scraping it
+	year, _ := resolveYear(ctx, "current")
+	if len(req.Years) > 0 {
+		year = req.Years[0]
+	}
+	if cached, _, ok := retrieveCached(ctx, handbookURL(year, urlKey, code)); ok {
+		var stored struct {
+			CurriculumStructure common.Curriculum `json:"curriculum_structure"`
+		}
+		if err := decodeInto(cached, &stored); err == nil {
+			stored.CurriculumStructure.AssignIDs()
+			if !stored.CurriculumStructure.FindBlock(req.Target) {
+				c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("the %s curriculum of %s has no part or container %s", year, code, req.Target)})
+				return
+			}
+		}
+	}
+
+	id, err := newPatchID()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	patch := curriculumPatch{
+		ID:        id,
+		Type:      urlKey,
+		Code:      code,
+		Tenant:    c.Query("tenant"),
+		Target:    req.Target,
+		Years:     req.Years,
+		Changes:   req.Changes,
+		Reason:    strings.TrimSpace(req.Reason),
+		Author:    c.GetString(SubjectKey),
+		CreatedAt: time.Now(),
+	}
+	if err := databases.FromContext(ctx).Store(databases.Patch, patchKey(urlKey, code, id), patch, 0); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	invalidateCurriculumPatches(ctx)
+
+	log.Infof("[PATCHES] %s patched %s of %s %s for %s: %s", patch.Author, patch.Target, urlKey, code, tenantName(patch.Tenant), patch.Reason)
+	c.JSON(http.StatusCreated, patch)
+}
+
+// DeleteCurriculumPatchHandler stops applying a curriculum patch, keeping it along with who deleted it
+func DeleteCurriculumPatchHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	key := patchKey(c.Param("type"), strings.ToUpper(c.Param("code")), c.Param("id"))
+
+	dbHandler := databases.FromContext(ctx)
+	var patch curriculumPatch
+	if err := dbHandler.Retrieve(databases.Patch, key, &patch); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no such curriculum patch"})
+		return
+	}
+	if patch.DeletedAt != nil {
+		c.Status(http.StatusNoContent)
+		return
+	}
+
+	now := time.Now()
+	patch.DeletedAt, patch.DeletedBy = &now, c.GetString(SubjectKey)
+	if err := dbHandler.Store(databases.Patch, key, patch, 0); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	invalidateCurriculumPatches(ctx)
+
+	log.Infof("[PATCHES] %s deleted patch %s of %s %s", patch.DeletedBy, patch.ID, patch.Type, patch.Code)
+	c.Status(http.StatusNoContent)
+}
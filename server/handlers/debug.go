@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"net/http"
+	"runtime"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"handbook-scraper/utils/databases"
+)
+
+// startedAt is when the process started, for the uptime in runtime stats
+var startedAt = time.Now()
+
+// RuntimeStatsHandler returns goroutine, heap, and garbage collector statistics of the process,
+// so memory growth during large crawls can be diagnosed without a profiler
+func RuntimeStatsHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	var lastGC time.Time
+	if mem.LastGC != 0 {
+		lastGC = time.Unix(0, int64(mem.LastGC))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"go_version": runtime.Version(),
+		"uptime":     time.Since(startedAt).Round(time.Second).String(),
+		"goroutines": runtime.NumGoroutine(),
+		"cpus":       runtime.NumCPU(),
+		"gomaxprocs": runtime.GOMAXPROCS(0),
+		"heap": gin.H{
+			"alloc_bytes":    mem.HeapAlloc,
+			"in_use_bytes":   mem.HeapInuse,
+			"idle_bytes":     mem.HeapIdle,
+			"released_bytes": mem.HeapReleased,
+			"sys_bytes":      mem.HeapSys,
+			"objects":        mem.HeapObjects,
+		},
+		"memory": gin.H{
+			"sys_bytes":         mem.Sys,
+			"total_alloc_bytes": mem.TotalAlloc,
+			"mallocs":           mem.Mallocs,
+			"frees":             mem.Frees,
+			"stack_in_use":      mem.StackInuse,
+		},
+		"gc": gin.H{
+			"runs":            mem.NumGC,
+			"forced_runs":     mem.NumForcedGC,
+			"last_run":        lastGC,
+			"pause_total":     time.Duration(mem.PauseTotalNs).String(),
+			"last_pause":      time.Duration(mem.PauseNs[(mem.NumGC+255)%256]).String(),
+			"next_heap_bytes": mem.NextGC,
+			"cpu_fraction":    mem.GCCPUFraction,
+		},
+		"handbook_cache": databases.FromContext(ctx).HandbookCacheStats(),
+	})
+}
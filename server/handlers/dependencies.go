@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"github.com/gocolly/colly/v2"
+	"handbook-scraper/server/jobs"
+	"handbook-scraper/utils/databases"
+	"handbook-scraper/utils/fetchlog"
+)
+
+// Dependencies are what the handlers of one server share: its storage, its jobs, and what is cached from its storage.
+// They are carried by the context of the server's requests and jobs, so servers embedded in one process are independent.
+type Dependencies struct {
+	storage  *databases.DatabaseHandler // The shared handler if nil, so reconnections are followed
+	readOnly bool
+	jobs     *jobs.Manager
+
+	equivalences             equivalenceGroups
+	patches                  activePatches
+	pins                     versionPins
+	watched                  watchedUnits
+	retention                retentionStats
+	serviceStatus            serviceStatus
+	enrichInFlight           sync.Map   // URLs being scraped in the background, so they are only scraped once
+	unlocksBuild             sync.Mutex // Held while an unlocks index is built, so it is only built once at a time
+	summaryBuild             sync.Mutex // Held while a summary index is built, so it is only built once at a time
+	refreshingPublishedYears atomic.Bool
+}
+
+type dependenciesKey struct{}
+
+// NewDependencies creates the dependencies of a server storing its data in storage, or the shared handler if it is nil.
+// Crawl jobs scrape with the collector. A read-only server serves stored data only, never fetching from upstream.
+func NewDependencies(storage *databases.DatabaseHandler, collector *colly.Collector, readOnly bool) *Dependencies {
+	deps := &Dependencies{
+		storage:       storage,
+		readOnly:      readOnly,
+		serviceStatus: serviceStatus{status: ServiceOK, reasons: []string{}},
+	}
+	deps.jobs = jobs.NewManager(WithDependencies(context.Background(), deps))
+	registerJobRunners(deps.jobs, collector)
+	return deps
+}
+
+// Jobs returns the manager running the jobs of the server whose dependencies ctx carries
+func Jobs(ctx context.Context) *jobs.Manager {
+	return dependencies(ctx).jobs
+}
+
+// Close cancels the running jobs of the server
+func (d *Dependencies) Close() {
+	d.jobs.Close()
+}
+
+// WithDependencies returns a context whose handlers and jobs use deps,
+// and whose upstream fetches are recorded in the audit trail of its storage
+func WithDependencies(ctx context.Context, deps *Dependencies) context.Context {
+	ctx = context.WithValue(ctx, dependenciesKey{}, deps)
+	if deps.storage != nil {
+		ctx = databases.WithHandler(ctx, deps.storage)
+	}
+	return fetchlog.WithRecorder(ctx, deps.recordFetch)
+}
+
+// recordFetch records an upstream fetch in the audit trail of the storage
+func (d *Dependencies) recordFetch(fetch fetchlog.Fetch) error {
+	if d.storage != nil {
+		return d.storage.RecordFetch(fetch)
+	}
+	return databases.GetDatabaseHandler().RecordFetch(fetch)
+}
+
+// defaultDependencies are used by contexts without dependencies, such as those of the dump command
+var (
+	defaultDependencies     *Dependencies
+	defaultDependenciesOnce sync.Once
+)
+
+// dependencies returns the dependencies of a context
+func dependencies(ctx context.Context) *Dependencies {
+	if deps, ok := ctx.Value(dependenciesKey{}).(*Dependencies); ok {
+		return deps
+	}
+	defaultDependenciesOnce.Do(func() {
+		defaultDependencies = NewDependencies(nil, nil, false)
+	})
+	return defaultDependencies
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gocolly/colly/v2"
+	"handbook-scraper/planner"
+	"handbook-scraper/scrapers/common"
+	"handbook-scraper/scrapers/units"
+)
+
+// electivesRequest is the request body for elective suggestions
+type electivesRequest struct {
+	Completed       []common.Unit `json:"completed"`
+	Pool            string        `json:"pool"`            // Title of the part or container, all elective options if empty
+	TeachingPeriod  string        `json:"teaching_period"` // Defaults to the next semester
+	Specialisations []string      `json:"specialisations"` // Chosen majors, minors or extended majors listed by the curriculum
+	Course          string        `json:"course"`          // Course of the student, defaults to the course electives are suggested from
+}
+
+// ElectiveSuggestionHandler suggests elective units from a course or area of study that the student
+// already qualifies for, listing units offered in the next teaching period first.
+// urlKey could be "courses" or "aos".
+func ElectiveSuggestionHandler(c *gin.Context, collector *colly.Collector, urlKey string) {
+	code := c.Param("code")
+
+	year, ok := yearParam(c)
+	if !ok {
+		return
+	}
+
+	var req electivesRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON format for electives request"})
+		return
+	}
+	if req.TeachingPeriod == "" {
+		req.TeachingPeriod = planner.NextTeachingPeriod(time.Now())
+	}
+	req.TeachingPeriod = strings.ToUpper(req.TeachingPeriod)
+	req.Course = strings.ToUpper(strings.TrimSpace(req.Course))
+	if req.Course == "" && urlKey == "courses" {
+		req.Course = strings.ToUpper(code)
+	}
+
+	data, err := ScrapeAndCache(c.Request.Context(), handbookURL(year, urlKey, code), collector, urlKey)
+	if err != nil {
+		respondWithScrapeError(c, err)
+		return
+	}
+
+	var item struct {
+		CurriculumStructure common.Curriculum `json:"curriculum_structure"`
+		CurriculumError     bool              `json:"curriculum_error"`
+	}
+	if err := decodeInto(data, &item); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to decode curriculum"})
+		return
+	}
+	if item.CurriculumError {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "the curriculum could not be parsed"})
+		return
+	}
+
+	curriculum, _, ok := resolveSpecialisations(c, year, collector, item.CurriculumStructure, req.Specialisations)
+	if !ok {
+		return
+	}
+
+	var pool []common.AcademicItem
+	if req.Pool == "" {
+		pool = planner.ElectiveOptions(curriculum, req.Completed)
+	} else {
+		var found bool
+		pool, found = planner.FindPool(curriculum, req.Pool)
+		if !found {
+			c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("no part or container with the ID or title %q", req.Pool)})
+			return
+		}
+	}
+
+	lookup := func(unitCode string) (units.UnitData, error) {
+		return fetchUnit(c.Request.Context(), year, unitCode, collector)
+	}
+	suggestions := planner.SuggestElectives(pool, req.Completed, req.TeachingPeriod, req.Course, lookup, unitEquivalents(c.Request.Context()))
+
+	c.JSON(http.StatusOK, gin.H{"teaching_period": req.TeachingPeriod, "suggestions": suggestions})
+}
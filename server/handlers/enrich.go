@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"context"
+
+	"github.com/gocolly/colly/v2"
+	"handbook-scraper/scrapers/units"
+	"handbook-scraper/utils/log"
+)
+
+// enrichRequisites fills in the titles and credit points of a unit's requisite units from stored data.
+// Requisite units which are not stored yet are scraped in the background, so they are enriched on a later request.
+func enrichRequisites(ctx context.Context, year string, data interface{}, collector *colly.Collector) (interface{}, error) {
+	var unitData units.UnitData
+	if err := decodeInto(data, &unitData); err != nil {
+		return nil, err
+	}
+
+	codes := map[string]bool{}
+	for _, requisite := range unitData.Requisites {
+		for _, container := range requisite.Containers {
+			collectRequisiteCodes(container, codes)
+		}
+	}
+	stored := retrieveStoredMany(ctx, year, "units", sortedCodes(codes))
+
+	missing := units.EnrichRequisites(unitData.Requisites, func(code string) (units.UnitSummary, bool) {
+		var requisite units.UnitData
+		data, ok := stored[code]
+		if !ok || decodeInto(data, &requisite) != nil || requisite.Code == "" {
+			return units.UnitSummary{}, false
+		}
+		return units.UnitSummary{Title: requisite.Title, CreditPoints: requisite.CreditPoints}, true
+	})
+
+	inFlight := &dependencies(ctx).enrichInFlight
+	var toScrape []string
+	for _, code := range missing {
+		baseURL := handbookURL(year, "units", code)
+		if _, loaded := inFlight.LoadOrStore(baseURL, true); !loaded {
+			toScrape = append(toScrape, baseURL)
+		}
+	}
+
+	// Scraped one at a time, as the collector is shared with incoming requests
+	if len(toScrape) > 0 {
+		go func() {
+			for _, baseURL := range toScrape {
+				if _, err := ScrapeAndCache(context.WithoutCancel(ctx), baseURL, collector, "units"); err != nil {
+					log.Errorf("[ENRICH] %s: %v", baseURL, err)
+				}
+				inFlight.Delete(baseURL)
+			}
+		}()
+	}
+
+	return unitData, nil
+}
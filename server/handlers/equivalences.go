@@ -0,0 +1,233 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"handbook-scraper/scrapers/units"
+	"handbook-scraper/utils/databases"
+	"handbook-scraper/utils/log"
+)
+
+// equivalenceRefresh is how often the equivalence groups are reloaded, so changes made by other instances are picked up
+const equivalenceRefresh = 5 * time.Minute
+
+// equivalenceRecord declares the units equivalent to a unit across code changes.
+// Records seeded from the handbook's "replaces" and "replaced by" notes are updated as units are scraped,
+// while records set by an admin override them and are never changed by scraping.
+type equivalenceRecord struct {
+	Code        string    `json:"code"`
+	Equivalents []string  `json:"equivalents"`
+	Source      string    `json:"source"` // handbook or admin
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// equivalenceGroups holds every unit with equivalents, mapped to the other units of its group
+type equivalenceGroups struct {
+	sync.Mutex
+	groups   map[string][]string
+	loadedAt time.Time
+}
+
+// unitEquivalents returns how the units equivalent to a unit, directly or through other equivalent units,
+// are looked up in the storage of a context
+func unitEquivalents(ctx context.Context) units.Equivalents {
+	equivalences := &dependencies(ctx).equivalences
+	return func(code string) []string {
+		equivalences.Lock()
+		defer equivalences.Unlock()
+
+		if equivalences.groups == nil || time.Since(equivalences.loadedAt) > equivalenceRefresh {
+			groups, err := loadEquivalenceGroups(ctx)
+			if err != nil {
+				log.Errorf("[EQUIVALENCES] Failed to load equivalences: %v", err)
+				// Try again on the next lookup rather than waiting for the next refresh
+				return equivalences.groups[code]
+			}
+			equivalences.groups, equivalences.loadedAt = groups, time.Now()
+		}
+		return equivalences.groups[code]
+	}
+}
+
+// invalidateEquivalences reloads the equivalence groups on the next lookup
+func invalidateEquivalences(ctx context.Context) {
+	equivalences := &dependencies(ctx).equivalences
+	equivalences.Lock()
+	equivalences.groups = nil
+	equivalences.Unlock()
+}
+
+// loadEquivalenceRecords loads every equivalence record in code order
+func loadEquivalenceRecords(ctx context.Context) ([]equivalenceRecord, error) {
+	dbHandler := databases.FromContext(ctx)
+	keys, err := dbHandler.ListKeys(databases.Equivalence, "^")
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]equivalenceRecord, 0, len(keys))
+	for _, key := range keys {
+		var record equivalenceRecord
+		if err := dbHandler.Retrieve(databases.Equivalence, key, &record); err != nil {
+			log.Errorf("[EQUIVALENCES] Error retrieving %s: %v", key, err)
+			continue
+		}
+		records = append(records, record)
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].Code < records[j].Code })
+	return records, nil
+}
+
+// loadEquivalenceGroups joins the equivalence records into groups of units which are all equivalent to each other.
+// Handbook hints involving a unit with an admin record are left out, so an admin can remove a wrong hint.
+func loadEquivalenceGroups(ctx context.Context) (map[string][]string, error) {
+	records, err := loadEquivalenceRecords(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	overridden := map[string]bool{}
+	for _, record := range records {
+		if record.Source == "admin" {
+			overridden[record.Code] = true
+		}
+	}
+
+	parent := map[string]string{}
+	var find func(code string) string
+	find = func(code string) string {
+		if _, ok := parent[code]; !ok {
+			parent[code] = code
+		}
+		if parent[code] != code {
+			parent[code] = find(parent[code])
+		}
+		return parent[code]
+	}
+	for _, record := range records {
+		for _, equivalent := range record.Equivalents {
+			if record.Source != "admin" && overridden[equivalent] {
+				continue
+			}
+			parent[find(record.Code)] = find(equivalent)
+		}
+	}
+
+	members := map[string][]string{}
+	for code := range parent {
+		root := find(code)
+		members[root] = append(members[root], code)
+	}
+	groups := map[string][]string{}
+	for _, group := range members {
+		sort.Strings(group)
+		for _, code := range group {
+			groups[code] = slices.DeleteFunc(slices.Clone(group), func(other string) bool { return other == code })
+		}
+	}
+	return groups, nil
+}
+
+// seedEquivalences records units the handbook says are equivalent to a unit, unless an admin has set its equivalents
+func seedEquivalences(ctx context.Context, code string, hints []string) {
+	if len(hints) == 0 {
+		return
+	}
+
+	dbHandler := databases.FromContext(ctx)
+	var record equivalenceRecord
+	if err := dbHandler.Retrieve(databases.Equivalence, code, &record); err != nil {
+		record = equivalenceRecord{Code: code, Source: "handbook"}
+	}
+	if record.Source == "admin" {
+		return
+	}
+
+	changed := false
+	for _, hint := range hints {
+		if hint != code && !slices.Contains(record.Equivalents, hint) {
+			record.Equivalents = append(record.Equivalents, hint)
+			changed = true
+		}
+	}
+	if !changed {
+		return
+	}
+
+	sort.Strings(record.Equivalents)
+	record.UpdatedAt = time.Now()
+	if err := dbHandler.Store(databases.Equivalence, code, record, 0); err != nil {
+		log.Errorf("[EQUIVALENCES] Error saving the equivalents of %s: %v", code, err)
+		return
+	}
+	log.Infof("[EQUIVALENCES] %s is equivalent to %s", code, strings.Join(record.Equivalents, ", "))
+	invalidateEquivalences(ctx)
+}
+
+// seedUnitEquivalences records the units a scraped unit replaces or is replaced by
+func seedUnitEquivalences(ctx context.Context, unitData units.UnitData) {
+	seedEquivalences(ctx, unitData.Code, append(slices.Clone(unitData.Replaces), unitData.ReplacedBy...))
+}
+
+// ListEquivalencesHandler lists every unit equivalence record, in code order
+func ListEquivalencesHandler(c *gin.Context) {
+	records, err := loadEquivalenceRecords(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	respondWithPage(c, records, listFields{Filter: []string{"code", "equivalents", "source"}, Sort: []string{"code", "updated_at"}})
+}
+
+// SetEquivalencesHandler overrides the equivalents of a unit. An empty list declares it has none,
+// which also removes the handbook hints naming it.
+func SetEquivalencesHandler(c *gin.Context) {
+	code := c.Param("code")
+
+	var req struct {
+		Equivalents []string `json:"equivalents"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || req.Equivalents == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "equivalents is required"})
+		return
+	}
+
+	record := equivalenceRecord{Code: code, Equivalents: []string{}, Source: "admin", UpdatedAt: time.Now()}
+	for _, equivalent := range uniqueCodes(req.Equivalents) {
+		if !ValidCode("units", equivalent) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("malformed code: %s", equivalent)})
+			return
+		}
+		if equivalent != code {
+			record.Equivalents = append(record.Equivalents, equivalent)
+		}
+	}
+	sort.Strings(record.Equivalents)
+
+	if err := databases.FromContext(c.Request.Context()).Store(databases.Equivalence, code, record, 0); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	invalidateEquivalences(c.Request.Context())
+
+	log.Infof("[EQUIVALENCES] Set the equivalents of %s to %v", code, record.Equivalents)
+	c.JSON(http.StatusOK, record)
+}
+
+// DeleteEquivalencesHandler removes the equivalence record of a unit, so it is seeded from the handbook again
+func DeleteEquivalencesHandler(c *gin.Context) {
+	if err := databases.FromContext(c.Request.Context()).Delete(databases.Equivalence, c.Param("code")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	invalidateEquivalences(c.Request.Context())
+	c.Status(http.StatusNoContent)
+}
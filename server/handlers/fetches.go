@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"handbook-scraper/utils/databases"
+	"handbook-scraper/utils/log"
+)
+
+// maxFetchHistory is how many of the most recent fetches can be paged through
+const maxFetchHistory = 1000
+
+// fetchHistoryFields are the queryable fields of the fetch history
+var fetchHistoryFields = listFields{
+	Filter: []string{"request_id", "status", "url", "method"},
+	Sort:   []string{"fetched_at", "duration_ms", "bytes", "status"},
+}
+
+// FetchHistoryHandler returns the most recent upstream fetches, newest first.
+// Filters on request_id, status, and url are given to MongoDB as typed values, as filter[field] or the older field
+// query parameters, matching as the shared query language does: any of several comma-separated values, with the
+// request ID and URL containing a value ignoring case. A status of 0 matches fetches which received no response.
+func FetchHistoryHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	query, ok := parseListQuery(c, fetchHistoryFields)
+	if !ok {
+		return
+	}
+	params := func(field string) []string {
+		if values, ok := query.values(field); ok {
+			return values
+		}
+		var values []string
+		for _, value := range strings.Split(c.Query(field), ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				values = append(values, value)
+			}
+		}
+		return values
+	}
+
+	filter := bson.M{}
+	for _, field := range []string{"request_id", "url"} {
+		if values := params(field); len(values) > 0 {
+			filter[field] = bson.M{"$in": containsPatterns(values)}
+		}
+	}
+	if values := params("status"); len(values) > 0 {
+		statuses := make([]int, 0, len(values))
+		for _, raw := range values {
+			status, err := strconv.Atoi(raw)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "status must be a number"})
+				return
+			}
+			statuses = append(statuses, status)
+		}
+		filter["status"] = bson.M{"$in": statuses}
+	}
+
+	fetches, err := databases.FromContext(ctx).RecentFetches(filter, maxFetchHistory)
+	if err != nil {
+		log.Errorf("[FETCH LOG] Failed to read recent fetches: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	respondWithPage(c, fetches, fetchHistoryFields)
+}
+
+// containsPatterns are the case-insensitive patterns of text containing any of values, with the values matched literally
+func containsPatterns(values []string) []primitive.Regex {
+	patterns := make([]primitive.Regex, 0, len(values))
+	for _, value := range values {
+		patterns = append(patterns, primitive.Regex{Pattern: regexp.QuoteMeta(value), Options: "i"})
+	}
+	return patterns
+}
@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"handbook-scraper/utils"
+)
+
+// respondWithFields responds with the data, keeping only the fields selected by the fields query parameter,
+// e.g. fields=common(code,title),assessments(assessment_name,weight)
+func respondWithFields(c *gin.Context, data interface{}) {
+	fields := c.Query("fields")
+	if fields == "" {
+		c.JSON(http.StatusOK, data)
+		return
+	}
+
+	mask, err := utils.ParseFieldMask(fields)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	masked, err := mask.Apply(data)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, masked)
+}
@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gocolly/colly/v2"
+	"handbook-scraper/scrapers/common"
+	"handbook-scraper/scrapers/units"
+	"handbook-scraper/utils"
+	"handbook-scraper/utils/log"
+)
+
+const (
+	defaultGraphDepth = 2
+	maxGraphDepth     = 4
+)
+
+// graphItem is the part of a handbook document needed to add it to a graph
+type graphItem struct {
+	common.CommonScraperData `json:"common"`
+	CurriculumStructure      common.Curriculum `json:"curriculum_structure"`
+}
+
+// UnitGraphHandler returns the prerequisite graph of a unit, following requisites up to the requested depth
+func UnitGraphHandler(c *gin.Context, collector *colly.Collector) {
+	code := c.Param("code")
+
+	year, ok := yearParam(c)
+	if !ok {
+		return
+	}
+
+	depth := defaultGraphDepth
+	if depthParam := c.Query("depth"); depthParam != "" {
+		parsed, err := strconv.Atoi(depthParam)
+		if err != nil || parsed < 1 || parsed > maxGraphDepth {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("depth must be between 1 and %d", maxGraphDepth)})
+			return
+		}
+		depth = parsed
+	}
+
+	graph := utils.Graph{Nodes: []utils.GraphNode{}, Edges: []utils.GraphEdge{}}
+	expanded := map[string]bool{}
+	frontier := []string{code}
+	for level := 0; level <= depth && len(frontier) > 0; level++ {
+		var next []string
+		for _, unitCode := range frontier {
+			if expanded[unitCode] {
+				continue
+			}
+			expanded[unitCode] = true
+
+			data, err := ScrapeAndCache(c.Request.Context(), handbookURL(year, "units", unitCode), collector, "units")
+			if err != nil {
+				if unitCode == code {
+					respondWithScrapeError(c, err)
+					return
+				}
+				log.Errorf("[GRAPH] Error fetching %s: %v", unitCode, err)
+				graph.AddNode(utils.GraphNode{ID: unitCode, Label: unitCode, Type: "units"})
+				continue
+			}
+
+			var unitData units.UnitData
+			if err := decodeInto(data, &unitData); err != nil {
+				log.Errorf("[GRAPH] Error decoding %s: %v", unitCode, err)
+				continue
+			}
+			graph.AddNode(utils.GraphNode{ID: unitCode, Label: unitData.Title, Type: "units"})
+
+			// Requisites of the deepest level are added as nodes, but not followed
+			if level == depth {
+				continue
+			}
+			for _, requisite := range unitData.Requisites {
+				seen := map[string]bool{}
+				for _, container := range requisite.Containers {
+					collectRequisiteCodes(container, seen)
+				}
+				for _, required := range sortedCodes(seen) {
+					graph.AddEdge(utils.GraphEdge{Source: unitCode, Target: required, Relationship: strings.ToLower(requisite.RequisiteType)})
+					if !expanded[required] {
+						next = append(next, required)
+					}
+				}
+			}
+		}
+		frontier = next
+	}
+
+	// Units beyond the depth, or which could not be fetched, are labelled with their code
+	for _, edge := range graph.Edges {
+		graph.AddNode(utils.GraphNode{ID: edge.Target, Label: edge.Target, Type: "units"})
+	}
+
+	respondWithGraph(c, graph)
+}
+
+// CurriculumGraphHandler returns the curriculum graph of a course or area of study.
+// The curricula of the areas of study it references are included as well.
+func CurriculumGraphHandler(c *gin.Context, collector *colly.Collector, urlKey string) {
+	ctx := c.Request.Context()
+	code := c.Param("code")
+
+	year, ok := yearParam(c)
+	if !ok {
+		return
+	}
+
+	data, err := ScrapeAndCache(c.Request.Context(), handbookURL(year, urlKey, code), collector, urlKey)
+	if err != nil {
+		respondWithScrapeError(c, err)
+		return
+	}
+
+	var root graphItem
+	if err := decodeInto(data, &root); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("failed to decode %s data: %v", urlKey, err)})
+		return
+	}
+
+	graph := utils.Graph{Nodes: []utils.GraphNode{}, Edges: []utils.GraphEdge{}}
+	graph.AddNode(utils.GraphNode{ID: code, Label: root.Title, Type: urlKey})
+
+	expanded := map[string]bool{code: true}
+	type pending struct {
+		parent string
+		item   graphItem
+	}
+	queue := []pending{{parent: code, item: root}}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		items := curriculumItems(current.item.CurriculumStructure)
+
+		// The stored areas of study of the curriculum are retrieved together, the rest are scraped one at a time
+		var aosCodes []string
+		for _, item := range items {
+			if itemURLKey(item) == "aos" && item.Code != "" && !expanded[item.Code] {
+				aosCodes = append(aosCodes, item.Code)
+			}
+		}
+		stored := retrieveStoredMany(ctx, year, "aos", aosCodes)
+
+		for _, item := range items {
+			itemType := itemURLKey(item)
+			if itemType == "" || item.Code == "" {
+				continue
+			}
+			graph.AddNode(utils.GraphNode{ID: item.Code, Label: item.Title, Type: itemType})
+			graph.AddEdge(utils.GraphEdge{Source: current.parent, Target: item.Code, Relationship: "curriculum"})
+
+			if itemType != "aos" || expanded[item.Code] {
+				continue
+			}
+			expanded[item.Code] = true
+
+			aosData, ok := stored[item.Code]
+			if !ok {
+				aosData, err = ScrapeAndCache(c.Request.Context(), handbookURL(year, "aos", item.Code), collector, "aos")
+				if err != nil {
+					log.Errorf("[GRAPH] Error fetching %s: %v", item.Code, err)
+					continue
+				}
+			}
+			var aos graphItem
+			if err := decodeInto(aosData, &aos); err == nil {
+				queue = append(queue, pending{parent: item.Code, item: aos})
+			}
+		}
+	}
+
+	respondWithGraph(c, graph)
+}
+
+// respondWithGraph writes a graph in the format requested by the format query parameter:
+// json (default), graphml, dot, or cyjson
+func respondWithGraph(c *gin.Context, graph utils.Graph) {
+	switch c.DefaultQuery("format", "json") {
+	case "json":
+		c.JSON(http.StatusOK, graph)
+	case "cyjson":
+		c.JSON(http.StatusOK, graph.CytoscapeJSON())
+	case "dot":
+		c.Data(http.StatusOK, "text/vnd.graphviz; charset=utf-8", []byte(graph.DOT()))
+	case "graphml":
+		graphML, err := graph.GraphML()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.Data(http.StatusOK, "application/graphml+xml; charset=utf-8", []byte(graphML))
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be one of json, graphml, dot, or cyjson"})
+	}
+}
+
+// collectRequisiteCodes adds the code of every unit in a requisite container to codes
+func collectRequisiteCodes(container units.CompressedContainer, codes map[string]bool) {
+	for _, unit := range container.Units {
+		codes[unit.UnitCode] = true
+	}
+	for _, subContainer := range container.Containers {
+		collectRequisiteCodes(subContainer, codes)
+	}
+}
+
+// sortedCodes returns the codes of a set in sorted order
+func sortedCodes(codes map[string]bool) []string {
+	sorted := make([]string, 0, len(codes))
+	for code := range codes {
+		sorted = append(sorted, code)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
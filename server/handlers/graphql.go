@@ -0,0 +1,191 @@
+package handlers
+
+//go:generate go run github.com/99designs/gqlgen generate --config graph/gqlgen.yml
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/gin-gonic/gin"
+	"github.com/gocolly/colly/v2"
+	"handbook-scraper/scrapers/area_of_study"
+	"handbook-scraper/scrapers/common"
+	"handbook-scraper/scrapers/courses"
+	"handbook-scraper/scrapers/units"
+	"handbook-scraper/server/handlers/graph"
+	"handbook-scraper/utils/log"
+)
+
+// defaultGraphQLComplexity is the most a GraphQL query may cost, where each selected field costs 1
+// and the selection of units costs as much once per code
+const defaultGraphQLComplexity = 5000
+
+// NewGraphQLHandler answers GraphQL queries over units, courses and areas of study, so clients can select only the
+// fields they need. Queries are sent as the body of a POST, or as the query parameter of a GET.
+// The schema is in graph/schema.graphqls.
+func NewGraphQLHandler(collector *colly.Collector) gin.HandlerFunc {
+	config := graph.Config{Resolvers: &graphqlResolver{collector: collector}}
+	config.Complexity.Query.Units = func(childComplexity int, year string, codes []string) int {
+		return len(codes) * childComplexity
+	}
+
+	server := handler.New(graph.NewExecutableSchema(config))
+	server.AddTransport(transport.GET{})
+	server.AddTransport(transport.POST{})
+	server.Use(extension.Introspection{})
+	server.Use(extension.FixedComplexityLimit(envInt("GRAPHQL_COMPLEXITY_LIMIT", defaultGraphQLComplexity, 1)))
+	return func(c *gin.Context) {
+		server.ServeHTTP(c.Writer, c.Request)
+	}
+}
+
+// graphqlResolver resolves the queries of the GraphQL schema, scraping the items which are not stored with collector
+type graphqlResolver struct {
+	collector *colly.Collector
+}
+
+func (r *graphqlResolver) Query() graph.QueryResolver             { return graphqlQuery{r} }
+func (r *graphqlResolver) Unit() graph.UnitResolver               { return graphqlUnit{} }
+func (r *graphqlResolver) Course() graph.CourseResolver           { return graphqlCourse{} }
+func (r *graphqlResolver) AreaOfStudy() graph.AreaOfStudyResolver { return graphqlAreaOfStudy{} }
+func (r *graphqlResolver) HTMLTable() graph.HTMLTableResolver     { return graphqlHTMLTable{} }
+func (r *graphqlResolver) FeeInformation() graph.FeeInformationResolver {
+	return graphqlFeeInformation{}
+}
+
+type graphqlQuery struct{ *graphqlResolver }
+
+func (q graphqlQuery) Unit(ctx context.Context, year string, code string) (*units.UnitData, error) {
+	var unit units.UnitData
+	if err := q.item(ctx, "units", year, code, &unit); err != nil {
+		return nil, err
+	}
+	return &unit, nil
+}
+
+func (q graphqlQuery) Course(ctx context.Context, year string, code string) (*courses.CourseData, error) {
+	var course courses.CourseData
+	if err := q.item(ctx, "courses", year, code, &course); err != nil {
+		return nil, err
+	}
+	return &course, nil
+}
+
+func (q graphqlQuery) AreaOfStudy(ctx context.Context, year string, code string) (*area_of_study.AosData, error) {
+	var aos area_of_study.AosData
+	if err := q.item(ctx, "aos", year, code, &aos); err != nil {
+		return nil, err
+	}
+	return &aos, nil
+}
+
+// Units resolves several units of a year the way the batch endpoint does: stored units are retrieved together and
+// the rest are scraped concurrently, and each code gets its own status, so one bad code never fails the rest
+func (q graphqlQuery) Units(ctx context.Context, year string, codes []string) ([]graph.UnitResult, error) {
+	year, err := resolveYear(ctx, year)
+	if err != nil {
+		return nil, err
+	}
+	codes = uniqueCodes(codes)
+	if len(codes) > maxBatchSize {
+		return nil, fmt.Errorf("at most %d codes can be requested", maxBatchSize)
+	}
+
+	results := fetchBatch(ctx, q.collector, year, "units", codes)
+	unitResults := make([]graph.UnitResult, 0, len(codes))
+	for i := range codes {
+		item := (<-results[i]).item
+		if item.Data != nil {
+			var unit units.UnitData
+			if err := q.decodeUnit(ctx, year, item.Data, &unit); err != nil {
+				log.Errorf("[GRAPHQL] Error decoding unit %s: %v", item.Code, err)
+				item.Status, item.Error = http.StatusBadGateway, err.Error()
+			} else {
+				unitResults = append(unitResults, graph.UnitResult{Code: item.Code, Status: item.Status, Unit: &unit})
+				continue
+			}
+		}
+		unitResults = append(unitResults, graph.UnitResult{Code: item.Code, Status: item.Status, Error: &item.Error})
+	}
+	return unitResults, nil
+}
+
+// item retrieves a handbook item by its year and code into out, scraping it if it is not stored
+func (q graphqlQuery) item(ctx context.Context, urlKey string, year string, code string, out interface{}) error {
+	year, err := resolveYear(ctx, year)
+	if err != nil {
+		return err
+	}
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if !ValidCode(urlKey, code) {
+		return fmt.Errorf("malformed code: %s", code)
+	}
+
+	data, err := ScrapeAndCache(ctx, handbookURL(year, urlKey, code), q.collector, urlKey)
+	if err != nil {
+		log.Infof("[GRAPHQL] Error resolving %s %s: %v", urlKey, code, err)
+		return err
+	}
+	if urlKey == "units" {
+		return q.decodeUnit(ctx, year, data, out)
+	}
+	return decodeInto(data, out)
+}
+
+// decodeUnit decodes the data of a unit into out, with its offering status if it is not offered in the year
+func (q graphqlQuery) decodeUnit(ctx context.Context, year string, data interface{}, out interface{}) error {
+	data, err := withOfferingStatus(ctx, year, data)
+	if err != nil {
+		return err
+	}
+	return decodeInto(data, out)
+}
+
+// The common fields of each item are embedded in its struct, under the common key of its JSON,
+// and maps of strings are returned as JSON objects
+
+type graphqlUnit struct{}
+
+func (graphqlUnit) Common(_ context.Context, unit *units.UnitData) (*common.CommonScraperData, error) {
+	return &unit.CommonScraperData, nil
+}
+
+func (graphqlUnit) Eftsl(_ context.Context, unit *units.UnitData) (float64, error) {
+	return float64(unit.EFTSL), nil
+}
+
+type graphqlCourse struct{}
+
+func (graphqlCourse) Common(_ context.Context, course *courses.CourseData) (*common.CommonScraperData, error) {
+	return &course.CommonScraperData, nil
+}
+
+type graphqlAreaOfStudy struct{}
+
+func (graphqlAreaOfStudy) Common(_ context.Context, aos *area_of_study.AosData) (*common.CommonScraperData, error) {
+	return &aos.CommonScraperData, nil
+}
+
+type graphqlHTMLTable struct{}
+
+func (graphqlHTMLTable) Rows(_ context.Context, table *common.HTMLTable) ([]interface{}, error) {
+	rows := make([]interface{}, len(table.Rows))
+	for i, row := range table.Rows {
+		rows[i] = row
+	}
+	return rows, nil
+}
+
+type graphqlFeeInformation struct{}
+
+func (graphqlFeeInformation) Other(_ context.Context, fees *courses.FeeInformation) (interface{}, error) {
+	if fees.Other == nil {
+		return nil, nil
+	}
+	return fees.Other, nil
+}
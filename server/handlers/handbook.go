@@ -1,88 +1,403 @@
 package handlers
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/gocolly/colly/v2"
+	"go.opentelemetry.io/otel/attribute"
 	"handbook-scraper/scrapers/area_of_study"
 	"handbook-scraper/scrapers/common"
 	"handbook-scraper/scrapers/courses"
 	"handbook-scraper/scrapers/units"
 	"handbook-scraper/utils/databases"
 	"handbook-scraper/utils/log"
+	"handbook-scraper/utils/tracing"
 	"net/http"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
 )
 
 // HandbookHandler is a generic handler for handbook data
 // urlKey could be "courses", "aos", or "units"
 func HandbookHandler(c *gin.Context, collector *colly.Collector, urlKey string) {
-
-	year := c.Param("year")
+	ctx := c.Request.Context()
 	code := c.Param("code")
 
-	if year == "current" {
-		year = fmt.Sprintf("%d", time.Now().Year())
+	year, ok := yearParam(c)
+	if !ok {
+		return
+	}
+
+	view := requisitesCompressed
+	if urlKey == "units" {
+		if view, ok = requisitesView(c); !ok {
+			return
+		}
 	}
 
-	baseURL := fmt.Sprintf("https://handbook.monash.edu/%s/%s/%s", year, urlKey, code)
+	baseURL := handbookURL(year, urlKey, code)
 
 	log.Infof("[START] Scraping %s", baseURL)
 
 	// Call the reusable scraping function
-	final, err := ScrapeAndCache(baseURL, collector, urlKey)
+	final, err := ScrapeAndCache(ctx, baseURL, collector, urlKey)
 
+	if multiple, ok := asMultipleEntries(err); ok {
+		respondWithVariants(c, collector, urlKey, code, multiple)
+		return
+	}
 	if err != nil {
 		log.Errorf("[ERROR] %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondWithScrapeError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, final)
+	if urlKey == "units" {
+		final, err = selectUnitVersion(c, year, final)
+		if errors.Is(err, errVersionNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if urlKey == "units" {
+		final, err = withOfferingStatus(ctx, year, final)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if urlKey == "units" && c.Query("enrich") == "true" {
+		final, err = enrichRequisites(ctx, year, final, collector)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if urlKey == "units" {
+		final, err = withRequisitesView(final, view)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	respondWithFields(c, final)
+}
+
+// respondWithScrapeError responds with the error of a failed scrape.
+// Clients are told when to retry if the handbook is throttling requests or too many pages are being fetched.
+func respondWithScrapeError(c *gin.Context, err error) {
+	var throttled *common.ThrottledError
+	var overloaded *overloadedError
+	var multiple *common.MultipleEntriesError
+	switch {
+	case errors.Is(err, errReadOnly):
+		c.JSON(readOnlyStatus(c), gin.H{"error": err.Error()})
+	case errors.As(err, &multiple):
+		c.JSON(http.StatusMultipleChoices, gin.H{"error": err.Error(), "choices": multiple.Variants})
+	case errors.As(err, &throttled):
+		c.Header("Retry-After", retryAfterSeconds(throttled.RetryAfter))
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	case errors.As(err, &overloaded):
+		c.Header("Retry-After", retryAfterSeconds(overloaded.RetryAfter))
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	case errors.Is(err, common.ErrPageNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// retryAfterSeconds formats a duration as a Retry-After header, in whole seconds rounded up
+func retryAfterSeconds(d time.Duration) string {
+	return strconv.Itoa(int((d + time.Second - 1) / time.Second))
 }
 
 // ScrapeAndCache is a reusable function for scraping and caching data
-func ScrapeAndCache(baseURL string, collector *colly.Collector, urlKey string) (interface{}, error) {
+func ScrapeAndCache(ctx context.Context, baseURL string, collector *colly.Collector, urlKey string) (result interface{}, err error) {
+	ctx, span := tracing.Start(ctx, "ScrapeAndCache", attribute.String("handbook.url", baseURL), attribute.String("handbook.url_key", urlKey))
+	defer func() { tracing.End(span, err) }()
 
-	dbHandler := databases.GetDatabaseHandler()
+	dbHandler := databases.FromContext(ctx)
 
 	// HandbookCache retrieval
-	var cached interface{}
-	err := dbHandler.Retrieve(databases.Handbook, baseURL, &cached)
+	cached, baseURL, ok := retrieveCached(ctx, baseURL)
+	span.SetAttributes(attribute.Bool("handbook.cache_hit", ok))
+	if ok {
+		return withCurriculumPatches(ctx, baseURL, urlKey, cached), nil
+	}
+
+	// Codes with several entries are answered with the entries of their chooser page
+	if err := knownVariants(ctx, baseURL); err != nil {
+		return nil, err
+	}
+
+	// No cache layer holds the page, so it comes from the handbook itself, within the budget of cold scrapes
+	if !ReadOnly(ctx) {
+		release, err := coldScrapes().acquire(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+	}
+	scraped, baseURL, err := scrapeItem(ctx, baseURL, collector, urlKey)
+	if !errors.Is(err, errReadOnly) {
+		dbHandler.RecordHandbookOrigin(err)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Wrap the data and save to cache
+	storeCtx, storeSpan := tracing.Start(ctx, "db.store", attribute.String("db.key", baseURL))
+	storeErr := dbHandler.StoreContext(storeCtx, databases.Handbook, baseURL, scraped, time.Hour*144)
+	tracing.End(storeSpan, storeErr)
+	if storeErr != nil {
+		log.Errorf("Error saving to cache: %v", storeErr)
+	}
+
+	log.Infof("[CACHE SAVE] %s", baseURL)
+
+	log.Successf("[SUCCESS] Finished scraping %s", baseURL)
 
-	if cached != nil {
+	return withCurriculumPatches(ctx, baseURL, urlKey, scraped), nil
+}
+
+// retrieveCached returns the stored document of a handbook URL.
+// Aliases, such as old codes which redirect to a renamed item, resolve to the canonical document,
+// so the URL the document is stored under is returned as well.
+func retrieveCached(ctx context.Context, baseURL string) (interface{}, string, bool) {
+	ctx, span := tracing.Start(ctx, "db.retrieve", attribute.String("db.key", baseURL))
+	defer span.End()
+
+	dbHandler := databases.FromContext(ctx)
+
+	var cached interface{}
+	if err := dbHandler.RetrieveContext(ctx, databases.Handbook, baseURL, &cached); err == nil && cached != nil {
 		log.Successf("[CACHE HIT] Success for %s", baseURL)
-		return cached, nil
+		return cached, baseURL, true
+	}
+
+	var alias aliasRecord
+	if err := dbHandler.RetrieveContext(ctx, databases.Alias, baseURL, &alias); err == nil && alias.Canonical != "" {
+		log.Infof("[ALIAS] %s is %s", baseURL, alias.Canonical)
+		baseURL = alias.Canonical
+		if err := dbHandler.RetrieveContext(ctx, databases.Handbook, baseURL, &cached); err == nil && cached != nil {
+			log.Successf("[CACHE HIT] Success for %s", baseURL)
+			return cached, baseURL, true
+		}
 	}
 
 	log.Infof("[CACHE MISS] %s", baseURL)
+	return nil, baseURL, false
+}
+
+// retrieveStoredMany returns the stored documents of many codes at once, keyed by code.
+// Codes which are not stored, including aliases, are left out, so callers fall back to ScrapeAndCache for them.
+func retrieveStoredMany(ctx context.Context, year string, urlKey string, codes []string) map[string]interface{} {
+	stored := make(map[string]interface{}, len(codes))
+	if len(codes) == 0 {
+		return stored
+	}
 
-	// If cache miss, scrape
-	data, err := common.ExtractRawJSON(baseURL, collector)
+	keys := make([]string, len(codes))
+	for i, code := range codes {
+		keys[i] = handbookURL(year, urlKey, code)
+	}
+	found, err := databases.FromContext(ctx).RetrieveMany(databases.Handbook, keys)
 	if err != nil {
-		return nil, fmt.Errorf("failed to extract JSON: %w", err)
+		log.Errorf("[CACHE] Error retrieving %d %s: %v", len(keys), urlKey, err)
+		return stored
+	}
+
+	for i, code := range codes {
+		raw, ok := found[keys[i]]
+		if !ok {
+			continue
+		}
+		var data interface{}
+		if err := json.Unmarshal(raw, &data); err == nil && data != nil {
+			stored[code] = withCurriculumPatches(ctx, keys[i], urlKey, data)
+		}
+	}
+	return stored
+}
+
+// scrapeItem scrapes a handbook page without storing it.
+// It returns the URL to store the data under, which differs from baseURL if the page redirected.
+func scrapeItem(ctx context.Context, baseURL string, collector *colly.Collector, urlKey string) (interface{}, string, error) {
+	if ReadOnly(ctx) {
+		return nil, baseURL, errReadOnly
+	}
+
+	_, fetchSpan := tracing.Start(ctx, "handbook.fetch", attribute.String("handbook.url", baseURL))
+	data, finalURL, err := common.ExtractPageContent(ctx, baseURL, collector)
+	tracing.End(fetchSpan, err)
+	if err != nil {
+		return nil, baseURL, fmt.Errorf("failed to extract JSON: %w", err)
 	}
 
 	if data == nil {
-		return nil, fmt.Errorf("failed to find JSON data in the HTML")
+		return nil, baseURL, fmt.Errorf("failed to find JSON data in the HTML")
 	}
+	return processPage(ctx, baseURL, finalURL, urlKey, data)
+}
+
+// processPage parses the raw JSON of a fetched handbook page and records what it tells about the item, whichever way
+// the page was fetched. It returns the URL to store the data under, which differs from baseURL if the page redirected
+// to finalURL.
+func processPage(ctx context.Context, baseURL string, finalURL string, urlKey string, data map[string]interface{}) (interface{}, string, error) {
+	// A code with several entries in the year resolves to a chooser page rather than an item
+	if variants, ok := common.ParseVariants(data); ok {
+		log.Infof("[VARIANTS] %s lists %d entries", baseURL, len(variants))
+		rememberVariants(ctx, baseURL, variants)
+		return nil, baseURL, &common.MultipleEntriesError{URL: baseURL, Variants: variants}
+	}
+
+	// Redirected pages are stored under the URL they redirected to, with the requested URL as an alias
+	if canonical := canonicalURL(finalURL); !strings.EqualFold(canonical, baseURL) {
+		if err := databases.FromContext(ctx).Store(databases.Alias, baseURL, aliasRecord{Canonical: canonical, RecordedAt: time.Now()}, 0); err != nil {
+			log.Errorf("Error saving alias: %v", err)
+		}
+		log.Infof("[ALIAS] Recorded %s as an alias of %s", baseURL, canonical)
+		// An old unit code redirecting to a new one is the same unit
+		if urlKey == "units" {
+			seedEquivalences(ctx, strings.ToUpper(path.Base(canonical)), []string{strings.ToUpper(path.Base(baseURL))})
+		}
+		baseURL = canonical
+	}
+
+	storeRaw(ctx, baseURL, urlKey, data)
 
 	// Scrape data based on urlKey
+	_, parseSpan := tracing.Start(ctx, "handbook.parse", attribute.String("handbook.url_key", urlKey))
 	scraped, err := scrapeData(urlKey, data, baseURL)
+	tracing.End(parseSpan, err)
 	if err != nil {
-		return nil, fmt.Errorf("failed to scrape data: %w", err)
+		return nil, baseURL, fmt.Errorf("failed to scrape data: %w", err)
 	}
+	storeVersion(ctx, baseURL, scraped)
+	alertUnitChanges(ctx, baseURL, scraped)
+	if unitData, ok := scraped.(units.UnitData); ok {
+		seedUnitEquivalences(ctx, unitData)
+	}
+	return scraped, baseURL, nil
+}
 
-	// Wrap the data and save to cache
-	if err := dbHandler.Store(databases.Handbook, baseURL, scraped, time.Hour*144); err != nil {
-		log.Errorf("Error saving to cache: %v", err)
+// aliasRecord maps an alternative handbook URL, such as an old code which redirects, to its canonical URL
+type aliasRecord struct {
+	Canonical  string    `json:"canonical"`
+	RecordedAt time.Time `json:"recorded_at"`
+}
+
+// canonicalURL strips the query string and trailing slash of a redirected handbook URL
+func canonicalURL(pageURL string) string {
+	if i := strings.IndexAny(pageURL, "?#"); i != -1 {
+		pageURL = pageURL[:i]
+	}
+	return strings.TrimSuffix(pageURL, "/")
+}
+
+// validatorTTL is how long page validators are kept for differential crawls
+const validatorTTL = 30 * 24 * time.Hour
+
+// scrapeIfChanged re-scrapes a handbook page only if it changed since its validator was last stored.
+// It returns nil data if the page is unchanged, along with the page's current validator.
+// Changed pages are processed as scrapeItem processes them, so the URL to store the data under is returned as well.
+func scrapeIfChanged(ctx context.Context, baseURL string, urlKey string) (interface{}, string, common.PageValidator, error) {
+	if ReadOnly(ctx) {
+		return nil, baseURL, common.PageValidator{}, errReadOnly
 	}
 
-	log.Infof("[CACHE SAVE] %s", baseURL)
+	dbHandler := databases.FromContext(ctx)
 
-	log.Successf("[SUCCESS] Finished scraping %s", baseURL)
+	// Without stored data there is nothing to compare against
+	var previous common.PageValidator
+	if storedUnder(ctx, baseURL) != "" {
+		_ = dbHandler.Retrieve(databases.Cache, validatorKey(baseURL), &previous)
+	}
 
-	return scraped, nil
+	fetchCtx, fetchSpan := tracing.Start(ctx, "handbook.fetch_if_changed", attribute.String("handbook.url", baseURL))
+	data, finalURL, validator, err := common.FetchIfChanged(fetchCtx, baseURL, previous)
+	fetchSpan.SetAttributes(attribute.Bool("handbook.changed", data != nil))
+	tracing.End(fetchSpan, err)
+	if err != nil || data == nil {
+		return nil, baseURL, validator, err
+	}
+
+	scraped, key, err := processPage(ctx, baseURL, finalURL, urlKey, data)
+	if err != nil {
+		return nil, key, validator, err
+	}
+	return scraped, key, validator, nil
+}
+
+// storedUnder returns the key a handbook URL is stored under, which is its canonical URL if it is an alias,
+// or an empty string if it is not stored
+func storedUnder(ctx context.Context, baseURL string) string {
+	dbHandler := databases.FromContext(ctx)
+	if exists, err := dbHandler.Exists(databases.Handbook, baseURL); err == nil && exists {
+		return baseURL
+	}
+	var alias aliasRecord
+	if err := dbHandler.Retrieve(databases.Alias, baseURL, &alias); err == nil && alias.Canonical != "" {
+		if exists, err := dbHandler.Exists(databases.Handbook, alias.Canonical); err == nil && exists {
+			return alias.Canonical
+		}
+	}
+	return ""
+}
+
+// validatorKey is the cache key of a page's validator
+func validatorKey(baseURL string) string {
+	return "validator:" + baseURL
+}
+
+// handbookURL builds the handbook page URL for an academic item
+func handbookURL(year string, urlKey string, code string) string {
+	return fmt.Sprintf("https://handbook.monash.edu/%s/%s/%s", year, urlKey, code)
+}
+
+// fetchUnit scrapes or retrieves the cached handbook data of a unit
+func fetchUnit(ctx context.Context, year string, code string, collector *colly.Collector) (units.UnitData, error) {
+	data, err := ScrapeAndCache(ctx, handbookURL(year, "units", code), collector, "units")
+	if err != nil {
+		return units.UnitData{}, err
+	}
+
+	var unitData units.UnitData
+	if err := decodeInto(data, &unitData); err != nil {
+		return units.UnitData{}, fmt.Errorf("failed to decode unit data: %w", err)
+	}
+	return unitData, nil
+}
+
+// storedItemKeys lists the keys of every stored handbook item of a year and urlKey in the storage of a context
+func storedItemKeys(ctx context.Context, year string, urlKey string) ([]string, error) {
+	return databases.FromContext(ctx).ListKeys(databases.Handbook, "^"+regexp.QuoteMeta(handbookURL(year, urlKey, "")))
+}
+
+// decodeInto converts scraped or cached data into a typed struct.
+// Cached data comes back as generic maps, so it is round-tripped through JSON.
+func decodeInto(data interface{}, out interface{}) error {
+	marshalled, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(marshalled, out)
 }
 
 // scrapeData handles the scraping logic based on the urlKey
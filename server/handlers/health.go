@@ -6,8 +6,13 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// HealthCheckHandler reports that the server is up, with why its service status is not ok, if it is not
 func HealthCheckHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	_, reasons := ServiceStatus(c.Request.Context())
 	c.JSON(http.StatusOK, gin.H{
-		"status": "ok",
+		"status":                 "ok",
+		"read_only":              ReadOnly(ctx),
+		"service_status_reasons": reasons,
 	})
 }
@@ -0,0 +1,633 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gocolly/colly/v2"
+	"handbook-scraper/scrapers/common"
+	"handbook-scraper/scrapers/courses"
+	"handbook-scraper/scrapers/pdf_archive"
+	"handbook-scraper/server/jobs"
+	"handbook-scraper/utils/databases"
+	"handbook-scraper/utils/log"
+)
+
+// crawlLockTTL is how long a crawl lock is held before it must be refreshed
+const crawlLockTTL = time.Minute
+
+// registerJobRunners registers the runners for all supported job types, scraping with the collector.
+// Without a collector, the job types which scrape are not supported.
+func registerJobRunners(manager *jobs.Manager, collector *colly.Collector) {
+	manager.Register("bulk_export", bulkExportJob)
+	manager.Register("analytics_export", analyticsExportJob)
+	manager.Register("import_pdf_archive", importPDFArchiveJob)
+	manager.Register("reparse_year", reparseYearJob)
+	manager.Register("requisite_report", requisiteReportJob)
+	manager.Register("precompute_course_graphs", precomputeCourseGraphsJob)
+	manager.Register("build_unlocks_index", buildUnlocksIndexJob)
+	manager.Register("enforce_retention", enforceRetentionJob)
+	manager.Register("check_consistency", checkConsistencyJob)
+	if collector == nil {
+		return
+	}
+	manager.Register("crawl_year", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		return crawlYearJob(ctx, params, collector)
+	})
+	manager.Register("resolve_course_graph", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		return resolveCourseGraphJob(ctx, params, collector)
+	})
+}
+
+// crawlYearParams are the parameters of a crawl_year job.
+// If Codes is empty, every page of ItemType listed in the handbook sitemap is crawled,
+// or every stored page if StoredOnly is set.
+type crawlYearParams struct {
+	Year         string   `json:"year"`
+	ItemType     string   `json:"item_type"` // "units", "courses", or "aos"
+	Codes        []string `json:"codes"`
+	StoredOnly   bool     `json:"stored_only"`  // Crawl only the pages already stored
+	Differential bool     `json:"differential"` // Re-scrape pages, skipping those unchanged since the last differential crawl
+	Scheduled    bool     `json:"scheduled"`    // Started by the scheduler, so tracked in the crawl health and skipping quarantined pages
+}
+
+// crawlItemTypes are the item types a crawl_year job can crawl
+var crawlItemTypes = []string{"units", "courses", "aos"}
+
+// validate defaults the item type of a crawl and normalises its codes,
+// rejecting item types and codes which are not of handbook items
+func (p *crawlYearParams) validate() error {
+	if p.Year == "" {
+		return fmt.Errorf("year is required")
+	}
+	if p.ItemType == "" {
+		p.ItemType = "units"
+	}
+	if !slices.Contains(crawlItemTypes, p.ItemType) {
+		return fmt.Errorf("item_type must be one of %s", strings.Join(crawlItemTypes, ", "))
+	}
+	for i, code := range p.Codes {
+		code = strings.ToUpper(strings.TrimSpace(code))
+		if !ValidCode(p.ItemType, code) {
+			return fmt.Errorf("malformed %s code: %s", p.ItemType, code)
+		}
+		p.Codes[i] = code
+	}
+	return nil
+}
+
+// checkCrawlYearParams rejects the params of a crawl_year job before it is submitted
+func checkCrawlYearParams(raw json.RawMessage) error {
+	var params crawlYearParams
+	if err := decodeParams(raw, &params); err != nil {
+		return err
+	}
+	return params.validate()
+}
+
+// crawlYearResult summarises a crawl_year job
+type crawlYearResult struct {
+	Scraped   int               `json:"scraped"`
+	Cached    int               `json:"cached"` // Pages already stored, so not fetched
+	Unchanged int               `json:"unchanged"`
+	Failed    map[string]string `json:"failed"`
+}
+
+// crawlYearJob scrapes and caches every requested page for a handbook year.
+// Only one replica crawls a given year and item type at a time.
+func crawlYearJob(ctx context.Context, raw json.RawMessage, collector *colly.Collector) (interface{}, error) {
+	var params crawlYearParams
+	if err := decodeParams(raw, &params); err != nil {
+		return nil, err
+	}
+	if err := params.validate(); err != nil {
+		return nil, err
+	}
+
+	result := crawlYearResult{Failed: map[string]string{}}
+	lockName := fmt.Sprintf("crawl:%s:%s", params.Year, params.ItemType)
+
+	ran, err := databases.FromContext(ctx).RunExclusive(lockName, crawlLockTTL, func(lockCtx context.Context) (err error) {
+		var urls []string
+		var health *crawlHealth
+		if params.Scheduled {
+			health = loadCrawlHealth(ctx, params.ItemType)
+			defer func() {
+				health.record(urls, result, err)
+				health.save(ctx)
+			}()
+		}
+
+		if len(params.Codes) > 0 {
+			for _, code := range params.Codes {
+				urls = append(urls, handbookURL(params.Year, params.ItemType, code))
+			}
+		} else if params.StoredOnly {
+			stored, err := storedItemKeys(ctx, params.Year, params.ItemType)
+			if err != nil {
+				return fmt.Errorf("failed to list stored pages: %w", err)
+			}
+			urls = stored
+		} else {
+			discovered, err := common.HandbookItemURLs(ctx, params.Year, params.ItemType)
+			if err != nil {
+				return fmt.Errorf("failed to discover pages: %w", err)
+			}
+			urls = discovered
+		}
+		if health != nil {
+			urls = slices.DeleteFunc(urls, health.skip)
+		}
+
+		batch := crawlBatch{ctx: ctx, result: &result}
+		defer batch.flush()
+
+		for i := 0; i < len(urls); i++ {
+			pageURL := urls[i]
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if lockCtx.Err() != nil {
+				return fmt.Errorf("lost crawl lock %s", lockName)
+			}
+
+			if params.Differential {
+				scraped, key, validator, err := scrapeIfChanged(ctx, pageURL, params.ItemType)
+				if waitForThrottle(ctx, err) {
+					i--
+					continue
+				}
+				if err != nil {
+					log.Errorf("[CRAWL] %s: %v", pageURL, err)
+					result.Failed[pageURL] = err.Error()
+					continue
+				}
+				batch.addValidator(pageURL, validator)
+				if scraped == nil {
+					log.Infof("[UNCHANGED] %s", pageURL)
+					result.Unchanged++
+					continue
+				}
+				batch.add(key, scraped)
+				continue
+			}
+
+			if _, _, ok := retrieveCached(ctx, pageURL); ok {
+				result.Cached++
+				continue
+			}
+			scraped, key, err := scrapeItem(ctx, pageURL, collector, params.ItemType)
+			if waitForThrottle(ctx, err) {
+				i--
+				continue
+			}
+			if err != nil {
+				log.Errorf("[CRAWL] %s: %v", pageURL, err)
+				result.Failed[pageURL] = err.Error()
+				continue
+			}
+			batch.add(key, scraped)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !ran {
+		return nil, fmt.Errorf("a crawl of %s %s is already running on another replica", params.Year, params.ItemType)
+	}
+
+	return result, nil
+}
+
+// waitForThrottle waits out the pause advised by the handbook if err is because it is throttling requests,
+// reporting whether the page should be retried
+func waitForThrottle(ctx context.Context, err error) bool {
+	var throttled *common.ThrottledError
+	if !errors.As(err, &throttled) {
+		return false
+	}
+
+	log.Warnf("[CRAWL] The handbook is throttling requests, pausing for %s", throttled.RetryAfter)
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(throttled.RetryAfter):
+		return true
+	}
+}
+
+// crawlBatchSize is the number of scraped pages stored together
+const crawlBatchSize = 100
+
+// crawlBatch buffers the pages scraped by a crawl, so they are stored with bulk writes
+type crawlBatch struct {
+	ctx        context.Context
+	result     *crawlYearResult
+	pages      []databases.BulkItem
+	validators []databases.BulkItem
+}
+
+// add buffers a scraped page, storing the buffered pages once the batch is full
+func (b *crawlBatch) add(key string, data interface{}) {
+	b.pages = append(b.pages, databases.BulkItem{Key: key, Data: data})
+	if len(b.pages) >= crawlBatchSize {
+		b.flush()
+	}
+}
+
+// addValidator buffers the validator of a page checked by a differential crawl
+func (b *crawlBatch) addValidator(pageURL string, validator common.PageValidator) {
+	b.validators = append(b.validators, databases.BulkItem{Key: validatorKey(pageURL), Data: validator})
+}
+
+// flush stores the buffered pages and validators
+func (b *crawlBatch) flush() {
+	dbHandler := databases.FromContext(b.ctx)
+
+	if len(b.pages) > 0 {
+		stored, err := dbHandler.BulkStore(databases.Handbook, b.pages, time.Hour*144)
+		if err != nil {
+			stored.Failed = map[string]string{}
+			for _, page := range b.pages {
+				stored.Failed[page.Key] = err.Error()
+			}
+		}
+		for key, failure := range stored.Failed {
+			log.Errorf("[CRAWL] Error saving %s: %s", key, failure)
+			b.result.Failed[key] = failure
+		}
+		b.result.Scraped += stored.Stored
+		log.Infof("[CACHE SAVE] Stored %d pages", stored.Stored)
+		b.pages = nil
+	}
+
+	// Validators of pages which failed to store are dropped, so they are re-scraped next time
+	var validators []databases.BulkItem
+	for _, validator := range b.validators {
+		if _, failed := b.result.Failed[strings.TrimPrefix(validator.Key, validatorKey(""))]; !failed {
+			validators = append(validators, validator)
+		}
+	}
+	if len(validators) > 0 {
+		if _, err := dbHandler.BulkStore(databases.Cache, validators, validatorTTL); err != nil {
+			log.Errorf("Error saving page validators: %v", err)
+		}
+	}
+	b.validators = nil
+}
+
+// resolveCourseGraphParams are the parameters of a resolve_course_graph job
+type resolveCourseGraphParams struct {
+	Year string `json:"year"`
+	Code string `json:"code"`
+}
+
+// courseGraph holds a course along with every unit and area of study referenced by its curriculum
+type courseGraph struct {
+	Course       interface{}            `json:"course"`
+	AreasOfStudy map[string]interface{} `json:"areas_of_study"`
+	Units        map[string]interface{} `json:"units"`
+	Failed       map[string]string      `json:"failed"`
+}
+
+// resolveCourseGraphJob scrapes a course and every academic item reachable from its curriculum
+func resolveCourseGraphJob(ctx context.Context, raw json.RawMessage, collector *colly.Collector) (interface{}, error) {
+	var params resolveCourseGraphParams
+	if err := decodeParams(raw, &params); err != nil {
+		return nil, err
+	}
+	if params.Year == "" || params.Code == "" {
+		return nil, fmt.Errorf("year and code are required")
+	}
+
+	course, err := ScrapeAndCache(ctx, handbookURL(params.Year, "courses", params.Code), collector, "courses")
+	if err != nil {
+		return nil, err
+	}
+
+	var courseData courses.CourseData
+	if err := decodeInto(course, &courseData); err != nil {
+		return nil, fmt.Errorf("failed to decode course data: %w", err)
+	}
+
+	graph := courseGraph{
+		Course:       course,
+		AreasOfStudy: map[string]interface{}{},
+		Units:        map[string]interface{}{},
+		Failed:       map[string]string{},
+	}
+
+	queue := curriculumItems(courseData.CurriculumStructure)
+	for len(queue) > 0 {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		item := queue[0]
+		queue = queue[1:]
+
+		urlKey := itemURLKey(item)
+		if urlKey == "" || item.Code == "" {
+			continue
+		}
+		if _, seen := graph.Units[item.Code]; seen {
+			continue
+		}
+		if _, seen := graph.AreasOfStudy[item.Code]; seen {
+			continue
+		}
+
+		data, err := ScrapeAndCache(ctx, handbookURL(params.Year, urlKey, item.Code), collector, urlKey)
+		if err != nil {
+			graph.Failed[item.Code] = err.Error()
+			continue
+		}
+
+		if urlKey == "units" {
+			graph.Units[item.Code] = data
+			continue
+		}
+
+		// Areas of study have their own curriculum which may reference more units
+		graph.AreasOfStudy[item.Code] = data
+		var aos struct {
+			CurriculumStructure common.Curriculum `json:"curriculum_structure"`
+		}
+		if err := decodeInto(data, &aos); err == nil {
+			queue = append(queue, curriculumItems(aos.CurriculumStructure)...)
+		}
+	}
+
+	return graph, nil
+}
+
+// bulkExportParams are the parameters of a bulk_export job
+type bulkExportParams struct {
+	Year     string `json:"year"`
+	ItemType string `json:"item_type"` // "units", "courses", or "aos"
+}
+
+// bulkExportResult summarises a bulk_export job, whose items are downloaded from GET /v1/jobs/:id/file
+type bulkExportResult struct {
+	File     string   `json:"file"`
+	Exported int      `json:"exported"`
+	Skipped  []string `json:"skipped"`
+}
+
+// bulkExportFile is the name of the bulk export of a year and type in the shared file store
+func bulkExportFile(year string, itemType string) string {
+	return "bulk_export/" + year + "/" + itemType + ".ndjson"
+}
+
+// bulkExportJob exports every stored handbook item of a year and type as NDJSON into the shared file store,
+// reading and writing them in batches so a whole year is never held in memory
+func bulkExportJob(ctx context.Context, raw json.RawMessage) (interface{}, error) {
+	var params bulkExportParams
+	if err := decodeParams(raw, &params); err != nil {
+		return nil, err
+	}
+	if params.Year == "" || params.ItemType == "" {
+		return nil, fmt.Errorf("year and item_type are required")
+	}
+
+	keys, err := storedItemKeys(ctx, params.Year, params.ItemType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stored items: %w", err)
+	}
+	base := handbookURL(params.Year, params.ItemType, "")
+	codes := make([]string, 0, len(keys))
+	for _, key := range keys {
+		codes = append(codes, strings.TrimPrefix(key, base))
+	}
+
+	result := bulkExportResult{File: bulkExportFile(params.Year, params.ItemType), Skipped: []string{}}
+	err = databases.FromContext(ctx).WriteFile(ctx, result.File, func(w io.Writer) error {
+		encoder := json.NewEncoder(w)
+		for start := 0; start < len(codes); start += dumpBatchSize {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			batch := codes[start:min(start+dumpBatchSize, len(codes))]
+			stored := retrieveStoredMany(ctx, params.Year, params.ItemType, batch)
+			for _, code := range batch {
+				item, ok := stored[code]
+				if !ok {
+					log.Errorf("[EXPORT] Error retrieving %s", base+code)
+					result.Skipped = append(result.Skipped, code)
+					continue
+				}
+				if err := encoder.Encode(item); err != nil {
+					return err
+				}
+				result.Exported++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// importPDFArchiveParams are the parameters of an import_pdf_archive job
+type importPDFArchiveParams struct {
+	Year      string `json:"year"`
+	URL       string `json:"url"`       // URL of the archived handbook PDF
+	Overwrite bool   `json:"overwrite"` // Replace units already stored for the year
+}
+
+// importPDFArchiveResult summarises an import_pdf_archive job
+type importPDFArchiveResult struct {
+	Extracted int      `json:"extracted"`
+	Stored    []string `json:"stored"`
+	Skipped   []string `json:"skipped"`
+}
+
+// importPDFArchiveJob extracts units from an archived handbook PDF and stores them under their
+// handbook URLs, so they are served by the regular unit endpoint for years without live pages.
+func importPDFArchiveJob(ctx context.Context, raw json.RawMessage) (interface{}, error) {
+	var params importPDFArchiveParams
+	if err := decodeParams(raw, &params); err != nil {
+		return nil, err
+	}
+	if params.Year == "" || params.URL == "" {
+		return nil, fmt.Errorf("year and url are required")
+	}
+
+	year, err := strconv.Atoi(params.Year)
+	if err != nil {
+		return nil, fmt.Errorf("invalid year: %s", params.Year)
+	}
+
+	text, err := pdf_archive.Download(params.URL)
+	if err != nil {
+		return nil, err
+	}
+	extracted := pdf_archive.ScrapeUnits(text, year, params.URL)
+
+	dbHandler, release := databases.Hold(ctx)
+	defer release()
+	result := importPDFArchiveResult{Extracted: len(extracted), Stored: []string{}, Skipped: []string{}}
+	var items []databases.BulkItem
+	codes := map[string]string{}
+	for _, unitData := range extracted {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		key := handbookURL(params.Year, "units", unitData.Code)
+		if !params.Overwrite {
+			if exists, err := dbHandler.Exists(databases.Handbook, key); err == nil && exists {
+				result.Skipped = append(result.Skipped, unitData.Code)
+				continue
+			}
+		}
+		items = append(items, databases.BulkItem{Key: key, Data: unitData})
+		codes[key] = unitData.Code
+	}
+
+	stored, err := dbHandler.BulkStore(databases.Handbook, items, time.Hour*144)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range items {
+		if failure, failed := stored.Failed[item.Key]; failed {
+			log.Errorf("[PDF ARCHIVE] Error saving %s: %s", item.Key, failure)
+			result.Skipped = append(result.Skipped, codes[item.Key])
+			continue
+		}
+		result.Stored = append(result.Stored, codes[item.Key])
+	}
+
+	return result, nil
+}
+
+// reparseYearParams are the parameters of a reparse_year job.
+// If ItemType is empty, every item type is reparsed.
+type reparseYearParams struct {
+	Year     string `json:"year"`
+	ItemType string `json:"item_type,omitempty"` // "units", "courses", or "aos"
+}
+
+// reparseYearResult summarises a reparse_year job
+type reparseYearResult struct {
+	Reparsed int               `json:"reparsed"`
+	Failed   map[string]string `json:"failed"`
+}
+
+// reparseYearJob re-runs the scrapers over every stored raw payload of a year and overwrites the stored documents,
+// so parser improvements roll out without crawling the handbook again
+func reparseYearJob(ctx context.Context, raw json.RawMessage) (interface{}, error) {
+	var params reparseYearParams
+	if err := decodeParams(raw, &params); err != nil {
+		return nil, err
+	}
+	if params.Year == "" {
+		return nil, fmt.Errorf("year is required")
+	}
+
+	urlKeys := itemTypes
+	if params.ItemType != "" {
+		if _, ok := codePatterns[params.ItemType]; !ok {
+			return nil, fmt.Errorf("invalid item_type: %s", params.ItemType)
+		}
+		urlKeys = []string{params.ItemType}
+	}
+
+	dbHandler, release := databases.Hold(ctx)
+	defer release()
+	result := reparseYearResult{Failed: map[string]string{}}
+	var batch []databases.BulkItem
+	flush := func() {
+		stored, err := dbHandler.BulkStore(databases.Handbook, batch, time.Hour*144)
+		if err != nil {
+			for _, item := range batch {
+				result.Failed[item.Key] = err.Error()
+			}
+		}
+		for key, failure := range stored.Failed {
+			result.Failed[key] = failure
+		}
+		result.Reparsed += stored.Stored
+		batch = nil
+	}
+
+	for _, urlKey := range urlKeys {
+		keys, err := dbHandler.ListKeys(databases.Raw, "^"+regexp.QuoteMeta(handbookURL(params.Year, urlKey, "")))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list raw payloads: %w", err)
+		}
+
+		for _, key := range keys {
+			if ctx.Err() != nil {
+				flush()
+				return nil, ctx.Err()
+			}
+
+			scraped, err := reparseItem(ctx, key)
+			if err != nil {
+				log.Errorf("[REPARSE] %s: %v", key, err)
+				result.Failed[key] = err.Error()
+				continue
+			}
+			batch = append(batch, databases.BulkItem{Key: key, Data: scraped})
+			if len(batch) >= crawlBatchSize {
+				flush()
+			}
+		}
+	}
+	flush()
+
+	return result, nil
+}
+
+// curriculumItems flattens every academic item in a curriculum
+func curriculumItems(curriculum common.Curriculum) []common.AcademicItem {
+	var items []common.AcademicItem
+	var walk func(containers []common.Container)
+	walk = func(containers []common.Container) {
+		for _, container := range containers {
+			items = append(items, container.AcademicItems...)
+			walk(container.Containers)
+		}
+	}
+
+	for _, part := range curriculum.Parts {
+		items = append(items, part.AcademicItems...)
+		walk(part.Containers)
+	}
+	return items
+}
+
+// itemURLKey works out the handbook urlKey of an academic item from its URL or type
+func itemURLKey(item common.AcademicItem) string {
+	switch {
+	case strings.Contains(item.URL, "/units/"), strings.EqualFold(item.Type, "unit"), strings.EqualFold(item.Type, "units"):
+		return "units"
+	case strings.Contains(item.URL, "/aos/"), strings.EqualFold(item.Type, "area_of_study"):
+		return "aos"
+	default:
+		return ""
+	}
+}
+
+// decodeParams decodes job parameters into the runner's parameter struct
+func decodeParams(raw json.RawMessage, params interface{}) error {
+	if len(raw) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(raw, params); err != nil {
+		return fmt.Errorf("invalid job params: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"path"
+
+	"github.com/gin-gonic/gin"
+	"handbook-scraper/server/jobs"
+	"handbook-scraper/utils/databases"
+)
+
+// adminJobTypes are the job types which overwrite stored data from outside the handbook, so require the admin role
+var adminJobTypes = map[string]bool{
+	"import_pdf_archive": true,
+}
+
+// jobParamChecks reject the params of a job type before it is submitted, so jobs which would fail are never started
+var jobParamChecks = map[string]func(params json.RawMessage) error{
+	"crawl_year": checkCrawlYearParams,
+}
+
+// jobRequest is the request body for submitting a job
+type jobRequest struct {
+	Type   string          `json:"type"`
+	Params json.RawMessage `json:"params"`
+}
+
+// SubmitJobHandler starts a long-running job and returns its ID
+func SubmitJobHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	var req jobRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON format for job request"})
+		return
+	}
+
+	if adminJobTypes[req.Type] && !c.GetBool(AdminKey) {
+		c.JSON(http.StatusForbidden, gin.H{"error": req.Type + " jobs require the admin role"})
+		return
+	}
+	if ReadOnly(ctx) && upstreamJobTypes[req.Type] {
+		c.JSON(http.StatusForbidden, gin.H{"error": req.Type + " jobs fetch from upstream, which is disabled on this read-only server"})
+		return
+	}
+
+	params, err := normaliseJobYear(req.Params)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if check, ok := jobParamChecks[req.Type]; ok {
+		if err := check(params); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	job, err := dependencies(ctx).jobs.Submit(req.Type, params)
+	if errors.Is(err, jobs.ErrUnknownJobType) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, job)
+}
+
+// normaliseJobYear normalises the year parameter of a job, so jobs are never started for years outside the allowed window
+func normaliseJobYear(raw json.RawMessage) (json.RawMessage, error) {
+	var params map[string]interface{}
+	if err := json.Unmarshal(raw, &params); err != nil {
+		// Params which are not an object are left for the job runner to reject
+		return raw, nil
+	}
+	year, ok := params["year"].(string)
+	if !ok {
+		return raw, nil
+	}
+
+	normalised, err := normaliseYear(year)
+	if err != nil {
+		return nil, err
+	}
+	params["year"] = normalised
+	return json.Marshal(params)
+}
+
+// GetJobHandler returns the status and result of a job
+func GetJobHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	job, err := dependencies(ctx).jobs.Get(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// JobFileHandler downloads the file written by a job, such as a bulk export, from the shared file store
+func JobFileHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	job, err := dependencies(ctx).jobs.Get(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	var result struct {
+		File string `json:"file"`
+	}
+	if job.Status != jobs.Succeeded || decodeInto(job.Result, &result) != nil || result.File == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "the job has not written a file"})
+		return
+	}
+	serveFile(c, result.File, result.File+" no longer exists")
+}
+
+// serveFile streams a file from the shared file store as a download, responding with notFound if it does not exist
+func serveFile(c *gin.Context, name string, notFound string) {
+	ctx := c.Request.Context()
+	file, err := databases.FromContext(ctx).OpenFile(name)
+	if errors.Is(err, databases.ErrFileNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": notFound})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer file.Close()
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", path.Base(name)))
+	c.Header("Last-Modified", file.UploadedAt.UTC().Format(http.TimeFormat))
+	c.DataFromReader(http.StatusOK, file.Size, mime.TypeByExtension(path.Ext(name)), file, nil)
+}
+
+// CancelJobHandler cancels a pending or running job
+func CancelJobHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	job, err := dependencies(ctx).jobs.Cancel(c.Param("id"))
+	switch {
+	case errors.Is(err, jobs.ErrJobNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, jobs.ErrJobFinished):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "job": job})
+	default:
+		c.JSON(http.StatusAccepted, job)
+	}
+}
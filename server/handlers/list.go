@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"handbook-scraper/utils/databases"
+	"handbook-scraper/utils/log"
+)
+
+// itemSummary is the entry of a handbook item in a list
+type itemSummary struct {
+	Code          string `json:"code"`
+	Title         string `json:"title,omitempty"`
+	Faculty       string `json:"faculty,omitempty"`
+	CreditPoints  int    `json:"credit_points,omitempty"`
+	ShortSynopsis string `json:"short_synopsis,omitempty"` // Units only
+}
+
+// ListItemsHandler lists the stored handbook items of a year in code order.
+// An optional prefix query parameter limits the list to codes starting with it, e.g. FIT.
+// Clients accepting NDJSON are streamed every item rather than a page.
+// urlKey could be "courses", "aos", or "units"
+func ListItemsHandler(c *gin.Context, urlKey string) {
+	ctx := c.Request.Context()
+	year, ok := yearParam(c)
+	if !ok {
+		return
+	}
+	query, ok := parseListQuery(c, itemSummaryFields)
+	if !ok {
+		return
+	}
+
+	keys, err := storedItemKeys(ctx, year, urlKey)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	base := handbookURL(year, urlKey, "")
+	prefix := strings.ToUpper(c.Query("prefix"))
+	codes := []string{}
+	for _, key := range keys {
+		code := strings.TrimPrefix(key, base)
+		if strings.HasPrefix(code, prefix) {
+			codes = append(codes, code)
+		}
+	}
+	sort.Strings(codes)
+
+	respondWithSummaries(c, year, urlKey, codes, query, func(summary itemSummary) itemSummary { return summary })
+}
+
+// respondWithSummaries responds with the page of the summaries of stored items, in the order of codes unless the query
+// sorts them. Only the items of the page are loaded, together. Queries which filter or sort the items are answered
+// from the cached summary index of the year instead, so they never load every item.
+// Clients accepting NDJSON are streamed every item rather than a page.
+func respondWithSummaries[T any](c *gin.Context, year string, urlKey string, codes []string, query listQuery, summarise func(summary itemSummary) T) {
+	ctx := c.Request.Context()
+	if !query.empty() {
+		indexed, err := summariesOf(ctx, year, urlKey, codes)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		summaries := make([]T, 0, len(indexed))
+		for _, summary := range indexed {
+			summaries = append(summaries, summarise(summary))
+		}
+		summaries = applyListQuery(summaries, query)
+		if wantsNDJSON(c) {
+			streamItems(c, summaries)
+			return
+		}
+		start, end, ok := pageBounds(c, len(summaries))
+		if !ok {
+			return
+		}
+		respondWithList(c, summaries[start:end], len(summaries), end)
+		return
+	}
+
+	// Streamed lists load and send every item one at a time, so memory stays flat however long the list is
+	if wantsNDJSON(c) {
+		base := handbookURL(year, urlKey, "")
+		stream := newNDJSONStream(c)
+		for _, code := range codes {
+			if !stream.Send(summarise(summariseItem(code, loadStoredItem(ctx, base, code)))) {
+				return
+			}
+		}
+		return
+	}
+
+	start, end, ok := pageBounds(c, len(codes))
+	if !ok {
+		return
+	}
+	page := codes[start:end]
+	stored := retrieveStoredMany(ctx, year, urlKey, page)
+	summaries := make([]T, 0, len(page))
+	for _, code := range page {
+		summaries = append(summaries, summarise(summariseItem(code, stored[code])))
+	}
+	respondWithList(c, summaries, len(codes), end)
+}
+
+// loadStoredItem loads the stored data of an item, or nil if it cannot be loaded
+func loadStoredItem(ctx context.Context, base string, code string) interface{} {
+	var data map[string]interface{}
+	if err := databases.FromContext(ctx).Retrieve(databases.Handbook, base+code, &data); err != nil {
+		log.Errorf("[LIST] Error retrieving %s: %v", base+code, err)
+		return nil
+	}
+	return data
+}
+
+// summariseItem summarises the stored data of an item. Only the code is set if there is no data.
+func summariseItem(code string, data interface{}) itemSummary {
+	summary := itemSummary{Code: code}
+	if data == nil {
+		return summary
+	}
+
+	var item struct {
+		Common        itemSummary `json:"common"`
+		CreditPoints  int         `json:"credit_points"`
+		ShortSynopsis string      `json:"short_synopsis"`
+	}
+	if err := decodeInto(data, &item); err == nil {
+		summary.Title, summary.Faculty, summary.CreditPoints = item.Common.Title, item.Common.Faculty, item.CreditPoints
+		summary.ShortSynopsis = item.ShortSynopsis
+	}
+	return summary
+}
@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// listFields are the fields of the items of a list endpoint which can be filtered and sorted by, by their JSON name.
+// Other fields are refused, so clients can only query the fields an endpoint means to expose.
+type listFields struct {
+	Filter []string
+	Sort   []string
+}
+
+// itemSummaryFields are the queryable fields of lists of item summaries
+var itemSummaryFields = listFields{
+	Filter: []string{"title", "faculty", "credit_points"},
+	Sort:   []string{"code", "title", "faculty", "credit_points"},
+}
+
+// listFilter keeps the items whose field matches any of the values
+type listFilter struct {
+	Field  string
+	Values []string
+}
+
+// listSort orders items by a field
+type listSort struct {
+	Field      string
+	Descending bool
+}
+
+// listQuery is a validated filter[field]=value and sort=-field,field query of a list
+type listQuery struct {
+	filters []listFilter
+	sorts   []listSort
+}
+
+// parseListQuery parses the filter and sort query parameters of a list against the fields of its endpoint,
+// responding with an error if they are invalid
+func parseListQuery(c *gin.Context, fields listFields) (listQuery, bool) {
+	var query listQuery
+	params := c.Request.URL.Query()
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		field, ok := strings.CutPrefix(name, "filter[")
+		if !ok {
+			continue
+		}
+		field, ok = strings.CutSuffix(field, "]")
+		if !ok || !slices.Contains(fields.Filter, field) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("cannot filter by %s, the fields are %s", strings.TrimSuffix(strings.TrimPrefix(name, "filter["), "]"), fieldList(fields.Filter))})
+			return listQuery{}, false
+		}
+		var values []string
+		for _, value := range strings.Split(params.Get(name), ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				values = append(values, value)
+			}
+		}
+		if len(values) > 0 {
+			query.filters = append(query.filters, listFilter{Field: field, Values: values})
+		}
+	}
+
+	for _, field := range strings.Split(c.Query("sort"), ",") {
+		if field = strings.TrimSpace(field); field == "" {
+			continue
+		}
+		descending := strings.HasPrefix(field, "-")
+		field = strings.TrimPrefix(field, "-")
+		if !slices.Contains(fields.Sort, field) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("cannot sort by %s, the fields are %s", field, fieldList(fields.Sort))})
+			return listQuery{}, false
+		}
+		query.sorts = append(query.sorts, listSort{Field: field, Descending: descending})
+	}
+	return query, true
+}
+
+// fieldList names the fields of a whitelist, or none
+func fieldList(fields []string) string {
+	if len(fields) == 0 {
+		return "none"
+	}
+	return strings.Join(fields, ", ")
+}
+
+// empty reports whether the query neither filters nor sorts, so a list can be paged before its items are loaded
+func (q listQuery) empty() bool {
+	return len(q.filters) == 0 && len(q.sorts) == 0
+}
+
+// values returns the values of the filter of a field
+func (q listQuery) values(field string) ([]string, bool) {
+	for _, filter := range q.filters {
+		if filter.Field == field {
+			return filter.Values, true
+		}
+	}
+	return nil, false
+}
+
+// applyListQuery filters and sorts items by their JSON fields. Strings match values they contain and lists
+// values they have, ignoring case, and other fields match equal values. Sorting is stable, so items otherwise keep
+// their order.
+func applyListQuery[T any](items []T, q listQuery) []T {
+	if q.empty() {
+		return items
+	}
+
+	type row struct {
+		item   T
+		fields map[string]interface{}
+	}
+	rows := make([]row, 0, len(items))
+	for _, item := range items {
+		var fields map[string]interface{}
+		_ = decodeInto(item, &fields)
+		if matchesFilters(fields, q.filters) {
+			rows = append(rows, row{item: item, fields: fields})
+		}
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		for _, by := range q.sorts {
+			order := compareListValues(rows[i].fields[by.Field], rows[j].fields[by.Field])
+			if order == 0 {
+				continue
+			}
+			if by.Descending {
+				return order > 0
+			}
+			return order < 0
+		}
+		return false
+	})
+
+	filtered := make([]T, 0, len(rows))
+	for _, r := range rows {
+		filtered = append(filtered, r.item)
+	}
+	return filtered
+}
+
+// matchesFilters reports whether the fields of an item match every filter
+func matchesFilters(fields map[string]interface{}, filters []listFilter) bool {
+	for _, filter := range filters {
+		if !slices.ContainsFunc(filter.Values, func(value string) bool { return matchesListValue(fields[filter.Field], value) }) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesListValue reports whether a field matches a filter value
+func matchesListValue(field interface{}, value string) bool {
+	switch field := field.(type) {
+	case string:
+		return strings.Contains(strings.ToLower(field), strings.ToLower(value))
+	case []interface{}:
+		return slices.ContainsFunc(field, func(element interface{}) bool { return strings.EqualFold(formatListValue(element), value) })
+	case nil:
+		return false
+	default:
+		return strings.EqualFold(formatListValue(field), value)
+	}
+}
+
+// formatListValue formats a JSON value as it is written in a query
+func formatListValue(value interface{}) string {
+	if number, ok := value.(float64); ok {
+		return strconv.FormatFloat(number, 'f', -1, 64)
+	}
+	return fmt.Sprint(value)
+}
+
+// compareListValues orders two JSON values, numbers by value, and others by their text ignoring case.
+// Missing values come first.
+func compareListValues(a interface{}, b interface{}) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+	if x, ok := a.(float64); ok {
+		if y, ok := b.(float64); ok {
+			switch {
+			case x < y:
+				return -1
+			case x > y:
+				return 1
+			}
+			return 0
+		}
+	}
+	return strings.Compare(strings.ToLower(formatListValue(a)), strings.ToLower(formatListValue(b)))
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// metricFamily is a gauge in the Prometheus text exposition format, with a sample per item type
+type metricFamily struct {
+	name    string
+	help    string
+	samples map[string]float64
+}
+
+// write appends the family to the exposition, with item types in the order they are crawled
+func (m metricFamily) write(b *strings.Builder) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s gauge\n", m.name, m.help, m.name)
+	for _, itemType := range ScheduledCrawlItemTypes {
+		fmt.Fprintf(b, "%s{item_type=%q} %s\n", m.name, itemType, formatMetric(m.samples[itemType]))
+	}
+}
+
+// writeGauge appends a gauge without labels to the exposition
+func writeGauge(b *strings.Builder, name string, help string, value float64) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s gauge\n%s %s\n", name, help, name, name, formatMetric(value))
+}
+
+// formatMetric formats a sample value, including infinity as Prometheus expects it
+func formatMetric(value float64) string {
+	if math.IsInf(value, 1) {
+		return "+Inf"
+	}
+	return fmt.Sprintf("%g", value)
+}
+
+// MetricsHandler exposes the health of the scheduled crawls, the cold scrape queue and the latest consistency check as Prometheus gauges,
+// so alerts such as "handbook data is going stale" can be set up without parsing logs
+func MetricsHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	failures := metricFamily{
+		name:    "handbook_crawl_consecutive_failures",
+		help:    "Scheduled crawls in a row which failed.",
+		samples: map[string]float64{},
+	}
+	quarantined := metricFamily{
+		name:    "handbook_crawl_quarantined_pages",
+		help:    "Pages skipped by scheduled crawls after failing repeatedly.",
+		samples: map[string]float64{},
+	}
+	lastSuccess := metricFamily{
+		name:    "handbook_crawl_last_success_timestamp_seconds",
+		help:    "Unix time of the last successful scheduled crawl, 0 if none has succeeded.",
+		samples: map[string]float64{},
+	}
+	sinceSuccess := metricFamily{
+		name:    "handbook_crawl_seconds_since_last_success",
+		help:    "Seconds since the last successful scheduled crawl, +Inf if none has succeeded.",
+		samples: map[string]float64{},
+	}
+
+	for _, itemType := range ScheduledCrawlItemTypes {
+		health := loadCrawlHealth(ctx, itemType)
+		failures.samples[itemType] = float64(health.ConsecutiveFailures)
+		quarantined.samples[itemType] = float64(health.quarantined())
+		sinceSuccess.samples[itemType] = math.Inf(1)
+		if health.LastSuccess != nil {
+			lastSuccess.samples[itemType] = float64(health.LastSuccess.Unix())
+			sinceSuccess.samples[itemType] = time.Since(*health.LastSuccess).Seconds()
+		}
+	}
+
+	var b strings.Builder
+	for _, family := range []metricFamily{failures, quarantined, lastSuccess, sinceSuccess} {
+		family.write(&b)
+	}
+	writeGauge(&b, "handbook_cold_scrapes_running", "Scrapes of pages missing from the cache running on this replica.", float64(coldScrapes().running()))
+	writeGauge(&b, "handbook_cold_scrapes_queued", "Scrapes of pages missing from the cache waiting for a slot on this replica.", float64(coldScrapes().queued()))
+	if report := latestConsistencyReport(ctx); report != nil {
+		writeGauge(&b, "handbook_consistency_last_check_timestamp_seconds", "Unix time of the latest consistency check between Redis and MongoDB.", float64(report.CheckedAt.Unix()))
+		writeGauge(&b, "handbook_consistency_mismatched_documents", "Documents cached in Redis with different content than MongoDB at the latest check.", float64(len(report.Mismatched)))
+		writeGauge(&b, "handbook_consistency_redis_only_documents", "Documents cached in Redis but missing from MongoDB at the latest check.", float64(len(report.RedisOnly)))
+		writeGauge(&b, "handbook_consistency_corrupt_documents", "Documents unreadable or empty in MongoDB at the latest check.", float64(len(report.Corrupt)))
+	}
+	c.Data(http.StatusOK, "text/plain; version=0.0.4; charset=utf-8", []byte(b.String()))
+}
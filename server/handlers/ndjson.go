@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"handbook-scraper/utils/log"
+)
+
+// ndjsonContentType is the content type of newline-delimited JSON, one document per line
+const ndjsonContentType = "application/x-ndjson"
+
+// wantsNDJSON reports whether the client asked for the items of a list or batch to be streamed as NDJSON
+func wantsNDJSON(c *gin.Context) bool {
+	return strings.Contains(c.GetHeader("Accept"), ndjsonContentType)
+}
+
+// ndjsonStream writes documents to the client as NDJSON, flushing each one so clients can process it straight away
+type ndjsonStream struct {
+	c       *gin.Context
+	encoder *json.Encoder
+	failed  bool
+}
+
+// newNDJSONStream starts an NDJSON response
+func newNDJSONStream(c *gin.Context) *ndjsonStream {
+	c.Header("Content-Type", ndjsonContentType)
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Status(http.StatusOK)
+	return &ndjsonStream{c: c, encoder: json.NewEncoder(c.Writer)}
+}
+
+// Send writes a document as a line. It returns false once the client has gone away, so producers can stop early.
+func (s *ndjsonStream) Send(document interface{}) bool {
+	if s.failed || s.c.Request.Context().Err() != nil {
+		return false
+	}
+	if err := s.encoder.Encode(document); err != nil {
+		log.Errorf("[NDJSON] Failed to write document: %v", err)
+		s.failed = true
+		return false
+	}
+	s.c.Writer.Flush()
+	return true
+}
+
+// SendError writes an error as the last line, since the status code was already sent with the first document
+func (s *ndjsonStream) SendError(err error) {
+	s.Send(gin.H{"error": err.Error()})
+}
+
+// streamItems streams every item of a list as NDJSON
+func streamItems[T any](c *gin.Context, items []T) {
+	stream := newNDJSONStream(c)
+	for _, item := range items {
+		if !stream.Send(item) {
+			return
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"handbook-scraper/scrapers/units"
+	"handbook-scraper/utils/databases"
+	"handbook-scraper/utils/log"
+)
+
+// withOfferingStatus explains a unit without offerings in a year, rather than leaving it as an empty list,
+// by looking up the closest earlier and later years its stored versions are offered in.
+// Only stored years are looked at, so no pages are scraped.
+func withOfferingStatus(ctx context.Context, year string, data interface{}) (interface{}, error) {
+	var unitData units.UnitData
+	if err := decodeInto(data, &unitData); err != nil {
+		return nil, err
+	}
+	if len(unitData.UnitOfferings) > 0 {
+		return data, nil
+	}
+	requested, err := strconv.Atoi(year)
+	if err != nil {
+		return data, nil
+	}
+
+	minYear, maxYear := yearWindow()
+	var keys []string
+	for other := minYear; other <= maxYear; other++ {
+		if other != requested {
+			keys = append(keys, handbookURL(strconv.Itoa(other), "units", unitData.Code))
+		}
+	}
+	stored, err := databases.FromContext(ctx).RetrieveMany(databases.Handbook, keys)
+	if err != nil {
+		log.Errorf("[OFFERINGS] Error retrieving other years of %s: %v", unitData.Code, err)
+	}
+
+	status := &units.OfferingStatus{}
+	for other := minYear; other <= maxYear; other++ {
+		raw, ok := stored[handbookURL(strconv.Itoa(other), "units", unitData.Code)]
+		if !ok {
+			continue
+		}
+		var otherData units.UnitData
+		if err := decodeInto(raw, &otherData); err != nil || len(otherData.UnitOfferings) == 0 {
+			continue
+		}
+
+		offerings := &units.YearOfferings{Year: strconv.Itoa(other), Offerings: otherData.UnitOfferings}
+		if other < requested {
+			status.LastOffered = offerings
+		} else if status.NextOffered == nil {
+			status.NextOffered = offerings
+		}
+	}
+
+	status.Message = fmt.Sprintf("%s is not offered in %s", unitData.Code, year)
+	switch {
+	case status.LastOffered != nil && status.NextOffered != nil:
+		status.Message += fmt.Sprintf(", it was last offered in %s and is next offered in %s", status.LastOffered.Year, status.NextOffered.Year)
+	case status.LastOffered != nil:
+		status.Message += fmt.Sprintf(", it was last offered in %s", status.LastOffered.Year)
+	case status.NextOffered != nil:
+		status.Message += fmt.Sprintf(", it is next offered in %s", status.NextOffered.Year)
+	}
+	unitData.OfferingStatus = status
+	return unitData, nil
+}
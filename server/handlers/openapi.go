@@ -0,0 +1,261 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"handbook-scraper/scrapers/area_of_study"
+	"handbook-scraper/scrapers/common"
+	"handbook-scraper/scrapers/courses"
+	"handbook-scraper/scrapers/units"
+)
+
+// openAPIDocument is generated once, from the types the handlers respond with
+var openAPIDocument = sync.OnceValue(buildOpenAPIDocument)
+
+// OpenAPIHandler serves the OpenAPI 3 document of the handbook endpoints, for generating clients
+func OpenAPIHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, openAPIDocument())
+}
+
+// buildOpenAPIDocument describes the handbook endpoints. The schemas of their bodies are generated from the Go types
+// of the handlers, so they follow the scrapers as fields are added.
+func buildOpenAPIDocument() map[string]interface{} {
+	schemas := newOpenAPISchemas()
+	errorResponse := openAPIResponse("An error", schemas.schema(reflect.TypeOf(openAPIError{})))
+	itemErrors := map[string]interface{}{
+		"300": openAPIResponse("The code has several entries in the year, listed in choices", schemas.schema(reflect.TypeOf(openAPIChoices{}))),
+		"400": errorResponse,
+		"404": errorResponse,
+		"503": errorResponse,
+	}
+
+	item := func(summary string, itemType reflect.Type, params ...map[string]interface{}) map[string]interface{} {
+		return openAPIOperation(summary, append([]map[string]interface{}{yearParameter, codeParameter}, params...), nil,
+			withResponses(itemErrors, "200", openAPIResponse(summary, schemas.schema(itemType))))
+	}
+
+	batchBody := map[string]interface{}{
+		"oneOf": []interface{}{
+			schemas.schema(reflect.TypeOf(batchRequest{})),
+			map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+		},
+	}
+	batch := func(summary string) map[string]interface{} {
+		return openAPIOperation(summary, []map[string]interface{}{yearParameter}, batchBody, map[string]interface{}{
+			"200": openAPIResponse("The result of each code", schemas.schema(reflect.TypeOf(openAPIBatchResponse{}))),
+			"400": errorResponse,
+		})
+	}
+
+	paths := map[string]interface{}{
+		"/v1/{year}/units/{code}": map[string]interface{}{"get": item("A unit", reflect.TypeOf(units.UnitData{}),
+			openAPIQuery("fields", "Fields to keep, e.g. common(code,title),requisites"),
+			openAPIQuery("enrich", "true to add the title and credit points of requisite units"),
+			openAPIQuery("kept_version", "A version of the unit kept since it was first scraped"),
+			openAPIQuery("requisites", "compressed (default), raw or both"),
+		)},
+		"/v1/{year}/courses/{code}": map[string]interface{}{"get": item("A course", reflect.TypeOf(courses.CourseData{}),
+			openAPIQuery("fields", "Fields to keep"),
+		)},
+		"/v1/{year}/aos/{code}": map[string]interface{}{"get": item("An area of study", reflect.TypeOf(area_of_study.AosData{}),
+			openAPIQuery("fields", "Fields to keep"),
+		)},
+		"/v1/{year}/units/batch":   map[string]interface{}{"post": batch("Several units")},
+		"/v1/{year}/courses/batch": map[string]interface{}{"post": batch("Several courses")},
+		"/v1/{year}/aos/batch":     map[string]interface{}{"post": batch("Several areas of study")},
+		"/v1/{year}/units/{code}/check": map[string]interface{}{"post": openAPIOperation("Check the requisites of a unit",
+			[]map[string]interface{}{yearParameter, codeParameter, openAPIQuery("course", "The course the student is admitted to, e.g. C2001")},
+			map[string]interface{}{"type": "array", "items": schemas.schema(reflect.TypeOf(common.Unit{}))},
+			withResponses(itemErrors, "200", openAPIResponse("Whether the completed units meet the requisites", schemas.schema(reflect.TypeOf(unitCheckResult{})))),
+		)},
+		"/v1/{year}/units/{code}/requisites/text": map[string]interface{}{"get": openAPIOperation("The requisites of a unit as text",
+			[]map[string]interface{}{yearParameter, codeParameter, openAPIQuery("format", "text for plain text")}, nil,
+			withResponses(itemErrors, "200", openAPIResponse("The rendered requisites", schemas.schema(reflect.TypeOf(openAPIRequisiteText{})))),
+		)},
+		"/v1/{year}/answer": map[string]interface{}{"post": openAPIOperation("Answer a question in natural language",
+			[]map[string]interface{}{yearParameter}, schemas.schema(reflect.TypeOf(answerRequest{})),
+			withResponses(itemErrors, "200", openAPIResponse("The answer", schemas.schema(reflect.TypeOf(openAPIAnswer{})))),
+		)},
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":       "Monash Handbook API",
+			"version":     "1",
+			"description": "Units, courses and areas of study scraped from the Monash University handbook",
+		},
+		"paths":      paths,
+		"components": map[string]interface{}{"schemas": schemas.components},
+	}
+}
+
+// Bodies which the handlers build as gin.H, described for the document
+type (
+	openAPIError struct {
+		Error string `json:"error"`
+	}
+	openAPIChoices struct {
+		Error    string           `json:"error"`
+		ItemType string           `json:"item_type,omitempty"`
+		Choices  []common.Variant `json:"choices"`
+	}
+	openAPIBatchResponse struct {
+		Items   []batchItem  `json:"items"`
+		Summary batchSummary `json:"summary"`
+	}
+	openAPIRequisiteText struct {
+		Code       string                    `json:"code"`
+		Text       string                    `json:"text"`
+		Requisites []units.RenderedRequisite `json:"requisites"`
+	}
+	openAPIAnswer struct {
+		Question string      `json:"question"`
+		Intent   string      `json:"intent"`
+		Unit     string      `json:"unit"`
+		Answer   string      `json:"answer"`
+		Data     interface{} `json:"data"`
+	}
+)
+
+var (
+	yearParameter = map[string]interface{}{
+		"name": "year", "in": "path", "required": true,
+		"description": "Year of the handbook, current or next",
+		"schema":      map[string]interface{}{"type": "string"},
+	}
+	codeParameter = map[string]interface{}{
+		"name": "code", "in": "path", "required": true,
+		"schema": map[string]interface{}{"type": "string"},
+	}
+)
+
+// openAPIQuery describes an optional query parameter
+func openAPIQuery(name string, description string) map[string]interface{} {
+	return map[string]interface{}{"name": name, "in": "query", "description": description, "schema": map[string]interface{}{"type": "string"}}
+}
+
+// openAPIOperation describes an operation, with a JSON request body if body is not nil
+func openAPIOperation(summary string, params []map[string]interface{}, body map[string]interface{}, responses map[string]interface{}) map[string]interface{} {
+	operation := map[string]interface{}{"summary": summary, "parameters": params, "responses": responses}
+	if body != nil {
+		operation["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content":  map[string]interface{}{"application/json": map[string]interface{}{"schema": body}},
+		}
+	}
+	return operation
+}
+
+// openAPIResponse describes a JSON response
+func openAPIResponse(description string, schema map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"description": description,
+		"content":     map[string]interface{}{"application/json": map[string]interface{}{"schema": schema}},
+	}
+}
+
+// withResponses adds a response to a copy of responses
+func withResponses(responses map[string]interface{}, status string, response map[string]interface{}) map[string]interface{} {
+	merged := map[string]interface{}{status: response}
+	for code, other := range responses {
+		merged[code] = other
+	}
+	return merged
+}
+
+// openAPISchemas generates the schemas of Go types from their JSON fields. Named structs become components,
+// referenced wherever they appear, so recursive structures such as requisite containers are supported.
+type openAPISchemas struct {
+	components map[string]interface{}
+	refs       map[reflect.Type]string
+}
+
+func newOpenAPISchemas() *openAPISchemas {
+	return &openAPISchemas{components: map[string]interface{}{}, refs: map[reflect.Type]string{}}
+}
+
+// schema returns the schema of a Go type
+func (s *openAPISchemas) schema(t reflect.Type) map[string]interface{} {
+	switch t.Kind() {
+	case reflect.Pointer:
+		schema := s.schema(t.Elem())
+		if _, ref := schema["$ref"]; ref {
+			return map[string]interface{}{"allOf": []interface{}{schema}, "nullable": true}
+		}
+		schema["nullable"] = true
+		return schema
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": s.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": s.schema(t.Elem())}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Struct:
+		if t.Implements(reflect.TypeOf((*json.Marshaler)(nil)).Elem()) {
+			return map[string]interface{}{}
+		}
+		if t.Name() == "" {
+			return s.object(t)
+		}
+		return s.ref(t)
+	default:
+		// Fields of any type, such as raw handbook structures
+		return map[string]interface{}{}
+	}
+}
+
+// ref returns a reference to the component of a named struct, adding it if it is new
+func (s *openAPISchemas) ref(t reflect.Type) map[string]interface{} {
+	name, ok := s.refs[t]
+	if !ok {
+		name = strings.TrimPrefix(t.Name(), "openAPI")
+		name = strings.ToUpper(name[:1]) + name[1:]
+		// Structs of different packages may share a name, such as the AcademicItem of units and of common
+		if _, taken := s.components[name]; taken {
+			pkg := t.PkgPath()[strings.LastIndex(t.PkgPath(), "/")+1:]
+			name = strings.ReplaceAll(pkg, "_", "") + "." + name
+		}
+		s.refs[t] = name
+		s.components[name] = map[string]interface{}{} // Taken before its fields are generated, for recursive types
+		s.components[name] = s.object(t)
+	}
+	return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+}
+
+// object returns the schema of a struct with its JSON fields as properties
+func (s *openAPISchemas) object(t reflect.Type) map[string]interface{} {
+	properties := map[string]interface{}{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		key, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if field.Anonymous && key == "" && field.Type.Kind() == reflect.Struct {
+			// Embedded structs without a name are flattened, as encoding/json does
+			embedded := s.object(field.Type)
+			for name, property := range embedded["properties"].(map[string]interface{}) {
+				properties[name] = property
+			}
+			continue
+		}
+		if !field.IsExported() || key == "-" {
+			continue
+		}
+		if key == "" {
+			key = field.Name
+		}
+		properties[key] = s.schema(field.Type)
+	}
+	return map[string]interface{}{"type": "object", "properties": properties}
+}
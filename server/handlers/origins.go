@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	"handbook-scraper/utils/log"
+)
+
+// AllowedOrigins returns the browser origins allowed to call the API, from the comma-separated CORS_ALLOWED_ORIGINS,
+// e.g. https://planner.example.com. It is empty if every origin is allowed. They are read once, when first needed.
+var AllowedOrigins = sync.OnceValue(func() []string {
+	var origins []string
+	for _, origin := range strings.Split(os.Getenv("CORS_ALLOWED_ORIGINS"), ",") {
+		origin = strings.TrimSuffix(strings.TrimSpace(origin), "/")
+		if origin == "" {
+			continue
+		}
+		if parsed, err := url.Parse(origin); err != nil || parsed.Scheme == "" || parsed.Host == "" {
+			log.Warnf("Ignoring CORS_ALLOWED_ORIGINS entry %q, expected scheme://host", origin)
+			continue
+		}
+		origins = append(origins, strings.ToLower(origin))
+	}
+	return origins
+})
+
+// AllowedOrigin reports whether a browser at origin is listed in CORS_ALLOWED_ORIGINS
+func AllowedOrigin(origin string) bool {
+	return slices.Contains(AllowedOrigins(), strings.ToLower(origin))
+}
+
+// sameOrigin reports whether the Origin of a request is the host it was sent to
+func sameOrigin(r *http.Request) bool {
+	parsed, err := url.Parse(r.Header.Get("Origin"))
+	return err == nil && strings.EqualFold(parsed.Host, r.Host)
+}
+
+// checkWebSocketOrigin accepts WebSocket connections from clients without an Origin, which are not browsers, and from
+// pages of the API itself or of a listed origin. WebSockets are not covered by CORS, so unlike the REST endpoints
+// they are never open to every origin, otherwise any website could open a session from a visitor's browser.
+func checkWebSocketOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	return origin == "" || sameOrigin(r) || AllowedOrigin(origin)
+}
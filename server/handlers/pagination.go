@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 500
+)
+
+// ListResponse is the envelope of every list endpoint.
+// NextCursor is passed as the page[cursor] query parameter to get the next page, and is empty on the last page.
+type ListResponse struct {
+	Items       interface{} `json:"items"`
+	Total       int         `json:"total"`
+	NextCursor  string      `json:"next_cursor"`
+	GeneratedAt time.Time   `json:"generated_at"`
+}
+
+// pageBounds resolves the page[cursor] and page[size] query parameters, or cursor and limit, into the bounds of the page
+// of a list of total items, responding with an error if they are invalid
+func pageBounds(c *gin.Context, total int) (int, int, bool) {
+	limit := defaultPageLimit
+	if raw := pageParam(c, "page[size]", "limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 || parsed > maxPageLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("page size must be between 1 and %d", maxPageLimit)})
+			return 0, 0, false
+		}
+		limit = parsed
+	}
+
+	start := 0
+	if cursor := pageParam(c, "page[cursor]", "cursor"); cursor != "" {
+		offset, err := decodeCursor(cursor)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid cursor"})
+			return 0, 0, false
+		}
+		start = min(offset, total)
+	}
+	return start, min(start+limit, total), true
+}
+
+// pageParam returns a pagination query parameter, or its older name if it is not set
+func pageParam(c *gin.Context, name string, legacyName string) string {
+	if value := c.Query(name); value != "" {
+		return value
+	}
+	return c.Query(legacyName)
+}
+
+// respondWithList responds with the items of a page ending at end, out of total items
+func respondWithList(c *gin.Context, items interface{}, total int, end int) {
+	response := ListResponse{Items: items, Total: total, GeneratedAt: time.Now()}
+	if end < total {
+		response.NextCursor = encodeCursor(end)
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// respondWithPage filters and sorts items by the query of the list, over the fields of its endpoint, and responds with
+// the page requested. Clients accepting NDJSON are streamed every matching item instead.
+func respondWithPage[T any](c *gin.Context, items []T, fields listFields) {
+	query, ok := parseListQuery(c, fields)
+	if !ok {
+		return
+	}
+	items = applyListQuery(items, query)
+
+	if wantsNDJSON(c) {
+		streamItems(c, items)
+		return
+	}
+
+	start, end, ok := pageBounds(c, len(items))
+	if !ok {
+		return
+	}
+	respondWithList(c, items[start:end], len(items), end)
+}
+
+// encodeCursor encodes the offset of the next page as an opaque cursor
+func encodeCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(offset)))
+}
+
+// decodeCursor decodes the offset of a cursor
+func decodeCursor(cursor string) (int, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, err
+	}
+	offset, err := strconv.Atoi(string(decoded))
+	if err != nil || offset < 0 {
+		return 0, fmt.Errorf("invalid cursor offset")
+	}
+	return offset, nil
+}
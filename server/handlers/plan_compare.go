@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gocolly/colly/v2"
+	"handbook-scraper/planner"
+	"handbook-scraper/scrapers/units"
+)
+
+// namedPlan is a candidate plan with an optional name, such as the major it is built around
+type namedPlan struct {
+	Name string `json:"name"`
+	planner.Plan
+}
+
+// compareRequest is the request body for a plan comparison
+type compareRequest struct {
+	A namedPlan `json:"a"`
+	B namedPlan `json:"b"`
+}
+
+// ComparePlansHandler compares two candidate plans by completion, estimated cost, workload per teaching period,
+// and the units unique to each, to help decide between them, e.g. when switching majors
+func ComparePlansHandler(c *gin.Context, collector *colly.Collector) {
+	ctx := c.Request.Context()
+	var req compareRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON format for plan comparison"})
+		return
+	}
+
+	rules := planner.LoadRulesFromEnv()
+	rates := planner.ContributionRatesFromEnv()
+	var summaries []planner.PlanSummary
+	for _, candidate := range []*namedPlan{&req.A, &req.B} {
+		if candidate.HandbookYear == "" {
+			candidate.HandbookYear = "current"
+		}
+		year, err := resolveYear(ctx, candidate.HandbookYear)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		for i := range candidate.Entries {
+			candidate.Entries[i].Code = strings.ToUpper(strings.TrimSpace(candidate.Entries[i].Code))
+		}
+
+		lookup := planLookup(c.Request.Context(), year, collector)
+		duration := checkPlanDuration(c.Request.Context(), candidate.Plan, collector)
+		summaries = append(summaries, planner.SummarisePlan(candidate.Name, candidate.Plan, lookup, unitEquivalents(c.Request.Context()), rules, rates, duration))
+	}
+
+	c.JSON(http.StatusOK, planner.ComparePlans(req.A.Plan, summaries[0], req.B.Plan, summaries[1]))
+}
+
+// planLookup looks up the units of a plan in a handbook year, memoising them since every check of the plan looks them up
+func planLookup(ctx context.Context, year string, collector *colly.Collector) planner.UnitLookup {
+	fetched := map[string]units.UnitData{}
+	return func(code string) (units.UnitData, error) {
+		if unitData, ok := fetched[code]; ok {
+			return unitData, nil
+		}
+		unitData, err := fetchUnit(ctx, year, code, collector)
+		if err != nil {
+			return units.UnitData{}, err
+		}
+		fetched[code] = unitData
+		return unitData, nil
+	}
+}
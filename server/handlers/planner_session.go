@@ -0,0 +1,273 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gocolly/colly/v2"
+	"github.com/gorilla/websocket"
+	"handbook-scraper/planner"
+	"handbook-scraper/scrapers/calendar"
+	"handbook-scraper/scrapers/common"
+	"handbook-scraper/scrapers/courses"
+	"handbook-scraper/scrapers/units"
+	"handbook-scraper/utils/databases"
+	"handbook-scraper/utils/log"
+)
+
+const (
+	// plannerReadLimit is the largest message a planner session accepts
+	plannerReadLimit = 64 << 10
+	// plannerPongWait is how long a planner session waits for a message or a pong before closing
+	plannerPongWait = 60 * time.Second
+	// plannerPingPeriod is how often a planner session is pinged, within plannerPongWait
+	plannerPingPeriod = plannerPongWait * 9 / 10
+	// maxPlanUnits is the most units a plan can have planned or completed, since every message revalidates the plan
+	maxPlanUnits = 64
+)
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	CheckOrigin:     checkWebSocketOrigin,
+}
+
+// plannerMessage is a message sent by the client during a planner session.
+// Action could be "init", "add", "remove", "intermit", "resume", or "validate".
+type plannerMessage struct {
+	Action string `json:"action"`
+
+	// init
+	Year             string        `json:"year"`
+	Course           string        `json:"course"`
+	CommencementYear int           `json:"commencement_year"`
+	Completed        []common.Unit `json:"completed"`
+
+	// add and remove, intermit and resume use the teaching period and year
+	planner.Entry
+}
+
+// UnmarshalJSON decodes a planner message. The year field is the handbook year for init and the planned year otherwise,
+// so it is decoded into both, as a number or a string.
+func (m *plannerMessage) UnmarshalJSON(data []byte) error {
+	type message plannerMessage
+	var decoded struct {
+		message
+		Year json.RawMessage `json:"year"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	*m = plannerMessage(decoded.message)
+
+	if len(decoded.Year) == 0 || string(decoded.Year) == "null" {
+		return nil
+	}
+	var year string
+	if err := json.Unmarshal(decoded.Year, &year); err != nil {
+		var number int
+		if err := json.Unmarshal(decoded.Year, &number); err != nil {
+			return fmt.Errorf("year must be a number or a string")
+		}
+		year = strconv.Itoa(number)
+	}
+	m.Year = year
+	m.Entry.Year, _ = strconv.Atoi(year)
+	return nil
+}
+
+// plannerResponse is sent back to the client after each message
+type plannerResponse struct {
+	Action   string                 `json:"action"`
+	OK       bool                   `json:"ok"`
+	Error    string                 `json:"error,omitempty"`
+	Results  []planner.EntryResult  `json:"results"`
+	Loads    []planner.PeriodLoad   `json:"loads"`
+	Duration *planner.DurationCheck `json:"duration,omitempty"` // Only once a unit is planned
+}
+
+// PlannerSessionHandler upgrades the connection to a WebSocket and keeps a study plan
+// for the lifetime of the connection. Clients send incremental actions and receive
+// the validation results of the affected entries straight away.
+func PlannerSessionHandler(c *gin.Context, collector *colly.Collector) {
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		log.Errorf("[PLANNER] Failed to upgrade connection: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	log.Infof("[PLANNER] Session started from %s", c.ClientIP())
+
+	// Clients which stop answering pings are disconnected, rather than holding the session open forever
+	conn.SetReadLimit(plannerReadLimit)
+	_ = conn.SetReadDeadline(time.Now().Add(plannerPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(plannerPongWait))
+	})
+	done := make(chan struct{})
+	defer close(done)
+	go pingPlannerSession(conn, done)
+
+	plan := planner.Plan{HandbookYear: "current"}
+	rules := planner.LoadRulesFromEnv()
+
+	for {
+		var msg plannerMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				log.Errorf("[PLANNER] Session closed: %v", err)
+			}
+			return
+		}
+
+		_ = conn.SetReadDeadline(time.Now().Add(plannerPongWait))
+
+		resp := handlePlannerMessage(c.Request.Context(), &plan, msg, rules, collector)
+
+		if err := conn.WriteJSON(resp); err != nil {
+			log.Errorf("[PLANNER] Failed to write response: %v", err)
+			return
+		}
+	}
+}
+
+// pingPlannerSession pings a planner session every plannerPingPeriod until done is closed
+func pingPlannerSession(conn *websocket.Conn, done <-chan struct{}) {
+	ticker := time.NewTicker(plannerPingPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			// WriteControl is safe alongside the writes of the session
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(plannerPongWait)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// handlePlannerMessage applies a single action to the plan and validates the result
+func handlePlannerMessage(ctx context.Context, plan *planner.Plan, msg plannerMessage, rules planner.LoadRules, collector *colly.Collector) plannerResponse {
+	resp := plannerResponse{Action: msg.Action, Results: []planner.EntryResult{}, Loads: []planner.PeriodLoad{}}
+
+	// Units are looked up by both the requisite and load checks, so they are memoised per message
+	fetched := map[string]units.UnitData{}
+	lookup := func(code string) (units.UnitData, error) {
+		if unitData, ok := fetched[code]; ok {
+			return unitData, nil
+		}
+
+		year, err := resolveYear(ctx, plan.HandbookYear)
+		if err != nil {
+			return units.UnitData{}, err
+		}
+		unitData, err := fetchUnit(ctx, year, code, collector)
+		if err != nil {
+			return units.UnitData{}, err
+		}
+		fetched[code] = unitData
+		return unitData, nil
+	}
+
+	switch msg.Action {
+	case "init":
+		course := strings.ToUpper(strings.TrimSpace(msg.Course))
+		if course != "" && !ValidCode("courses", course) {
+			resp.Error = fmt.Sprintf("malformed course code: %s", course)
+			return resp
+		}
+		completed := normaliseCompleted(msg.Completed)
+		if len(completed) > maxPlanUnits {
+			resp.Error = fmt.Sprintf("at most %d units can be completed", maxPlanUnits)
+			return resp
+		}
+		plan.Course = course
+		plan.CommencementYear = msg.CommencementYear
+		plan.Completed = completed
+		plan.Entries = nil
+		plan.Intermissions = nil
+		if msg.Year != "" {
+			plan.HandbookYear = msg.Year
+		}
+	case "add":
+		if code := strings.ToUpper(strings.TrimSpace(msg.Code)); !ValidCode("units", code) {
+			resp.Error = fmt.Sprintf("malformed unit code: %s", code)
+			return resp
+		}
+		if len(plan.Entries) >= maxPlanUnits {
+			resp.Error = fmt.Sprintf("at most %d units can be planned", maxPlanUnits)
+			return resp
+		}
+		if err := plan.Add(msg.Entry); err != nil {
+			resp.Error = err.Error()
+			return resp
+		}
+	case "remove":
+		if !plan.Remove(msg.Code) {
+			resp.Error = fmt.Sprintf("%s is not in the plan", msg.Code)
+			return resp
+		}
+	case "intermit":
+		if err := plan.Intermit(planner.Intermission{TeachingPeriod: msg.TeachingPeriod, Year: msg.Entry.Year}); err != nil {
+			resp.Error = err.Error()
+			return resp
+		}
+	case "resume":
+		if !plan.Resume(msg.TeachingPeriod, msg.Entry.Year) {
+			resp.Error = fmt.Sprintf("%s %d is not an intermission", msg.TeachingPeriod, msg.Entry.Year)
+			return resp
+		}
+	case "validate":
+		// Nothing to apply, the plan is revalidated below
+	default:
+		resp.Error = fmt.Sprintf("unknown action: %s", msg.Action)
+		return resp
+	}
+
+	// Adding or removing a unit can change the requisites of later entries, so the whole plan is revalidated
+	resp.Results = planner.Validate(*plan, lookup, unitEquivalents(ctx))
+	resp.Loads = planner.CheckLoad(*plan, lookup, rules)
+	resp.Duration = checkPlanDuration(ctx, *plan, collector)
+	resp.OK = true
+	return resp
+}
+
+// checkPlanDuration checks the plan against the maximum duration of its course.
+// The expected completion date is added if the calendar of the final year is stored.
+func checkPlanDuration(ctx context.Context, plan planner.Plan, collector *colly.Collector) *planner.DurationCheck {
+	maximumDuration := 0
+	if plan.Course != "" {
+		if year, err := resolveYear(ctx, plan.HandbookYear); err == nil {
+			data, err := ScrapeAndCache(ctx, handbookURL(year, "courses", plan.Course), collector, "courses")
+			var courseData courses.CourseData
+			if err == nil {
+				err = decodeInto(data, &courseData)
+			}
+			if err != nil {
+				log.Errorf("[PLANNER] Failed to get the maximum duration of %s: %v", plan.Course, err)
+			}
+			maximumDuration = courseData.MaximumDuration
+		}
+	}
+
+	check := planner.CheckDuration(plan, maximumDuration)
+	if check == nil {
+		return nil
+	}
+
+	var calendarData calendar.CalendarData
+	if err := databases.FromContext(ctx).Retrieve(databases.Handbook, calendar.URL(strconv.Itoa(check.ExpectedCompletionYear)), &calendarData); err == nil {
+		if period, ok := calendarData.FindTeachingPeriod(check.ExpectedCompletionPeriod); ok {
+			check.ExpectedCompletionDate = period.EndDate()
+		}
+	}
+	return check
+}
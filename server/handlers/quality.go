@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"handbook-scraper/utils"
+	"handbook-scraper/utils/databases"
+	"handbook-scraper/utils/log"
+)
+
+// qualitySummary holds the data quality counters of a single year and item type
+type qualitySummary struct {
+	Year                   string `json:"year" bson:"year"`
+	ItemType               string `json:"item_type" bson:"item_type"`
+	Documents              int    `json:"documents" bson:"documents"`
+	EmptySynopsis          int    `json:"empty_synopsis" bson:"empty_synopsis"`                     // Units without a synopsis
+	ZeroOfferings          int    `json:"zero_offerings" bson:"zero_offerings"`                     // Units without any offering
+	ParseWarnings          int    `json:"parse_warnings" bson:"parse_warnings"`                     // Documents missing their code, title, or faculty
+	CurriculumErrors       int    `json:"curriculum_errors" bson:"curriculum_errors"`               // Courses and areas of study whose curriculum failed to parse
+	RequisiteParseFailures int    `json:"requisite_parse_failures" bson:"requisite_parse_failures"` // Units with a requisite container that is neither AND nor OR
+}
+
+// QualityHandler summarises the quality of the stored handbook data per year and item type,
+// so parser breakage can be spotted before users report it.
+func QualityHandler(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	collection := databases.FromContext(ctx).GetMongoDatabase().Collection("handbook")
+	cursor, err := collection.Aggregate(ctx, qualityPipeline())
+	if err != nil {
+		log.Errorf("[QUALITY] Aggregation failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer cursor.Close(ctx)
+
+	summaries := []qualitySummary{}
+	if err := cursor.All(ctx, &summaries); err != nil {
+		log.Errorf("[QUALITY] Decoding failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	sort.Slice(summaries, func(i, j int) bool {
+		if summaries[i].Year != summaries[j].Year {
+			return summaries[i].Year > summaries[j].Year
+		}
+		return summaries[i].ItemType < summaries[j].ItemType
+	})
+
+	// Fields which moved between handbook revisions show up as matches of their fallback paths
+	c.JSON(http.StatusOK, gin.H{"generated_at": time.Now(), "summaries": summaries, "path_matches": utils.PathMatches()})
+}
+
+// qualityPipeline groups the handbook documents by the year and item type in their URL
+// (https://handbook.monash.edu/<year>/<type>/<code>) and counts each kind of problem.
+func qualityPipeline() []bson.M {
+	countIf := func(condition bson.M) bson.M {
+		return bson.M{"$sum": bson.M{"$cond": bson.A{condition, 1, 0}}}
+	}
+	isEmpty := func(field string) bson.M {
+		return bson.M{"$in": bson.A{bson.M{"$ifNull": bson.A{field, ""}}, bson.A{""}}}
+	}
+	isUnit := bson.M{"$eq": bson.A{"$item_type", "units"}}
+
+	badContainers := bson.M{"$filter": bson.M{
+		"input": bson.M{"$ifNull": bson.A{"$$requisite.containers", bson.A{}}},
+		"as":    "container",
+		"cond":  bson.M{"$not": bson.A{bson.M{"$in": bson.A{"$$container.relationship", bson.A{"AND", "OR"}}}}},
+	}}
+	badRequisites := bson.M{"$filter": bson.M{
+		"input": bson.M{"$ifNull": bson.A{"$requisites", bson.A{}}},
+		"as":    "requisite",
+		"cond":  bson.M{"$gt": bson.A{bson.M{"$size": badContainers}, 0}},
+	}}
+
+	return []bson.M{
+		{"$match": bson.M{"_id": bson.M{"$regex": "^https://handbook\\.monash\\.edu/"}}},
+		{"$addFields": bson.M{
+			"year":      bson.M{"$arrayElemAt": bson.A{bson.M{"$split": bson.A{"$_id", "/"}}, 3}},
+			"item_type": bson.M{"$arrayElemAt": bson.A{bson.M{"$split": bson.A{"$_id", "/"}}, 4}},
+		}},
+		{"$group": bson.M{
+			"_id":                      bson.M{"year": "$year", "item_type": "$item_type"},
+			"documents":                bson.M{"$sum": 1},
+			"empty_synopsis":           countIf(bson.M{"$and": bson.A{isUnit, isEmpty("$synopsis")}}),
+			"zero_offerings":           countIf(bson.M{"$and": bson.A{isUnit, bson.M{"$eq": bson.A{bson.M{"$size": bson.M{"$ifNull": bson.A{"$unit_offerings", bson.A{}}}}, 0}}}}),
+			"parse_warnings":           countIf(bson.M{"$or": bson.A{isEmpty("$common.code"), isEmpty("$common.title"), isEmpty("$common.faculty")}}),
+			"curriculum_errors":        countIf(bson.M{"$eq": bson.A{"$curriculum_error", true}}),
+			"requisite_parse_failures": countIf(bson.M{"$and": bson.A{isUnit, bson.M{"$gt": bson.A{bson.M{"$size": badRequisites}, 0}}}}),
+		}},
+		{"$project": bson.M{
+			"_id":                      0,
+			"year":                     "$_id.year",
+			"item_type":                "$_id.item_type",
+			"documents":                1,
+			"empty_synopsis":           1,
+			"zero_offerings":           1,
+			"parse_warnings":           1,
+			"curriculum_errors":        1,
+			"requisite_parse_failures": 1,
+		}},
+	}
+}
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"handbook-scraper/utils/databases"
+	"handbook-scraper/utils/log"
+)
+
+// rawPayload is the raw JSON of a handbook page, kept so the page can be re-parsed without fetching it again
+type rawPayload struct {
+	URLKey    string    `json:"url_key"`
+	FetchedAt time.Time `json:"fetched_at"`
+	Payload   string    `json:"payload"` // Kept as a string, as the raw keys are not always valid BSON field names
+}
+
+// rawStoreEnabled reports whether RAW_STORE_ENABLED enables keeping the raw payloads of scraped pages
+func rawStoreEnabled() bool {
+	enabled, _ := strconv.ParseBool(os.Getenv("RAW_STORE_ENABLED"))
+	return enabled
+}
+
+// storeRaw keeps the raw JSON of a scraped page if the raw store is enabled
+func storeRaw(ctx context.Context, baseURL string, urlKey string, data map[string]interface{}) {
+	if !rawStoreEnabled() {
+		return
+	}
+
+	payload, err := json.Marshal(data)
+	if err != nil {
+		log.Errorf("Error marshalling raw payload: %v", err)
+		return
+	}
+
+	raw := rawPayload{URLKey: urlKey, FetchedAt: time.Now(), Payload: string(payload)}
+	if err := databases.FromContext(ctx).Store(databases.Raw, baseURL, raw, 0); err != nil {
+		log.Errorf("Error saving raw payload: %v", err)
+	}
+}
+
+// reparse re-runs the scraper over the stored raw payload of a page and overwrites its stored document
+func reparse(ctx context.Context, baseURL string) (interface{}, error) {
+	scraped, err := reparseItem(ctx, baseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := databases.FromContext(ctx).Store(databases.Handbook, baseURL, scraped, time.Hour*144); err != nil {
+		return nil, fmt.Errorf("failed to save to cache: %w", err)
+	}
+	return scraped, nil
+}
+
+// reparseItem re-runs the scraper over the stored raw payload of a page without storing the result
+func reparseItem(ctx context.Context, baseURL string) (interface{}, error) {
+	var raw rawPayload
+	if err := databases.FromContext(ctx).Retrieve(databases.Raw, baseURL, &raw); err != nil {
+		return nil, fmt.Errorf("no raw payload stored for %s", baseURL)
+	}
+
+	var data map[string]interface{}
+	if err := json.Unmarshal([]byte(raw.Payload), &data); err != nil {
+		return nil, fmt.Errorf("failed to decode raw payload: %w", err)
+	}
+
+	scraped, err := scrapeData(raw.URLKey, data, baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scrape data: %w", err)
+	}
+	log.Infof("[REPARSE] %s from payload fetched %s", baseURL, raw.FetchedAt.Format(time.RFC3339))
+	return scraped, nil
+}
+
+// ReparseYearHandler starts a reparse_year job, re-running the scrapers over every stored raw payload of a year
+func ReparseYearHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	year, ok := yearParam(c)
+	if !ok {
+		return
+	}
+
+	params, _ := json.Marshal(reparseYearParams{Year: year, ItemType: c.Query("item_type")})
+	job, err := dependencies(ctx).jobs.Submit("reparse_year", params)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, job)
+}
+
+// ReparseHandler re-runs the scraper over the stored raw payload of a page,
+// so scraper fixes can be applied without fetching the page from the handbook again
+func ReparseHandler(c *gin.Context) {
+	urlKey := c.Param("type")
+	code := strings.ToUpper(c.Param("code"))
+	if !ValidCode(urlKey, code) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("malformed %s code: %s", urlKey, c.Param("code"))})
+		return
+	}
+
+	year, ok := yearParam(c)
+	if !ok {
+		return
+	}
+
+	scraped, err := reparse(c.Request.Context(), handbookURL(year, urlKey, code))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, scraped)
+}
@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// errReadOnly is returned instead of fetching a page which is not stored, when upstream fetches are disabled
+var errReadOnly = errors.New("not stored, and upstream fetches are disabled on this read-only server")
+
+// upstreamJobTypes are the job types which fetch from upstream, so are refused in read-only mode
+var upstreamJobTypes = map[string]bool{
+	"crawl_year":         true,
+	"import_pdf_archive": true,
+}
+
+// ReadOnly reports whether the server of a context only serves stored data, never fetching from upstream,
+// such as a mirror or an archive of a past year
+func ReadOnly(ctx context.Context) bool {
+	return dependencies(ctx).readOnly
+}
+
+// readOnlyStatus is the status of a request for a page which is not stored on a read-only server.
+// Past years of the handbook are frozen, so their missing pages are gone for good.
+func readOnlyStatus(c *gin.Context) int {
+	return readOnlyYearStatus(c.Param("year"))
+}
+
+// readOnlyYearStatus is the status of an item of a year which is not stored while the server is read-only
+func readOnlyYearStatus(year string) int {
+	if year, err := strconv.Atoi(year); err == nil && year < time.Now().Year() {
+		return http.StatusGone
+	}
+	return http.StatusNotFound
+}
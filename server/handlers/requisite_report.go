@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"handbook-scraper/scrapers/units"
+	"handbook-scraper/utils/databases"
+	"handbook-scraper/utils/log"
+)
+
+// requisiteReportTTL is how long the requisite report of a year is kept
+const requisiteReportTTL = 7 * 24 * time.Hour
+
+// requisiteReportParams are the parameters of a requisite_report job
+type requisiteReportParams struct {
+	Year string `json:"year"`
+}
+
+// requisiteReport is the stored result of a requisite_report job
+type requisiteReport struct {
+	Year        string    `json:"year"`
+	GeneratedAt time.Time `json:"generated_at"`
+	units.RequisiteReport
+}
+
+// requisiteReportKey is the cache key of the requisite report of a year
+func requisiteReportKey(year string) string {
+	return "requisite_report:" + year
+}
+
+// requisiteReportJob analyses the requisites of every stored unit of a year for cycles and impossible structures.
+// These point to errors in the handbook or bugs in the requisite parser.
+func requisiteReportJob(ctx context.Context, raw json.RawMessage) (interface{}, error) {
+	var params requisiteReportParams
+	if err := decodeParams(raw, &params); err != nil {
+		return nil, err
+	}
+	if params.Year == "" {
+		return nil, fmt.Errorf("year is required")
+	}
+
+	dbHandler, release := databases.Hold(ctx)
+	defer release()
+	keys, err := storedItemKeys(ctx, params.Year, "units")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stored units: %w", err)
+	}
+
+	unitsByCode := make(map[string]units.UnitData, len(keys))
+	for _, key := range keys {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		var unitData units.UnitData
+		if err := dbHandler.Retrieve(databases.Handbook, key, &unitData); err != nil {
+			log.Errorf("[REQUISITE REPORT] Error retrieving %s: %v", key, err)
+			continue
+		}
+		if unitData.Code != "" {
+			unitsByCode[unitData.Code] = unitData
+		}
+	}
+
+	report := requisiteReport{Year: params.Year, GeneratedAt: time.Now(), RequisiteReport: units.AnalyseRequisites(unitsByCode)}
+	if err := dbHandler.Store(databases.Cache, requisiteReportKey(params.Year), report, requisiteReportTTL); err != nil {
+		log.Errorf("[REQUISITE REPORT] Error saving report for %s: %v", params.Year, err)
+	}
+	log.Infof("[REQUISITE REPORT] %s: %d cycles and %d impossible requisites across %d units",
+		params.Year, len(report.Cycles), len(report.Impossible), report.Units)
+
+	return report, nil
+}
+
+// RequisiteReportHandler starts a requisite_report job for a year
+func RequisiteReportHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	year, ok := yearParam(c)
+	if !ok {
+		return
+	}
+
+	params, _ := json.Marshal(requisiteReportParams{Year: year})
+	job, err := dependencies(ctx).jobs.Submit("requisite_report", params)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, job)
+}
+
+// GetRequisiteReportHandler returns the latest requisite report of a year
+func GetRequisiteReportHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	year, ok := yearParam(c)
+	if !ok {
+		return
+	}
+
+	var report requisiteReport
+	if err := databases.FromContext(ctx).Retrieve(databases.Cache, requisiteReportKey(year), &report); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("no requisite report for %s, run one with POST /v1/admin/requisite_report/%s", year, year)})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gocolly/colly/v2"
+	"handbook-scraper/scrapers/units"
+)
+
+// RequisiteTextHandler renders the requisites of a unit as a paragraph in the phrasing of the handbook,
+// for chatbots and voice assistants. With format=text the paragraph is returned as plain text.
+func RequisiteTextHandler(c *gin.Context, collector *colly.Collector) {
+	code := c.Param("code")
+
+	year, ok := yearParam(c)
+	if !ok {
+		return
+	}
+
+	unitData, err := fetchUnit(c.Request.Context(), year, code, collector)
+	if err != nil {
+		respondWithScrapeError(c, err)
+		return
+	}
+
+	text := units.RequisiteParagraph(unitData)
+	if c.Query("format") == "text" {
+		c.String(http.StatusOK, text)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": unitData.Code, "text": text, "requisites": units.RenderRequisites(unitData)})
+}
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Forms of requisites a unit response can include, chosen with ?requisites=
+const (
+	requisitesCompressed = "compressed" // The compressed requisites only, the default
+	requisitesRaw        = "raw"        // The requisites as the handbook lists them only
+	requisitesBoth       = "both"       // Both side by side
+)
+
+// requisitesView reads the form of requisites a unit response should include, responding with 400 if it is unknown
+func requisitesView(c *gin.Context) (string, bool) {
+	switch view := c.DefaultQuery("requisites", requisitesCompressed); view {
+	case requisitesCompressed, requisitesRaw, requisitesBoth:
+		return view, true
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid requisites %q, expected raw, compressed or both", view)})
+		return "", false
+	}
+}
+
+// withRequisitesView keeps the forms of requisites of a unit asked for and drops the others
+func withRequisitesView(data interface{}, view string) (interface{}, error) {
+	var unit map[string]interface{}
+	if err := decodeInto(data, &unit); err != nil {
+		return nil, err
+	}
+
+	switch view {
+	case requisitesCompressed:
+		delete(unit, "raw_requisites")
+	case requisitesRaw:
+		delete(unit, "requisites")
+		fallthrough
+	case requisitesBoth:
+		// Units without requisites, or stored before raw requisites were kept, have none
+		if _, ok := unit["raw_requisites"]; !ok {
+			unit["raw_requisites"] = []interface{}{}
+		}
+	}
+	return unit, nil
+}
@@ -0,0 +1,217 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"handbook-scraper/utils/databases"
+	"handbook-scraper/utils/log"
+)
+
+// retentionLockTTL is how long the retention lock is held before it must be refreshed
+const retentionLockTTL = time.Minute
+
+// retentionRule purges the documents of a storage type once their timestamp field is older than the rule's period
+type retentionRule struct {
+	Name          string
+	StorageType   databases.StorageType
+	Field         string
+	Env           string
+	DefaultPeriod time.Duration
+	Keep          func(ctx context.Context) ([]string, error) // Keys kept whatever their age
+}
+
+// retentionRules are the rules enforced by the enforce_retention job.
+// Planner sessions are not persisted, so there are no saved plans to purge.
+var retentionRules = []retentionRule{
+	{
+		Name:          "raw_payloads",
+		StorageType:   databases.Raw,
+		Field:         "fetched_at",
+		Env:           "RETENTION_RAW_PAYLOADS",
+		DefaultPeriod: 30 * 24 * time.Hour,
+	},
+	{
+		Name:          "unit_versions",
+		StorageType:   databases.Version,
+		Field:         "scraped_at",
+		Env:           "RETENTION_UNIT_VERSIONS",
+		DefaultPeriod: 365 * 24 * time.Hour,
+		Keep:          pinnedVersionKeys,
+	},
+}
+
+// retentionPeriod returns how long a rule keeps documents, from its environment variable.
+// Periods are Go durations or whole days such as 30d, and a period of 0 disables the rule.
+func retentionPeriod(rule retentionRule) time.Duration {
+	raw := strings.TrimSpace(os.Getenv(rule.Env))
+	if raw == "" {
+		return rule.DefaultPeriod
+	}
+	if days, ok := strings.CutSuffix(raw, "d"); ok {
+		if parsed, err := strconv.Atoi(days); err == nil && parsed >= 0 {
+			return time.Duration(parsed) * 24 * time.Hour
+		}
+	}
+	if parsed, err := time.ParseDuration(raw); err == nil && parsed >= 0 {
+		return parsed
+	}
+	log.Warnf("[RETENTION] Invalid %s %q, using %s", rule.Env, raw, rule.DefaultPeriod)
+	return rule.DefaultPeriod
+}
+
+// pinnedVersionKeys returns the keys of the versions of units pinned for any tenant, which are kept while pinned
+func pinnedVersionKeys(ctx context.Context) ([]string, error) {
+	pins, err := loadVersionPins(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(pins))
+	for pinKey, version := range pins {
+		// Pins of a tenant are keyed by the item's URL followed by the tenant
+		baseURL, _, _ := strings.Cut(strings.TrimPrefix(pinKey, versionPinKey("")), "#")
+		keys = append(keys, versionKey(baseURL, version))
+	}
+	return keys, nil
+}
+
+// retentionRuleStats counts the documents purged by a rule since the process started
+type retentionRuleStats struct {
+	Name        string     `json:"name"`
+	Period      string     `json:"period"` // Empty if the rule is disabled
+	LastRun     *time.Time `json:"last_run,omitempty"`
+	LastPurged  int64      `json:"last_purged"`
+	TotalPurged int64      `json:"total_purged"`
+	LastError   string     `json:"last_error,omitempty"`
+}
+
+// retentionStats holds the counters of each rule, by name
+type retentionStats struct {
+	sync.Mutex
+	rules map[string]*retentionRuleStats
+}
+
+// recordRetention updates the counters of a rule after it runs
+func recordRetention(ctx context.Context, name string, purged int64, err error) {
+	retention := &dependencies(ctx).retention
+	retention.Lock()
+	defer retention.Unlock()
+
+	stats, ok := retention.rules[name]
+	if !ok {
+		if retention.rules == nil {
+			retention.rules = map[string]*retentionRuleStats{}
+		}
+		stats = &retentionRuleStats{Name: name}
+		retention.rules[name] = stats
+	}
+	now := time.Now()
+	stats.LastRun = &now
+	stats.LastPurged = purged
+	stats.TotalPurged += purged
+	stats.LastError = ""
+	if err != nil {
+		stats.LastError = err.Error()
+	}
+}
+
+// retentionResult summarises an enforce_retention job
+type retentionResult struct {
+	Purged map[string]int64  `json:"purged"`
+	Failed map[string]string `json:"failed"`
+}
+
+// enforceRetentionJob purges the documents older than the period of each retention rule.
+// Only one replica enforces retention at a time.
+func enforceRetentionJob(ctx context.Context, _ json.RawMessage) (interface{}, error) {
+	result := retentionResult{Purged: map[string]int64{}, Failed: map[string]string{}}
+	dbHandler, release := databases.Hold(ctx)
+	defer release()
+
+	ran, err := dbHandler.RunExclusive("retention", retentionLockTTL, func(lockCtx context.Context) error {
+		for _, rule := range retentionRules {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if lockCtx.Err() != nil {
+				return fmt.Errorf("lost retention lock")
+			}
+
+			period := retentionPeriod(rule)
+			if period == 0 {
+				continue
+			}
+
+			var keep []string
+			if rule.Keep != nil {
+				var err error
+				if keep, err = rule.Keep(ctx); err != nil {
+					// Without the keys to keep, nothing of the rule is purged rather than purging too much
+					result.Failed[rule.Name] = err.Error()
+					recordRetention(ctx, rule.Name, 0, err)
+					continue
+				}
+			}
+
+			purged, err := dbHandler.PurgeBefore(rule.StorageType, rule.Field, time.Now().Add(-period), keep)
+			result.Purged[rule.Name] = purged
+			recordRetention(ctx, rule.Name, purged, err)
+			if err != nil {
+				result.Failed[rule.Name] = err.Error()
+				log.Errorf("[RETENTION] Failed to purge %s: %v", rule.Name, err)
+				continue
+			}
+			log.Infof("[RETENTION] Purged %d %s older than %s", purged, rule.Name, period)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !ran {
+		return nil, fmt.Errorf("retention is already being enforced by another replica")
+	}
+	return result, nil
+}
+
+// RetentionHandler lists the retention rules and how many documents each has purged
+func RetentionHandler(c *gin.Context) {
+	retention := &dependencies(c.Request.Context()).retention
+	retention.Lock()
+	defer retention.Unlock()
+
+	rules := make([]retentionRuleStats, 0, len(retentionRules))
+	for _, rule := range retentionRules {
+		stats := retentionRuleStats{Name: rule.Name}
+		if recorded, ok := retention.rules[rule.Name]; ok {
+			stats = *recorded
+		}
+		if period := retentionPeriod(rule); period > 0 {
+			stats.Period = period.String()
+		}
+		rules = append(rules, stats)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"rules": rules})
+}
+
+// EnforceRetentionHandler starts an enforce_retention job
+func EnforceRetentionHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	job, err := dependencies(ctx).jobs.Submit("enforce_retention", nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, job)
+}
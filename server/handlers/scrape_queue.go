@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"handbook-scraper/utils/log"
+)
+
+const (
+	// defaultScrapeConcurrency is how many cold scrapes run at once unless SCRAPE_CONCURRENCY is set
+	defaultScrapeConcurrency = 8
+	// defaultScrapeQueueSize is how many cold scrapes can wait for a slot unless SCRAPE_QUEUE_SIZE is set
+	defaultScrapeQueueSize = 64
+	// defaultScrapeQueueTimeout is how long a cold scrape waits for a slot unless SCRAPE_QUEUE_TIMEOUT is set
+	defaultScrapeQueueTimeout = 10 * time.Second
+)
+
+// overloadedError is returned when too many cold scrapes are running and queued, so clients should retry later
+type overloadedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *overloadedError) Error() string {
+	return fmt.Sprintf("too many pages are being fetched from the handbook, retry after %s", e.RetryAfter.Round(time.Second))
+}
+
+// scrapeQueue bounds the cold scrapes made for requests, which fetch pages missing from the cache from the handbook.
+// Scrapes beyond the upstream budget wait in a bounded queue, and are rejected once it is full or they wait too long,
+// so a load spike cannot grow latency and goroutines without bound.
+type scrapeQueue struct {
+	slots    chan struct{}
+	waiting  atomic.Int64
+	maxQueue int64
+	timeout  time.Duration
+	rejected atomic.Int64 // When a scrape was last rejected, in Unix nanoseconds
+}
+
+// coldScrapes is the queue of the cold scrapes of this replica, configured from the environment on first use
+var coldScrapes = sync.OnceValue(func() *scrapeQueue {
+	return &scrapeQueue{
+		slots:    make(chan struct{}, envInt("SCRAPE_CONCURRENCY", defaultScrapeConcurrency, 1)),
+		maxQueue: int64(envInt("SCRAPE_QUEUE_SIZE", defaultScrapeQueueSize, 0)),
+		timeout:  envDuration("SCRAPE_QUEUE_TIMEOUT", defaultScrapeQueueTimeout),
+	}
+})
+
+// acquire takes a slot for a cold scrape, waiting in the queue if every slot is taken.
+// The returned function releases the slot once the scrape is done.
+func (q *scrapeQueue) acquire(ctx context.Context) (func(), error) {
+	release := func() { <-q.slots }
+	select {
+	case q.slots <- struct{}{}:
+		return release, nil
+	default:
+	}
+
+	if q.waiting.Add(1) > q.maxQueue {
+		q.waiting.Add(-1)
+		log.Warnf("[SCRAPE QUEUE] Rejecting a cold scrape, %d are running and %d queued", cap(q.slots), q.maxQueue)
+		q.rejected.Store(time.Now().UnixNano())
+		return nil, &overloadedError{RetryAfter: q.timeout}
+	}
+	defer q.waiting.Add(-1)
+
+	timer := time.NewTimer(q.timeout)
+	defer timer.Stop()
+	select {
+	case q.slots <- struct{}{}:
+		return release, nil
+	case <-timer.C:
+		log.Warnf("[SCRAPE QUEUE] A cold scrape waited %s without a slot", q.timeout)
+		q.rejected.Store(time.Now().UnixNano())
+		return nil, &overloadedError{RetryAfter: q.timeout}
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// running returns how many cold scrapes hold a slot
+func (q *scrapeQueue) running() int {
+	return len(q.slots)
+}
+
+// lastRejected returns when a cold scrape was last rejected, or the zero time if none was
+func (q *scrapeQueue) lastRejected() time.Time {
+	if rejected := q.rejected.Load(); rejected != 0 {
+		return time.Unix(0, rejected)
+	}
+	return time.Time{}
+}
+
+// queued returns how many cold scrapes are waiting for a slot
+func (q *scrapeQueue) queued() int {
+	return int(q.waiting.Load())
+}
+
+// envInt reads a whole number of at least minimum from the environment, falling back to a default if unset or invalid
+func envInt(name string, fallback int, minimum int) int {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return fallback
+	}
+	value, err := strconv.Atoi(raw)
+	if err != nil || value < minimum {
+		log.Warnf("[CONFIG] Invalid %s %q, using %d", name, raw, fallback)
+		return fallback
+	}
+	return value
+}
+
+// envDuration reads a positive duration from the environment, falling back to a default if unset or invalid
+func envDuration(name string, fallback time.Duration) time.Duration {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return fallback
+	}
+	value, err := time.ParseDuration(raw)
+	if err != nil || value <= 0 {
+		log.Warnf("[CONFIG] Invalid %s %q, using %s", name, raw, fallback)
+		return fallback
+	}
+	return value
+}
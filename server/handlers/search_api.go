@@ -10,8 +10,9 @@ import (
 )
 
 func GetHandbookSearchAPI(c *gin.Context, collector *colly.Collector) {
+	ctx := c.Request.Context()
 
-	dbHandler := databases.GetDatabaseHandler()
+	dbHandler := databases.FromContext(ctx)
 
 	// Check cache
 	var cachedData string
@@ -21,6 +22,11 @@ func GetHandbookSearchAPI(c *gin.Context, collector *colly.Collector) {
 		return
 	}
 
+	if ReadOnly(ctx) {
+		respondWithScrapeError(c, errReadOnly)
+		return
+	}
+
 	// Get the handbook search URL
 	result, err := common.ExtractRawJSON("https://handbook.monash.edu/search", collector)
 	if err != nil {
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"handbook-scraper/scrapers/common"
+	"handbook-scraper/utils/databases"
+	"handbook-scraper/utils/log"
+)
+
+// Service statuses, reported with every response so clients can warn that data may be out of date
+const (
+	ServiceOK       = "ok"       // Upstream and the databases are healthy, and the data is fresh
+	ServiceDegraded = "degraded" // The handbook or the databases are failing or overloaded, so uncached data may be missing
+	ServiceStale    = "stale"    // Scheduled crawls have not succeeded lately, so stored data may be out of date
+)
+
+const (
+	// serviceStatusTTL is how long the service status is reused before it is checked again
+	serviceStatusTTL = 15 * time.Second
+	// overloadWindow is how long the service is degraded after the scrape queue rejects a scrape
+	overloadWindow = time.Minute
+	// defaultStaleAfter is how long after the last successful scheduled crawl data is stale, unless SERVICE_STALE_AFTER is set
+	defaultStaleAfter = 48 * time.Hour
+)
+
+// serviceStatus is the latest service status, checked again in the background once it expires
+type serviceStatus struct {
+	sync.Mutex
+	status     string
+	reasons    []string
+	checkedAt  time.Time
+	refreshing bool
+}
+
+// ServiceStatus returns the status of the service of a context, ok, degraded or stale, and the reasons it is not ok.
+// It never waits on the checks: an expired status is returned while it is checked again in the background.
+func ServiceStatus(ctx context.Context) (string, []string) {
+	current := &dependencies(ctx).serviceStatus
+	current.Lock()
+	defer current.Unlock()
+	if time.Since(current.checkedAt) > serviceStatusTTL && !current.refreshing {
+		current.refreshing = true
+		go refreshServiceStatus(context.WithoutCancel(ctx))
+	}
+	return current.status, current.reasons
+}
+
+// refreshServiceStatus checks the service status again
+func refreshServiceStatus(ctx context.Context) {
+	status, reasons := checkServiceStatus(ctx)
+	current := &dependencies(ctx).serviceStatus
+	current.Lock()
+	if status != current.status {
+		log.Infof("[SERVICE STATUS] The service is %s %v", status, reasons)
+	}
+	current.status, current.reasons = status, reasons
+	current.checkedAt = time.Now()
+	current.refreshing = false
+	current.Unlock()
+}
+
+// checkServiceStatus finds why the service is degraded or its data stale. A degraded service takes precedence,
+// and both kinds of reasons are listed.
+func checkServiceStatus(ctx context.Context) (string, []string) {
+	var degraded, stale []string
+	if pause := common.ThrottlePause(); pause > 0 {
+		degraded = append(degraded, fmt.Sprintf("the handbook is throttling requests for another %s", pause.Round(time.Second)))
+	}
+	if rejected := coldScrapes().lastRejected(); !rejected.IsZero() && time.Since(rejected) < overloadWindow {
+		degraded = append(degraded, "too many pages are being fetched from the handbook")
+	}
+
+	if err := databases.FromContext(ctx).Ping(ctx); err != nil {
+		degraded = append(degraded, err.Error())
+	} else if !ReadOnly(ctx) {
+		// Read-only servers serve a snapshot by design, so their data is never stale
+		staleAfter := envDuration("SERVICE_STALE_AFTER", defaultStaleAfter)
+		for _, itemType := range ScheduledCrawlItemTypes {
+			health := loadCrawlHealth(ctx, itemType)
+			switch {
+			case health.LastSuccess != nil && time.Since(*health.LastSuccess) > staleAfter:
+				stale = append(stale, fmt.Sprintf("the %s crawl last succeeded %s ago", itemType, time.Since(*health.LastSuccess).Round(time.Hour)))
+			case health.LastSuccess == nil && health.ConsecutiveFailures > 0:
+				stale = append(stale, fmt.Sprintf("the %s crawl has never succeeded", itemType))
+			}
+		}
+	}
+
+	reasons := append(append([]string{}, degraded...), stale...)
+	switch {
+	case len(degraded) > 0:
+		return ServiceDegraded, reasons
+	case len(stale) > 0:
+		return ServiceStale, reasons
+	default:
+		return ServiceOK, reasons
+	}
+}
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gocolly/colly/v2"
+	"handbook-scraper/planner"
+	"handbook-scraper/scrapers/area_of_study"
+	"handbook-scraper/scrapers/common"
+)
+
+// maxSpecialisations limits how many specialisations a request can choose
+const maxSpecialisations = 5
+
+// resolveSpecialisations retrieves the chosen majors, minors or extended majors and resolves them into a curriculum,
+// responding with an error if one is invalid or not listed by the curriculum
+func resolveSpecialisations(c *gin.Context, year string, collector *colly.Collector, curriculum common.Curriculum, codes []string) (common.Curriculum, map[string]planner.Specialisation, bool) {
+	codes = uniqueCodes(codes)
+	if len(codes) > maxSpecialisations {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("at most %d specialisations can be chosen", maxSpecialisations)})
+		return curriculum, nil, false
+	}
+
+	chosen := make(map[string]planner.Specialisation, len(codes))
+	for _, code := range codes {
+		if !ValidCode("aos", code) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid specialisation code %q", code)})
+			return curriculum, nil, false
+		}
+
+		data, err := ScrapeAndCache(c.Request.Context(), handbookURL(year, "aos", code), collector, "aos")
+		if err != nil {
+			respondWithScrapeError(c, err)
+			return curriculum, nil, false
+		}
+		var aosData area_of_study.AosData
+		if err := decodeInto(data, &aosData); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to decode area of study data"})
+			return curriculum, nil, false
+		}
+		if aosData.CurriculumError {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": fmt.Sprintf("the curriculum of specialisation %s could not be parsed", code)})
+			return curriculum, nil, false
+		}
+
+		chosen[code] = planner.Specialisation{
+			Code:       code,
+			Title:      aosData.Title,
+			Type:       aosData.SpecificAosType,
+			Curriculum: aosData.CurriculumStructure,
+		}
+	}
+
+	resolved, unused := planner.ResolveSpecialisations(curriculum, chosen)
+	if len(unused) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("specialisations not offered by this curriculum: %s", strings.Join(unused, ", "))})
+		return curriculum, nil, false
+	}
+	return resolved, chosen, true
+}
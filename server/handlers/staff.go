@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"handbook-scraper/scrapers/units"
+	"handbook-scraper/utils/log"
+)
+
+// facultyStaffMember aggregates the roles and units of a staff member within a faculty
+type facultyStaffMember struct {
+	Name  string   `json:"name"`
+	Roles []string `json:"roles"`
+	Units []string `json:"units"`
+}
+
+// FacultyStaffHandler aggregates the staff of every stored unit of a year by faculty.
+// An optional faculty query parameter limits the result to a single faculty.
+func FacultyStaffHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	facultyFilter := c.Query("faculty")
+
+	year, ok := yearParam(c)
+	if !ok {
+		return
+	}
+
+	keys, err := storedItemKeys(ctx, year, "units")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	base := handbookURL(year, "units", "")
+	codes := make([]string, 0, len(keys))
+	for _, key := range keys {
+		codes = append(codes, strings.TrimPrefix(key, base))
+	}
+
+	// faculty -> staff name -> member
+	faculties := map[string]map[string]*facultyStaffMember{}
+	for start := 0; start < len(codes); start += dumpBatchSize {
+		batch := codes[start:min(start+dumpBatchSize, len(codes))]
+		stored := retrieveStoredMany(ctx, year, "units", batch)
+		for _, code := range batch {
+			var unitData units.UnitData
+			if data, ok := stored[code]; !ok || decodeInto(data, &unitData) != nil {
+				log.Errorf("[STAFF] Error retrieving %s", base+code)
+				continue
+			}
+			if facultyFilter != "" && !strings.EqualFold(unitData.Faculty, facultyFilter) {
+				continue
+			}
+
+			members, ok := faculties[unitData.Faculty]
+			if !ok {
+				members = map[string]*facultyStaffMember{}
+				faculties[unitData.Faculty] = members
+			}
+
+			for _, staff := range unitData.Staff {
+				member, ok := members[staff.Name]
+				if !ok {
+					member = &facultyStaffMember{Name: staff.Name, Roles: []string{}, Units: []string{}}
+					members[staff.Name] = member
+				}
+				member.Roles = appendUnique(member.Roles, staff.Role)
+				member.Units = appendUnique(member.Units, unitData.Code)
+			}
+		}
+	}
+
+	result := map[string][]facultyStaffMember{}
+	for faculty, members := range faculties {
+		list := make([]facultyStaffMember, 0, len(members))
+		for _, member := range members {
+			list = append(list, *member)
+		}
+		sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+		result[faculty] = list
+	}
+
+	respondWithFields(c, gin.H{"year": year, "faculties": result})
+}
+
+// appendUnique appends the value if it is non-empty and not already present
+func appendUnique(values []string, value string) []string {
+	if value == "" {
+		return values
+	}
+	for _, existing := range values {
+		if existing == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"handbook-scraper/utils/log"
+)
+
+// dumpBatchSize is how many stored items are retrieved at once while dumping
+const dumpBatchSize = 500
+
+// DumpManifest is the top-level index of a static dump, listing how many items of each type every year has
+type DumpManifest struct {
+	GeneratedAt time.Time                 `json:"generated_at"`
+	Years       map[string]map[string]int `json:"years"` // Year to type to item count
+}
+
+// dumpIndex lists the items of a type in a year of a static dump, in code order
+type dumpIndex struct {
+	Year        string        `json:"year"`
+	Type        string        `json:"type"`
+	GeneratedAt time.Time     `json:"generated_at"`
+	Items       []itemSummary `json:"items"`
+}
+
+// DumpStatic renders the stored handbook data into a directory of JSON files which can be served as is, such as from
+// a CDN. Each item is written to <year>/<type>/<code>.json, with curriculum patches applied, alongside an index.json
+// of the summaries of its type, and the dump has an index.json of its years. Without years, every year of the handbook
+// window with stored data is dumped. The dump is written next to dir and swapped in once complete, replacing dir.
+func DumpStatic(ctx context.Context, dir string, years []string) (DumpManifest, error) {
+	manifest := DumpManifest{GeneratedAt: time.Now().UTC(), Years: map[string]map[string]int{}}
+
+	if len(years) == 0 {
+		minYear, maxYear := yearWindow()
+		for year := minYear; year <= maxYear; year++ {
+			years = append(years, strconv.Itoa(year))
+		}
+	} else {
+		for i, year := range years {
+			resolved, err := resolveYear(ctx, year)
+			if err != nil {
+				return manifest, err
+			}
+			years[i] = resolved
+		}
+	}
+
+	dir = filepath.Clean(dir)
+	staging := dir + ".partial"
+	if err := os.RemoveAll(staging); err != nil {
+		return manifest, err
+	}
+
+	for _, year := range years {
+		for _, urlKey := range []string{"units", "courses", "aos"} {
+			count, err := dumpItems(ctx, staging, year, urlKey, manifest.GeneratedAt)
+			if err != nil {
+				return manifest, fmt.Errorf("failed to dump the %s %s: %w", year, urlKey, err)
+			}
+			if count == 0 {
+				continue
+			}
+			if manifest.Years[year] == nil {
+				manifest.Years[year] = map[string]int{}
+			}
+			manifest.Years[year][urlKey] = count
+		}
+	}
+
+	if err := writeDumpFile(filepath.Join(staging, "index.json"), manifest); err != nil {
+		return manifest, err
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return manifest, err
+	}
+	if err := os.Rename(staging, dir); err != nil {
+		return manifest, err
+	}
+	return manifest, nil
+}
+
+// dumpItems writes the stored items of a type in a year and their index, and returns how many were written.
+// Nothing is written if the year has no stored items of the type.
+func dumpItems(ctx context.Context, staging string, year string, urlKey string, generatedAt time.Time) (int, error) {
+	keys, err := storedItemKeys(ctx, year, urlKey)
+	if err != nil {
+		return 0, err
+	}
+	base := handbookURL(year, urlKey, "")
+	codes := make([]string, 0, len(keys))
+	for _, key := range keys {
+		codes = append(codes, strings.TrimPrefix(key, base))
+	}
+	sort.Strings(codes)
+	if len(codes) == 0 {
+		return 0, nil
+	}
+
+	typeDir := filepath.Join(staging, year, urlKey)
+	if err := os.MkdirAll(typeDir, 0o755); err != nil {
+		return 0, err
+	}
+
+	index := dumpIndex{Year: year, Type: urlKey, GeneratedAt: generatedAt, Items: make([]itemSummary, 0, len(codes))}
+	for start := 0; start < len(codes); start += dumpBatchSize {
+		batch := codes[start:min(start+dumpBatchSize, len(codes))]
+		stored := retrieveStoredMany(ctx, year, urlKey, batch)
+		for _, code := range batch {
+			data, ok := stored[code]
+			if !ok {
+				log.Warnf("[DUMP] Skipping %s, which could not be retrieved", base+code)
+				continue
+			}
+			if err := writeDumpFile(filepath.Join(typeDir, code+".json"), data); err != nil {
+				return 0, err
+			}
+			index.Items = append(index.Items, summariseItem(code, data))
+		}
+	}
+
+	if err := writeDumpFile(filepath.Join(typeDir, "index.json"), index); err != nil {
+		return 0, err
+	}
+	log.Infof("[DUMP] Wrote %d %s of %s", len(index.Items), urlKey, year)
+	return len(index.Items), nil
+}
+
+// writeDumpFile writes a value as JSON to a file of a static dump
+func writeDumpFile(path string, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"handbook-scraper/utils/databases"
+	"handbook-scraper/utils/log"
+)
+
+const (
+	// summaryIndexTTL is how long the summaries of the stored items of a year are cached, so changed titles and
+	// credit points show up in filtered lists soon after
+	summaryIndexTTL = 10 * time.Minute
+	// summaryBatchSize is how many items are read at once while building a summary index
+	summaryBatchSize = 500
+)
+
+// summaryIndexKey is the cache key of the summary index of a year and item type
+func summaryIndexKey(year string, urlKey string) string {
+	return "summary_index:" + year + ":" + urlKey
+}
+
+// summaryIndex maps the code of each stored item of a year and type to its summary, so lists can be filtered and
+// sorted without loading every item. An index is built once at a time per server, so requests arriving while it is
+// cold wait for the build rather than each reading every item.
+func summaryIndex(ctx context.Context, year string, urlKey string) (map[string]itemSummary, error) {
+	if index := cachedSummaryIndex(ctx, year, urlKey); index != nil {
+		return index, nil
+	}
+
+	building := &dependencies(ctx).summaryBuild
+	building.Lock()
+	defer building.Unlock()
+	if index := cachedSummaryIndex(ctx, year, urlKey); index != nil {
+		return index, nil
+	}
+	return buildSummaryIndex(ctx, year, urlKey)
+}
+
+// cachedSummaryIndex returns the cached summary index of a year and item type, or nil if it is not cached
+func cachedSummaryIndex(ctx context.Context, year string, urlKey string) map[string]itemSummary {
+	var index map[string]itemSummary
+	if err := databases.FromContext(ctx).Retrieve(databases.Cache, summaryIndexKey(year, urlKey), &index); err != nil {
+		return nil
+	}
+	return index
+}
+
+// buildSummaryIndex builds and caches the summary index of a year and item type from its stored items,
+// read summaryBatchSize at a time
+func buildSummaryIndex(ctx context.Context, year string, urlKey string) (map[string]itemSummary, error) {
+	dbHandler, release := databases.Hold(ctx)
+	defer release()
+
+	keys, err := storedItemKeys(ctx, year, urlKey)
+	if err != nil {
+		return nil, err
+	}
+
+	base := handbookURL(year, urlKey, "")
+	index := make(map[string]itemSummary, len(keys))
+	for start := 0; start < len(keys); start += summaryBatchSize {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		batch := keys[start:min(start+summaryBatchSize, len(keys))]
+		found, err := dbHandler.RetrieveMany(databases.Handbook, batch)
+		if err != nil {
+			return nil, fmt.Errorf("failed to retrieve the %s of %s: %w", urlKey, year, err)
+		}
+		for key, raw := range found {
+			var data interface{}
+			if err := json.Unmarshal(raw, &data); err != nil || data == nil {
+				log.Errorf("[LIST] Error decoding %s: %v", key, err)
+				continue
+			}
+			code := key[len(base):]
+			index[code] = summariseItem(code, data)
+		}
+	}
+
+	if err := dbHandler.Store(databases.Cache, summaryIndexKey(year, urlKey), index, summaryIndexTTL); err != nil {
+		log.Errorf("[LIST] Error caching the summary index of %s %s: %v", year, urlKey, err)
+	}
+	return index, nil
+}
+
+// summariesOf returns the summaries of the stored items of codes, in their order, from the summary index.
+// Items stored since the index was built are summarised from their stored data.
+func summariesOf(ctx context.Context, year string, urlKey string, codes []string) ([]itemSummary, error) {
+	index, err := summaryIndex(ctx, year, urlKey)
+	if err != nil {
+		return nil, err
+	}
+
+	var missing []string
+	for _, code := range codes {
+		if _, ok := index[code]; !ok {
+			missing = append(missing, code)
+		}
+	}
+	stored := retrieveStoredMany(ctx, year, urlKey, missing)
+
+	summaries := make([]itemSummary, 0, len(codes))
+	for _, code := range codes {
+		summary, ok := index[code]
+		if !ok {
+			summary = summariseItem(code, stored[code])
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries, nil
+}
@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"handbook-scraper/scrapers/units"
+	"handbook-scraper/utils/databases"
+	"handbook-scraper/utils/log"
+)
+
+// tagIndexTTL is how long the tag index of a year is cached, so newly stored units show up under their tags soon after
+const tagIndexTTL = time.Hour
+
+// tagCount is the entry of a tag in the list of tags
+type tagCount struct {
+	Tag   string `json:"tag"`
+	Units int    `json:"units"`
+}
+
+// tagIndexKey is the cache key of the tag index of a year
+func tagIndexKey(year string) string {
+	return "tag_index:" + year
+}
+
+// tagIndex maps each tag of the stored units of a year to their codes in code order.
+// Units stored before tags were extracted have theirs extracted from their synopsis and learning outcomes.
+func tagIndex(ctx context.Context, year string) (map[string][]string, error) {
+	dbHandler := databases.FromContext(ctx)
+
+	var index map[string][]string
+	if err := dbHandler.Retrieve(databases.Cache, tagIndexKey(year), &index); err == nil && index != nil {
+		return index, nil
+	}
+
+	keys, err := storedItemKeys(ctx, year, "units")
+	if err != nil {
+		return nil, err
+	}
+
+	index = map[string][]string{}
+	for _, key := range keys {
+		var unitData units.UnitData
+		if err := dbHandler.Retrieve(databases.Handbook, key, &unitData); err != nil {
+			log.Errorf("[TAGS] Error retrieving %s: %v", key, err)
+			continue
+		}
+		tags := unitData.Tags
+		if len(tags) == 0 {
+			tags = units.Tags(unitData.Synopsis, unitData.LearningOutcomes)
+		}
+		for _, tag := range tags {
+			index[tag] = append(index[tag], unitData.Code)
+		}
+	}
+	for _, codes := range index {
+		sort.Strings(codes)
+	}
+
+	if err := dbHandler.Store(databases.Cache, tagIndexKey(year), index, tagIndexTTL); err != nil {
+		log.Errorf("[TAGS] Error caching the tag index of %s: %v", year, err)
+	}
+	return index, nil
+}
+
+// TagsHandler lists the tags of the stored units of a year, most common first.
+// An optional q query parameter limits the list to tags containing it.
+func TagsHandler(c *gin.Context) {
+	year, ok := yearParam(c)
+	if !ok {
+		return
+	}
+
+	index, err := tagIndex(c.Request.Context(), year)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	query := strings.ToLower(strings.TrimSpace(c.Query("q")))
+	tags := []tagCount{}
+	for tag, codes := range index {
+		if strings.Contains(tag, query) {
+			tags = append(tags, tagCount{Tag: tag, Units: len(codes)})
+		}
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		if tags[i].Units != tags[j].Units {
+			return tags[i].Units > tags[j].Units
+		}
+		return tags[i].Tag < tags[j].Tag
+	})
+
+	respondWithPage(c, tags, listFields{Filter: []string{"tag", "units"}, Sort: []string{"tag", "units"}})
+}
+
+// TagUnitsHandler lists the stored units of a year tagged with a tag, in code order
+func TagUnitsHandler(c *gin.Context) {
+	year, ok := yearParam(c)
+	if !ok {
+		return
+	}
+	query, ok := parseListQuery(c, itemSummaryFields)
+	if !ok {
+		return
+	}
+
+	index, err := tagIndex(c.Request.Context(), year)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	tag := strings.ToLower(strings.TrimSpace(c.Param("tag")))
+	codes, ok := index[tag]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no units are tagged " + tag})
+		return
+	}
+
+	respondWithSummaries(c, year, "units", codes, query, func(summary itemSummary) itemSummary { return summary })
+}
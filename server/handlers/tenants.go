@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"handbook-scraper/utils/databases"
+	"handbook-scraper/utils/log"
+)
+
+const (
+	// SubjectKey is the context key of who made a request, recorded as the author of admin changes
+	SubjectKey = "subject"
+	// AdminKey is the context key of whether a request was made with the admin role
+	AdminKey = "admin"
+	// anonymousTenant tallies the usage of requests made without an API key
+	anonymousTenant = "anonymous"
+	// usageRetention is how many days of usage are kept
+	usageRetention = 35
+)
+
+type tenantKey struct{}
+
+// WithTenant returns a context whose requests were made by a tenant, the name of the API key they were made with
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// requestTenant returns the tenant of a request, or an empty string if it was made without an API key
+func requestTenant(ctx context.Context) string {
+	tenant, _ := ctx.Value(tenantKey{}).(string)
+	return tenant
+}
+
+// tenantName names a tenant in logs and usage, where requests without an API key are anonymous
+func tenantName(tenant string) string {
+	if tenant == "" {
+		return anonymousTenant
+	}
+	return tenant
+}
+
+// usageKey is the cache key of the usage of a tenant on a day
+func usageKey(tenant string, day string) string {
+	return "usage:" + tenantName(tenant) + ":" + day
+}
+
+// RecordUsage tallies a request of a tenant to a route, for the usage analytics of each tenant
+func RecordUsage(ctx context.Context, tenant string, method string, route string) {
+	if route == "" {
+		return
+	}
+	key := usageKey(tenant, time.Now().UTC().Format(time.DateOnly))
+	if err := databases.FromContext(ctx).IncrementTally(key, method+" "+route, usageRetention*24*time.Hour); err != nil {
+		log.Errorf("[TENANTS] Failed to record usage: %v", err)
+	}
+}
+
+// tenantUsage is the usage of a tenant over the requested days
+type tenantUsage struct {
+	Total  int64                       `json:"total"`
+	Routes map[string]int64            `json:"routes"` // Requests per route over every day
+	Days   map[string]map[string]int64 `json:"days"`   // Requests per route by UTC day
+}
+
+// TenantUsageHandler returns the requests of each tenant per route, over the days query parameter (default 7)
+func TenantUsageHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	days := 7
+	if raw := c.Query("days"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 || parsed > usageRetention {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "days must be between 1 and " + strconv.Itoa(usageRetention)})
+			return
+		}
+		days = parsed
+	}
+
+	dbHandler := databases.FromContext(ctx)
+	usage := map[string]*tenantUsage{}
+	today := time.Now().UTC()
+	for i := 0; i < days; i++ {
+		day := today.AddDate(0, 0, -i).Format(time.DateOnly)
+		keys, err := dbHandler.ListKeys(databases.Cache, "usage:*:"+day)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		for _, key := range keys {
+			tallies, err := dbHandler.Tallies(key)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			tenant := strings.TrimSuffix(strings.TrimPrefix(key, "usage:"), ":"+day)
+			if usage[tenant] == nil {
+				usage[tenant] = &tenantUsage{Routes: map[string]int64{}, Days: map[string]map[string]int64{}}
+			}
+			usage[tenant].Days[day] = tallies
+			for route, count := range tallies {
+				usage[tenant].Routes[route] += count
+				usage[tenant].Total += count
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"days": days, "tenants": usage})
+}
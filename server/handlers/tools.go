@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"encoding/json"
+	"maps"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"handbook-scraper/scrapers/common"
+)
+
+// toolManifest is generated once, like the OpenAPI document
+var toolManifest = sync.OnceValue(buildToolManifest)
+
+// tool is a capability of the API described for LLM agents, in the shape of an MCP tool, with the HTTP request
+// which calls it
+type tool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	InputSchema map[string]interface{} `json:"inputSchema"`
+	HTTP        toolBinding            `json:"http"`
+}
+
+// toolBinding maps the arguments of a tool to an HTTP request. Arguments named in the path, such as {year},
+// are substituted into it, arguments listed in Query are sent as query parameters, and Body names the argument
+// sent as the JSON body, or is "*" for an object of the remaining arguments.
+type toolBinding struct {
+	Method string   `json:"method"`
+	Path   string   `json:"path"`
+	Query  []string `json:"query,omitempty"`
+	Body   string   `json:"body,omitempty"`
+}
+
+// ToolManifestHandler serves the manifest of the tools LLM agents and MCP clients can call: unit and course lookups,
+// questions and searches, requisite checks, and planning
+func ToolManifestHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"tools": toolManifest()})
+}
+
+// buildToolManifest describes the tools. Schemas of request bodies are generated from the handler types.
+func buildToolManifest() []tool {
+	year := map[string]interface{}{"type": "string", "description": "Year of the handbook, e.g. 2025, current or next"}
+	unitCode := map[string]interface{}{"type": "string", "description": "Unit code, e.g. FIT2004"}
+	courseCode := map[string]interface{}{"type": "string", "description": "Course code, e.g. C2001"}
+	completed := toolSchema(reflect.TypeOf([]common.Unit{}))
+	completed["description"] = "Units the student has completed, each with its code"
+
+	return []tool{
+		{
+			Name:        "get_unit",
+			Description: "Look up a unit of the Monash handbook: its synopsis, credit points, offerings, assessments and requisites.",
+			InputSchema: toolObject(map[string]interface{}{"year": year, "code": unitCode}, "year", "code"),
+			HTTP:        toolBinding{Method: http.MethodGet, Path: "/v1/{year}/units/{code}"},
+		},
+		{
+			Name:        "get_course",
+			Description: "Look up a course of the Monash handbook: its requirements, curriculum structure and areas of study.",
+			InputSchema: toolObject(map[string]interface{}{"year": year, "code": courseCode}, "year", "code"),
+			HTTP:        toolBinding{Method: http.MethodGet, Path: "/v1/{year}/courses/{code}"},
+		},
+		{
+			Name: "ask",
+			Description: "Answer a question about units in natural language, such as \"when is FIT2004 offered?\" or " +
+				"\"units about machine learning\", with a short answer and the data it is based on. Use it to search units by topic.",
+			InputSchema: toolObject(map[string]interface{}{
+				"year":     year,
+				"question": map[string]interface{}{"type": "string", "description": "The question"},
+			}, "year", "question"),
+			HTTP: toolBinding{Method: http.MethodPost, Path: "/v1/{year}/answer", Body: "*"},
+		},
+		{
+			Name:        "check_requisites",
+			Description: "Check whether a student who completed some units meets the prerequisites and prohibitions of a unit, listing the requisites which are not met.",
+			InputSchema: toolObject(map[string]interface{}{
+				"year":      year,
+				"code":      unitCode,
+				"completed": completed,
+				"course":    map[string]interface{}{"type": "string", "description": "The course the student is admitted to, e.g. C2001, for units restricted to some courses"},
+			}, "year", "code", "completed"),
+			HTTP: toolBinding{Method: http.MethodPost, Path: "/v1/{year}/units/{code}/check", Query: []string{"course"}, Body: "completed"},
+		},
+		{
+			Name:        "suggest_electives",
+			Description: "Suggest elective units of a course which the student already meets the requisites for, units offered in the next teaching period first.",
+			InputSchema: toolBody(reflect.TypeOf(electivesRequest{}), map[string]interface{}{"year": year, "code": courseCode}),
+			HTTP:        toolBinding{Method: http.MethodPost, Path: "/v1/{year}/courses/{code}/electives", Body: "*"},
+		},
+		{
+			Name:        "count_course_credits",
+			Description: "Count the credit points of completed and planned units towards each part of a course, to see what is left to complete.",
+			InputSchema: toolBody(reflect.TypeOf(creditsRequest{}), map[string]interface{}{"year": year, "code": courseCode}),
+			HTTP:        toolBinding{Method: http.MethodPost, Path: "/v1/{year}/courses/{code}/credits", Body: "*"},
+		},
+		{
+			Name:        "compare_plans",
+			Description: "Compare two study plans, each with its units per teaching period, by duration, load, cost, unmet requisites and the units unique to each.",
+			InputSchema: toolBody(reflect.TypeOf(compareRequest{}), nil),
+			HTTP:        toolBinding{Method: http.MethodPost, Path: "/v1/plan/compare", Body: "*"},
+		},
+	}
+}
+
+// toolObject is the input schema of a tool with the given arguments
+func toolObject(properties map[string]interface{}, required ...string) map[string]interface{} {
+	return hoistDefs(map[string]interface{}{"type": "object", "properties": properties, "required": required})
+}
+
+// toolBody is the input schema of a tool whose arguments are the fields of a request body, and the path arguments
+func toolBody(body reflect.Type, pathArgs map[string]interface{}) map[string]interface{} {
+	schema := toolSchema(body)
+	properties, _ := schema["properties"].(map[string]interface{})
+	required := append([]string{}, slices.Sorted(maps.Keys(pathArgs))...)
+	for name, property := range pathArgs {
+		properties[name] = property
+	}
+	schema["required"] = required
+	return hoistDefs(schema)
+}
+
+// hoistDefs moves the $defs of the properties of a schema to the schema itself, where their references point
+func hoistDefs(schema map[string]interface{}) map[string]interface{} {
+	defs, _ := schema["$defs"].(map[string]interface{})
+	if defs == nil {
+		defs = map[string]interface{}{}
+	}
+	properties, _ := schema["properties"].(map[string]interface{})
+	for _, property := range properties {
+		property, _ := property.(map[string]interface{})
+		if nested, ok := property["$defs"].(map[string]interface{}); ok {
+			for name, def := range nested {
+				defs[name] = def
+			}
+			delete(property, "$defs")
+		}
+	}
+	if len(defs) > 0 {
+		schema["$defs"] = defs
+	}
+	return schema
+}
+
+// toolSchema generates a self-contained JSON schema of a Go type, with the structs it refers to in $defs,
+// since tool schemas cannot refer to the components of the OpenAPI document
+func toolSchema(t reflect.Type) map[string]interface{} {
+	schemas := newOpenAPISchemas()
+	schema := schemas.schema(t)
+	if _, ref := schema["$ref"]; ref {
+		// The root is copied, as it is also among the $defs it is given
+		name := strings.TrimPrefix(schema["$ref"].(string), "#/components/schemas/")
+		schema = maps.Clone(schemas.components[name].(map[string]interface{}))
+	}
+	schema["$defs"] = schemas.components
+
+	// References are rewritten by round-tripping through JSON, as they are nested anywhere in the schema
+	encoded, _ := json.Marshal(schema)
+	encoded = []byte(strings.ReplaceAll(string(encoded), "#/components/schemas/", "#/$defs/"))
+	var rewritten map[string]interface{}
+	_ = json.Unmarshal(encoded, &rewritten)
+	return rewritten
+}
@@ -7,28 +7,14 @@ import (
 	"handbook-scraper/scrapers/common"
 	"handbook-scraper/scrapers/units"
 	"net/http"
-	"time"
+	"strings"
 )
 
 func UnitCheckHandler(c *gin.Context, collector *colly.Collector) {
-	year := c.Param("year")
 	code := c.Param("code")
 
-	if year == "current" {
-		year = fmt.Sprintf("%d", time.Now().Year())
-	}
-
-	baseURL := fmt.Sprintf("https://handbook.monash.edu/%s/units/%s", year, code)
-
-	data, err := ScrapeAndCache(baseURL, collector, "units")
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err})
-		return
-	}
-
-	unitData, ok := data.(units.UnitData)
+	year, ok := yearParam(c)
 	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to cast scraped data to UnitData"})
 		return
 	}
 
@@ -37,17 +23,61 @@ func UnitCheckHandler(c *gin.Context, collector *colly.Collector) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON format for completed units"})
 		return
 	}
+	completedUnits = normaliseCompleted(completedUnits)
 
-	met, unmetRequisites, err := units.CheckRequisites(unitData, completedUnits)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err})
+	// The course the student is admitted to, for requisites on course admission
+	course := strings.ToUpper(strings.TrimSpace(c.Query("course")))
+	if course != "" && !ValidCode("courses", course) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("malformed course code: %s", course)})
 		return
 	}
 
+	respondWithCachedCheck(c, "unit:"+course, year, code, completedUnits, func() (interface{}, bool) {
+		result, ok := checkUnit(c, collector, year, code, completedUnits, course)
+		return result, ok
+	})
+}
+
+// unitCheckResult is the result of checking the requisites of a unit
+type unitCheckResult struct {
+	Met        bool                    `json:"met_requisites"`
+	Message    []string                `json:"message"`    // Requisites which are not met
+	Warning    string                  `json:"warning"`    // Enrolment rules, which cannot be checked
+	Advisories []units.HiddenRequisite `json:"advisories"` // Units only mentioned in the synopsis or enrolment rules
+}
+
+// checkUnit checks the requisites of a unit against the completed units and the course of the student,
+// responding with an error if it fails
+func checkUnit(c *gin.Context, collector *colly.Collector, year string, code string, completedUnits []common.Unit, course string) (unitCheckResult, bool) {
+	// Cached units come back as generic maps, so they are decoded rather than cast
+	unitData, err := fetchUnit(c.Request.Context(), year, code, collector)
+	if err != nil {
+		respondWithScrapeError(c, err)
+		return unitCheckResult{}, false
+	}
+
+	met, unmetRequisites, err := units.CheckRequisitesForCourse(unitData, completedUnits, course, unitEquivalents(c.Request.Context()))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return unitCheckResult{}, false
+	}
+
 	enrolmentRulesString := ""
 	for _, rule := range unitData.EnrolmentRules {
 		enrolmentRulesString += rule.Description + " "
 	}
 
-	c.JSON(http.StatusOK, gin.H{"met_requisites": met, "message": unmetRequisites, "warning": enrolmentRulesString})
+	// Units mentioned in the text which the student already completed need no advice
+	completed := map[string]bool{}
+	for _, unit := range completedUnits {
+		completed[unit.Code] = true
+	}
+	advisories := []units.HiddenRequisite{}
+	for _, hidden := range units.FindHiddenRequisites(unitData) {
+		if !completed[hidden.UnitCode] {
+			advisories = append(advisories, hidden)
+		}
+	}
+
+	return unitCheckResult{Met: met, Message: unmetRequisites, Warning: enrolmentRulesString, Advisories: advisories}, true
 }
@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gocolly/colly/v2"
+	"handbook-scraper/scrapers/common"
+	"handbook-scraper/utils/databases"
+	"handbook-scraper/utils/log"
+)
+
+// maxUnitSetSize is the most units a unit set can hold
+const maxUnitSetSize = 100
+
+// unitSetName matches the names unit sets can be given
+var unitSetName = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// unitSet is a named collection of units curated by an advisor, such as the electives of a specialisation
+type unitSet struct {
+	Name        string    `json:"name"`
+	Title       string    `json:"title"`
+	Description string    `json:"description,omitempty"`
+	Codes       []string  `json:"codes"` // In the order they were given
+	UpdatedBy   string    `json:"updated_by"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// unitSetUnit summarises a unit of a set from the handbook of a year.
+// Error is set instead of the summary if the unit could not be retrieved.
+type unitSetUnit struct {
+	itemSummary
+	Error string `json:"error,omitempty"`
+}
+
+// loadUnitSets loads every unit set in name order
+func loadUnitSets(ctx context.Context) ([]unitSet, error) {
+	dbHandler := databases.FromContext(ctx)
+	keys, err := dbHandler.ListKeys(databases.UnitSet, "^")
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]unitSet, 0, len(keys))
+	for _, key := range keys {
+		var record unitSet
+		if err := dbHandler.Retrieve(databases.UnitSet, key, &record); err != nil {
+			log.Errorf("[UNIT SETS] Error retrieving %s: %v", key, err)
+			continue
+		}
+		records = append(records, record)
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].Name < records[j].Name })
+	return records, nil
+}
+
+// ListUnitSetsHandler lists every unit set, in name order
+func ListUnitSetsHandler(c *gin.Context) {
+	records, err := loadUnitSets(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	respondWithPage(c, records, listFields{Filter: []string{"name", "title", "codes", "updated_by"}, Sort: []string{"name", "title", "updated_at"}})
+}
+
+// UnitSetHandler returns a unit set with a summary of each of its units from the handbook of a year.
+// Stored units are retrieved together, and the others are scraped.
+func UnitSetHandler(c *gin.Context, collector *colly.Collector) {
+	ctx := c.Request.Context()
+	year, ok := yearParam(c)
+	if !ok {
+		return
+	}
+
+	var record unitSet
+	if err := databases.FromContext(ctx).Retrieve(databases.UnitSet, c.Param("name"), &record); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no such unit set"})
+		return
+	}
+
+	stored := retrieveStoredMany(ctx, year, "units", record.Codes)
+	summaries := make([]unitSetUnit, 0, len(record.Codes))
+	for _, code := range record.Codes {
+		data, ok := stored[code]
+		if !ok {
+			var err error
+			data, err = ScrapeAndCache(c.Request.Context(), handbookURL(year, "units", code), collector, "units")
+			switch {
+			case errors.Is(err, common.ErrPageNotFound):
+				summaries = append(summaries, unitSetUnit{itemSummary: itemSummary{Code: code}, Error: fmt.Sprintf("%s was not found in the %s handbook", code, year)})
+				continue
+			case err != nil:
+				log.Errorf("[UNIT SETS] Error fetching %s: %v", code, err)
+				summaries = append(summaries, unitSetUnit{itemSummary: itemSummary{Code: code}, Error: err.Error()})
+				continue
+			}
+		}
+		summaries = append(summaries, unitSetUnit{itemSummary: summariseItem(code, data)})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"name":        record.Name,
+		"title":       record.Title,
+		"description": record.Description,
+		"year":        year,
+		"updated_at":  record.UpdatedAt,
+		"units":       summaries,
+	})
+}
+
+// SetUnitSetHandler creates or replaces a unit set
+func SetUnitSetHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	name := c.Param("name")
+	if !unitSetName.MatchString(name) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name must be 1 to 64 letters, digits, underscores or hyphens"})
+		return
+	}
+
+	var req struct {
+		Title       string   `json:"title"`
+		Description string   `json:"description"`
+		Codes       []string `json:"codes"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Title) == "" || len(req.Codes) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "title and codes are required"})
+		return
+	}
+
+	record := unitSet{
+		Name:        name,
+		Title:       strings.TrimSpace(req.Title),
+		Description: strings.TrimSpace(req.Description),
+		Codes:       uniqueCodes(req.Codes),
+		UpdatedBy:   c.GetString(SubjectKey),
+		UpdatedAt:   time.Now(),
+	}
+	if len(record.Codes) > maxUnitSetSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("a unit set holds at most %d units", maxUnitSetSize)})
+		return
+	}
+	for _, code := range record.Codes {
+		if !ValidCode("units", code) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("malformed code: %s", code)})
+			return
+		}
+	}
+
+	if err := databases.FromContext(ctx).Store(databases.UnitSet, name, record, 0); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	log.Infof("[UNIT SETS] %s set unit set %s to %v", record.UpdatedBy, name, record.Codes)
+	c.JSON(http.StatusOK, record)
+}
+
+// DeleteUnitSetHandler removes a unit set
+func DeleteUnitSetHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	if err := databases.FromContext(ctx).Delete(databases.UnitSet, c.Param("name")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
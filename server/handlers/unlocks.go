@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"handbook-scraper/scrapers/units"
+	"handbook-scraper/utils/databases"
+	"handbook-scraper/utils/log"
+)
+
+const (
+	// unlocksIndexTTL is how long the unlocks index of a year is cached, so newly stored requisites show up soon after
+	unlocksIndexTTL = time.Hour
+	// unlocksBatchSize is how many units are read at once while building the unlocks index
+	unlocksBatchSize = 500
+)
+
+// unlock is a unit listing another unit as a prerequisite
+type unlock struct {
+	Code      string `json:"code"`
+	Mandatory bool   `json:"mandatory"` // Whether every way of meeting the prerequisites requires the other unit
+}
+
+// unlockedUnit is the entry of a unit in the list of units a unit unlocks
+type unlockedUnit struct {
+	itemSummary
+	Mandatory bool `json:"mandatory"`
+}
+
+// unlocksIndexKey is the cache key of the unlocks index of a year
+func unlocksIndexKey(year string) string {
+	return "unlocks_index:" + year
+}
+
+// unlocksIndex maps each unit mentioned by the prerequisites of the stored units of a year to the units listing it,
+// in code order. A year's index is built once at a time per server, so requests arriving while it is cold wait for
+// the build rather than each reading every unit.
+func unlocksIndex(ctx context.Context, year string) (map[string][]unlock, error) {
+	if index := cachedUnlocksIndex(ctx, year); index != nil {
+		return index, nil
+	}
+
+	building := &dependencies(ctx).unlocksBuild
+	building.Lock()
+	defer building.Unlock()
+	if index := cachedUnlocksIndex(ctx, year); index != nil {
+		return index, nil
+	}
+	return buildUnlocksIndex(ctx, year)
+}
+
+// cachedUnlocksIndex returns the cached unlocks index of a year, or nil if it is not cached
+func cachedUnlocksIndex(ctx context.Context, year string) map[string][]unlock {
+	var index map[string][]unlock
+	if err := databases.FromContext(ctx).Retrieve(databases.Cache, unlocksIndexKey(year), &index); err != nil {
+		return nil
+	}
+	return index
+}
+
+// buildUnlocksIndex builds and caches the unlocks index of a year from its stored units,
+// read unlocksBatchSize at a time
+func buildUnlocksIndex(ctx context.Context, year string) (map[string][]unlock, error) {
+	dbHandler, release := databases.Hold(ctx)
+	defer release()
+
+	keys, err := storedItemKeys(ctx, year, "units")
+	if err != nil {
+		return nil, err
+	}
+
+	index := map[string][]unlock{}
+	for start := 0; start < len(keys); start += unlocksBatchSize {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		batch := keys[start:min(start+unlocksBatchSize, len(keys))]
+		found, err := dbHandler.RetrieveMany(databases.Handbook, batch)
+		if err != nil {
+			return nil, fmt.Errorf("failed to retrieve the units of %s: %w", year, err)
+		}
+		for _, key := range batch {
+			raw, ok := found[key]
+			if !ok {
+				continue
+			}
+			var unitData units.UnitData
+			if err := json.Unmarshal(raw, &unitData); err != nil {
+				log.Errorf("[UNLOCKS] Error decoding %s: %v", key, err)
+				continue
+			}
+			for prerequisite, mandatory := range units.PrerequisiteUnits(unitData) {
+				index[prerequisite] = append(index[prerequisite], unlock{Code: unitData.Code, Mandatory: mandatory})
+			}
+		}
+	}
+	for _, unlocks := range index {
+		sort.Slice(unlocks, func(i, j int) bool { return unlocks[i].Code < unlocks[j].Code })
+	}
+
+	if err := dbHandler.Store(databases.Cache, unlocksIndexKey(year), index, unlocksIndexTTL); err != nil {
+		log.Errorf("[UNLOCKS] Error caching the unlocks index of %s: %v", year, err)
+	}
+	return index, nil
+}
+
+// unlocksIndexResult is the result of a build_unlocks_index job
+type unlocksIndexResult struct {
+	Year  string `json:"year"`
+	Units int    `json:"units"` // Units listed as a prerequisite by at least one stored unit
+}
+
+// buildUnlocksIndexJob rebuilds the unlocks index of a year, so it is warm before it is requested,
+// such as after a crawl
+func buildUnlocksIndexJob(ctx context.Context, raw json.RawMessage) (interface{}, error) {
+	var params struct {
+		Year string `json:"year"`
+	}
+	if err := decodeParams(raw, &params); err != nil {
+		return nil, err
+	}
+	year, err := resolveYear(ctx, params.Year)
+	if err != nil {
+		return nil, err
+	}
+
+	building := &dependencies(ctx).unlocksBuild
+	building.Lock()
+	defer building.Unlock()
+	index, err := buildUnlocksIndex(ctx, year)
+	if err != nil {
+		return nil, err
+	}
+	return unlocksIndexResult{Year: year, Units: len(index)}, nil
+}
+
+// UnlocksHandler lists the stored units of a year which list a unit as a prerequisite, in code order.
+// Each is marked mandatory if every way of meeting its prerequisites requires the unit.
+func UnlocksHandler(c *gin.Context) {
+	year, ok := yearParam(c)
+	if !ok {
+		return
+	}
+	query, ok := parseListQuery(c, listFields{
+		Filter: append(slices.Clone(itemSummaryFields.Filter), "mandatory"),
+		Sort:   append(slices.Clone(itemSummaryFields.Sort), "mandatory"),
+	})
+	if !ok {
+		return
+	}
+
+	index, err := unlocksIndex(c.Request.Context(), year)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	unlocks := index[c.Param("code")]
+	mandatory := make(map[string]bool, len(unlocks))
+	codes := make([]string, 0, len(unlocks))
+	for _, unlocked := range unlocks {
+		mandatory[unlocked.Code] = unlocked.Mandatory
+		codes = append(codes, unlocked.Code)
+	}
+	respondWithSummaries(c, year, "units", codes, query, func(summary itemSummary) unlockedUnit {
+		return unlockedUnit{itemSummary: summary, Mandatory: mandatory[summary.Code]}
+	})
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gocolly/colly/v2"
+	"handbook-scraper/scrapers/common"
+	"handbook-scraper/utils/databases"
+	"handbook-scraper/utils/log"
+)
+
+// variantsTTL is how long the entries listed by a chooser page are remembered, as long as scraped items are cached
+const variantsTTL = time.Hour * 144
+
+// variantResult is an entry of a code with several entries, with its handbook data or why it could not be fetched
+type variantResult struct {
+	common.Variant
+	Data  interface{} `json:"data,omitempty"`
+	Error string      `json:"error,omitempty"`
+}
+
+// variantsKey is the cache key of the entries of a chooser page
+func variantsKey(baseURL string) string {
+	return "variants:" + baseURL
+}
+
+// rememberVariants caches the entries of a chooser page, so later requests for the code are answered without
+// fetching the page again
+func rememberVariants(ctx context.Context, baseURL string, variants []common.Variant) {
+	if err := databases.FromContext(ctx).Store(databases.Cache, variantsKey(baseURL), variants, variantsTTL); err != nil {
+		log.Errorf("Error saving the entries of %s: %v", baseURL, err)
+	}
+}
+
+// knownVariants returns a MultipleEntriesError if a handbook URL was found to be a chooser page, and nil otherwise
+func knownVariants(ctx context.Context, baseURL string) error {
+	var variants []common.Variant
+	if err := databases.FromContext(ctx).Retrieve(databases.Cache, variantsKey(baseURL), &variants); err != nil || len(variants) == 0 {
+		return nil
+	}
+	return &common.MultipleEntriesError{URL: baseURL, Variants: variants}
+}
+
+// respondWithVariants responds to a request for a code with several entries. With variants=all, every entry is
+// fetched and returned, each with its data or error. Otherwise it responds with 300 and the entries to choose from.
+func respondWithVariants(c *gin.Context, collector *colly.Collector, urlKey string, code string, multiple *common.MultipleEntriesError) {
+	if c.Query("variants") != "all" {
+		c.JSON(http.StatusMultipleChoices, gin.H{"error": multiple.Error(), "item_type": urlKey, "choices": multiple.Variants})
+		return
+	}
+
+	results := make([]variantResult, 0, len(multiple.Variants))
+	for _, variant := range multiple.Variants {
+		result := variantResult{Variant: variant}
+		data, err := scrapeVariant(c.Request.Context(), variant, collector, urlKey)
+		if err != nil {
+			log.Errorf("[VARIANTS] Error fetching %s: %v", variant.URL, err)
+			result.Error = err.Error()
+		} else {
+			result.Data = data
+		}
+		results = append(results, result)
+	}
+	c.JSON(http.StatusOK, gin.H{"code": code, "item_type": urlKey, "variants": results})
+}
+
+// scrapeVariant fetches and parses an entry of a chooser page, within the budget of cold scrapes.
+// Entries are not cached, as they have no URL of their own among the stored items.
+func scrapeVariant(ctx context.Context, variant common.Variant, collector *colly.Collector, urlKey string) (interface{}, error) {
+	if ReadOnly(ctx) {
+		return nil, errReadOnly
+	}
+	release, err := coldScrapes().acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	data, _, err := common.ExtractPageContent(ctx, variant.URL, collector)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := common.ParseVariants(data); ok {
+		return nil, fmt.Errorf("%s is a chooser page as well", variant.URL)
+	}
+	return scrapeData(urlKey, data, variant.URL)
+}
+
+// asMultipleEntries reports whether an error is a MultipleEntriesError
+func asMultipleEntries(err error) (*common.MultipleEntriesError, bool) {
+	var multiple *common.MultipleEntriesError
+	return multiple, errors.As(err, &multiple)
+}
@@ -0,0 +1,298 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gocolly/colly/v2"
+	"handbook-scraper/scrapers/units"
+	"handbook-scraper/utils/databases"
+	"handbook-scraper/utils/log"
+)
+
+// unitVersion is a scraped version of a unit, kept so it can still be served after the handbook publishes a newer one
+type unitVersion struct {
+	Version   string         `json:"version"`
+	ScrapedAt time.Time      `json:"scraped_at"`
+	Data      units.UnitData `json:"data"`
+}
+
+// pinRefresh is how often the pinned versions are reloaded, so pins made by other instances are picked up
+const pinRefresh = 5 * time.Minute
+
+// versionPins holds the pinned versions of every item, keyed by their pin keys
+type versionPins struct {
+	sync.Mutex
+	pins     map[string]string
+	loadedAt time.Time
+}
+
+// versionPin is the version of a unit served by default for a year, instead of the version the handbook serves
+type versionPin struct {
+	Version  string    `json:"version"`
+	PinnedAt time.Time `json:"pinned_at"`
+}
+
+// unitVersionSummary is the entry of a version in the version list of a unit
+type unitVersionSummary struct {
+	Version   string     `json:"version"`
+	ScrapedAt *time.Time `json:"scraped_at,omitempty"` // Unknown for the current version if it was scraped before versions were kept
+	Current   bool       `json:"current"`              // Whether the handbook currently serves this version
+	Pinned    bool       `json:"pinned"`               // Whether this version is served by default
+}
+
+// versionKey is the key of a version of a handbook item
+func versionKey(baseURL string, version string) string {
+	return baseURL + "@" + version
+}
+
+// versionPinKey is the key of the pinned version of a handbook item
+func versionPinKey(baseURL string) string {
+	return "pin:" + baseURL
+}
+
+// tenantPinKey is the key of the pinned version of a handbook item for one tenant, which overrides its pinned version
+func tenantPinKey(baseURL string, tenant string) string {
+	return versionPinKey(baseURL) + "#" + tenant
+}
+
+// pinKey is the key of the pinned version of a handbook item for a tenant, or for every tenant if tenant is empty
+func pinKey(baseURL string, tenant string) string {
+	if tenant == "" {
+		return versionPinKey(baseURL)
+	}
+	return tenantPinKey(baseURL, tenant)
+}
+
+// storeVersion keeps the scraped version of a unit. Other item types are not versioned by the handbook.
+func storeVersion(ctx context.Context, baseURL string, scraped interface{}) {
+	unit, ok := scraped.(units.UnitData)
+	if !ok || unit.HandbookVersion == "" {
+		return
+	}
+
+	record := unitVersion{Version: unit.HandbookVersion, ScrapedAt: time.Now(), Data: unit}
+	if err := databases.FromContext(ctx).Store(databases.Version, versionKey(baseURL, unit.HandbookVersion), record, 0); err != nil {
+		log.Errorf("Error saving version %s of %s: %v", unit.HandbookVersion, baseURL, err)
+	}
+}
+
+// pinnedVersion returns the version of a handbook item pinned for a tenant, or for every tenant if the tenant has not
+// pinned one, or an empty string if none is pinned
+func pinnedVersion(ctx context.Context, baseURL string, tenant string) string {
+	pins := cachedVersionPins(ctx)
+	if tenant != "" {
+		if version, ok := pins[tenantPinKey(baseURL, tenant)]; ok {
+			return version
+		}
+	}
+	return pins[versionPinKey(baseURL)]
+}
+
+// cachedVersionPins returns the pinned versions of every item, keyed by their pin keys, so serving a unit does not
+// read its pins from MongoDB
+func cachedVersionPins(ctx context.Context) map[string]string {
+	cached := &dependencies(ctx).pins
+	cached.Lock()
+	defer cached.Unlock()
+
+	if cached.pins == nil || time.Since(cached.loadedAt) > pinRefresh {
+		pins, err := loadVersionPins(ctx)
+		if err != nil {
+			log.Errorf("[VERSIONS] Failed to load pinned versions: %v", err)
+			return cached.pins
+		}
+		cached.pins, cached.loadedAt = pins, time.Now()
+	}
+	return cached.pins
+}
+
+// invalidateVersionPins reloads the pinned versions on the next lookup
+func invalidateVersionPins(ctx context.Context) {
+	cached := &dependencies(ctx).pins
+	cached.Lock()
+	cached.pins = nil
+	cached.Unlock()
+}
+
+// loadVersionPins loads the pinned versions of every item, keyed by their pin keys
+func loadVersionPins(ctx context.Context) (map[string]string, error) {
+	dbHandler := databases.FromContext(ctx)
+	pinKeys, err := dbHandler.ListKeys(databases.Version, "^"+regexp.QuoteMeta(versionPinKey("")))
+	if err != nil {
+		return nil, err
+	}
+
+	pins := make(map[string]string, len(pinKeys))
+	for _, pinKey := range pinKeys {
+		var pin versionPin
+		if err := dbHandler.Retrieve(databases.Version, pinKey, &pin); err != nil {
+			return nil, fmt.Errorf("failed to retrieve %s: %w", pinKey, err)
+		}
+		pins[pinKey] = pin.Version
+	}
+	return pins, nil
+}
+
+// storedVersions lists the kept versions of a handbook item
+func storedVersions(ctx context.Context, baseURL string) (map[string]unitVersion, error) {
+	dbHandler := databases.FromContext(ctx)
+	keys, err := dbHandler.ListKeys(databases.Version, "^"+regexp.QuoteMeta(versionKey(baseURL, "")))
+	if err != nil {
+		return nil, err
+	}
+
+	versions := map[string]unitVersion{}
+	for _, key := range keys {
+		var record unitVersion
+		if err := dbHandler.Retrieve(databases.Version, key, &record); err != nil {
+			log.Errorf("[VERSIONS] Error retrieving %s: %v", key, err)
+			continue
+		}
+		versions[record.Version] = record
+	}
+	return versions, nil
+}
+
+// errVersionNotFound is returned when a requested version of an item was never scraped
+var errVersionNotFound = errors.New("version was never kept")
+
+// selectUnitVersion returns the version of a unit requested by the kept_version query parameter, or its pinned version.
+// The current unit data is returned as it is if neither is set or they are the current version.
+// Only versions kept since the unit was first scraped can be served, the handbook only serves its current version.
+func selectUnitVersion(c *gin.Context, year string, data interface{}) (interface{}, error) {
+	var current units.UnitData
+	if err := decodeInto(data, &current); err != nil {
+		return nil, fmt.Errorf("failed to decode unit data: %w", err)
+	}
+	baseURL := handbookURL(year, "units", current.Code)
+
+	version := c.Query("kept_version")
+	if version == "" {
+		version = pinnedVersion(c.Request.Context(), baseURL, requestTenant(c.Request.Context()))
+	}
+	if version == "" || version == current.HandbookVersion {
+		return data, nil
+	}
+
+	var record unitVersion
+	if err := databases.FromContext(c.Request.Context()).Retrieve(databases.Version, versionKey(baseURL, version), &record); err != nil {
+		return nil, fmt.Errorf("%w: %s of %s", errVersionNotFound, version, current.Code)
+	}
+	return record.Data, nil
+}
+
+// UnitVersionsHandler lists the versions of a unit kept since it was first scraped, newest first
+func UnitVersionsHandler(c *gin.Context, collector *colly.Collector) {
+	ctx := c.Request.Context()
+	year, ok := yearParam(c)
+	if !ok {
+		return
+	}
+
+	current, err := fetchUnit(c.Request.Context(), year, c.Param("code"), collector)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	baseURL := handbookURL(year, "units", current.Code)
+	versions, err := storedVersions(ctx, baseURL)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Units scraped before versions were kept only have their current version
+	if _, ok := versions[current.HandbookVersion]; !ok && current.HandbookVersion != "" {
+		storeVersion(ctx, baseURL, current)
+		versions[current.HandbookVersion] = unitVersion{Version: current.HandbookVersion}
+	}
+
+	pinned := pinnedVersion(c.Request.Context(), baseURL, requestTenant(c.Request.Context()))
+	summaries := []unitVersionSummary{}
+	for version, record := range versions {
+		summary := unitVersionSummary{Version: version, Current: version == current.HandbookVersion, Pinned: version == pinned}
+		if !record.ScrapedAt.IsZero() {
+			scrapedAt := record.ScrapedAt
+			summary.ScrapedAt = &scrapedAt
+		}
+		summaries = append(summaries, summary)
+	}
+	sort.Slice(summaries, func(i, j int) bool { return compareVersions(summaries[i].Version, summaries[j].Version) > 0 })
+
+	respondWithPage(c, summaries, listFields{Filter: []string{"current", "pinned"}, Sort: []string{"scraped_at"}})
+}
+
+// PinVersionHandler pins the version of a unit served by default for a year, to every tenant or to the tenant query parameter
+func PinVersionHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	code := c.Param("code")
+	year, ok := yearParam(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Version string `json:"version" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "version is required"})
+		return
+	}
+
+	baseURL := handbookURL(year, "units", code)
+	dbHandler := databases.FromContext(ctx)
+	if exists, err := dbHandler.Exists(databases.Version, versionKey(baseURL, req.Version)); err != nil || !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("version %s of %s was not found, list its versions first", req.Version, code)})
+		return
+	}
+
+	tenant := c.Query("tenant")
+	pin := versionPin{Version: req.Version, PinnedAt: time.Now()}
+	if err := dbHandler.Store(databases.Version, pinKey(baseURL, tenant), pin, 0); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	invalidateVersionPins(ctx)
+
+	log.Infof("[VERSIONS] Pinned %s %s to version %s for %s", year, code, req.Version, tenantName(tenant))
+	c.JSON(http.StatusOK, gin.H{"year": year, "code": code, "version": pin.Version, "pinned_at": pin.PinnedAt, "tenant": tenant})
+}
+
+// UnpinVersionHandler removes the pinned version of a unit, for every tenant or for the tenant query parameter,
+// so the version the handbook serves is returned again
+func UnpinVersionHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	code := c.Param("code")
+	year, ok := yearParam(c)
+	if !ok {
+		return
+	}
+
+	if err := databases.FromContext(ctx).Delete(databases.Version, pinKey(handbookURL(year, "units", code), c.Query("tenant"))); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	invalidateVersionPins(ctx)
+	c.Status(http.StatusNoContent)
+}
+
+// compareVersions orders version names numerically where both are numbers, and lexically otherwise
+func compareVersions(a string, b string) int {
+	var x, y int
+	if _, err := fmt.Sscanf(a, "%d", &x); err == nil {
+		if _, err := fmt.Sscanf(b, "%d", &y); err == nil && x != y {
+			return x - y
+		}
+	}
+	return strings.Compare(a, b)
+}
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gocolly/colly/v2"
+	"handbook-scraper/planner"
+	"handbook-scraper/scrapers/units"
+)
+
+// maxWAMResults limits how many unit results a WAM request can include
+const maxWAMResults = 100
+
+// wamRequest is the request body for the WAM calculator
+type wamRequest struct {
+	Results []planner.UnitResult `json:"results"`
+}
+
+// WAMHandler computes the weighted average mark and grade point average of a student's unit results,
+// weighting each unit by the level of its handbook entry in the year
+func WAMHandler(c *gin.Context, collector *colly.Collector) {
+	year, ok := yearParam(c)
+	if !ok {
+		return
+	}
+
+	var req wamRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON format for WAM request"})
+		return
+	}
+	if len(req.Results) > maxWAMResults {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("at most %d results can be included", maxWAMResults)})
+		return
+	}
+	for _, result := range req.Results {
+		if result.Mark != nil && (*result.Mark < 0 || *result.Mark > 100) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("the mark of %s must be between 0 and 100", result.Code)})
+			return
+		}
+	}
+
+	lookup := func(unitCode string) (units.UnitData, error) {
+		return fetchUnit(c.Request.Context(), year, unitCode, collector)
+	}
+	c.JSON(http.StatusOK, planner.CalculateWAM(req.Results, lookup))
+}
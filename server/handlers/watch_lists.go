@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"handbook-scraper/scrapers/units"
+	"handbook-scraper/utils/databases"
+	"handbook-scraper/utils/log"
+	"handbook-scraper/utils/notifier"
+)
+
+// watchRefresh is how often the watch lists are reloaded, so changes made by other instances are picked up
+const watchRefresh = 5 * time.Minute
+
+// watchListName matches the names watch lists can be given
+var watchListName = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// watchList is a named list of units whose changes are posted to the change alert webhooks
+type watchList struct {
+	Name      string    `json:"name"`
+	Codes     []string  `json:"codes"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// watchedUnits holds every watched unit, mapped to the names of the watch lists it is on
+type watchedUnits struct {
+	sync.Mutex
+	lists    map[string][]string
+	loadedAt time.Time
+}
+
+// unitWatchLists returns the names of the watch lists a unit is on
+func unitWatchLists(ctx context.Context, code string) []string {
+	watchedUnits := &dependencies(ctx).watched
+	watchedUnits.Lock()
+	defer watchedUnits.Unlock()
+
+	if watchedUnits.lists == nil || time.Since(watchedUnits.loadedAt) > watchRefresh {
+		records, err := loadWatchLists(ctx)
+		if err != nil {
+			log.Errorf("[WATCH] Failed to load watch lists: %v", err)
+			return watchedUnits.lists[code]
+		}
+		lists := map[string][]string{}
+		for _, record := range records {
+			for _, watched := range record.Codes {
+				lists[watched] = append(lists[watched], record.Name)
+			}
+		}
+		watchedUnits.lists, watchedUnits.loadedAt = lists, time.Now()
+	}
+	return watchedUnits.lists[code]
+}
+
+// invalidateWatchLists reloads the watch lists on the next lookup
+func invalidateWatchLists(ctx context.Context) {
+	watchedUnits := &dependencies(ctx).watched
+	watchedUnits.Lock()
+	watchedUnits.lists = nil
+	watchedUnits.Unlock()
+}
+
+// loadWatchLists loads every watch list in name order
+func loadWatchLists(ctx context.Context) ([]watchList, error) {
+	dbHandler := databases.FromContext(ctx)
+	keys, err := dbHandler.ListKeys(databases.Watch, "^")
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]watchList, 0, len(keys))
+	for _, key := range keys {
+		var record watchList
+		if err := dbHandler.Retrieve(databases.Watch, key, &record); err != nil {
+			log.Errorf("[WATCH] Error retrieving %s: %v", key, err)
+			continue
+		}
+		records = append(records, record)
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].Name < records[j].Name })
+	return records, nil
+}
+
+// alertUnitChanges posts a summary of how a freshly scraped unit differs from its stored data, if the unit is watched.
+// It must be called before the scraped unit is stored. Units scraped for the first time have nothing to compare against.
+func alertUnitChanges(ctx context.Context, baseURL string, scraped interface{}) {
+	unit, ok := scraped.(units.UnitData)
+	if !ok || !notifier.Enabled() {
+		return
+	}
+	lists := unitWatchLists(ctx, unit.Code)
+	if len(lists) == 0 {
+		return
+	}
+
+	var previous units.UnitData
+	if err := databases.FromContext(ctx).Retrieve(databases.Handbook, baseURL, &previous); err != nil {
+		return
+	}
+	changes := unitChanges(previous, unit)
+	if len(changes) == 0 {
+		return
+	}
+
+	log.Infof("[WATCH] %s changed: %s", unit.Code, strings.Join(changes, "; "))
+	message := fmt.Sprintf("%s %s changed in the %d handbook (watched by %s):\n• %s\n%s",
+		unit.Code, unit.Title, unit.CurrentYear, strings.Join(lists, ", "), strings.Join(changes, "\n• "), baseURL)
+	notifier.Notify(message)
+}
+
+// unitChanges describes the changes to the assessments and prerequisites of a unit
+func unitChanges(previous units.UnitData, current units.UnitData) []string {
+	var changes []string
+
+	weights := map[string]string{}
+	for _, assessment := range previous.Assessments {
+		weights[assessment.AssessmentName] = assessment.Weight
+	}
+	for _, assessment := range current.Assessments {
+		weight, ok := weights[assessment.AssessmentName]
+		switch {
+		case !ok:
+			changes = append(changes, fmt.Sprintf("New assessment %q, weighted %s", assessment.AssessmentName, assessment.Weight))
+		case weight != assessment.Weight:
+			changes = append(changes, fmt.Sprintf("Weight of %q changed from %s to %s", assessment.AssessmentName, weight, assessment.Weight))
+		}
+		delete(weights, assessment.AssessmentName)
+	}
+	removed := make([]string, 0, len(weights))
+	for name := range weights {
+		removed = append(removed, name)
+	}
+	sort.Strings(removed)
+	for _, name := range removed {
+		changes = append(changes, fmt.Sprintf("Assessment %q was removed", name))
+	}
+
+	before, after := unitPrerequisites(previous), unitPrerequisites(current)
+	for _, code := range after {
+		if !slices.Contains(before, code) {
+			changes = append(changes, "New prerequisite "+code)
+		}
+	}
+	for _, code := range before {
+		if !slices.Contains(after, code) {
+			changes = append(changes, "Prerequisite "+code+" was removed")
+		}
+	}
+	return changes
+}
+
+// ListWatchListsHandler lists every watch list, in name order
+func ListWatchListsHandler(c *gin.Context) {
+	records, err := loadWatchLists(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	respondWithPage(c, records, listFields{Filter: []string{"name", "codes"}, Sort: []string{"name", "updated_at"}})
+}
+
+// SetWatchListHandler creates or replaces a watch list
+func SetWatchListHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	name := c.Param("name")
+	if !watchListName.MatchString(name) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name must be 1 to 64 letters, digits, underscores or hyphens"})
+		return
+	}
+
+	var req struct {
+		Codes []string `json:"codes"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || len(req.Codes) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "codes is required"})
+		return
+	}
+
+	record := watchList{Name: name, Codes: uniqueCodes(req.Codes), UpdatedAt: time.Now()}
+	for _, code := range record.Codes {
+		if !ValidCode("units", code) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("malformed code: %s", code)})
+			return
+		}
+	}
+	sort.Strings(record.Codes)
+
+	if err := databases.FromContext(ctx).Store(databases.Watch, name, record, 0); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	invalidateWatchLists(ctx)
+
+	log.Infof("[WATCH] Set watch list %s to %v", name, record.Codes)
+	c.JSON(http.StatusOK, record)
+}
+
+// DeleteWatchListHandler removes a watch list
+func DeleteWatchListHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	if err := databases.FromContext(ctx).Delete(databases.Watch, c.Param("name")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	invalidateWatchLists(ctx)
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"handbook-scraper/scrapers/common"
+	"handbook-scraper/utils/databases"
+	"handbook-scraper/utils/log"
+)
+
+const (
+	// publishedYearsTTL is how long the list of published handbook years is cached
+	publishedYearsTTL = 6 * time.Hour
+	// publishedYearsRetry is how long after failing to find the published years they are looked up again
+	publishedYearsRetry = time.Minute
+	// publishedYearsKey and publishedYearsFailedKey cache the published years, and that looking them up failed
+	publishedYearsKey       = "handbook_years"
+	publishedYearsFailedKey = "handbook_years_failed"
+)
+
+// defaultMinYear is the earliest handbook year served unless HANDBOOK_MIN_YEAR is set
+const defaultMinYear = 2020
+
+// errInvalidYear is returned for years which are malformed or outside the allowed window
+var errInvalidYear = errors.New("invalid year")
+
+// yearParam resolves the :year path parameter, responding with an error if it cannot be resolved
+func yearParam(c *gin.Context) (string, bool) {
+	year, err := resolveYear(c.Request.Context(), c.Param("year"))
+	if errors.Is(err, errInvalidYear) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return "", false
+	}
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return "", false
+	}
+	return year, true
+}
+
+// yearWindow returns the earliest and latest handbook years served, from HANDBOOK_MIN_YEAR and HANDBOOK_MAX_YEAR.
+// The latest year defaults to next year, since its handbook is published during this one.
+func yearWindow() (int, int) {
+	minYear, maxYear := defaultMinYear, time.Now().Year()+1
+	if parsed, err := strconv.Atoi(os.Getenv("HANDBOOK_MIN_YEAR")); err == nil {
+		minYear = parsed
+	}
+	if parsed, err := strconv.Atoi(os.Getenv("HANDBOOK_MAX_YEAR")); err == nil {
+		maxYear = parsed
+	}
+	return minYear, maxYear
+}
+
+// normaliseYear trims and lower-cases a year, expands two-digit years such as "25" to 2025,
+// and checks the year is within the allowed window, so no requests are made for years the handbook cannot have
+func normaliseYear(year string) (string, error) {
+	year = strings.ToLower(strings.TrimSpace(year))
+	if year == "current" || year == "next" {
+		return year, nil
+	}
+
+	parsed, err := strconv.Atoi(year)
+	if err != nil || parsed < 0 || (len(year) != 2 && len(year) != 4) {
+		return "", fmt.Errorf("%w: %q, use a year such as 2025, current or next", errInvalidYear, year)
+	}
+	if len(year) == 2 {
+		parsed += 2000
+	}
+
+	minYear, maxYear := yearWindow()
+	if parsed < minYear || parsed > maxYear {
+		return "", fmt.Errorf("%w: %d, handbook years range from %d to %d", errInvalidYear, parsed, minYear, maxYear)
+	}
+	return strconv.Itoa(parsed), nil
+}
+
+// resolveYear normalises a year and resolves the "current" and "next" aliases to handbook years.
+// "current" is this calendar year, or last year while this year's handbook is not published yet.
+// "next" is the year after "current", once its handbook is published.
+// Until the published years are known, "current" falls back to this calendar year.
+func resolveYear(ctx context.Context, year string) (string, error) {
+	year, err := normaliseYear(year)
+	if err != nil {
+		return "", err
+	}
+	if year != "current" && year != "next" {
+		return year, nil
+	}
+
+	published := publishedYears(ctx)
+	current := time.Now().Year()
+	if published != nil && !published[current] && published[current-1] {
+		current--
+	}
+
+	if year == "current" {
+		return strconv.Itoa(current), nil
+	}
+	if published != nil && !published[current+1] {
+		return "", fmt.Errorf("the %d handbook is not published yet", current+1)
+	}
+	return strconv.Itoa(current + 1), nil
+}
+
+// publishedYears returns the set of handbook years published upstream, or nil if they cannot be determined yet.
+// The years are looked up from the sitemap in the background, so requests never wait on upstream, and a failed
+// lookup is not retried for publishedYearsRetry.
+func publishedYears(ctx context.Context) map[int]bool {
+	var years []int
+	if err := databases.FromContext(ctx).Retrieve(databases.Cache, publishedYearsKey, &years); err != nil || len(years) == 0 {
+		if !ReadOnly(ctx) {
+			go refreshPublishedYears(context.WithoutCancel(ctx))
+		}
+		return nil
+	}
+
+	published := map[int]bool{}
+	for _, year := range years {
+		published[year] = true
+	}
+	return published
+}
+
+// refreshPublishedYears looks up the published handbook years from the sitemap and caches them,
+// unless a lookup is running or failed within publishedYearsRetry
+func refreshPublishedYears(ctx context.Context) {
+	refreshing := &dependencies(ctx).refreshingPublishedYears
+	if !refreshing.CompareAndSwap(false, true) {
+		return
+	}
+	defer refreshing.Store(false)
+
+	dbHandler := databases.FromContext(ctx)
+	if failed, err := dbHandler.Exists(databases.Cache, publishedYearsFailedKey); err == nil && failed {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	years, err := common.PublishedYears(ctx)
+	if err != nil || len(years) == 0 {
+		log.Errorf("[YEAR] Failed to find published handbook years, retrying in %s: %v", publishedYearsRetry, err)
+		if err := dbHandler.Store(databases.Cache, publishedYearsFailedKey, true, publishedYearsRetry); err != nil {
+			log.Errorf("Error saving to cache: %v", err)
+		}
+		return
+	}
+	if err := dbHandler.Store(databases.Cache, publishedYearsKey, years, publishedYearsTTL); err != nil {
+		log.Errorf("Error saving to cache: %v", err)
+	}
+}
+
+// CurrentYear resolves the "current" handbook year of the server of a context, for callers outside of requests
+// such as the scheduler
+func CurrentYear(ctx context.Context) string {
+	year, err := resolveYear(ctx, "current")
+	if err != nil {
+		return strconv.Itoa(time.Now().Year())
+	}
+	return year
+}
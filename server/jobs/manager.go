@@ -0,0 +1,285 @@
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"handbook-scraper/utils/databases"
+	"handbook-scraper/utils/fetchlog"
+	"handbook-scraper/utils/log"
+)
+
+const (
+	// jobTTL is how long job status and results are kept in Redis
+	jobTTL = 24 * time.Hour
+	// finishedJobRetention is how long finished jobs are kept in memory, after which they are read from Redis
+	finishedJobRetention = 10 * time.Minute
+	// heartbeatInterval is how often the replica running a job reports it alive
+	heartbeatInterval = 30 * time.Second
+	// jobLease is how long a pending or running job may go without a heartbeat before it is considered orphaned,
+	// such as when the replica running it was stopped
+	jobLease = 3 * heartbeatInterval
+)
+
+var (
+	ErrUnknownJobType = errors.New("unknown job type")
+	ErrJobNotFound    = errors.New("job not found")
+	ErrJobFinished    = errors.New("job has already finished")
+)
+
+// Manager runs jobs in the background and tracks their status.
+// Job status is mirrored to Redis so any replica can report on it.
+type Manager struct {
+	ctx     context.Context // Jobs run with contexts derived from it
+	stop    context.CancelFunc
+	mu      sync.Mutex
+	runners map[string]Runner
+	jobs    map[string]*Job
+	cancels map[string]context.CancelFunc
+}
+
+// NewManager creates a manager whose jobs run with contexts derived from ctx,
+// and are mirrored to the database handler of ctx
+func NewManager(ctx context.Context) *Manager {
+	ctx, stop := context.WithCancel(ctx)
+	m := &Manager{
+		ctx:     ctx,
+		stop:    stop,
+		runners: map[string]Runner{},
+		jobs:    map[string]*Job{},
+		cancels: map[string]context.CancelFunc{},
+	}
+	go m.evictFinished()
+	return m
+}
+
+// Close cancels the running jobs and stops the manager
+func (m *Manager) Close() {
+	m.stop()
+}
+
+// Register registers the runner for a job type
+func (m *Manager) Register(jobType string, runner Runner) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.runners[jobType] = runner
+}
+
+// Submit creates a job of the given type and starts it in the background
+func (m *Manager) Submit(jobType string, params json.RawMessage) (Job, error) {
+	m.mu.Lock()
+	runner, ok := m.runners[jobType]
+	if !ok {
+		m.mu.Unlock()
+		return Job{}, fmt.Errorf("%w: %s", ErrUnknownJobType, jobType)
+	}
+
+	id, err := newJobID()
+	if err != nil {
+		m.mu.Unlock()
+		return Job{}, fmt.Errorf("failed to generate job ID: %w", err)
+	}
+
+	// Upstream fetches made by the job are attributed to it in the fetch audit trail
+	ctx, cancel := context.WithCancel(fetchlog.WithRequestID(m.ctx, "job:"+id))
+	now := time.Now()
+	job := &Job{
+		ID:          id,
+		Type:        jobType,
+		Params:      params,
+		Status:      Pending,
+		CreatedAt:   now,
+		HeartbeatAt: &now,
+	}
+	m.jobs[id] = job
+	m.cancels[id] = cancel
+	snapshot := *job
+	m.mu.Unlock()
+
+	m.persist(snapshot)
+	log.Infof("[JOBS] Submitted %s job %s", jobType, id)
+
+	go m.run(ctx, id, runner)
+
+	return snapshot, nil
+}
+
+// Get returns the job with the given ID, falling back to Redis for jobs started by other replicas.
+// A job another replica stopped reporting alive is marked failed.
+func (m *Manager) Get(id string) (Job, error) {
+	m.mu.Lock()
+	if job, ok := m.jobs[id]; ok {
+		snapshot := *job
+		m.mu.Unlock()
+		return snapshot, nil
+	}
+	m.mu.Unlock()
+
+	var job Job
+	if err := databases.FromContext(m.ctx).Retrieve(databases.Cache, jobKey(id), &job); err != nil {
+		return Job{}, ErrJobNotFound
+	}
+	if !job.finished() && (job.HeartbeatAt == nil || time.Since(*job.HeartbeatAt) > jobLease) {
+		now := time.Now()
+		job.Status = Failed
+		job.Error = "the replica running the job stopped"
+		job.FinishedAt = &now
+		m.persist(job)
+		log.Warnf("[JOBS] Job %s was orphaned, marking it failed", id)
+	}
+	return job, nil
+}
+
+// Cancel cancels a pending or running job.
+// Only jobs running on this replica can be cancelled.
+func (m *Manager) Cancel(id string) (Job, error) {
+	m.mu.Lock()
+	job, ok := m.jobs[id]
+	if !ok {
+		m.mu.Unlock()
+		return Job{}, ErrJobNotFound
+	}
+	if job.finished() {
+		snapshot := *job
+		m.mu.Unlock()
+		return snapshot, ErrJobFinished
+	}
+	if cancel, ok := m.cancels[id]; ok {
+		cancel()
+	}
+	snapshot := *job
+	m.mu.Unlock()
+
+	log.Infof("[JOBS] Cancellation requested for job %s", id)
+	return snapshot, nil
+}
+
+// run executes the job and records its outcome
+func (m *Manager) run(ctx context.Context, id string, runner Runner) {
+	m.update(id, func(job *Job) {
+		now := time.Now()
+		job.Status = Running
+		job.StartedAt = &now
+	})
+
+	var params json.RawMessage
+	m.mu.Lock()
+	params = m.jobs[id].Params
+	m.mu.Unlock()
+
+	stopHeartbeat := m.heartbeat(id)
+	result, err := runner(ctx, params)
+	stopHeartbeat()
+
+	m.update(id, func(job *Job) {
+		now := time.Now()
+		job.FinishedAt = &now
+		switch {
+		case ctx.Err() != nil:
+			job.Status = Cancelled
+			job.Error = ctx.Err().Error()
+		case err != nil:
+			job.Status = Failed
+			job.Error = err.Error()
+		default:
+			job.Status = Succeeded
+			job.Result = result
+		}
+	})
+
+	m.mu.Lock()
+	if cancel, ok := m.cancels[id]; ok {
+		cancel()
+		delete(m.cancels, id)
+	}
+	status := m.jobs[id].Status
+	m.mu.Unlock()
+
+	log.Infof("[JOBS] Job %s finished with status %s", id, status)
+}
+
+// heartbeat reports a job alive every heartbeatInterval until the returned function is called,
+// which waits for the last heartbeat to be saved so it never overwrites the outcome of the job
+func (m *Manager) heartbeat(id string) func() {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(heartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				m.update(id, func(job *Job) {
+					now := time.Now()
+					job.HeartbeatAt = &now
+				})
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// evictFinished removes jobs from memory once they have been finished for finishedJobRetention.
+// They can still be read from Redis until they expire.
+func (m *Manager) evictFinished() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		m.mu.Lock()
+		for id, job := range m.jobs {
+			if job.finished() && job.FinishedAt != nil && time.Since(*job.FinishedAt) > finishedJobRetention {
+				delete(m.jobs, id)
+			}
+		}
+		m.mu.Unlock()
+	}
+}
+
+// update applies fn to the job under lock and persists the new state
+func (m *Manager) update(id string, fn func(job *Job)) {
+	m.mu.Lock()
+	job := m.jobs[id]
+	fn(job)
+	snapshot := *job
+	m.mu.Unlock()
+
+	m.persist(snapshot)
+}
+
+// persist mirrors the job state to Redis
+func (m *Manager) persist(job Job) {
+	if err := databases.FromContext(m.ctx).Store(databases.Cache, jobKey(job.ID), job, jobTTL); err != nil {
+		log.Errorf("[JOBS] Error saving job %s: %v", job.ID, err)
+	}
+}
+
+// jobKey returns the Redis key for a job
+func jobKey(id string) string {
+	return "job:" + id
+}
+
+// newJobID generates a random job ID
+func newJobID() (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
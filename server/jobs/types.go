@@ -0,0 +1,42 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// Status represents the lifecycle state of a job
+type Status string
+
+const (
+	Pending   Status = "pending"
+	Running   Status = "running"
+	Succeeded Status = "succeeded"
+	Failed    Status = "failed"
+	Cancelled Status = "cancelled"
+)
+
+// Job represents a long-running operation submitted through the jobs API
+type Job struct {
+	ID         string          `json:"id"`
+	Type       string          `json:"type"`   // e.g. crawl_year, resolve_course_graph, bulk_export
+	Params     json.RawMessage `json:"params"` // Runner specific parameters
+	Status     Status          `json:"status"`
+	Result     interface{}     `json:"result,omitempty"`
+	Error      string          `json:"error,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+	StartedAt  *time.Time      `json:"started_at,omitempty"`
+	FinishedAt *time.Time      `json:"finished_at,omitempty"`
+	// HeartbeatAt is when the replica running the job last reported it alive, so other replicas can tell it was orphaned
+	HeartbeatAt *time.Time `json:"heartbeat_at,omitempty"`
+}
+
+// finished reports whether the job has stopped, successfully or not
+func (j Job) finished() bool {
+	return j.Status != Pending && j.Status != Running
+}
+
+// Runner executes a job of a given type.
+// It should stop early and return ctx.Err() once ctx is cancelled.
+type Runner func(ctx context.Context, params json.RawMessage) (interface{}, error)
@@ -0,0 +1,151 @@
+package server
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"handbook-scraper/utils"
+	"handbook-scraper/utils/log"
+)
+
+// jsonCaseHeader negotiates the casing of JSON keys, as an alternative to the case query parameter
+const jsonCaseHeader = "X-JSON-Case"
+
+// fieldName matches the field names of a fields query parameter
+var fieldName = regexp.MustCompile(`[A-Za-z0-9_]+`)
+
+// jsonCaseMiddleware serves camelCase JSON keys instead of snake_case when requested with case=camel
+// or the X-JSON-Case header. Query parameters and JSON request bodies may then use camelCase names as well,
+// which are converted back before the handlers see them. Responses which are not JSON are passed through.
+func jsonCaseMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Writer.Header().Add("Vary", jsonCaseHeader)
+
+		// The query is read from the URL rather than the context, which would cache it before it is rewritten
+		query := c.Request.URL.Query()
+		requested := c.GetHeader(jsonCaseHeader)
+		if requested == "" {
+			requested = query.Get("case")
+		}
+		if !strings.EqualFold(requested, "camel") || c.IsWebsocket() {
+			c.Next()
+			return
+		}
+
+		for key, values := range query {
+			if strings.HasPrefix(key, "filter[") {
+				// Unknown filters are refused, so the camelCase name is replaced rather than kept
+				delete(query, key)
+				query[fieldName.ReplaceAllStringFunc(key, utils.ToSnakeCase)] = values
+				continue
+			}
+			if snake := utils.ToSnakeCase(key); snake != key && !query.Has(snake) {
+				query[snake] = values
+			}
+		}
+		for _, param := range []string{"fields", "sort"} {
+			if value := query.Get(param); value != "" {
+				query.Set(param, fieldName.ReplaceAllStringFunc(value, utils.ToSnakeCase))
+			}
+		}
+		c.Request.URL.RawQuery = query.Encode()
+
+		if c.Request.Body != nil && strings.Contains(c.ContentType(), "json") {
+			body, err := io.ReadAll(c.Request.Body)
+			if err == nil && len(body) > 0 {
+				if renamed, err := utils.RenameJSONKeys(body, utils.ToSnakeCase); err == nil {
+					body = renamed
+				}
+			}
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		}
+
+		writer := &camelCaseWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+		writer.flush()
+	}
+}
+
+// camelCaseWriter holds back JSON responses so their keys can be converted to camelCase once complete.
+// NDJSON responses are converted a line at a time, so they are still streamed.
+type camelCaseWriter struct {
+	gin.ResponseWriter
+	decided   bool
+	buffering bool
+	lines     bool
+	body      bytes.Buffer
+}
+
+// decide works out from the content type whether the response is JSON or NDJSON, on its first write
+func (w *camelCaseWriter) decide() {
+	if !w.decided {
+		w.decided = true
+		contentType := w.Header().Get("Content-Type")
+		w.buffering = strings.Contains(contentType, "application/json")
+		w.lines = strings.Contains(contentType, "application/x-ndjson")
+	}
+}
+
+func (w *camelCaseWriter) Write(data []byte) (int, error) {
+	w.decide()
+	switch {
+	case w.buffering:
+		return w.body.Write(data)
+	case w.lines:
+		w.body.Write(data)
+		for {
+			line, err := w.body.ReadBytes('\n')
+			if err != nil {
+				// An incomplete line is kept until the rest of it is written
+				w.body.Write(line)
+				break
+			}
+			if _, err := w.ResponseWriter.Write(camelCaseKeys(line)); err != nil {
+				return 0, err
+			}
+		}
+		return len(data), nil
+	default:
+		return w.ResponseWriter.Write(data)
+	}
+}
+
+func (w *camelCaseWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Flush passes streamed responses straight through
+func (w *camelCaseWriter) Flush() {
+	if !w.buffering {
+		w.ResponseWriter.Flush()
+	}
+}
+
+// flush writes the held back JSON response, or the last NDJSON line, with its keys in camelCase
+func (w *camelCaseWriter) flush() {
+	if !w.buffering && w.body.Len() == 0 {
+		return
+	}
+
+	if _, err := w.ResponseWriter.Write(camelCaseKeys(w.body.Bytes())); err != nil && err != http.ErrHijacked {
+		log.Errorf("[JSON CASE] Failed to write response: %v", err)
+	}
+}
+
+// camelCaseKeys converts the keys of a JSON document to camelCase, returning it as it is if it is not valid JSON
+func camelCaseKeys(document []byte) []byte {
+	renamed, err := utils.RenameJSONKeys(document, utils.ToCamelCase)
+	if err != nil {
+		log.Errorf("[JSON CASE] Failed to convert response keys: %v", err)
+		return document
+	}
+	if bytes.HasSuffix(document, []byte("\n")) {
+		renamed = append(renamed, '\n')
+	}
+	return renamed
+}
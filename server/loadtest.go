@@ -0,0 +1,392 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"handbook-scraper/utils/log"
+)
+
+// defaultLoadTestMix is the traffic mix of a load test, as scenario=weight, modelled on the requests of planner apps:
+// mostly unit lookups, then requisite checks and batches of a plan's units, and a few graphs, lists and unknown codes
+const defaultLoadTestMix = "unit=50,check=15,batch=10,any=10,graph=5,list=5,miss=5"
+
+// loadTestFixtures are the units a load test requests, read from an NDJSON file of unit documents
+type loadTestFixtures struct {
+	codes      []string
+	requisites map[string][]string // Unit code to the codes of its requisites
+}
+
+// loadTestScenario builds the requests of a kind of traffic
+type loadTestScenario func(rng *rand.Rand, year string, fixtures *loadTestFixtures) (string, string, interface{})
+
+// loadTestScenarios are the kinds of traffic a load test mix can weight, as the method, path and body of a request
+var loadTestScenarios = map[string]loadTestScenario{
+	"unit": func(rng *rand.Rand, year string, f *loadTestFixtures) (string, string, interface{}) {
+		return http.MethodGet, "/v1/" + year + "/units/" + f.pick(rng), nil
+	},
+	"any": func(rng *rand.Rand, year string, f *loadTestFixtures) (string, string, interface{}) {
+		return http.MethodGet, "/v1/" + year + "/any/" + f.pick(rng), nil
+	},
+	"graph": func(rng *rand.Rand, year string, f *loadTestFixtures) (string, string, interface{}) {
+		return http.MethodGet, "/v1/" + year + "/units/" + f.pick(rng) + "/graph", nil
+	},
+	"list": func(rng *rand.Rand, year string, f *loadTestFixtures) (string, string, interface{}) {
+		return http.MethodGet, "/v1/" + year + "/units?limit=100", nil
+	},
+	"miss": func(rng *rand.Rand, year string, f *loadTestFixtures) (string, string, interface{}) {
+		return http.MethodGet, fmt.Sprintf("/v1/%s/units/ZZZ%04d", year, rng.IntN(10000)), nil
+	},
+	"batch": func(rng *rand.Rand, year string, f *loadTestFixtures) (string, string, interface{}) {
+		codes := make([]string, 0, 24)
+		for i := 0; i < cap(codes); i++ {
+			codes = append(codes, f.pick(rng))
+		}
+		return http.MethodPost, "/v1/" + year + "/units/batch", map[string][]string{"codes": codes}
+	},
+	"check": func(rng *rand.Rand, year string, f *loadTestFixtures) (string, string, interface{}) {
+		code := f.pick(rng)
+		completed := []map[string]string{}
+		for _, requisite := range f.requisites[code] {
+			if rng.IntN(2) == 0 {
+				completed = append(completed, map[string]string{"code": requisite})
+			}
+		}
+		return http.MethodPost, "/v1/" + year + "/units/" + code + "/check", completed
+	},
+}
+
+// pick returns a random fixture unit code
+func (f *loadTestFixtures) pick(rng *rand.Rand) string {
+	return f.codes[rng.IntN(len(f.codes))]
+}
+
+// loadTestStats are the outcomes of the requests of a scenario
+type loadTestStats struct {
+	Requests  int            `json:"requests"`
+	Failures  int            `json:"failures"` // Requests which failed to complete or returned a 5xx status
+	Statuses  map[string]int `json:"statuses"`
+	P50       time.Duration  `json:"p50"`
+	P90       time.Duration  `json:"p90"`
+	P99       time.Duration  `json:"p99"`
+	Max       time.Duration  `json:"max"`
+	latencies []time.Duration
+}
+
+// LoadTestReport summarises a load test, by scenario and in total
+type LoadTestReport struct {
+	Target     string                    `json:"target"`
+	Mix        string                    `json:"mix"`
+	Duration   time.Duration             `json:"duration"`
+	Throughput float64                   `json:"throughput"` // Requests per second
+	Total      *loadTestStats            `json:"total"`
+	Scenarios  map[string]*loadTestStats `json:"scenarios"`
+}
+
+// RunLoadTest sends a weighted mix of realistic traffic to a server and reports the latency and status codes of each
+// kind of request, so performance changes can be compared before and after. The units it requests are read from an
+// NDJSON fixture of unit documents, such as an export of the units the target server has stored:
+//
+//	handbook-scraper loadtest -fixtures units_2025.ndjson [-target http://localhost:8080] [-duration 30s]
+func RunLoadTest(args []string) error {
+	flags := flag.NewFlagSet("loadtest", flag.ContinueOnError)
+	target := flags.String("target", "http://localhost:8080", "base URL of the server to load test")
+	fixturesFile := flags.String("fixtures", "units_2025.ndjson", "NDJSON file of the unit documents to request")
+	year := flags.String("year", "2025", "handbook year of the fixtures")
+	mix := flags.String("mix", defaultLoadTestMix, "comma-separated scenario=weight traffic mix")
+	duration := flags.Duration("duration", 30*time.Second, "how long to send requests for")
+	concurrency := flags.Int("concurrency", 16, "how many requests are in flight at once")
+	rate := flags.Int("rate", 0, "requests per second across all workers, unlimited if 0")
+	apiKey := flags.String("key", "", "bearer key sent with every request, for the limits of a tenant")
+	out := flags.String("out", "", "file to write the report to as JSON")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *concurrency < 1 {
+		return fmt.Errorf("concurrency must be at least 1")
+	}
+
+	weights, err := parseLoadTestMix(*mix)
+	if err != nil {
+		return err
+	}
+	fixtures, err := readLoadTestFixtures(*fixturesFile)
+	if err != nil {
+		return err
+	}
+	log.Infof("[LOAD TEST] Read %d fixture units from %s", len(fixtures.codes), *fixturesFile)
+
+	report := runLoadTest(strings.TrimSuffix(*target, "/"), *year, fixtures, weights, *duration, *concurrency, *rate, *apiKey)
+	report.Mix = *mix
+	printLoadTestReport(os.Stdout, report)
+
+	if *out != "" {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(*out, data, 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseLoadTestMix parses a scenario=weight traffic mix into the scenario of each unit of weight
+func parseLoadTestMix(mix string) ([]string, error) {
+	var weights []string
+	for _, entry := range strings.Split(mix, ",") {
+		name, rawWeight, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid mix entry %q, expected scenario=weight", entry)
+		}
+		if _, known := loadTestScenarios[name]; !known {
+			return nil, fmt.Errorf("unknown scenario %q", name)
+		}
+		weight, err := strconv.Atoi(rawWeight)
+		if err != nil || weight < 0 {
+			return nil, fmt.Errorf("invalid weight of %s: %q", name, rawWeight)
+		}
+		for i := 0; i < weight; i++ {
+			weights = append(weights, name)
+		}
+	}
+	if len(weights) == 0 {
+		return nil, fmt.Errorf("the mix has no weight")
+	}
+	return weights, nil
+}
+
+// readLoadTestFixtures reads the unit documents of an NDJSON file, and the requisites of each unit
+func readLoadTestFixtures(path string) (*loadTestFixtures, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	fixtures := &loadTestFixtures{requisites: map[string][]string{}}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 1024*1024), 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var unit struct {
+			Common struct {
+				Code string `json:"code"`
+			} `json:"common"`
+			Requisites []struct {
+				Containers []requisiteFixture `json:"containers"`
+			} `json:"requisites"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &unit); err != nil {
+			return nil, fmt.Errorf("invalid fixture on line %d: %w", line, err)
+		}
+		code := strings.ToUpper(unit.Common.Code)
+		if code == "" {
+			continue
+		}
+
+		fixtures.codes = append(fixtures.codes, code)
+		for _, requisite := range unit.Requisites {
+			for _, container := range requisite.Containers {
+				fixtures.requisites[code] = container.codes(fixtures.requisites[code])
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(fixtures.codes) == 0 {
+		return nil, fmt.Errorf("%s has no units", path)
+	}
+	return fixtures, nil
+}
+
+// requisiteFixture is a group of requisites of a fixture unit
+type requisiteFixture struct {
+	Units []struct {
+		UnitCode string `json:"unit_code"`
+	} `json:"units"`
+	Containers []requisiteFixture `json:"containers"`
+}
+
+// codes appends the codes of the requisites of a group and its nested groups
+func (r requisiteFixture) codes(codes []string) []string {
+	for _, unit := range r.Units {
+		codes = append(codes, unit.UnitCode)
+	}
+	for _, child := range r.Containers {
+		codes = child.codes(codes)
+	}
+	return codes
+}
+
+// runLoadTest sends the requests of a mix from concurrent workers until the duration is up
+func runLoadTest(target string, year string, fixtures *loadTestFixtures, weights []string, duration time.Duration, concurrency int, rate int, apiKey string) LoadTestReport {
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
+	defer cancel()
+
+	// Without a rate, workers send their next request as soon as the last one completes
+	var tickets <-chan time.Time
+	if rate > 0 {
+		ticker := time.NewTicker(time.Second / time.Duration(rate))
+		defer ticker.Stop()
+		tickets = ticker.C
+	}
+
+	client := &http.Client{
+		Timeout:   30 * time.Second,
+		Transport: &http.Transport{MaxIdleConnsPerHost: concurrency},
+	}
+	report := LoadTestReport{Target: target, Total: &loadTestStats{}, Scenarios: map[string]*loadTestStats{}}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	start := time.Now()
+
+	for worker := 0; worker < concurrency; worker++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			rng := rand.New(rand.NewPCG(uint64(worker), uint64(start.UnixNano())))
+			for {
+				if tickets != nil {
+					select {
+					case <-tickets:
+					case <-ctx.Done():
+						return
+					}
+				}
+				if ctx.Err() != nil {
+					return
+				}
+
+				name := weights[rng.IntN(len(weights))]
+				method, path, body := loadTestScenarios[name](rng, year, fixtures)
+				status, latency := sendLoadTestRequest(ctx, client, method, target+path, body, apiKey)
+				if status == 0 && ctx.Err() != nil {
+					return // Cut off by the end of the load test
+				}
+
+				mu.Lock()
+				if report.Scenarios[name] == nil {
+					report.Scenarios[name] = &loadTestStats{}
+				}
+				report.Scenarios[name].record(status, latency)
+				report.Total.record(status, latency)
+				mu.Unlock()
+			}
+		}(worker)
+	}
+	wg.Wait()
+
+	report.Duration = time.Since(start)
+	report.Throughput = float64(report.Total.Requests) / report.Duration.Seconds()
+	report.Total.summarise()
+	for _, stats := range report.Scenarios {
+		stats.summarise()
+	}
+	return report
+}
+
+// sendLoadTestRequest sends a request and returns its status, 0 if it failed to complete, and its latency
+func sendLoadTestRequest(ctx context.Context, client *http.Client, method string, url string, body interface{}, apiKey string) (int, time.Duration) {
+	var reader io.Reader
+	if body != nil {
+		data, _ := json.Marshal(body)
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return 0, 0
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, time.Since(start)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return resp.StatusCode, time.Since(start)
+}
+
+// record counts the outcome of a request
+func (s *loadTestStats) record(status int, latency time.Duration) {
+	if s.Statuses == nil {
+		s.Statuses = map[string]int{}
+	}
+	s.Requests++
+	if status == 0 || status >= 500 {
+		s.Failures++
+	}
+	label := strconv.Itoa(status)
+	if status == 0 {
+		label = "error"
+	}
+	s.Statuses[label]++
+	s.latencies = append(s.latencies, latency)
+}
+
+// summarise computes the latency percentiles of the recorded requests
+func (s *loadTestStats) summarise() {
+	if len(s.latencies) == 0 {
+		return
+	}
+	sort.Slice(s.latencies, func(i, j int) bool { return s.latencies[i] < s.latencies[j] })
+	percentile := func(p float64) time.Duration {
+		return s.latencies[min(int(p*float64(len(s.latencies))), len(s.latencies)-1)]
+	}
+	s.P50, s.P90, s.P99 = percentile(0.5), percentile(0.9), percentile(0.99)
+	s.Max = s.latencies[len(s.latencies)-1]
+}
+
+// printLoadTestReport prints a report as a table of scenarios
+func printLoadTestReport(w io.Writer, report LoadTestReport) {
+	fmt.Fprintf(w, "%d requests to %s in %s, %.1f requests/s\n\n", report.Total.Requests, report.Target, report.Duration.Round(time.Millisecond), report.Throughput)
+
+	names := make([]string, 0, len(report.Scenarios))
+	for name := range report.Scenarios {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "SCENARIO\tREQUESTS\tFAILURES\tP50\tP90\tP99\tMAX\tSTATUSES")
+	row := func(name string, stats *loadTestStats) {
+		statuses := make([]string, 0, len(stats.Statuses))
+		for status, count := range stats.Statuses {
+			statuses = append(statuses, fmt.Sprintf("%s:%d", status, count))
+		}
+		sort.Strings(statuses)
+		fmt.Fprintf(table, "%s\t%d\t%d\t%s\t%s\t%s\t%s\t%s\n", name, stats.Requests, stats.Failures,
+			stats.P50.Round(time.Microsecond), stats.P90.Round(time.Microsecond), stats.P99.Round(time.Microsecond),
+			stats.Max.Round(time.Microsecond), strings.Join(statuses, " "))
+	}
+	for _, name := range names {
+		row(name, report.Scenarios[name])
+	}
+	row("total", report.Total)
+	table.Flush()
+}
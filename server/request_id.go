@@ -0,0 +1,38 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+	"handbook-scraper/utils/fetchlog"
+)
+
+// requestIDHeader carries the ID of a request, which upstream fetches made for it are attributed to
+const requestIDHeader = "X-Request-ID"
+
+// validRequestID matches request IDs accepted from callers, such as those set by a proxy
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// requestIDMiddleware gives every request an ID, reusing the caller's X-Request-ID if it is valid,
+// and returns it in the X-Request-ID response header
+func requestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(requestIDHeader)
+		if !validRequestID.MatchString(requestID) {
+			requestID = newRequestID()
+		}
+
+		c.Header(requestIDHeader, requestID)
+		c.Request = c.Request.WithContext(fetchlog.WithRequestID(c.Request.Context(), requestID))
+		c.Next()
+	}
+}
+
+// newRequestID generates a random request ID
+func newRequestID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
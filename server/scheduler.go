@@ -0,0 +1,152 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gocolly/colly/v2"
+	"handbook-scraper/scrapers/common"
+	"handbook-scraper/server/handlers"
+	"handbook-scraper/utils/databases"
+	"handbook-scraper/utils/log"
+)
+
+const (
+	calendarRefreshInterval = 24 * time.Hour
+	crawlRefreshInterval    = 24 * time.Hour
+	retentionInterval       = 24 * time.Hour
+	consistencyInterval     = 24 * time.Hour
+	schedulerLockTTL        = time.Minute
+)
+
+// startScheduler runs the background refresh jobs of the server whose dependencies ctx carries, until ctx is cancelled.
+// Jobs are guarded by distributed locks so only one replica runs each of them at a time.
+func startScheduler(ctx context.Context, calendarCollector *colly.Collector) {
+	go func() {
+		for {
+			refreshCalendars(ctx, calendarCollector)
+			if !sleep(ctx, calendarRefreshInterval) {
+				return
+			}
+		}
+	}()
+	go func() {
+		for {
+			// Replicas start together, so the refresh is staggered to spread the load upstream
+			if !sleep(ctx, databases.Jitter(crawlRefreshInterval)) {
+				return
+			}
+
+			// Crawls wait until the handbook stops throttling requests
+			if pause := common.ThrottlePause(); pause > 0 {
+				log.Infof("[SCHEDULER] The handbook is throttling requests, delaying the crawl by %s", pause)
+				if !sleep(ctx, pause) {
+					return
+				}
+			}
+			refreshHandbook(ctx)
+		}
+	}()
+	go func() {
+		for sleep(ctx, databases.Jitter(retentionInterval)) {
+			enforceRetention(ctx)
+		}
+	}()
+	go func() {
+		for sleep(ctx, databases.Jitter(consistencyInterval)) {
+			checkConsistency(ctx)
+		}
+	}()
+}
+
+// sleep waits for d, returning false if ctx is cancelled first
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// refreshHandbook starts differential crawls of the current year's stored pages,
+// so pages which changed upstream are re-scraped while unchanged pages are skipped
+func refreshHandbook(ctx context.Context) {
+	_, err := databases.FromContext(ctx).RunExclusive("scheduler:crawl", schedulerLockTTL, func(_ context.Context) error {
+		year := handlers.CurrentYear(ctx)
+		for _, itemType := range handlers.ScheduledCrawlItemTypes {
+			params, _ := json.Marshal(map[string]interface{}{
+				"year":         year,
+				"item_type":    itemType,
+				"stored_only":  true,
+				"differential": true,
+				"scheduled":    true,
+			})
+			job, err := handlers.Jobs(ctx).Submit("crawl_year", params)
+			if err != nil {
+				log.Errorf("[SCHEDULER] Failed to start %s %s crawl: %v", year, itemType, err)
+				continue
+			}
+			log.Infof("[SCHEDULER] Started %s %s crawl as job %s", year, itemType, job.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Errorf("[SCHEDULER] %v", err)
+	}
+}
+
+// enforceRetention starts a job purging stored documents older than their retention period
+func enforceRetention(ctx context.Context) {
+	_, err := databases.FromContext(ctx).RunExclusive("scheduler:retention", schedulerLockTTL, func(_ context.Context) error {
+		job, err := handlers.Jobs(ctx).Submit("enforce_retention", nil)
+		if err != nil {
+			return fmt.Errorf("failed to start retention job: %w", err)
+		}
+		log.Infof("[SCHEDULER] Started retention job %s", job.ID)
+		return nil
+	})
+	if err != nil {
+		log.Errorf("[SCHEDULER] %v", err)
+	}
+}
+
+// checkConsistency starts a job repairing drift between the handbook documents in Redis and MongoDB
+func checkConsistency(ctx context.Context) {
+	_, err := databases.FromContext(ctx).RunExclusive("scheduler:consistency", schedulerLockTTL, func(_ context.Context) error {
+		params, _ := json.Marshal(map[string]interface{}{"repair": true})
+		job, err := handlers.Jobs(ctx).Submit("check_consistency", params)
+		if err != nil {
+			return fmt.Errorf("failed to start consistency job: %w", err)
+		}
+		log.Infof("[SCHEDULER] Started consistency job %s", job.ID)
+		return nil
+	})
+	if err != nil {
+		log.Errorf("[SCHEDULER] %v", err)
+	}
+}
+
+// refreshCalendars refreshes the academic calendar of the current and next year
+func refreshCalendars(ctx context.Context, collector *colly.Collector) {
+	_, err := databases.FromContext(ctx).RunExclusive("scheduler:calendar", schedulerLockTTL, func(lockCtx context.Context) error {
+		thisYear, _ := strconv.Atoi(handlers.CurrentYear(ctx))
+		for _, year := range []int{thisYear, thisYear + 1} {
+			if lockCtx.Err() != nil {
+				return lockCtx.Err()
+			}
+			if _, err := handlers.RefreshCalendar(ctx, fmt.Sprintf("%d", year), collector); err != nil {
+				log.Errorf("[SCHEDULER] Failed to refresh %d calendar: %v", year, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Errorf("[SCHEDULER] %v", err)
+	}
+}
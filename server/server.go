@@ -1,46 +1,204 @@
 package server
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
 	"github.com/gin-gonic/gin"
 	"github.com/gocolly/colly/v2"
+	"handbook-scraper/scrapers/calendar"
 	"handbook-scraper/scrapers/common"
 	"handbook-scraper/server/handlers"
 	"handbook-scraper/utils/databases"
 	"handbook-scraper/utils/log"
+	"handbook-scraper/utils/tracing"
 )
 
+// shutdownTimeout is how long in-flight requests are given to finish when the standalone server is stopped
+const shutdownTimeout = 30 * time.Second
+
+// Config configures a server created with NewServer
+type Config struct {
+	Addr           string   // Address StartServer listens on
+	HandbookDomain string   // Domain the handbook is scraped from
+	CalendarDomain string   // Domain the academic calendar is scraped from
+	TrustedProxies []string // Proxies trusted to set the client IP
+	Scheduler      bool     // Run the background calendar and handbook refreshes
+	ReadOnly       bool     // Serve stored data only, never fetching from upstream, e.g. for an archive of a past year
+}
+
+// DefaultConfig is the configuration of the standalone server
+func DefaultConfig() Config {
+	return Config{
+		Addr:           ":8080",
+		HandbookDomain: "handbook.monash.edu",
+		CalendarDomain: calendar.BaseDomain,
+		TrustedProxies: []string{"127.0.0.1", "::1"},
+		Scheduler:      true,
+		ReadOnly:       readOnlyEnabled(),
+	}
+}
+
+// CollectorFactory creates the collector used to scrape a domain
+type CollectorFactory func(domain string) *colly.Collector
+
+// Server is the API created by NewServer, serving requests as an http.Handler
+type Server struct {
+	http.Handler
+	deps          *handlers.Dependencies
+	stopScheduler context.CancelFunc
+}
+
+// NewServer creates the API as an http.Handler, so it can be embedded in another Go program instead of running the binary.
+// Its handlers, jobs, and scheduler store data in storage, or the shared database handler, connected from
+// environment variables on first use, if it is nil. Servers in one process are independent of each other.
+// Collectors are created with common.SetupCollyCollector if collectorFactory is nil.
+func NewServer(config Config, storage *databases.DatabaseHandler, collectorFactory CollectorFactory) (*Server, error) {
+	if collectorFactory == nil {
+		collectorFactory = common.SetupCollyCollector
+	}
+
+	// A read-only server serves stored data only, so there is nothing for the scheduler to refresh
+	if config.ReadOnly {
+		log.Infof("Serving stored data only, upstream fetches are disabled")
+	}
+
+	collector := collectorFactory(config.HandbookDomain)
+	calendarCollector := collectorFactory(config.CalendarDomain)
+	deps := handlers.NewDependencies(storage, collector, config.ReadOnly)
+	router, err := newRouter(deps, config.TrustedProxies, collector, calendarCollector)
+	if err != nil {
+		deps.Close()
+		return nil, err
+	}
+
+	ctx, stop := context.WithCancel(handlers.WithDependencies(context.Background(), deps))
+	if config.Scheduler && !config.ReadOnly {
+		startScheduler(ctx, calendarCollector)
+	}
+	return &Server{Handler: router, deps: deps, stopScheduler: stop}, nil
+}
+
+// Close stops the scheduler and cancels the running jobs of the server
+func (s *Server) Close() {
+	s.stopScheduler()
+	s.deps.Close()
+}
+
+// readOnlyEnabled reports whether READ_ONLY makes the standalone server serve stored data only
+func readOnlyEnabled() bool {
+	enabled, _ := strconv.ParseBool(os.Getenv("READ_ONLY"))
+	return enabled
+}
+
+// StartServer runs the standalone server with the default configuration
 func StartServer() {
-	databases.GetDatabaseHandler()
+	if err := databases.Init(); err != nil {
+		log.Fatalf("%v", err)
+	}
+	databases.ReconnectOnRotation()
+	defer func() {
+		if err := databases.Shutdown(); err != nil {
+			log.Errorf("Failed to close databases: %v", err)
+		}
+	}()
+
+	shutdownTracing, err := tracing.Init(context.Background())
+	if err != nil {
+		log.Errorf("Failed to set up tracing: %v", err)
+	} else {
+		defer shutdownTracing(context.Background())
+	}
+
+	config := DefaultConfig()
+	handler, err := NewServer(config, nil, nil)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	defer handler.Close()
+
+	// On SIGINT or SIGTERM, such as during a deploy, in-flight requests are finished before the deferred shutdown
+	// of the databases writes the queued write-behind documents
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	collector := common.SetupCollyCollector("handbook.monash.edu")
-	router := SetupRouter(collector)
+	server := &http.Server{Addr: config.Addr, Handler: handler}
+	go func() {
+		log.Infof("Server started on %s", config.Addr)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Errorf("Server stopped: %v", err)
+			stop()
+		}
+	}()
+
+	<-ctx.Done()
+	log.Infof("Shutting down, finishing in-flight requests")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Errorf("Failed to finish in-flight requests: %v", err)
+	}
+}
 
-	log.Infof("Server started on port 8080")
-	err := router.Run(":8080")
+// SetupRouter creates the router serving the API with the given database handler, or the shared handler if it is nil.
+// Unlike NewServer, it runs no scheduler.
+func SetupRouter(dbHandler *databases.DatabaseHandler, c *colly.Collector, calendarCollector *colly.Collector) *gin.Engine {
+	router, err := newRouter(handlers.NewDependencies(dbHandler, c, false), DefaultConfig().TrustedProxies, c, calendarCollector)
 	if err != nil {
-		return
+		log.Fatal(err.Error())
 	}
+	return router
 }
 
-func SetupRouter(c *colly.Collector) *gin.Engine {
+// newRouter creates the router serving the API
+func newRouter(deps *handlers.Dependencies, trustedProxies []string, c *colly.Collector, calendarCollector *colly.Collector) (*gin.Engine, error) {
 	router := gin.Default()
 
+	// Handlers find the storage, jobs, and caches of the server in the context of its requests
+	router.Use(dependenciesMiddleware(deps))
+
 	// Add CORS middleware
 	router.Use(corsMiddleware())
+	router.Use(requestIDMiddleware())
+	router.Use(jsonCaseMiddleware())
+	router.Use(tracingMiddleware())
+	router.Use(deprecationMiddleware())
+	router.Use(serviceStatusMiddleware())
 
-	err := router.SetTrustedProxies([]string{"127.0.0.1", "::1"})
-	if err != nil {
-		log.Fatal(err.Error())
+	if err := router.SetTrustedProxies(trustedProxies); err != nil {
+		return nil, fmt.Errorf("invalid trusted proxies: %w", err)
 	}
-	SetupRoutes(router, c)
-	return router
+	SetupRoutes(router, c, calendarCollector)
+	return router, nil
 }
 
+// dependenciesMiddleware adds the dependencies of the server to the context of every request
+func dependenciesMiddleware(deps *handlers.Dependencies) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request = c.Request.WithContext(handlers.WithDependencies(c.Request.Context(), deps))
+		c.Next()
+	}
+}
+
+// corsMiddleware allows browsers at the origins of CORS_ALLOWED_ORIGINS to call the API, or at any origin if it is unset
 func corsMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, GET")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Accept, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization")
+		if len(handlers.AllowedOrigins()) == 0 {
+			c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
+		} else if origin := c.GetHeader("Origin"); handlers.AllowedOrigin(origin) {
+			c.Writer.Header().Set("Access-Control-Allow-Origin", origin)
+		}
+		c.Writer.Header().Add("Vary", "Origin")
+		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, GET, PUT, DELETE")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Accept, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, X-JSON-Case")
+		c.Writer.Header().Set("Access-Control-Expose-Headers", "Deprecation, Sunset, Link, Retry-After, X-RateLimit-Limit, X-RateLimit-Remaining, X-Service-Status")
 		c.Writer.Header().Set("Access-Control-Max-Age", "86400") // 24 hours
 
 		if c.Request.Method == "OPTIONS" {
@@ -52,21 +210,138 @@ func corsMiddleware() gin.HandlerFunc {
 	}
 }
 
-func SetupRoutes(router *gin.Engine, collector *colly.Collector) {
-	router.GET("v1/:year/units/:code", func(c *gin.Context) {
+func SetupRoutes(router *gin.Engine, collector *colly.Collector, calendarCollector *colly.Collector) {
+	router.Use(authorizationMiddleware(), tenantMiddleware())
+
+	router.GET("v1/:year/units", func(c *gin.Context) {
+		handlers.ListItemsHandler(c, "units")
+	})
+	router.GET("v1/:year/courses", func(c *gin.Context) {
+		handlers.ListItemsHandler(c, "courses")
+	})
+	router.GET("v1/:year/aos", func(c *gin.Context) {
+		handlers.ListItemsHandler(c, "aos")
+	})
+	router.POST("v1/:year/units/batch", func(c *gin.Context) {
+		handlers.BatchHandler(c, collector, "units")
+	})
+	router.POST("v1/:year/courses/batch", func(c *gin.Context) {
+		handlers.BatchHandler(c, collector, "courses")
+	})
+	router.POST("v1/:year/aos/batch", func(c *gin.Context) {
+		handlers.BatchHandler(c, collector, "aos")
+	})
+	router.GET("v1/:year/units/:code", codeValidationMiddleware("units"), func(c *gin.Context) {
 		handlers.HandbookHandler(c, collector, "units")
 	})
-	router.GET("v1/:year/courses/:code", func(c *gin.Context) {
+	router.GET("v1/:year/courses/:code", codeValidationMiddleware("courses"), func(c *gin.Context) {
 		handlers.HandbookHandler(c, collector, "courses")
 	})
-	router.GET("v1/:year/aos/:code", func(c *gin.Context) {
+	router.GET("v1/:year/aos/:code", codeValidationMiddleware("aos"), func(c *gin.Context) {
 		handlers.HandbookHandler(c, collector, "aos")
 	})
-	router.POST("v1/:year/units/:code/check", func(c *gin.Context) {
+	router.GET("v1/:year/any/:code", codeValidationMiddleware("any"), func(c *gin.Context) {
+		handlers.AnyItemHandler(c, collector)
+	})
+	router.GET("v1/:year/units/:code/graph", codeValidationMiddleware("units"), func(c *gin.Context) {
+		handlers.UnitGraphHandler(c, collector)
+	})
+	router.GET("v1/:year/courses/:code/graph", codeValidationMiddleware("courses"), func(c *gin.Context) {
+		handlers.CurriculumGraphHandler(c, collector, "courses")
+	})
+	router.GET("v1/:year/aos/:code/graph", codeValidationMiddleware("aos"), func(c *gin.Context) {
+		handlers.CurriculumGraphHandler(c, collector, "aos")
+	})
+	router.GET("v1/:year/courses/:code/precomputed_graph", codeValidationMiddleware("courses"), handlers.CourseGraphSummaryHandler)
+	router.GET("v1/:year/units/:code/versions", codeValidationMiddleware("units"), func(c *gin.Context) {
+		handlers.UnitVersionsHandler(c, collector)
+	})
+	router.GET("v1/:year/units/:code/availability", codeValidationMiddleware("units"), handlers.AvailabilityHandler)
+	router.GET("v1/:year/units/:code/unlocks", codeValidationMiddleware("units"), handlers.UnlocksHandler)
+	router.GET("v1/:year/units/:code/requisites/text", codeValidationMiddleware("units"), func(c *gin.Context) {
+		handlers.RequisiteTextHandler(c, collector)
+	})
+	router.POST("v1/:year/units/:code/check", codeValidationMiddleware("units"), func(c *gin.Context) {
 		handlers.UnitCheckHandler(c, collector)
 	})
+	router.POST("v1/:year/aos/:code/audit", codeValidationMiddleware("aos"), func(c *gin.Context) {
+		handlers.AosAuditHandler(c, collector)
+	})
+	router.POST("v1/:year/courses/:code/credits", codeValidationMiddleware("courses"), func(c *gin.Context) {
+		handlers.CourseCreditsHandler(c, collector)
+	})
+	router.POST("v1/:year/courses/:code/electives", codeValidationMiddleware("courses"), func(c *gin.Context) {
+		handlers.ElectiveSuggestionHandler(c, collector, "courses")
+	})
+	router.POST("v1/:year/aos/:code/electives", codeValidationMiddleware("aos"), func(c *gin.Context) {
+		handlers.ElectiveSuggestionHandler(c, collector, "aos")
+	})
+	graphql := handlers.NewGraphQLHandler(collector)
+	router.GET("graphql", graphql)
+	router.POST("graphql", graphql)
+	router.POST("v1/:year/answer", func(c *gin.Context) {
+		handlers.AnswerHandler(c, collector)
+	})
+	router.POST("v1/:year/wam", func(c *gin.Context) {
+		handlers.WAMHandler(c, collector)
+	})
+	router.GET("v1/:year/calendar", func(c *gin.Context) {
+		handlers.CalendarHandler(c, calendarCollector)
+	})
+	router.GET("v1/:year/faculties/staff", handlers.FacultyStaffHandler)
+	router.GET("v1/:year/tags", handlers.TagsHandler)
+	router.GET("v1/:year/tags/:tag/units", handlers.TagUnitsHandler)
+	router.GET("v1/:year/analytics/credit_points", handlers.CreditPointsAnalyticsHandler)
+	router.GET("v1/:year/analytics/assessments", handlers.AssessmentAnalyticsHandler)
+	router.GET("v1/:year/analytics/prerequisite_depth", handlers.PrerequisiteDepthAnalyticsHandler)
+	router.GET("v1/:year/unit_sets/:name", func(c *gin.Context) {
+		handlers.UnitSetHandler(c, collector)
+	})
+	router.GET("v1/unit_sets", handlers.ListUnitSetsHandler)
 	router.GET("v1/handbook/search_url", func(c *gin.Context) {
 		handlers.GetHandbookSearchAPI(c, collector)
 	})
+	router.POST("v1/plan/compare", func(c *gin.Context) {
+		handlers.ComparePlansHandler(c, collector)
+	})
+	router.GET("v1/planner/session", func(c *gin.Context) {
+		handlers.PlannerSessionHandler(c, collector)
+	})
+	router.POST("v1/jobs", handlers.SubmitJobHandler)
+	router.GET("v1/jobs/:id", handlers.GetJobHandler)
+	router.GET("v1/jobs/:id/file", handlers.JobFileHandler)
+	router.DELETE("v1/jobs/:id", handlers.CancelJobHandler)
 	router.GET("v1/health", handlers.HealthCheckHandler)
+	router.GET("v1/openapi.json", handlers.OpenAPIHandler)
+	router.GET("v1/tools", handlers.ToolManifestHandler)
+
+	admin := router.Group("v1/admin")
+	admin.GET("quality", handlers.QualityHandler)
+	admin.GET("fetches", handlers.FetchHistoryHandler)
+	admin.GET("usage", handlers.TenantUsageHandler)
+	admin.POST("version_pins/:year/:code", codeValidationMiddleware("units"), handlers.PinVersionHandler)
+	admin.DELETE("version_pins/:year/:code", codeValidationMiddleware("units"), handlers.UnpinVersionHandler)
+	admin.POST("reparse/:year", handlers.ReparseYearHandler)
+	admin.POST("reparse/:year/:type/:code", handlers.ReparseHandler)
+	admin.GET("requisite_report/:year", handlers.GetRequisiteReportHandler)
+	admin.GET("analytics_export/:year", handlers.AnalyticsExportHandler)
+	admin.POST("requisite_report/:year", handlers.RequisiteReportHandler)
+	admin.POST("course_graphs/:year", handlers.PrecomputeCourseGraphsHandler)
+	admin.GET("equivalences", handlers.ListEquivalencesHandler)
+	admin.PUT("equivalences/:code", codeValidationMiddleware("units"), handlers.SetEquivalencesHandler)
+	admin.DELETE("equivalences/:code", codeValidationMiddleware("units"), handlers.DeleteEquivalencesHandler)
+	admin.GET("watch_lists", handlers.ListWatchListsHandler)
+	admin.PUT("watch_lists/:name", handlers.SetWatchListHandler)
+	admin.DELETE("watch_lists/:name", handlers.DeleteWatchListHandler)
+	admin.PUT("unit_sets/:name", handlers.SetUnitSetHandler)
+	admin.DELETE("unit_sets/:name", handlers.DeleteUnitSetHandler)
+	admin.GET("curriculum_patches", handlers.ListCurriculumPatchesHandler)
+	admin.POST("curriculum_patches/:type/:code", handlers.CreateCurriculumPatchHandler)
+	admin.DELETE("curriculum_patches/:type/:code/:id", handlers.DeleteCurriculumPatchHandler)
+	admin.GET("retention", handlers.RetentionHandler)
+	admin.POST("retention", handlers.EnforceRetentionHandler)
+	admin.GET("consistency", handlers.ConsistencyHandler)
+	admin.POST("consistency", handlers.CheckConsistencyHandler)
+
+	setupDebugRoutes(router)
 }
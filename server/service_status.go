@@ -0,0 +1,76 @@
+package server
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"handbook-scraper/server/handlers"
+)
+
+// serviceStatusHeader reports the service status on every response, including those which are not JSON objects
+const serviceStatusHeader = "X-Service-Status"
+
+// serviceStatusExcludedRoutes are documents whose shape is fixed by a specification, so no field is added to them
+var serviceStatusExcludedRoutes = map[string]bool{
+	"/v1/openapi.json": true,
+	"/v1/tools":        true,
+	"/graphql":         true,
+}
+
+// serviceStatusMiddleware adds the service status, ok, degraded or stale, to every response as the service_status
+// field of JSON objects and the X-Service-Status header, so clients can show that data may be out of date
+// instead of guessing from latency
+func serviceStatusMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		status, _ := handlers.ServiceStatus(c.Request.Context())
+		c.Header(serviceStatusHeader, status)
+		if !serviceStatusExcludedRoutes[c.FullPath()] {
+			c.Writer = &serviceStatusWriter{ResponseWriter: c.Writer, field: `"service_status":"` + status + `"`}
+		}
+		c.Next()
+	}
+}
+
+// serviceStatusWriter adds the service status as the first field of JSON object responses
+type serviceStatusWriter struct {
+	gin.ResponseWriter
+	field   string
+	started bool
+	pending bool // The field was written after the opening brace, and is followed by a comma unless the object is empty
+}
+
+func (w *serviceStatusWriter) Write(data []byte) (int, error) {
+	if w.pending {
+		if trimmed := bytes.TrimLeft(data, " \t\r\n"); len(trimmed) > 0 {
+			w.pending = false
+			if trimmed[0] != '}' {
+				if _, err := w.ResponseWriter.Write([]byte(",")); err != nil {
+					return 0, err
+				}
+			}
+		}
+		return w.ResponseWriter.Write(data)
+	}
+	if w.started {
+		return w.ResponseWriter.Write(data)
+	}
+	w.started = true
+
+	trimmed := bytes.TrimLeft(data, " \t\r\n")
+	if !strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") || len(trimmed) == 0 || trimmed[0] != '{' {
+		return w.ResponseWriter.Write(data)
+	}
+	if _, err := w.ResponseWriter.Write([]byte("{" + w.field)); err != nil {
+		return 0, err
+	}
+	w.pending = true
+	if _, err := w.Write(trimmed[1:]); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+func (w *serviceStatusWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
@@ -0,0 +1,143 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"handbook-scraper/server/handlers"
+	"handbook-scraper/utils/databases"
+	"handbook-scraper/utils/log"
+)
+
+// tenantLimit is how many requests a tenant may make per minute and per UTC day, 0 being unlimited
+type tenantLimit struct {
+	PerMinute int64
+	PerDay    int64
+}
+
+// parseTenantLimit parses a limit written as per_minute:per_day, either of which may be empty or 0 for no limit
+func parseTenantLimit(raw string) (tenantLimit, error) {
+	perMinute, perDay, _ := strings.Cut(strings.TrimSpace(raw), ":")
+	var limit tenantLimit
+	for _, part := range []struct {
+		raw   string
+		value *int64
+	}{{perMinute, &limit.PerMinute}, {perDay, &limit.PerDay}} {
+		if part.raw == "" {
+			continue
+		}
+		parsed, err := strconv.ParseInt(part.raw, 10, 64)
+		if err != nil || parsed < 0 {
+			return tenantLimit{}, fmt.Errorf("invalid limit %q", raw)
+		}
+		*part.value = parsed
+	}
+	return limit, nil
+}
+
+// tenantLimits are the request limits of tenants
+type tenantLimits struct {
+	byTenant map[string]tenantLimit
+	fallback tenantLimit // Of tenants without a limit and requests without an API key
+}
+
+// parseTenantLimits reads the limits of tenants from TENANT_LIMITS, a comma-separated list of name:per_minute:per_day,
+// and DEFAULT_TENANT_LIMIT (per_minute:per_day) for tenants without one and requests without an API key.
+// Malformed limits are logged and ignored.
+func parseTenantLimits() tenantLimits {
+	limits := tenantLimits{byTenant: map[string]tenantLimit{}}
+	for _, entry := range strings.Split(os.Getenv("TENANT_LIMITS"), ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		name, raw, _ := strings.Cut(entry, ":")
+		limit, err := parseTenantLimit(raw)
+		if err != nil {
+			log.Warnf("[TENANTS] Ignoring the limit of %s: %v", name, err)
+			continue
+		}
+		limits.byTenant[name] = limit
+	}
+
+	fallback, err := parseTenantLimit(os.Getenv("DEFAULT_TENANT_LIMIT"))
+	if err != nil {
+		log.Warnf("[TENANTS] Ignoring DEFAULT_TENANT_LIMIT: %v", err)
+	}
+	limits.fallback = fallback
+	return limits
+}
+
+// of returns the limit of a tenant, or the default limit if it has none or the request has no API key
+func (l tenantLimits) of(tenant string) tenantLimit {
+	if limit, ok := l.byTenant[tenant]; ok && tenant != "" {
+		return limit
+	}
+	return l.fallback
+}
+
+// tenantMiddleware limits the requests of each tenant and tallies them for its usage analytics.
+// Tenants are the API keys, and requests without an API key are limited per client IP.
+// Limits are counted in Redis so they are shared by every replica, and are not enforced if Redis fails.
+// They are read once, when the router is created.
+func tenantMiddleware() gin.HandlerFunc {
+	limits := parseTenantLimits()
+	return func(c *gin.Context) {
+		tenant := ""
+		if value, ok := c.Get(principalKey); ok {
+			tenant = value.(principal).Tenant
+		}
+		limited := "tenant:" + tenant
+		if tenant == "" {
+			limited = "ip:" + c.ClientIP()
+		} else {
+			c.Request = c.Request.WithContext(handlers.WithTenant(c.Request.Context(), tenant))
+		}
+
+		if !withinLimit(c, limited, limits.of(tenant)) {
+			return
+		}
+		c.Next()
+		handlers.RecordUsage(c.Request.Context(), tenant, c.Request.Method, c.FullPath())
+	}
+}
+
+// withinLimit counts a request against the limits of who made it, and responds with 429 if either is exceeded
+func withinLimit(c *gin.Context, limited string, limit tenantLimit) bool {
+	now := time.Now().UTC()
+	windows := []struct {
+		name  string
+		limit int64
+		key   string
+		ends  time.Time
+	}{
+		{"minute", limit.PerMinute, "ratelimit:" + limited + ":" + now.Format("200601021504"), now.Truncate(time.Minute).Add(time.Minute)},
+		{"day", limit.PerDay, "quota:" + limited + ":" + now.Format(time.DateOnly), now.Truncate(24 * time.Hour).Add(24 * time.Hour)},
+	}
+
+	dbHandler := databases.FromContext(c.Request.Context())
+	for _, window := range windows {
+		if window.limit == 0 {
+			continue
+		}
+		count, err := dbHandler.IncrementCounter(window.key, time.Until(window.ends)+time.Minute)
+		if err != nil {
+			log.Errorf("[TENANTS] Failed to count the requests of %s: %v", limited, err)
+			continue
+		}
+		if window.name == "minute" {
+			c.Header("X-RateLimit-Limit", strconv.FormatInt(window.limit, 10))
+			c.Header("X-RateLimit-Remaining", strconv.FormatInt(max(window.limit-count, 0), 10))
+		}
+		if count > window.limit {
+			c.Header("Retry-After", strconv.Itoa(int(time.Until(window.ends).Seconds())+1))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": fmt.Sprintf("limit of %d requests per %s exceeded", window.limit, window.name)})
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,31 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"handbook-scraper/server/handlers"
+)
+
+// codeValidationMiddleware upper-cases the :code path parameter and rejects codes
+// which do not match the format of the urlKey, so malformed codes are never scraped
+func codeValidationMiddleware(urlKey string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for i, param := range c.Params {
+			if param.Key != "code" {
+				continue
+			}
+
+			code := strings.ToUpper(strings.TrimSpace(param.Value))
+			if !handlers.ValidCode(urlKey, code) {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("malformed code: %s", param.Value)})
+				return
+			}
+			c.Params[i].Value = code
+		}
+
+		c.Next()
+	}
+}
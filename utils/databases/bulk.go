@@ -0,0 +1,182 @@
+package databases
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// bulkChunkSize is the number of documents written per Mongo bulk write or Redis pipeline
+const bulkChunkSize = 500
+
+// BulkItem is a single document of a bulk store
+type BulkItem struct {
+	Key  string
+	Data interface{}
+}
+
+// BulkResult reports the outcome of a bulk store.
+// A document which fails to store does not stop the others from being stored.
+type BulkResult struct {
+	Stored int               `json:"stored"`
+	Failed map[string]string `json:"failed"` // Error by key
+}
+
+// BulkStore stores many documents using the specified storage strategy,
+// writing them in chunks with Mongo bulk writes and Redis pipelines
+func (h *DatabaseHandler) BulkStore(storageType StorageType, items []BulkItem, ttl time.Duration) (BulkResult, error) {
+	result := BulkResult{Failed: map[string]string{}}
+
+	if storageType == Handbook {
+		h.bulkStoreHandbook(items, ttl, &result)
+		return result, nil
+	}
+
+	var collection string
+	if storageType != Cache {
+		var err error
+		if collection, err = collectionFor(storageType); err != nil {
+			return result, err
+		}
+	}
+
+	for start := 0; start < len(items); start += bulkChunkSize {
+		chunk := items[start:min(start+bulkChunkSize, len(items))]
+		ctx, cancel := context.WithTimeout(context.Background(), h.timeouts.Bulk)
+		if storageType == Cache {
+			chunk = h.bulkStoreRedis(ctx, chunk, ttl, result.Failed)
+		} else {
+			chunk = h.bulkStoreMongo(ctx, collection, chunk, result.Failed)
+		}
+		cancel()
+		result.Stored += len(chunk)
+	}
+	return result, nil
+}
+
+// bulkStoreHandbook writes handbook documents through every layer of the handbook cache, as Store does, a chunk at a time
+func (h *DatabaseHandler) bulkStoreHandbook(items []BulkItem, ttl time.Duration, result *BulkResult) {
+	docs := make([]BulkItem, 0, len(items))
+	for _, item := range items {
+		jsonData, err := json.Marshal(item.Data)
+		if err != nil {
+			result.Failed[item.Key] = fmt.Sprintf("failed to marshal data: %v", err)
+			continue
+		}
+		docs = append(docs, BulkItem{Key: item.Key, Data: json.RawMessage(jsonData)})
+	}
+
+	for start := 0; start < len(docs); start += bulkChunkSize {
+		chunk := docs[start:min(start+bulkChunkSize, len(docs))]
+		ctx, cancel := context.WithTimeout(context.Background(), h.timeouts.Bulk)
+		stored := h.handbook.SetMany(ctx, chunk, ttl, result.Failed)
+		cancel()
+		if len(stored) > 0 {
+			h.notifyInvalidation("stored", bulkKeys(stored)...)
+		}
+		result.Stored += len(stored)
+	}
+}
+
+// bulkKeys returns the keys of a chunk of documents
+func bulkKeys(items []BulkItem) []string {
+	keys := make([]string, len(items))
+	for i, item := range items {
+		keys[i] = item.Key
+	}
+	return keys
+}
+
+// bulkStoreRedis stores a chunk of documents in a single Redis pipeline.
+// Failures are added to failed, and the items which were stored are returned.
+func (h *DatabaseHandler) bulkStoreRedis(ctx context.Context, items []BulkItem, ttl time.Duration, failed map[string]string) []BulkItem {
+	pending := make([]BulkItem, 0, len(items))
+	cmds := make([]*redis.StatusCmd, 0, len(items))
+	pipe := h.redisClient.Pipeline()
+	for _, item := range items {
+		jsonData, err := json.Marshal(item.Data)
+		if err != nil {
+			failed[item.Key] = fmt.Sprintf("failed to marshal data: %v", err)
+			continue
+		}
+		value, err := h.codec.encode(jsonData)
+		if err != nil {
+			failed[item.Key] = err.Error()
+			continue
+		}
+		pending = append(pending, item)
+		cmds = append(cmds, pipe.Set(ctx, item.Key, value, Jitter(ttl)))
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+	// Errors are reported per command below
+	_, _ = pipe.Exec(ctx)
+
+	stored := make([]BulkItem, 0, len(pending))
+	for i, cmd := range cmds {
+		if err := cmd.Err(); err != nil {
+			failed[pending[i].Key] = fmt.Sprintf("failed to store in Redis cache: %v", err)
+			continue
+		}
+		stored = append(stored, pending[i])
+	}
+	return stored
+}
+
+// bulkStoreMongo replaces or inserts a chunk of documents in a single unordered Mongo bulk write.
+// Failures are added to failed, and the items which were stored are returned.
+func (h *DatabaseHandler) bulkStoreMongo(ctx context.Context, collection string, items []BulkItem, failed map[string]string) []BulkItem {
+	pending := make([]BulkItem, 0, len(items))
+	models := make([]mongo.WriteModel, 0, len(items))
+	for _, item := range items {
+		bsonData, err := toBSON(item.Data)
+		if err != nil {
+			failed[item.Key] = fmt.Sprintf("failed to convert data to BSON: %v", err)
+			continue
+		}
+		pending = append(pending, item)
+		models = append(models, mongo.NewReplaceOneModel().
+			SetFilter(bson.M{"_id": item.Key}).
+			SetReplacement(bsonData).
+			SetUpsert(true))
+	}
+	if len(models) == 0 {
+		return nil
+	}
+
+	_, err := h.mongoDB.Collection(collection).BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+	if err == nil {
+		return pending
+	}
+
+	// Unordered bulk writes report the index of each failed document, the rest were written
+	var bulkErr mongo.BulkWriteException
+	if !errors.As(err, &bulkErr) || len(bulkErr.WriteErrors) == 0 {
+		for _, item := range pending {
+			failed[item.Key] = err.Error()
+		}
+		return nil
+	}
+
+	failedIndexes := map[int]bool{}
+	for _, writeErr := range bulkErr.WriteErrors {
+		failedIndexes[writeErr.Index] = true
+		failed[pending[writeErr.Index].Key] = writeErr.Error()
+	}
+
+	stored := make([]BulkItem, 0, len(pending)-len(failedIndexes))
+	for i, item := range pending {
+		if !failedIndexes[i] {
+			stored = append(stored, item)
+		}
+	}
+	return stored
+}
@@ -0,0 +1,159 @@
+package databases
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.mongodb.org/mongo-driver/bson"
+	"handbook-scraper/utils/log"
+)
+
+// handbookKeyPattern matches the Redis keys of handbook documents, which are their page URLs
+const handbookKeyPattern = "https://*"
+
+// ConsistencyReport compares the handbook documents cached in Redis with MongoDB, which holds the canonical copies.
+// Documents only in MongoDB are expected, since Redis copies expire.
+type ConsistencyReport struct {
+	CheckedAt  time.Time `json:"checked_at"`
+	Repair     bool      `json:"repair"`
+	MongoKeys  int       `json:"mongo_keys"`
+	RedisKeys  int       `json:"redis_keys"`
+	Matching   int       `json:"matching"`
+	MongoOnly  int       `json:"mongo_only"`
+	Mismatched []string  `json:"mismatched"` // Cached with different content, re-primed from MongoDB when repairing
+	RedisOnly  []string  `json:"redis_only"` // Missing from MongoDB, such as a lost write-behind write, copied to MongoDB when repairing
+	Corrupt    []string  `json:"corrupt"`    // Unreadable or empty in MongoDB, never repaired since they need to be scraped again
+	Repaired   int       `json:"repaired"`
+}
+
+// CheckHandbookConsistency compares the key sets and content hashes of the handbook documents in Redis and MongoDB.
+// When repairing, mismatched Redis copies are replaced by the MongoDB documents, keeping their expiry,
+// and documents only in Redis are copied to MongoDB.
+func (h *DatabaseHandler) CheckHandbookConsistency(ctx context.Context, repair bool) (ConsistencyReport, error) {
+	report := ConsistencyReport{CheckedAt: time.Now(), Repair: repair, Mismatched: []string{}, RedisOnly: []string{}, Corrupt: []string{}}
+	if !h.handbook.hasLayer("redis") {
+		return report, errors.New("redis is not a layer of the handbook cache")
+	}
+
+	redisKeys, err := h.redisKeys(ctx, handbookKeyPattern)
+	if err != nil {
+		return report, fmt.Errorf("failed to list Redis keys: %w", err)
+	}
+	report.RedisKeys = len(redisKeys)
+	cached := make(map[string]bool, len(redisKeys))
+	for _, key := range redisKeys {
+		cached[key] = true
+	}
+
+	cursor, err := h.mongoDB.Collection("handbook").Find(ctx, bson.M{})
+	if err != nil {
+		return report, fmt.Errorf("failed to list documents: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	canonical := map[string][]byte{}
+	for cursor.Next(ctx) {
+		key, ok := cursor.Current.Lookup("_id").StringValueOK()
+		if !ok {
+			continue
+		}
+		report.MongoKeys++
+		if !cached[key] {
+			report.MongoOnly++
+		}
+
+		var doc bson.M
+		var data []byte
+		if err := cursor.Decode(&doc); err == nil {
+			data, err = json.Marshal(doc)
+		}
+		if err != nil || len(doc) <= 1 {
+			report.Corrupt = append(report.Corrupt, key)
+			delete(cached, key)
+			continue
+		}
+		if cached[key] {
+			canonical[key] = data
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return report, fmt.Errorf("failed to list documents: %w", err)
+	}
+
+	keys := make([]string, 0, len(cached))
+	for key := range cached {
+		keys = append(keys, key)
+	}
+	found := map[string]json.RawMessage{}
+	if err := h.redisGetMany(ctx, keys, found); err != nil {
+		return report, err
+	}
+
+	var reprimed []string
+	for _, key := range keys {
+		value, ok := found[key]
+		if !ok {
+			continue // Expired since it was listed
+		}
+		mongoData, inMongo := canonical[key]
+		switch {
+		case !inMongo:
+			report.RedisOnly = append(report.RedisOnly, key)
+			if repair {
+				if err := h.storeMongo(ctx, "handbook", key, value); err != nil {
+					log.Errorf("[CONSISTENCY] Failed to copy %s to MongoDB: %v", key, err)
+					continue
+				}
+				report.Repaired++
+			}
+		case contentHash(value) != contentHash(mongoData):
+			report.Mismatched = append(report.Mismatched, key)
+			if repair {
+				encoded, err := h.codec.encode(mongoData)
+				if err == nil {
+					err = h.redisClient.SetArgs(ctx, key, encoded, redis.SetArgs{KeepTTL: true}).Err()
+				}
+				if err != nil {
+					log.Errorf("[CONSISTENCY] Failed to re-prime %s: %v", key, err)
+					continue
+				}
+				reprimed = append(reprimed, key)
+				report.Repaired++
+			}
+		default:
+			report.Matching++
+		}
+	}
+	if len(reprimed) > 0 {
+		h.handbook.forget(reprimed...)
+		h.notifyInvalidation("stored", reprimed...)
+	}
+
+	sort.Strings(report.Mismatched)
+	sort.Strings(report.RedisOnly)
+	sort.Strings(report.Corrupt)
+	return report, nil
+}
+
+// contentHash hashes the JSON of a handbook document without its MongoDB _id, with object keys in sorted order,
+// so a Redis copy and its MongoDB document hash the same whichever of them it was read from
+func contentHash(data []byte) [sha256.Size]byte {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return sha256.Sum256(data)
+	}
+	if doc, ok := value.(map[string]interface{}); ok {
+		delete(doc, "_id")
+	}
+	normalised, err := json.Marshal(value)
+	if err != nil {
+		return sha256.Sum256(data)
+	}
+	return sha256.Sum256(normalised)
+}
@@ -0,0 +1,42 @@
+package databases
+
+import "context"
+
+type handlerKey struct{}
+
+// WithHandler returns a context whose database operations use handler instead of the shared handler,
+// so servers embedded in the same process can each use their own databases
+func WithHandler(ctx context.Context, handler *DatabaseHandler) context.Context {
+	return context.WithValue(ctx, handlerKey{}, handler)
+}
+
+// FromContext returns the handler of a context, or the shared handler if it has none
+func FromContext(ctx context.Context) *DatabaseHandler {
+	if handler, ok := ctx.Value(handlerKey{}).(*DatabaseHandler); ok && handler != nil {
+		return handler
+	}
+	return GetDatabaseHandler()
+}
+
+// Hold returns the handler of a context like FromContext, and keeps it open until release is called, even if the
+// shared handler is replaced meanwhile, for jobs which use the handler for longer than a single call
+func Hold(ctx context.Context) (handler *DatabaseHandler, release func()) {
+	if handler, ok := ctx.Value(handlerKey{}).(*DatabaseHandler); ok && handler != nil {
+		handler.holds.Add(1)
+		return handler, handler.holds.Done
+	}
+
+	// The hold is taken under the lock, so the handler cannot be retired before it is counted
+	for {
+		dbMu.RLock()
+		handler := dbHandler
+		if handler != nil {
+			handler.holds.Add(1)
+		}
+		dbMu.RUnlock()
+		if handler != nil {
+			return handler, handler.holds.Done
+		}
+		GetDatabaseHandler()
+	}
+}
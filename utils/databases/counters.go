@@ -0,0 +1,63 @@
+package databases
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// IncrementCounter adds one to a counter in Redis and returns its new value.
+// The counter expires ttl after it was created, so it counts the events of a fixed window shared by every replica.
+func (h *DatabaseHandler) IncrementCounter(key string, ttl time.Duration) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), h.timeouts.Write)
+	defer cancel()
+
+	count, err := h.redisClient.Incr(ctx, key).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to increment %s: %w", key, err)
+	}
+	if count == 1 {
+		if err := h.redisClient.Expire(ctx, key, ttl).Err(); err != nil {
+			return count, fmt.Errorf("failed to expire %s: %w", key, err)
+		}
+	}
+	return count, nil
+}
+
+// IncrementTally adds one to a field of a hash of tallies in Redis, which expires ttl after its latest increment
+func (h *DatabaseHandler) IncrementTally(key string, field string, ttl time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), h.timeouts.Write)
+	defer cancel()
+
+	pipe := h.redisClient.Pipeline()
+	pipe.HIncrBy(ctx, key, field, 1)
+	pipe.Expire(ctx, key, ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to increment %s of %s: %w", field, key, err)
+	}
+	return nil
+}
+
+// Tallies returns the fields of a hash of tallies, or an empty map if it does not exist
+func (h *DatabaseHandler) Tallies(key string) (map[string]int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), h.timeouts.Read)
+	defer cancel()
+
+	values, err := readRedis(h, func(client redis.UniversalClient) (map[string]string, error) {
+		return client.HGetAll(ctx, key).Result()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve %s: %w", key, err)
+	}
+
+	tallies := make(map[string]int64, len(values))
+	for field, value := range values {
+		var count int64
+		if _, err := fmt.Sscan(value, &count); err == nil {
+			tallies[field] = count
+		}
+	}
+	return tallies, nil
+}
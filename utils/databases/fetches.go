@@ -0,0 +1,76 @@
+package databases
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strconv"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"handbook-scraper/utils/fetchlog"
+)
+
+const (
+	fetchesCollection      = "fetches"
+	defaultFetchLogMaxDocs = 100000
+	fetchLogMaxSizeBytes   = 64 << 20
+	namespaceExistsCode    = 48
+)
+
+// fetchLogMaxDocuments reads FETCH_LOG_MAX_DOCUMENTS, how many fetches the audit trail keeps
+func fetchLogMaxDocuments() int64 {
+	if parsed, err := strconv.ParseInt(os.Getenv("FETCH_LOG_MAX_DOCUMENTS"), 10, 64); err == nil && parsed > 0 {
+		return parsed
+	}
+	return defaultFetchLogMaxDocs
+}
+
+// ensureFetchLog creates the capped collection of the fetch audit trail, so the oldest fetches are dropped
+// once it is full. An existing collection is left as it is.
+func (h *DatabaseHandler) ensureFetchLog() error {
+	ctx, cancel := context.WithTimeout(context.Background(), h.timeouts.Write)
+	defer cancel()
+
+	opts := options.CreateCollection().
+		SetCapped(true).
+		SetSizeInBytes(fetchLogMaxSizeBytes).
+		SetMaxDocuments(fetchLogMaxDocuments())
+	err := h.mongoDB.CreateCollection(ctx, fetchesCollection, opts)
+
+	var cmdErr mongo.CommandError
+	if errors.As(err, &cmdErr) && cmdErr.Code == namespaceExistsCode {
+		return nil
+	}
+	return err
+}
+
+// RecordFetch stores an upstream fetch in the audit trail
+func (h *DatabaseHandler) RecordFetch(fetch fetchlog.Fetch) error {
+	ctx, cancel := context.WithTimeout(context.Background(), h.timeouts.Write)
+	defer cancel()
+
+	_, err := h.mongoDB.Collection(fetchesCollection).InsertOne(ctx, fetch)
+	return err
+}
+
+// RecentFetches returns up to limit of the most recent fetches matching filter, newest first
+func (h *DatabaseHandler) RecentFetches(filter bson.M, limit int64) ([]fetchlog.Fetch, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), h.timeouts.List)
+	defer cancel()
+
+	// Capped collections keep insertion order, so the natural order is the fetch order
+	opts := options.Find().SetSort(bson.D{{Key: "$natural", Value: -1}}).SetLimit(limit)
+	cursor, err := h.mongoDB.Collection(fetchesCollection).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	fetches := []fetchlog.Fetch{}
+	if err := cursor.All(ctx, &fetches); err != nil {
+		return nil, err
+	}
+	return fetches, nil
+}
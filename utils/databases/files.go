@@ -0,0 +1,89 @@
+package databases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// filesBucket is the GridFS bucket of files written by jobs, such as exports, so any replica can serve them
+const filesBucket = "files"
+
+// ErrFileNotFound is returned when no file has been written under a name
+var ErrFileNotFound = errors.New("file not found")
+
+// File is a file read from the shared file store. It must be closed.
+type File struct {
+	io.ReadCloser
+	Size       int64
+	UploadedAt time.Time
+}
+
+// bucket returns the GridFS bucket of the shared files
+func (h *DatabaseHandler) bucket() (*gridfs.Bucket, error) {
+	return gridfs.NewBucket(h.mongoDB, options.GridFSBucket().SetName(filesBucket))
+}
+
+// WriteFile streams a file into the shared file store, replacing the previous file of the name once it is complete.
+// If write fails the partial file is discarded, and the previous file is kept.
+func (h *DatabaseHandler) WriteFile(ctx context.Context, name string, write func(w io.Writer) error) error {
+	bucket, err := h.bucket()
+	if err != nil {
+		return err
+	}
+	upload, err := bucket.OpenUploadStream(name)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", name, err)
+	}
+	if err := write(upload); err != nil {
+		_ = upload.Abort()
+		return err
+	}
+	if err := upload.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+
+	// Earlier revisions of the file are removed once the new one is readable
+	listCtx, cancel := context.WithTimeout(ctx, h.timeouts.List)
+	defer cancel()
+	cursor, err := bucket.FindContext(listCtx, bson.M{"filename": name, "_id": bson.M{"$ne": upload.FileID}})
+	if err != nil {
+		return fmt.Errorf("failed to find the previous revisions of %s: %w", name, err)
+	}
+	defer cursor.Close(listCtx)
+	for cursor.Next(listCtx) {
+		var previous struct {
+			ID interface{} `bson:"_id"`
+		}
+		if err := cursor.Decode(&previous); err != nil {
+			return err
+		}
+		if err := bucket.DeleteContext(listCtx, previous.ID); err != nil {
+			return fmt.Errorf("failed to delete a previous revision of %s: %w", name, err)
+		}
+	}
+	return cursor.Err()
+}
+
+// OpenFile opens the latest file written under a name
+func (h *DatabaseHandler) OpenFile(name string) (*File, error) {
+	bucket, err := h.bucket()
+	if err != nil {
+		return nil, err
+	}
+	download, err := bucket.OpenDownloadStreamByName(name)
+	if errors.Is(err, gridfs.ErrFileNotFound) {
+		return nil, ErrFileNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", name, err)
+	}
+	file := download.GetFile()
+	return &File{ReadCloser: download, Size: file.Length, UploadedAt: file.UploadDate}, nil
+}
@@ -6,7 +6,6 @@ import (
 	"errors"
 	"fmt"
 	"os"
-	"strconv"
 	"sync"
 	"time"
 
@@ -15,95 +14,251 @@ import (
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"handbook-scraper/utils/log"
+	"handbook-scraper/utils/secrets"
 )
 
 // StorageType represents the different storage strategies we support
 type StorageType string
 
 const (
-	Timetable StorageType = "timetable" // Direct MongoDB storage
-	Handbook  StorageType = "handbook"  // Redis-cached MongoDB storage
-	Cache     StorageType = "cache"     // Pure Redis storage
+	Timetable   StorageType = "timetable"   // Direct MongoDB storage
+	Handbook    StorageType = "handbook"    // Redis-cached MongoDB storage
+	Cache       StorageType = "cache"       // Pure Redis storage
+	Alias       StorageType = "alias"       // Direct MongoDB storage of alternative handbook URLs
+	Raw         StorageType = "raw"         // Direct MongoDB storage of raw page payloads
+	Version     StorageType = "version"     // Direct MongoDB storage of every scraped version of handbook items
+	Equivalence StorageType = "equivalence" // Direct MongoDB storage of unit equivalences across code changes
+	Watch       StorageType = "watch"       // Direct MongoDB storage of the watch lists of change alerts
+	Patch       StorageType = "patch"       // Direct MongoDB storage of admin patches to parsed curricula
+	UnitSet     StorageType = "unit_set"    // Direct MongoDB storage of curated unit collections
 )
 
+// collections are the MongoDB collections of the storage types. The handbook's holds the documents of record below
+// the layers of the handbook cache.
+var collections = map[StorageType]string{
+	Timetable:   "timetable",
+	Handbook:    "handbook",
+	Alias:       "aliases",
+	Raw:         "raw",
+	Version:     "versions",
+	Equivalence: "equivalences",
+	Watch:       "watches",
+	Patch:       "patches",
+	UnitSet:     "unit_sets",
+}
+
+// collectionFor returns the MongoDB collection of a storage type, or an error for types not stored in MongoDB
+func collectionFor(storageType StorageType) (string, error) {
+	collection, ok := collections[storageType]
+	if !ok {
+		return "", fmt.Errorf("unsupported storage type: %s", storageType)
+	}
+	return collection, nil
+}
+
 var (
 	dbHandler *DatabaseHandler
-	dbOnce    sync.Once
+	dbMu      sync.RWMutex
+	retiring  = map[*DatabaseHandler]*time.Timer{} // Replaced handlers which are closed once their calls finish
 )
 
 // DatabaseHandler provides a unified interface for different storage strategies
 type DatabaseHandler struct {
-	redisClient *redis.Client
-	mongoClient *mongo.Client
-	mongoDB     *mongo.Database
+	redisClient     redis.UniversalClient // Primary, used for writes
+	redisReadClient redis.UniversalClient // Read replica, the primary if none is configured
+	mongoClient     *mongo.Client
+	mongoDB         *mongo.Database
+	writeBehind     *writeBehind  // Queues handbook writes to MongoDB, nil unless enabled
+	handbook        *layeredCache // Read-through cache of handbook documents
+	invalidator     *invalidator  // Publishes changes to handbook documents, nil unless enabled
+	timeouts        timeoutBudgets
+	codec           redisCodec     // Encodes documents stored in Redis
+	holds           sync.WaitGroup // Jobs using the handler for longer than a call, see Hold
 }
 
-// GetDatabaseHandler returns the singleton instance of DatabaseHandler
+// GetDatabaseHandler returns the shared DatabaseHandler, connecting on first use if Init was not called
 func GetDatabaseHandler() *DatabaseHandler {
-	dbOnce.Do(func() {
-		dbHandler = newDatabaseHandler()
-	})
+	dbMu.RLock()
+	handler := dbHandler
+	dbMu.RUnlock()
+	if handler != nil {
+		return handler
+	}
+
+	dbMu.Lock()
+	defer dbMu.Unlock()
+	if dbHandler == nil {
+		connected, err := NewDatabaseHandler()
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		dbHandler = connected
+	}
 	return dbHandler
 }
 
-// newDatabaseHandler creates a new database handler with environment variables
-func newDatabaseHandler() *DatabaseHandler {
-	// Get configuration from environment variables
-	mongoURI := os.Getenv("MONGO_URI")
-	mongoDB := os.Getenv("MONGO_DB")
-	redisURL := os.Getenv("REDIS_URL")
+// Init connects to the databases with the current environment variables and makes the connection the shared handler.
+// Calling it again reconnects, e.g. after credentials are rotated, closing the previous connection once the calls
+// already using it have finished.
+func Init() error {
+	handler, err := NewDatabaseHandler()
+	if err != nil {
+		return err
+	}
+	SetDatabaseHandler(handler)
+	return nil
+}
 
-	var redisAddr, redisPass string
-	var redisDB int
-	if redisURL == "" {
-		redisAddr = os.Getenv("REDIS_ADDR")
-		redisPass = os.Getenv("REDIS_PASSWORD")
+// credentialSecrets are the secrets NewDatabaseHandler connects with
+var credentialSecrets = []string{"MONGO_URI", "REDIS_URL", "REDIS_PASSWORD", "REDIS_READ_URL", "REDIS_READ_PASSWORD"}
 
-		var err error
-		redisDB, err = strconv.Atoi(os.Getenv("REDIS_DB"))
-		if err != nil {
-			log.Fatalf("Invalid REDIS_DB value: %v", err)
+// ReconnectOnRotation reconnects the shared handler whenever the credentials of the databases are rotated.
+// If connecting with the new credentials fails, the previous connection is kept.
+func ReconnectOnRotation() {
+	secrets.OnChange(func() {
+		if err := Init(); err != nil {
+			log.Errorf("[SECRETS] Failed to reconnect with rotated database credentials, keeping the previous connection: %v", err)
+			return
+		}
+		log.Infof("[SECRETS] Reconnected to the databases with rotated credentials")
+	}, credentialSecrets...)
+}
+
+// SetDatabaseHandler replaces the shared handler, closing the previous one once the calls already using it have
+// finished. Tests use it to inject a handler connected to their own databases.
+func SetDatabaseHandler(handler *DatabaseHandler) {
+	dbMu.Lock()
+	defer dbMu.Unlock()
+	previous := dbHandler
+	dbHandler = handler
+	if previous != nil && previous != handler {
+		retire(previous)
+	}
+}
+
+// retire closes a replaced handler after its longest timeout budget, by when every call which got it before it was
+// replaced has either finished or timed out, and once every Hold of it is released, so rotating credentials never
+// fails requests or jobs in flight. The caller must hold dbMu.
+func retire(previous *DatabaseHandler) {
+	if _, ok := retiring[previous]; ok {
+		return
+	}
+	grace := max(previous.timeouts.Read, previous.timeouts.Write, previous.timeouts.List, previous.timeouts.Bulk, previous.timeouts.Flush)
+	retiring[previous] = time.AfterFunc(grace, func() {
+		previous.holds.Wait()
+		dbMu.Lock()
+		delete(retiring, previous)
+		dbMu.Unlock()
+
+		if err := previous.Close(); err != nil {
+			log.Errorf("Failed to close previous database connection: %v", err)
+		}
+	})
+}
+
+// Shutdown closes the shared handler, and any replaced handlers still waiting for their calls to finish.
+// The next GetDatabaseHandler or Init connects again.
+func Shutdown() error {
+	dbMu.Lock()
+	handler := dbHandler
+	dbHandler = nil
+	var closing []*DatabaseHandler
+	for previous, timer := range retiring {
+		if timer.Stop() {
+			closing = append(closing, previous)
 		}
+		delete(retiring, previous)
+	}
+	dbMu.Unlock()
+
+	var errs []error
+	for _, previous := range closing {
+		if err := previous.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if handler != nil {
+		if err := handler.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// NewDatabaseHandler connects to MongoDB and Redis with environment variables
+func NewDatabaseHandler() (*DatabaseHandler, error) {
+	// Get configuration from environment variables
+	mongoURI := secrets.Get("MONGO_URI")
+	mongoDB := os.Getenv("MONGO_DB")
+	codec, err := redisCodecFromEnv()
+	if err != nil {
+		return nil, err
 	}
 
 	// Initialize MongoDB
-	mongoClient, err := mongo.Connect(context.Background(), options.Client().ApplyURI(mongoURI))
+	mongoClient, err := mongo.Connect(context.Background(), mongoClientOptions(mongoURI))
 	if err != nil {
-		log.Fatalf("Failed to connect to MongoDB: %v", err)
+		return nil, fmt.Errorf("failed to connect to MongoDB: %w", err)
 	}
 
-	var redisClient *redis.Client
-	if redisURL != "" {
-		opts, err := redis.ParseURL(redisURL)
-		if err != nil {
-			log.Fatalf("Failed to parse Redis URL: %v", err)
-		}
-		redisClient = redis.NewClient(opts)
-	} else {
-		// Initialize Redis
-		redisClient = redis.NewClient(&redis.Options{
-			Addr:     redisAddr,
-			Password: redisPass,
-			DB:       redisDB,
-		})
+	// Initialize Redis
+	redisClient, redisReadClient, err := newRedisClients()
+	if err != nil {
+		_ = mongoClient.Disconnect(context.Background())
+		return nil, err
+	}
+
+	handler := &DatabaseHandler{
+		redisClient:     redisClient,
+		redisReadClient: redisReadClient,
+		mongoClient:     mongoClient,
+		mongoDB:         mongoClient.Database(mongoDB),
+		timeouts:        timeoutBudgetsFromEnv(),
+		codec:           codec,
 	}
 
 	// Verify connections
 	if err := redisClient.Ping(context.Background()).Err(); err != nil {
-		log.Fatalf("Failed to connect to Redis: %v", err)
+		_ = handler.Close()
+		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
+	}
+	if redisReadClient != redisClient {
+		if err := redisReadClient.Ping(context.Background()).Err(); err != nil {
+			log.Errorf("Failed to connect to Redis read replica, reads will fall back to the primary: %v", err)
+		}
 	}
 
 	if err := mongoClient.Ping(context.Background(), nil); err != nil {
-		log.Fatalf("Failed to ping MongoDB: %v", err)
+		_ = handler.Close()
+		return nil, fmt.Errorf("failed to ping MongoDB: %w", err)
+	}
+
+	log.Successf("Successfully connected to databases!")
+
+	if err := handler.ensureFetchLog(); err != nil {
+		log.Errorf("Failed to create the fetch log collection: %v", err)
 	}
 
-	log.Successf("Successfully connected to databases for the first time!")
+	if writeBehindEnabled() {
+		handler.startWriteBehind()
+	}
+	handler.handbook = handler.newHandbookCache()
+	handler.invalidator = newInvalidator()
+	handler.subscribeInvalidations()
+	return handler, nil
+}
 
-	return &DatabaseHandler{
-		redisClient: redisClient,
-		mongoClient: mongoClient,
-		mongoDB:     mongoClient.Database(mongoDB),
+// Ping checks that the Redis primary and MongoDB are reachable within the read budget
+func (h *DatabaseHandler) Ping(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, h.timeouts.Read)
+	defer cancel()
+	if err := h.redisClient.Ping(ctx).Err(); err != nil {
+		return fmt.Errorf("redis is unreachable: %w", err)
+	}
+	if err := h.mongoClient.Ping(ctx, nil); err != nil {
+		return fmt.Errorf("mongodb is unreachable: %w", err)
 	}
+	return nil
 }
 
 // GetMongoClient returns the underlying MongoDB client for direct access
@@ -120,9 +275,23 @@ func (h *DatabaseHandler) GetMongoDatabase() *mongo.Database {
 func (h *DatabaseHandler) Close() error {
 	var errs []error
 
+	// Queued writes need MongoDB, so they are finished first
+	h.flushWriteBehind()
+
+	if h.invalidator != nil {
+		if err := h.invalidator.close(); err != nil {
+			errs = append(errs, fmt.Errorf("invalidation subscription close error: %w", err))
+		}
+	}
+
 	if err := h.redisClient.Close(); err != nil {
 		errs = append(errs, fmt.Errorf("redis close error: %w", err))
 	}
+	if h.redisReadClient != h.redisClient {
+		if err := h.redisReadClient.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("redis read replica close error: %w", err))
+		}
+	}
 
 	if err := h.mongoClient.Disconnect(context.Background()); err != nil {
 		errs = append(errs, fmt.Errorf("mongodb close error: %w", err))
@@ -136,24 +305,41 @@ func (h *DatabaseHandler) Close() error {
 
 // Store stores data using the specified storage strategy
 func (h *DatabaseHandler) Store(storageType StorageType, key string, data interface{}, ttl time.Duration) error {
+	return h.StoreContext(context.Background(), storageType, key, data, ttl)
+}
+
+// StoreContext stores data like Store, tracing the database commands as children of the span of ctx.
+// Cancelling ctx does not abandon the write.
+func (h *DatabaseHandler) StoreContext(ctx context.Context, storageType StorageType, key string, data interface{}, ttl time.Duration) error {
+	ctx = context.WithoutCancel(ctx)
 	switch storageType {
-	case Timetable:
-		return h.storeMongo("timetable", key, data)
 	case Handbook:
-		if err := h.storeRedis(key, data, ttl); err != nil {
-			return fmt.Errorf("failed to store in Redis cache: %w", err)
+		jsonData, err := json.Marshal(data)
+		if err != nil {
+			return fmt.Errorf("failed to marshal data: %w", err)
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, h.timeouts.Write)
+		defer cancel()
+		if err := h.handbook.Set(ctx, key, jsonData, ttl); err != nil {
+			return err
 		}
-		return h.storeMongo("handbook", key, data)
+		h.notifyInvalidation("stored", key)
+		return nil
 	case Cache:
-		return h.storeRedis(key, data, ttl)
-	default:
-		return fmt.Errorf("unsupported storage type: %s", storageType)
+		return h.storeRedis(ctx, key, data, ttl)
 	}
+
+	collection, err := collectionFor(storageType)
+	if err != nil {
+		return err
+	}
+	return h.storeMongo(ctx, collection, key, data)
 }
 
 // storeMongo stores data in MongoDB
-func (h *DatabaseHandler) storeMongo(collection string, key string, data interface{}) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (h *DatabaseHandler) storeMongo(ctx context.Context, collection string, key string, data interface{}) error {
+	ctx, cancel := context.WithTimeout(ctx, h.timeouts.Write)
 	defer cancel()
 
 	// Convert data to BSON
@@ -162,27 +348,31 @@ func (h *DatabaseHandler) storeMongo(collection string, key string, data interfa
 		return fmt.Errorf("failed to convert data to BSON: %w", err)
 	}
 
-	// Upsert document
-	_, err = h.mongoDB.Collection(collection).UpdateOne(
+	// Replace the whole document, so fields dropped since it was last stored do not linger
+	_, err = h.mongoDB.Collection(collection).ReplaceOne(
 		ctx,
 		bson.M{"_id": key},
-		bson.M{"$set": bsonData},
-		options.Update().SetUpsert(true),
+		bsonData,
+		options.Replace().SetUpsert(true),
 	)
 	return err
 }
 
 // storeRedis stores data in Redis
-func (h *DatabaseHandler) storeRedis(key string, data interface{}, ttl time.Duration) error {
+func (h *DatabaseHandler) storeRedis(ctx context.Context, key string, data interface{}, ttl time.Duration) error {
 	jsonData, err := json.Marshal(data)
 	if err != nil {
 		return fmt.Errorf("failed to marshal data: %w", err)
 	}
+	value, err := h.codec.encode(jsonData)
+	if err != nil {
+		return err
+	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, h.timeouts.Write)
 	defer cancel()
 
-	return h.redisClient.Set(ctx, key, jsonData, ttl).Err()
+	return h.redisClient.Set(ctx, key, value, Jitter(ttl)).Err()
 }
 
 // toBSON converts data to BSON format
@@ -198,30 +388,38 @@ func toBSON(data interface{}) (bson.M, error) {
 
 // Retrieve retrieves data using the specified storage strategy
 func (h *DatabaseHandler) Retrieve(storageType StorageType, key string, result interface{}) error {
+	return h.RetrieveContext(context.Background(), storageType, key, result)
+}
+
+// RetrieveContext retrieves data like Retrieve, tracing the database commands as children of the span of ctx
+func (h *DatabaseHandler) RetrieveContext(ctx context.Context, storageType StorageType, key string, result interface{}) error {
 	switch storageType {
-	case Timetable:
-		return h.retrieveMongo("timetable", key, result)
 	case Handbook:
-		// Try Redis first
-		if err := h.retrieveRedis(key, result); err == nil {
-			return nil
+		ctx, cancel := context.WithTimeout(ctx, h.timeouts.Read)
+		defer cancel()
+
+		data, err := h.handbook.Get(ctx, key)
+		if errors.Is(err, errCacheMiss) {
+			return fmt.Errorf("document not found")
 		}
-		// Fallback to MongoDB
-		if err := h.retrieveMongo("handbook", key, result); err != nil {
+		if err != nil {
 			return err
 		}
-		// Cache the result back in Redis
-		return h.storeRedis(key, result, 24*time.Hour)
+		return json.Unmarshal(data, result)
 	case Cache:
-		return h.retrieveRedis(key, result)
-	default:
-		return fmt.Errorf("unsupported storage type: %s", storageType)
+		return h.retrieveRedis(ctx, key, result)
 	}
+
+	collection, err := collectionFor(storageType)
+	if err != nil {
+		return err
+	}
+	return h.retrieveMongo(ctx, collection, key, result)
 }
 
 // retrieveMongo retrieves data from MongoDB
-func (h *DatabaseHandler) retrieveMongo(collection string, key string, result interface{}) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (h *DatabaseHandler) retrieveMongo(ctx context.Context, collection string, key string, result interface{}) error {
+	ctx, cancel := context.WithTimeout(ctx, h.timeouts.Read)
 	defer cancel()
 
 	var doc bson.M
@@ -241,86 +439,95 @@ func (h *DatabaseHandler) retrieveMongo(collection string, key string, result in
 }
 
 // retrieveRedis retrieves data from Redis
-func (h *DatabaseHandler) retrieveRedis(key string, result interface{}) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (h *DatabaseHandler) retrieveRedis(ctx context.Context, key string, result interface{}) error {
+	ctx, cancel := context.WithTimeout(ctx, h.timeouts.Read)
 	defer cancel()
 
-	data, err := h.redisClient.Get(ctx, key).Result()
+	value, err := readRedis(h, func(client redis.UniversalClient) ([]byte, error) {
+		return client.Get(ctx, key).Bytes()
+	})
 	if err != nil {
 		return fmt.Errorf("failed to retrieve from Redis: %w", err)
 	}
-	return json.Unmarshal([]byte(data), result)
+	data, err := h.codec.decode(value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, result)
 }
 
 // Delete removes data using the specified storage strategy
 func (h *DatabaseHandler) Delete(storageType StorageType, key string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), h.timeouts.Write)
 	defer cancel()
 
 	switch storageType {
-	case Timetable:
-		_, err := h.mongoDB.Collection("timetable").DeleteOne(ctx, bson.M{"_id": key})
-		return err
 	case Handbook:
-		if err := h.redisClient.Del(ctx, key).Err(); err != nil {
+		if err := h.handbook.Delete(ctx, key); err != nil {
 			return err
 		}
-		_, err := h.mongoDB.Collection("handbook").DeleteOne(ctx, bson.M{"_id": key})
-		return err
+		h.notifyInvalidation("deleted", key)
+		return nil
 	case Cache:
 		return h.redisClient.Del(ctx, key).Err()
-	default:
-		return fmt.Errorf("unsupported storage type: %s", storageType)
 	}
+
+	collection, err := collectionFor(storageType)
+	if err != nil {
+		return err
+	}
+	_, err = h.mongoDB.Collection(collection).DeleteOne(ctx, bson.M{"_id": key})
+	return err
 }
 
 // Exists checks if a key exists using the specified storage strategy
 func (h *DatabaseHandler) Exists(storageType StorageType, key string) (bool, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), h.timeouts.Read)
 	defer cancel()
 
 	switch storageType {
-	case Timetable:
-		count, err := h.mongoDB.Collection("timetable").CountDocuments(ctx, bson.M{"_id": key})
-		return count > 0, err
 	case Handbook:
 		// Check Redis first
-		exists, err := h.redisClient.Exists(ctx, key).Result()
+		exists, err := h.redisExists(ctx, key)
 		if err != nil || exists > 0 {
 			return exists > 0, err
 		}
-		// Check MongoDB
-		count, err := h.mongoDB.Collection("handbook").CountDocuments(ctx, bson.M{"_id": key})
-		return count > 0, err
 	case Cache:
-		exists, err := h.redisClient.Exists(ctx, key).Result()
+		exists, err := h.redisExists(ctx, key)
 		return exists > 0, err
-	default:
-		return false, fmt.Errorf("unsupported storage type: %s", storageType)
 	}
+
+	// Handbook documents not in Redis are checked in MongoDB
+	collection, err := collectionFor(storageType)
+	if err != nil {
+		return false, err
+	}
+	count, err := h.mongoDB.Collection(collection).CountDocuments(ctx, bson.M{"_id": key})
+	return count > 0, err
 }
 
 // ListKeys returns all keys matching a pattern using the specified storage strategy
 func (h *DatabaseHandler) ListKeys(storageType StorageType, pattern string) ([]string, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), h.timeouts.List)
 	defer cancel()
 
-	switch storageType {
-	case Timetable:
-		return h.listMongoKeys("timetable", pattern, ctx)
-	case Handbook:
-		return h.listMongoKeys("handbook", pattern, ctx)
-	case Cache:
-		return h.redisClient.Keys(ctx, pattern).Result()
-	default:
-		return nil, fmt.Errorf("unsupported storage type: %s", storageType)
+	if storageType == Cache {
+		return h.redisKeys(ctx, pattern)
 	}
+
+	// Handbook documents are all in MongoDB, while Redis only holds those read recently
+	collection, err := collectionFor(storageType)
+	if err != nil {
+		return nil, err
+	}
+	return h.listMongoKeys(collection, pattern, ctx)
 }
 
 // listMongoKeys is a helper function to list keys from MongoDB
 func (h *DatabaseHandler) listMongoKeys(collection string, pattern string, ctx context.Context) ([]string, error) {
+	// Only the keys are read, rather than every document matching the pattern
 	filter := bson.M{"_id": bson.M{"$regex": pattern}}
-	cursor, err := h.mongoDB.Collection(collection).Find(ctx, filter)
+	cursor, err := h.mongoDB.Collection(collection).Find(ctx, filter, options.Find().SetProjection(bson.M{"_id": 1}))
 	if err != nil {
 		return nil, err
 	}
@@ -341,22 +548,30 @@ func (h *DatabaseHandler) listMongoKeys(collection string, pattern string, ctx c
 
 // Flush clears data using the specified storage strategy
 func (h *DatabaseHandler) Flush(storageType StorageType) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), h.timeouts.Flush)
 	defer cancel()
 
 	switch storageType {
-	case Timetable:
-		_, err := h.mongoDB.Collection("timetable").DeleteMany(ctx, bson.M{})
-		return err
 	case Handbook:
-		if err := h.redisClient.FlushDB(ctx).Err(); err != nil {
+		h.handbook.clearMemory()
+		if err := h.flushRedis(ctx); err != nil {
 			return err
 		}
-		_, err := h.mongoDB.Collection("handbook").DeleteMany(ctx, bson.M{})
-		return err
+		if _, err := h.mongoDB.Collection(collections[Handbook]).DeleteMany(ctx, bson.M{}); err != nil {
+			return err
+		}
+		h.notifyInvalidation("flushed")
+		return nil
 	case Cache:
-		return h.redisClient.FlushDB(ctx).Err()
-	default:
-		return fmt.Errorf("unsupported storage type: %s", storageType)
+		// Flushing Redis removes the cached handbook documents as well
+		h.handbook.clearMemory()
+		return h.flushRedis(ctx)
 	}
+
+	collection, err := collectionFor(storageType)
+	if err != nil {
+		return err
+	}
+	_, err = h.mongoDB.Collection(collection).DeleteMany(ctx, bson.M{})
+	return err
 }
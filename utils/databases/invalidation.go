@@ -0,0 +1,168 @@
+package databases
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"handbook-scraper/utils/log"
+	"handbook-scraper/utils/signing"
+)
+
+const (
+	invalidationQueueSize      = 1000
+	invalidationWebhookTimeout = 5 * time.Second
+)
+
+// Invalidation is published whenever handbook documents are stored or deleted,
+// so services keeping their own copies can drop exactly the documents which changed
+type Invalidation struct {
+	Event  string    `json:"event"` // stored, deleted, or flushed if every document was deleted
+	Keys   []string  `json:"keys"`  // The handbook URLs of the documents, empty when flushed
+	At     time.Time `json:"at"`
+	Origin string    `json:"origin"` // The instance which stored the documents
+}
+
+// invalidator publishes invalidations to a Redis channel and posts them to webhooks.
+// Webhooks are called from a background worker, and invalidations are dropped if it falls behind.
+type invalidator struct {
+	channel  string
+	webhooks []signing.Subscription
+	origin   string
+	queue    chan Invalidation
+	client   *http.Client
+	pubsub   *redis.PubSub
+	mu       sync.RWMutex
+	closed   bool
+}
+
+// newInvalidator creates the invalidator of HANDBOOK_INVALIDATION_CHANNEL and HANDBOOK_INVALIDATION_WEBHOOKS,
+// or returns nil if neither is set. Webhooks can have their own signing secret, as url#secret.
+func newInvalidator() *invalidator {
+	channel := strings.TrimSpace(os.Getenv("HANDBOOK_INVALIDATION_CHANNEL"))
+	webhooks := signing.ParseSubscriptions(os.Getenv("HANDBOOK_INVALIDATION_WEBHOOKS"))
+	if channel == "" && len(webhooks) == 0 {
+		return nil
+	}
+
+	origin := make([]byte, 8)
+	_, _ = rand.Read(origin)
+	inv := &invalidator{
+		channel:  channel,
+		webhooks: webhooks,
+		origin:   hex.EncodeToString(origin),
+		client:   &http.Client{Timeout: invalidationWebhookTimeout},
+	}
+	if len(webhooks) > 0 {
+		inv.queue = make(chan Invalidation, invalidationQueueSize)
+		go inv.postWebhooks()
+	}
+	log.Infof("Handbook invalidations enabled, channel %q and %d webhooks", channel, len(webhooks))
+	return inv
+}
+
+// notifyInvalidation publishes an invalidation of handbook documents, if invalidations are enabled
+func (h *DatabaseHandler) notifyInvalidation(event string, keys ...string) {
+	inv := h.invalidator
+	if inv == nil {
+		return
+	}
+	if keys == nil {
+		keys = []string{}
+	}
+	invalidation := Invalidation{Event: event, Keys: keys, At: time.Now(), Origin: inv.origin}
+
+	if inv.channel != "" {
+		payload, err := json.Marshal(invalidation)
+		if err != nil {
+			log.Errorf("Failed to marshal invalidation: %v", err)
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), h.timeouts.Write)
+		defer cancel()
+		if err := h.redisClient.Publish(ctx, inv.channel, payload).Err(); err != nil {
+			log.Errorf("Failed to publish invalidation of %d documents: %v", len(keys), err)
+		}
+	}
+
+	inv.mu.RLock()
+	defer inv.mu.RUnlock()
+	if inv.queue != nil && !inv.closed {
+		select {
+		case inv.queue <- invalidation:
+		default:
+			log.Warnf("Invalidation webhooks are behind, dropped the invalidation of %d documents", len(keys))
+		}
+	}
+}
+
+// postWebhooks posts queued invalidations to every webhook
+func (inv *invalidator) postWebhooks() {
+	for invalidation := range inv.queue {
+		payload, err := json.Marshal(invalidation)
+		if err != nil {
+			log.Errorf("Failed to marshal invalidation: %v", err)
+			continue
+		}
+		for _, webhook := range inv.webhooks {
+			if err := inv.post(webhook, payload); err != nil {
+				log.Errorf("Failed to post invalidation to %s: %v", webhook.URL, err)
+			}
+		}
+	}
+}
+
+// post sends a signed invalidation to a webhook
+func (inv *invalidator) post(webhook signing.Subscription, payload []byte) error {
+	return webhook.Post(inv.client, payload)
+}
+
+// subscribeInvalidations drops documents stored by other instances from the in-process layer of the handbook cache,
+// which they could not invalidate otherwise
+func (h *DatabaseHandler) subscribeInvalidations() {
+	inv := h.invalidator
+	if inv == nil || inv.channel == "" || !h.handbook.hasLayer("memory") {
+		return
+	}
+
+	inv.pubsub = h.redisClient.Subscribe(context.Background(), inv.channel)
+	go func() {
+		for message := range inv.pubsub.Channel() {
+			var invalidation Invalidation
+			if err := json.Unmarshal([]byte(message.Payload), &invalidation); err != nil {
+				log.Errorf("Failed to decode invalidation: %v", err)
+				continue
+			}
+			if invalidation.Origin == inv.origin {
+				continue
+			}
+			if invalidation.Event == "flushed" {
+				h.handbook.clearMemory()
+				continue
+			}
+			h.handbook.forget(invalidation.Keys...)
+		}
+	}()
+}
+
+// close stops the subscription and the webhook worker
+func (inv *invalidator) close() error {
+	inv.mu.Lock()
+	if inv.queue != nil && !inv.closed {
+		close(inv.queue)
+	}
+	inv.closed = true
+	inv.mu.Unlock()
+
+	if inv.pubsub != nil {
+		return inv.pubsub.Close()
+	}
+	return nil
+}
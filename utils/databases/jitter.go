@@ -0,0 +1,45 @@
+package databases
+
+import (
+	"math/rand/v2"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"handbook-scraper/utils/log"
+)
+
+// defaultTTLJitter spreads expiry times by up to 10% either side of the TTL
+const defaultTTLJitter = 0.1
+
+var (
+	ttlJitter     float64
+	ttlJitterOnce sync.Once
+)
+
+// jitterFraction returns the TTL jitter configured with CACHE_TTL_JITTER, as a fraction of the TTL between 0 and 1
+func jitterFraction() float64 {
+	ttlJitterOnce.Do(func() {
+		ttlJitter = defaultTTLJitter
+		if value := os.Getenv("CACHE_TTL_JITTER"); value != "" {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil || parsed < 0 || parsed > 1 {
+				log.Errorf("Invalid CACHE_TTL_JITTER value %q, using %.2f", value, defaultTTLJitter)
+				return
+			}
+			ttlJitter = parsed
+		}
+	})
+	return ttlJitter
+}
+
+// Jitter randomly offsets a duration by up to the configured jitter fraction either side,
+// so entries stored together, or refreshes scheduled together, are spread out over time
+func Jitter(d time.Duration) time.Duration {
+	spread := int64(float64(d) * jitterFraction())
+	if d <= 0 || spread <= 0 {
+		return d
+	}
+	return d - time.Duration(spread) + time.Duration(rand.Int64N(2*spread+1))
+}
@@ -0,0 +1,389 @@
+package databases
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"handbook-scraper/utils/log"
+)
+
+const (
+	// defaultHandbookLayers are the layers of the handbook cache unless HANDBOOK_CACHE_LAYERS is set
+	defaultHandbookLayers = "redis,mongo"
+	// handbookBackfillTTL is how long documents found in a lower layer are kept in the layers above it
+	handbookBackfillTTL = 24 * time.Hour
+)
+
+// errCacheMiss is returned by a cache layer which does not hold a key
+var errCacheMiss = errors.New("cache miss")
+
+// CacheLayer is a layer of the handbook read-through cache, holding documents as JSON
+type CacheLayer interface {
+	Name() string
+	Get(ctx context.Context, key string) ([]byte, error) // errCacheMiss if the layer does not hold the key
+	GetMany(ctx context.Context, keys []string) (map[string][]byte, error)
+	Set(ctx context.Context, key string, data []byte, ttl time.Duration) error
+	// SetMany writes documents whose Data is their JSON as a json.RawMessage, adding failures to failed,
+	// and returns the items which were stored
+	SetMany(ctx context.Context, items []BulkItem, ttl time.Duration, failed map[string]string) []BulkItem
+	Delete(ctx context.Context, key string) error
+}
+
+// LayerStats counts the reads and writes of a cache layer since the process started.
+// The origin layer counts the pages scraped from the handbook when every layer missed.
+type LayerStats struct {
+	Name   string `json:"name"`
+	Hits   uint64 `json:"hits"`
+	Misses uint64 `json:"misses"`
+	Errors uint64 `json:"errors"`
+	Writes uint64 `json:"writes"`
+}
+
+// layerMetrics holds the counters of a cache layer
+type layerMetrics struct {
+	hits, misses, errors, writes atomic.Uint64
+}
+
+// stats returns the counters of a layer
+func (m *layerMetrics) stats(name string) LayerStats {
+	return LayerStats{Name: name, Hits: m.hits.Load(), Misses: m.misses.Load(), Errors: m.errors.Load(), Writes: m.writes.Load()}
+}
+
+// layeredCache reads handbook documents through its layers in order, such as memory, then Redis, then MongoDB.
+// A document found in a lower layer is copied into the layers above it, and writes go to every layer.
+type layeredCache struct {
+	layers  []CacheLayer
+	metrics []*layerMetrics
+	origin  layerMetrics
+}
+
+// newLayeredCache creates a cache reading through the layers in order
+func newLayeredCache(layers ...CacheLayer) *layeredCache {
+	cache := &layeredCache{layers: layers}
+	for range layers {
+		cache.metrics = append(cache.metrics, &layerMetrics{})
+	}
+	return cache
+}
+
+// Get returns the document of a key from the first layer holding it, or errCacheMiss if none does
+func (l *layeredCache) Get(ctx context.Context, key string) ([]byte, error) {
+	for i, layer := range l.layers {
+		data, err := layer.Get(ctx, key)
+		if errors.Is(err, errCacheMiss) {
+			l.metrics[i].misses.Add(1)
+			continue
+		}
+		if err != nil {
+			// A failing layer is skipped, so the layers below it still serve the document
+			l.metrics[i].errors.Add(1)
+			log.Errorf("Failed to retrieve %s from the %s cache layer: %v", key, layer.Name(), err)
+			continue
+		}
+
+		l.metrics[i].hits.Add(1)
+		l.backfill(ctx, i, key, data)
+		return data, nil
+	}
+	return nil, errCacheMiss
+}
+
+// GetMany returns the documents of the keys held by any layer, reading each layer only for the keys the layers above it missed
+func (l *layeredCache) GetMany(ctx context.Context, keys []string) (map[string][]byte, error) {
+	found := make(map[string][]byte, len(keys))
+	missing := keys
+	var lastErr error
+	for i, layer := range l.layers {
+		if len(missing) == 0 {
+			break
+		}
+
+		data, err := layer.GetMany(ctx, missing)
+		if err != nil {
+			l.metrics[i].errors.Add(1)
+			log.Errorf("Failed to retrieve %d keys from the %s cache layer: %v", len(missing), layer.Name(), err)
+			lastErr = err
+			continue
+		}
+		lastErr = nil
+
+		stillMissing := make([]string, 0, len(missing)-len(data))
+		for _, key := range missing {
+			value, ok := data[key]
+			if !ok {
+				stillMissing = append(stillMissing, key)
+				continue
+			}
+			found[key] = value
+			l.backfill(ctx, i, key, value)
+		}
+		l.metrics[i].hits.Add(uint64(len(data)))
+		l.metrics[i].misses.Add(uint64(len(stillMissing)))
+		missing = stillMissing
+	}
+
+	// Keys missing from every layer are not an error, but the last layer failing is
+	return found, lastErr
+}
+
+// backfill copies a document found in a layer into the layers above it
+func (l *layeredCache) backfill(ctx context.Context, found int, key string, data []byte) {
+	for i := found - 1; i >= 0; i-- {
+		if err := l.layers[i].Set(ctx, key, data, handbookBackfillTTL); err != nil {
+			l.metrics[i].errors.Add(1)
+			log.Errorf("Failed to cache %s in the %s cache layer: %v", key, l.layers[i].Name(), err)
+			continue
+		}
+		l.metrics[i].writes.Add(1)
+	}
+}
+
+// Set writes a document to every layer, stopping at the first layer which fails
+func (l *layeredCache) Set(ctx context.Context, key string, data []byte, ttl time.Duration) error {
+	for i, layer := range l.layers {
+		if err := layer.Set(ctx, key, data, ttl); err != nil {
+			l.metrics[i].errors.Add(1)
+			return fmt.Errorf("failed to store in the %s cache layer: %w", layer.Name(), err)
+		}
+		l.metrics[i].writes.Add(1)
+	}
+	return nil
+}
+
+// SetMany writes documents to every layer, as Set does. A document which fails in a layer is not written to the layers below it.
+func (l *layeredCache) SetMany(ctx context.Context, items []BulkItem, ttl time.Duration, failed map[string]string) []BulkItem {
+	for i, layer := range l.layers {
+		if len(items) == 0 {
+			break
+		}
+		stored := layer.SetMany(ctx, items, ttl, failed)
+		l.metrics[i].writes.Add(uint64(len(stored)))
+		l.metrics[i].errors.Add(uint64(len(items) - len(stored)))
+		items = stored
+	}
+	return items
+}
+
+// Delete removes a document from every layer
+func (l *layeredCache) Delete(ctx context.Context, key string) error {
+	for i, layer := range l.layers {
+		if err := layer.Delete(ctx, key); err != nil {
+			l.metrics[i].errors.Add(1)
+			return fmt.Errorf("failed to delete from the %s cache layer: %w", layer.Name(), err)
+		}
+	}
+	return nil
+}
+
+// forget removes documents from the in-process layer, after they are written around it
+func (l *layeredCache) forget(keys ...string) {
+	for _, layer := range l.layers {
+		if memory, ok := layer.(*memoryLayer); ok {
+			memory.forget(keys...)
+		}
+	}
+}
+
+// hasLayer reports whether the cache has a layer, such as memory for the in-process layer
+func (l *layeredCache) hasLayer(name string) bool {
+	for _, layer := range l.layers {
+		if layer.Name() == name {
+			return true
+		}
+	}
+	return false
+}
+
+// clearMemory removes every document from the in-process layer, after the layers below it are flushed
+func (l *layeredCache) clearMemory() {
+	for _, layer := range l.layers {
+		if memory, ok := layer.(*memoryLayer); ok {
+			memory.clear()
+		}
+	}
+}
+
+// Stats returns the counters of every layer, followed by the origin
+func (l *layeredCache) Stats() []LayerStats {
+	stats := make([]LayerStats, 0, len(l.layers)+1)
+	for i, layer := range l.layers {
+		stats = append(stats, l.metrics[i].stats(layer.Name()))
+	}
+	return append(stats, l.origin.stats("origin"))
+}
+
+// newHandbookCache creates the layers of the handbook cache from HANDBOOK_CACHE_LAYERS, a comma-separated list of
+// memory, redis and mongo. Layers are always read in that order, and MongoDB is always included as the store of record.
+func (h *DatabaseHandler) newHandbookCache() *layeredCache {
+	enabled := map[string]bool{}
+	raw := os.Getenv("HANDBOOK_CACHE_LAYERS")
+	if raw == "" {
+		raw = defaultHandbookLayers
+	}
+	for _, name := range strings.Split(raw, ",") {
+		enabled[strings.ToLower(strings.TrimSpace(name))] = true
+	}
+	if !enabled["mongo"] {
+		log.Warnf("HANDBOOK_CACHE_LAYERS leaves out mongo, which always holds handbook documents")
+	}
+
+	var layers []CacheLayer
+	if enabled["memory"] {
+		size, err := strconv.Atoi(os.Getenv("HANDBOOK_MEMORY_CACHE_SIZE"))
+		if err != nil || size <= 0 {
+			size = defaultMemoryCacheSize
+		}
+		ttl, err := time.ParseDuration(os.Getenv("HANDBOOK_MEMORY_CACHE_TTL"))
+		if err != nil || ttl <= 0 {
+			ttl = defaultMemoryCacheTTL
+		}
+		layers = append(layers, newMemoryLayer(size, ttl))
+	}
+	if enabled["redis"] {
+		layers = append(layers, redisLayer{h})
+	}
+	layers = append(layers, mongoLayer{h, collections[Handbook]})
+
+	names := make([]string, len(layers))
+	for i, layer := range layers {
+		names[i] = layer.Name()
+	}
+	log.Infof("Handbook cache layers: %s", strings.Join(names, ", "))
+	return newLayeredCache(layers...)
+}
+
+// HandbookCacheStats returns the counters of each layer of the handbook cache
+func (h *DatabaseHandler) HandbookCacheStats() []LayerStats {
+	return h.handbook.Stats()
+}
+
+// RecordHandbookOrigin counts a handbook page scraped because no cache layer held it
+func (h *DatabaseHandler) RecordHandbookOrigin(err error) {
+	if err != nil {
+		h.handbook.origin.errors.Add(1)
+		return
+	}
+	h.handbook.origin.hits.Add(1)
+}
+
+// redisLayer is the Redis layer of the handbook cache, shared by every instance
+type redisLayer struct {
+	h *DatabaseHandler
+}
+
+func (r redisLayer) Name() string { return "redis" }
+
+func (r redisLayer) Get(ctx context.Context, key string) ([]byte, error) {
+	value, err := readRedis(r.h, func(client redis.UniversalClient) ([]byte, error) {
+		return client.Get(ctx, key).Bytes()
+	})
+	if errors.Is(err, redis.Nil) {
+		return nil, errCacheMiss
+	}
+	if err != nil {
+		return nil, err
+	}
+	return r.h.codec.decode(value)
+}
+
+func (r redisLayer) GetMany(ctx context.Context, keys []string) (map[string][]byte, error) {
+	found := map[string]json.RawMessage{}
+	if err := r.h.redisGetMany(ctx, keys, found); err != nil {
+		return nil, err
+	}
+	data := make(map[string][]byte, len(found))
+	for key, value := range found {
+		data[key] = value
+	}
+	return data, nil
+}
+
+func (r redisLayer) Set(ctx context.Context, key string, data []byte, ttl time.Duration) error {
+	value, err := r.h.codec.encode(data)
+	if err != nil {
+		return err
+	}
+	return r.h.redisClient.Set(ctx, key, value, Jitter(ttl)).Err()
+}
+
+func (r redisLayer) SetMany(ctx context.Context, items []BulkItem, ttl time.Duration, failed map[string]string) []BulkItem {
+	return r.h.bulkStoreRedis(ctx, items, ttl, failed)
+}
+
+func (r redisLayer) Delete(ctx context.Context, key string) error {
+	return r.h.redisClient.Del(ctx, key).Err()
+}
+
+// mongoLayer is the MongoDB layer of the handbook cache, which keeps documents without expiry.
+// Writes are queued when write-behind is enabled.
+type mongoLayer struct {
+	h          *DatabaseHandler
+	collection string
+}
+
+func (m mongoLayer) Name() string { return "mongo" }
+
+func (m mongoLayer) Get(ctx context.Context, key string) ([]byte, error) {
+	var doc bson.M
+	err := m.h.mongoDB.Collection(m.collection).FindOne(ctx, bson.M{"_id": key}).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, errCacheMiss
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve document: %w", err)
+	}
+	return json.Marshal(doc)
+}
+
+func (m mongoLayer) GetMany(ctx context.Context, keys []string) (map[string][]byte, error) {
+	found := map[string]json.RawMessage{}
+	if _, err := m.h.mongoGetMany(ctx, m.collection, keys, found); err != nil {
+		return nil, err
+	}
+	data := make(map[string][]byte, len(found))
+	for key, value := range found {
+		data[key] = value
+	}
+	return data, nil
+}
+
+func (m mongoLayer) Set(ctx context.Context, key string, data []byte, _ time.Duration) error {
+	if m.h.writeBehind != nil {
+		return m.h.enqueueMongo(ctx, m.collection, key, json.RawMessage(data))
+	}
+	return m.h.storeMongo(ctx, m.collection, key, json.RawMessage(data))
+}
+
+func (m mongoLayer) SetMany(ctx context.Context, items []BulkItem, _ time.Duration, failed map[string]string) []BulkItem {
+	if m.h.writeBehind == nil {
+		return m.h.bulkStoreMongo(ctx, m.collection, items, failed)
+	}
+
+	stored := make([]BulkItem, 0, len(items))
+	for _, item := range items {
+		if err := m.h.enqueueMongo(ctx, m.collection, item.Key, item.Data); err != nil {
+			failed[item.Key] = err.Error()
+			continue
+		}
+		stored = append(stored, item)
+	}
+	return stored
+}
+
+func (m mongoLayer) Delete(ctx context.Context, key string) error {
+	if m.h.writeBehind != nil {
+		m.h.writeBehind.dropMongo(m.collection, key)
+	}
+	_, err := m.h.mongoDB.Collection(m.collection).DeleteOne(ctx, bson.M{"_id": key})
+	return err
+}
@@ -0,0 +1,145 @@
+package databases
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"handbook-scraper/utils/log"
+)
+
+// lockKeyPrefix namespaces distributed lock keys in Redis
+const lockKeyPrefix = "lock:"
+
+// ErrLockHeld is returned when a lock is already held by another replica
+var ErrLockHeld = errors.New("lock is held by another replica")
+
+// releaseScript deletes the lock only if it is still owned by the caller
+var releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// refreshScript extends the lock TTL only if it is still owned by the caller
+var refreshScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// Lock represents a distributed lock held in Redis
+type Lock struct {
+	handler *DatabaseHandler
+	key     string
+	token   string
+	ttl     time.Duration
+}
+
+// AcquireLock tries to acquire the named lock for the given TTL.
+// It returns ErrLockHeld if another replica currently holds the lock.
+func (h *DatabaseHandler) AcquireLock(name string, ttl time.Duration) (*Lock, error) {
+	token, err := newLockToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate lock token: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.timeouts.Write)
+	defer cancel()
+
+	key := lockKeyPrefix + name
+	acquired, err := h.redisClient.SetNX(ctx, key, token, ttl).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire lock %s: %w", name, err)
+	}
+	if !acquired {
+		return nil, ErrLockHeld
+	}
+
+	return &Lock{handler: h, key: key, token: token, ttl: ttl}, nil
+}
+
+// Refresh extends the lock by its original TTL.
+// It returns ErrLockHeld if the lock expired and was taken by another replica.
+func (l *Lock) Refresh() error {
+	ctx, cancel := context.WithTimeout(context.Background(), l.handler.timeouts.Write)
+	defer cancel()
+
+	res, err := refreshScript.Run(ctx, l.handler.redisClient, []string{l.key}, l.token, l.ttl.Milliseconds()).Int()
+	if err != nil {
+		return fmt.Errorf("failed to refresh lock %s: %w", l.key, err)
+	}
+	if res == 0 {
+		return ErrLockHeld
+	}
+	return nil
+}
+
+// Release releases the lock if it is still owned by this replica
+func (l *Lock) Release() error {
+	ctx, cancel := context.WithTimeout(context.Background(), l.handler.timeouts.Write)
+	defer cancel()
+
+	if err := releaseScript.Run(ctx, l.handler.redisClient, []string{l.key}, l.token).Err(); err != nil {
+		return fmt.Errorf("failed to release lock %s: %w", l.key, err)
+	}
+	return nil
+}
+
+// RunExclusive runs fn while holding the named lock, so only one replica runs it at a time.
+// The lock is refreshed in the background until fn returns; if the lock is lost,
+// the context passed to fn is cancelled. It returns false if another replica holds the lock.
+func (h *DatabaseHandler) RunExclusive(name string, ttl time.Duration, fn func(ctx context.Context) error) (bool, error) {
+	lock, err := h.AcquireLock(name, ttl)
+	if errors.Is(err, ErrLockHeld) {
+		log.Infof("[LOCK] %s is held by another replica, skipping", name)
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Keep the lock alive while fn is running
+	go func() {
+		ticker := time.NewTicker(ttl / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := lock.Refresh(); err != nil {
+					log.Errorf("[LOCK] Lost lock %s: %v", name, err)
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+
+	defer func() {
+		if err := lock.Release(); err != nil {
+			log.Errorf("[LOCK] %v", err)
+		}
+	}()
+
+	return true, fn(ctx)
+}
+
+// newLockToken generates a random token identifying the lock owner
+func newLockToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,126 @@
+package databases
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultMemoryCacheSize is how many documents the in-process layer holds unless HANDBOOK_MEMORY_CACHE_SIZE is set
+	defaultMemoryCacheSize = 1000
+	// defaultMemoryCacheTTL is how long the in-process layer holds a document unless HANDBOOK_MEMORY_CACHE_TTL is set.
+	// It is short, since other instances cannot invalidate it.
+	defaultMemoryCacheTTL = time.Minute
+)
+
+// memoryEntry is a document held by the in-process layer
+type memoryEntry struct {
+	key       string
+	data      []byte
+	expiresAt time.Time
+}
+
+// memoryLayer is the in-process layer of the handbook cache, holding the most recently used documents
+type memoryLayer struct {
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration
+	entries map[string]*list.Element
+	recent  *list.List // Most recently used first
+}
+
+// newMemoryLayer creates an in-process layer holding up to size documents for at most ttl
+func newMemoryLayer(size int, ttl time.Duration) *memoryLayer {
+	return &memoryLayer{size: size, ttl: ttl, entries: map[string]*list.Element{}, recent: list.New()}
+}
+
+func (m *memoryLayer) Name() string { return "memory" }
+
+func (m *memoryLayer) Get(_ context.Context, key string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	element, ok := m.entries[key]
+	if !ok {
+		return nil, errCacheMiss
+	}
+	entry := element.Value.(*memoryEntry)
+	if time.Now().After(entry.expiresAt) {
+		m.remove(element)
+		return nil, errCacheMiss
+	}
+	m.recent.MoveToFront(element)
+	return entry.data, nil
+}
+
+func (m *memoryLayer) GetMany(ctx context.Context, keys []string) (map[string][]byte, error) {
+	found := map[string][]byte{}
+	for _, key := range keys {
+		if data, err := m.Get(ctx, key); err == nil {
+			found[key] = data
+		}
+	}
+	return found, nil
+}
+
+func (m *memoryLayer) Set(_ context.Context, key string, data []byte, ttl time.Duration) error {
+	if ttl <= 0 || ttl > m.ttl {
+		ttl = m.ttl
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry := &memoryEntry{key: key, data: data, expiresAt: time.Now().Add(ttl)}
+	if element, ok := m.entries[key]; ok {
+		element.Value = entry
+		m.recent.MoveToFront(element)
+		return nil
+	}
+	m.entries[key] = m.recent.PushFront(entry)
+	for m.recent.Len() > m.size {
+		m.remove(m.recent.Back())
+	}
+	return nil
+}
+
+func (m *memoryLayer) SetMany(ctx context.Context, items []BulkItem, ttl time.Duration, _ map[string]string) []BulkItem {
+	for _, item := range items {
+		data, _ := item.Data.(json.RawMessage)
+		_ = m.Set(ctx, item.Key, data, ttl)
+	}
+	return items
+}
+
+func (m *memoryLayer) Delete(_ context.Context, key string) error {
+	m.forget(key)
+	return nil
+}
+
+// forget removes documents from the layer
+func (m *memoryLayer) forget(keys ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, key := range keys {
+		if element, ok := m.entries[key]; ok {
+			m.remove(element)
+		}
+	}
+}
+
+// clear removes every document from the layer
+func (m *memoryLayer) clear() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries = map[string]*list.Element{}
+	m.recent.Init()
+}
+
+// remove removes an entry, with the lock held
+func (m *memoryLayer) remove(element *list.Element) {
+	m.recent.Remove(element)
+	delete(m.entries, element.Value.(*memoryEntry).key)
+}
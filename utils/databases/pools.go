@@ -0,0 +1,135 @@
+package databases
+
+import (
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"handbook-scraper/utils/log"
+	"handbook-scraper/utils/tracing"
+)
+
+// timeoutBudgets are the time budgets of database operations. Listing keys and bulk reads and writes get longer
+// budgets than single-document operations, so jobs over a whole year do not fail against the limit of a single read.
+type timeoutBudgets struct {
+	Read  time.Duration // Single-document reads and existence checks
+	Write time.Duration // Single-document writes and deletes, locks and invalidations
+	List  time.Duration // Listing keys, which can scan every document of a year
+	Bulk  time.Duration // Each chunk of a bulk read or write
+	Flush time.Duration // Clearing a collection, or finishing the queued handbook writes on close
+}
+
+// timeoutBudgetsFromEnv reads the budgets of database operations from DB_READ_TIMEOUT, DB_WRITE_TIMEOUT,
+// DB_LIST_TIMEOUT, DB_BULK_TIMEOUT and DB_FLUSH_TIMEOUT
+func timeoutBudgetsFromEnv() timeoutBudgets {
+	return timeoutBudgets{
+		Read:  envDuration("DB_READ_TIMEOUT", 5*time.Second),
+		Write: envDuration("DB_WRITE_TIMEOUT", 5*time.Second),
+		List:  envDuration("DB_LIST_TIMEOUT", 30*time.Second),
+		Bulk:  envDuration("DB_BULK_TIMEOUT", 30*time.Second),
+		Flush: envDuration("DB_FLUSH_TIMEOUT", time.Minute),
+	}
+}
+
+// mongoClientOptions applies the pool settings of MONGO_MAX_POOL_SIZE, MONGO_MIN_POOL_SIZE, MONGO_MAX_CONN_IDLE_TIME,
+// MONGO_CONNECT_TIMEOUT and MONGO_SERVER_SELECTION_TIMEOUT over the options of the connection URI.
+// Settings which are unset keep the URI's values or the driver's defaults. Every command is traced.
+func mongoClientOptions(uri string) *options.ClientOptions {
+	opts := options.Client().ApplyURI(uri).SetMonitor(tracing.MongoMonitor())
+	if size := envInt("MONGO_MAX_POOL_SIZE", 0); size > 0 {
+		opts.SetMaxPoolSize(uint64(size))
+	}
+	if size := envInt("MONGO_MIN_POOL_SIZE", 0); size > 0 {
+		opts.SetMinPoolSize(uint64(size))
+	}
+	if idle := envDuration("MONGO_MAX_CONN_IDLE_TIME", 0); idle > 0 {
+		opts.SetMaxConnIdleTime(idle)
+	}
+	if timeout := envDuration("MONGO_CONNECT_TIMEOUT", 0); timeout > 0 {
+		opts.SetConnectTimeout(timeout)
+	}
+	if timeout := envDuration("MONGO_SERVER_SELECTION_TIMEOUT", 0); timeout > 0 {
+		opts.SetServerSelectionTimeout(timeout)
+	}
+	return opts
+}
+
+// redisPool holds the pool settings of REDIS_POOL_SIZE, REDIS_MIN_IDLE_CONNS, REDIS_MAX_IDLE_CONNS, REDIS_POOL_TIMEOUT,
+// REDIS_DIAL_TIMEOUT, REDIS_READ_TIMEOUT and REDIS_WRITE_TIMEOUT, applied to every Redis client.
+// Settings which are unset keep the URL's values or the client's defaults.
+type redisPool struct {
+	poolSize, minIdleConns, maxIdleConns                int
+	poolTimeout, dialTimeout, readTimeout, writeTimeout time.Duration
+}
+
+// redisPoolFromEnv reads the Redis pool settings
+func redisPoolFromEnv() redisPool {
+	return redisPool{
+		poolSize:     envInt("REDIS_POOL_SIZE", 0),
+		minIdleConns: envInt("REDIS_MIN_IDLE_CONNS", 0),
+		maxIdleConns: envInt("REDIS_MAX_IDLE_CONNS", 0),
+		poolTimeout:  envDuration("REDIS_POOL_TIMEOUT", 0),
+		dialTimeout:  envDuration("REDIS_DIAL_TIMEOUT", 0),
+		readTimeout:  envDuration("REDIS_READ_TIMEOUT", 0),
+		writeTimeout: envDuration("REDIS_WRITE_TIMEOUT", 0),
+	}
+}
+
+// apply sets the pool settings on the options of a Redis client
+func (p redisPool) apply(opts *redis.Options) {
+	setIfPositive(&opts.PoolSize, p.poolSize)
+	setIfPositive(&opts.MinIdleConns, p.minIdleConns)
+	setIfPositive(&opts.MaxIdleConns, p.maxIdleConns)
+	setIfPositive(&opts.PoolTimeout, p.poolTimeout)
+	setIfPositive(&opts.DialTimeout, p.dialTimeout)
+	setIfPositive(&opts.ReadTimeout, p.readTimeout)
+	setIfPositive(&opts.WriteTimeout, p.writeTimeout)
+}
+
+// applyCluster sets the pool settings on the options of a Redis Cluster client, for the pool of each node
+func (p redisPool) applyCluster(opts *redis.ClusterOptions) {
+	setIfPositive(&opts.PoolSize, p.poolSize)
+	setIfPositive(&opts.MinIdleConns, p.minIdleConns)
+	setIfPositive(&opts.MaxIdleConns, p.maxIdleConns)
+	setIfPositive(&opts.PoolTimeout, p.poolTimeout)
+	setIfPositive(&opts.DialTimeout, p.dialTimeout)
+	setIfPositive(&opts.ReadTimeout, p.readTimeout)
+	setIfPositive(&opts.WriteTimeout, p.writeTimeout)
+}
+
+// setIfPositive sets a setting to a value, unless the value is unset
+func setIfPositive[T int | time.Duration](setting *T, value T) {
+	if value > 0 {
+		*setting = value
+	}
+}
+
+// envInt reads a positive whole number from the environment, falling back to a default if unset or invalid
+func envInt(name string, fallback int) int {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return fallback
+	}
+	value, err := strconv.Atoi(raw)
+	if err != nil || value <= 0 {
+		log.Warnf("Ignoring invalid %s %q", name, raw)
+		return fallback
+	}
+	return value
+}
+
+// envDuration reads a positive duration from the environment, falling back to a default if unset or invalid
+func envDuration(name string, fallback time.Duration) time.Duration {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return fallback
+	}
+	value, err := time.ParseDuration(raw)
+	if err != nil || value <= 0 {
+		log.Warnf("Ignoring invalid %s %q", name, raw)
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,150 @@
+package databases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/redis/go-redis/extra/redisotel/v9"
+	"github.com/redis/go-redis/v9"
+	"handbook-scraper/utils/log"
+	"handbook-scraper/utils/secrets"
+)
+
+// newRedisClients creates the Redis clients used for writes and reads from environment variables.
+//
+// REDIS_CLUSTER_ADDRS connects to a Redis Cluster, routing reads to the node with the lowest latency.
+// Otherwise REDIS_URL or REDIS_ADDR is the primary, and REDIS_READ_URL or REDIS_READ_ADDR an optional
+// read replica. Without a replica, reads go to the primary. Every client gets the pool settings of redisPoolFromEnv.
+// URLs and passwords are secrets, which can also be read from files or a secrets provider. Every command is traced.
+func newRedisClients() (redis.UniversalClient, redis.UniversalClient, error) {
+	writeClient, readClient, err := connectRedisClients()
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, client := range []redis.UniversalClient{writeClient, readClient} {
+		if err := redisotel.InstrumentTracing(client, redisotel.WithDBStatement(false)); err != nil {
+			log.Errorf("Failed to trace Redis commands: %v", err)
+		}
+		if writeClient == readClient {
+			break
+		}
+	}
+	return writeClient, readClient, nil
+}
+
+// connectRedisClients creates the Redis clients used for writes and reads, which are the same client without a replica
+func connectRedisClients() (redis.UniversalClient, redis.UniversalClient, error) {
+	if clusterAddrs := os.Getenv("REDIS_CLUSTER_ADDRS"); clusterAddrs != "" {
+		opts := &redis.ClusterOptions{
+			Addrs:          strings.Split(clusterAddrs, ","),
+			Password:       secrets.Get("REDIS_PASSWORD"),
+			RouteByLatency: true,
+		}
+		redisPoolFromEnv().applyCluster(opts)
+		client := redis.NewClusterClient(opts)
+		return client, client, nil
+	}
+
+	writeClient, err := newRedisClient(secrets.Get("REDIS_URL"), os.Getenv("REDIS_ADDR"), secrets.Get("REDIS_PASSWORD"))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	readURL, readAddr := secrets.Get("REDIS_READ_URL"), os.Getenv("REDIS_READ_ADDR")
+	if readURL == "" && readAddr == "" {
+		return writeClient, writeClient, nil
+	}
+
+	readPassword := secrets.Get("REDIS_READ_PASSWORD")
+	if readPassword == "" {
+		readPassword = secrets.Get("REDIS_PASSWORD")
+	}
+	readClient, err := newRedisClient(readURL, readAddr, readPassword)
+	if err != nil {
+		_ = writeClient.Close()
+		return nil, nil, err
+	}
+	return writeClient, readClient, nil
+}
+
+// newRedisClient creates a Redis client from a URL, or from an address, password and REDIS_DB
+func newRedisClient(redisURL string, redisAddr string, redisPass string) (*redis.Client, error) {
+	if redisURL != "" {
+		opts, err := redis.ParseURL(redisURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse Redis URL: %w", err)
+		}
+		redisPoolFromEnv().apply(opts)
+		return redis.NewClient(opts), nil
+	}
+
+	redisDB, err := strconv.Atoi(os.Getenv("REDIS_DB"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid REDIS_DB value: %w", err)
+	}
+
+	opts := &redis.Options{
+		Addr:     redisAddr,
+		Password: redisPass,
+		DB:       redisDB,
+	}
+	redisPoolFromEnv().apply(opts)
+	return redis.NewClient(opts), nil
+}
+
+// readRedis runs a read against the read client, falling back to the primary if the replica is unreachable
+func readRedis[T any](h *DatabaseHandler, read func(client redis.UniversalClient) (T, error)) (T, error) {
+	result, err := read(h.redisReadClient)
+	if err != nil && !errors.Is(err, redis.Nil) && h.redisReadClient != h.redisClient {
+		log.Errorf("Redis read replica failed, falling back to primary: %v", err)
+		return read(h.redisClient)
+	}
+	return result, err
+}
+
+// redisExists counts how many of the keys exist
+func (h *DatabaseHandler) redisExists(ctx context.Context, keys ...string) (int64, error) {
+	return readRedis(h, func(client redis.UniversalClient) (int64, error) {
+		return client.Exists(ctx, keys...).Result()
+	})
+}
+
+// redisKeys lists the keys matching a pattern.
+// In a cluster every master holds a share of the keys, so each of them is queried.
+func (h *DatabaseHandler) redisKeys(ctx context.Context, pattern string) ([]string, error) {
+	cluster, ok := h.redisClient.(*redis.ClusterClient)
+	if !ok {
+		return readRedis(h, func(client redis.UniversalClient) ([]string, error) {
+			return client.Keys(ctx, pattern).Result()
+		})
+	}
+
+	var keys []string
+	var mu sync.Mutex
+	err := cluster.ForEachMaster(ctx, func(ctx context.Context, client *redis.Client) error {
+		nodeKeys, err := client.Keys(ctx, pattern).Result()
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		keys = append(keys, nodeKeys...)
+		mu.Unlock()
+		return nil
+	})
+	return keys, err
+}
+
+// flushRedis clears the Redis database, or every master of a cluster
+func (h *DatabaseHandler) flushRedis(ctx context.Context) error {
+	if cluster, ok := h.redisClient.(*redis.ClusterClient); ok {
+		return cluster.ForEachMaster(ctx, func(ctx context.Context, client *redis.Client) error {
+			return client.FlushDB(ctx).Err()
+		})
+	}
+	return h.redisClient.FlushDB(ctx).Err()
+}
@@ -0,0 +1,149 @@
+package databases
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/klauspost/compress/s2"
+	"github.com/klauspost/compress/zstd"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+const (
+	// codecMagic starts the header of encoded Redis values. It never starts a JSON document, so plain JSON values
+	// written before encoding was enabled still decode.
+	codecMagic byte = 0xff
+
+	formatJSON    byte = 'j'
+	formatMsgpack byte = 'm'
+
+	compressionNone   byte = 'n'
+	compressionZstd   byte = 'z'
+	compressionSnappy byte = 's'
+
+	// minCompressedSize is the smallest value which is compressed, below it compression saves too little to be worth it
+	minCompressedSize = 512
+)
+
+var (
+	zstdEncoder, _ = zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedDefault))
+	zstdDecoder, _ = zstd.NewReader(nil)
+)
+
+// redisCodec encodes documents stored in Redis in the format of REDIS_VALUE_FORMAT, json (default) or msgpack,
+// compressed with REDIS_VALUE_COMPRESSION, none (default), zstd or snappy. Encoded values start with a header
+// naming how they were encoded, so values decode whatever the settings were when they were written,
+// and the settings can be changed without flushing Redis.
+type redisCodec struct {
+	format      byte
+	compression byte
+}
+
+// redisCodecFromEnv reads the encoding of Redis values from REDIS_VALUE_FORMAT and REDIS_VALUE_COMPRESSION
+func redisCodecFromEnv() (redisCodec, error) {
+	codec := redisCodec{format: formatJSON, compression: compressionNone}
+
+	switch format := strings.ToLower(os.Getenv("REDIS_VALUE_FORMAT")); format {
+	case "", "json":
+	case "msgpack":
+		codec.format = formatMsgpack
+	default:
+		return codec, fmt.Errorf("invalid REDIS_VALUE_FORMAT %q, expected json or msgpack", format)
+	}
+
+	switch compression := strings.ToLower(os.Getenv("REDIS_VALUE_COMPRESSION")); compression {
+	case "", "none":
+	case "zstd":
+		codec.compression = compressionZstd
+	case "snappy":
+		codec.compression = compressionSnappy
+	default:
+		return codec, fmt.Errorf("invalid REDIS_VALUE_COMPRESSION %q, expected none, zstd or snappy", compression)
+	}
+	return codec, nil
+}
+
+// encode encodes the JSON of a document for Redis. Plain JSON is stored as is, without a header.
+func (c redisCodec) encode(jsonData []byte) ([]byte, error) {
+	payload := jsonData
+	if c.format == formatMsgpack {
+		var value interface{}
+		if err := json.Unmarshal(jsonData, &value); err != nil {
+			return nil, fmt.Errorf("failed to encode value: %w", err)
+		}
+		encoded, err := marshalMsgpack(value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode value: %w", err)
+		}
+		payload = encoded
+	}
+
+	compression := c.compression
+	if len(payload) < minCompressedSize {
+		compression = compressionNone
+	}
+	switch compression {
+	case compressionZstd:
+		payload = zstdEncoder.EncodeAll(payload, nil)
+	case compressionSnappy:
+		payload = s2.EncodeSnappy(nil, payload)
+	}
+
+	if c.format == formatJSON && compression == compressionNone {
+		return payload, nil
+	}
+	return append([]byte{codecMagic, c.format, compression}, payload...), nil
+}
+
+// decode returns the JSON of a value read from Redis, however it was encoded
+func (c redisCodec) decode(value []byte) ([]byte, error) {
+	if len(value) == 0 || value[0] != codecMagic {
+		return value, nil
+	}
+	if len(value) < 3 {
+		return nil, fmt.Errorf("failed to decode value: truncated header")
+	}
+
+	format, compression, payload := value[1], value[2], value[3:]
+	var err error
+	switch compression {
+	case compressionNone:
+	case compressionZstd:
+		payload, err = zstdDecoder.DecodeAll(payload, nil)
+	case compressionSnappy:
+		payload, err = s2.Decode(nil, payload)
+	default:
+		err = fmt.Errorf("unknown compression %q", compression)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode value: %w", err)
+	}
+
+	switch format {
+	case formatJSON:
+		return payload, nil
+	case formatMsgpack:
+		var decoded interface{}
+		if err := msgpack.Unmarshal(payload, &decoded); err != nil {
+			return nil, fmt.Errorf("failed to decode value: %w", err)
+		}
+		return json.Marshal(decoded)
+	default:
+		return nil, fmt.Errorf("failed to decode value: unknown format %q", format)
+	}
+}
+
+// marshalMsgpack encodes a decoded JSON value as MessagePack, with whole numbers as integers rather than floats
+func marshalMsgpack(value interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := msgpack.NewEncoder(&buf)
+	encoder.UseCompactInts(true)
+	encoder.UseCompactFloats(true)
+	if err := encoder.Encode(value); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,69 @@
+package databases
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// PurgeBefore deletes the documents of a storage type whose timestamp field is before cutoff, except the keys in keep,
+// and returns how many were deleted. Timestamps are stored as RFC 3339 strings, so they are compared here rather than
+// in the query. Documents without the field, such as version pins, are kept.
+func (h *DatabaseHandler) PurgeBefore(storageType StorageType, field string, cutoff time.Time, keep []string) (int64, error) {
+	if storageType != Timetable && storageType != Raw && storageType != Version {
+		return 0, fmt.Errorf("unsupported storage type: %s", storageType)
+	}
+	collection, err := collectionFor(storageType)
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.timeouts.List)
+	defer cancel()
+
+	filter := bson.M{field: bson.M{"$exists": true}}
+	if len(keep) > 0 {
+		filter["_id"] = bson.M{"$nin": keep}
+	}
+	cursor, err := h.mongoDB.Collection(collection).Find(ctx, filter, options.Find().SetProjection(bson.M{field: 1}))
+	if err != nil {
+		return 0, fmt.Errorf("failed to list documents: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var expired []string
+	for cursor.Next(ctx) {
+		var doc bson.M
+		if err := cursor.Decode(&doc); err != nil {
+			return 0, fmt.Errorf("failed to decode document: %w", err)
+		}
+		key, ok := doc["_id"].(string)
+		if !ok {
+			continue
+		}
+		raw, _ := doc[field].(string)
+		stamp, err := time.Parse(time.RFC3339Nano, raw)
+		if err == nil && stamp.Before(cutoff) {
+			expired = append(expired, key)
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return 0, fmt.Errorf("failed to list documents: %w", err)
+	}
+
+	var deleted int64
+	for start := 0; start < len(expired); start += bulkChunkSize {
+		chunk := expired[start:min(start+bulkChunkSize, len(expired))]
+		chunkCtx, cancelChunk := context.WithTimeout(context.Background(), h.timeouts.Bulk)
+		result, err := h.mongoDB.Collection(collection).DeleteMany(chunkCtx, bson.M{"_id": bson.M{"$in": chunk}})
+		cancelChunk()
+		if err != nil {
+			return deleted, fmt.Errorf("failed to delete documents: %w", err)
+		}
+		deleted += result.DeletedCount
+	}
+	return deleted, nil
+}
@@ -0,0 +1,133 @@
+package databases
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+	"go.mongodb.org/mongo-driver/bson"
+	"handbook-scraper/utils/log"
+)
+
+// RetrieveMany retrieves the documents of many keys at once using the specified storage strategy,
+// returning the JSON of each key found. Keys which are not stored are left out rather than failing the others.
+// Redis is read with MGET, or pipelined GETs across a cluster, and Mongo with a single $in query per chunk of keys,
+// instead of a round-trip per key. Handbook documents are read through the layers of the handbook cache, as with Retrieve.
+func (h *DatabaseHandler) RetrieveMany(storageType StorageType, keys []string) (map[string]json.RawMessage, error) {
+	found := make(map[string]json.RawMessage, len(keys))
+	if len(keys) == 0 {
+		return found, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.timeouts.Bulk)
+	defer cancel()
+
+	switch storageType {
+	case Handbook:
+		data, err := h.handbook.GetMany(ctx, keys)
+		for key, value := range data {
+			found[key] = value
+		}
+		return found, err
+	case Cache:
+		return found, h.redisGetMany(ctx, keys, found)
+	}
+
+	collection, err := collectionFor(storageType)
+	if err != nil {
+		return nil, err
+	}
+
+	missing := make([]string, 0, len(keys)-len(found))
+	for _, key := range keys {
+		if _, ok := found[key]; !ok {
+			missing = append(missing, key)
+		}
+	}
+	if _, err := h.mongoGetMany(ctx, collection, missing, found); err != nil {
+		return nil, err
+	}
+	return found, nil
+}
+
+// redisGetMany adds the values of the keys which exist in Redis to found
+func (h *DatabaseHandler) redisGetMany(ctx context.Context, keys []string, found map[string]json.RawMessage) error {
+	for start := 0; start < len(keys); start += bulkChunkSize {
+		chunk := keys[start:min(start+bulkChunkSize, len(keys))]
+
+		values, err := readRedis(h, func(client redis.UniversalClient) ([]interface{}, error) {
+			// Keys of a cluster are spread over its nodes, and a single MGET can only read keys of the same slot
+			if _, ok := client.(*redis.ClusterClient); !ok {
+				return client.MGet(ctx, chunk...).Result()
+			}
+
+			cmds := make([]*redis.StringCmd, len(chunk))
+			pipe := client.Pipeline()
+			for i, key := range chunk {
+				cmds[i] = pipe.Get(ctx, key)
+			}
+			if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+				return nil, err
+			}
+			values := make([]interface{}, len(chunk))
+			for i, cmd := range cmds {
+				if value, err := cmd.Result(); err == nil {
+					values[i] = value
+				}
+			}
+			return values, nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to retrieve from Redis: %w", err)
+		}
+
+		for i, value := range values {
+			value, ok := value.(string)
+			if !ok {
+				continue
+			}
+			data, err := h.codec.decode([]byte(value))
+			if err != nil {
+				log.Errorf("Skipping %s: %v", chunk[i], err)
+				continue
+			}
+			found[chunk[i]] = data
+		}
+	}
+	return nil
+}
+
+// mongoGetMany adds the documents of the keys which exist in a Mongo collection to found, and returns them
+func (h *DatabaseHandler) mongoGetMany(ctx context.Context, collection string, keys []string, found map[string]json.RawMessage) ([]BulkItem, error) {
+	var retrieved []BulkItem
+	for start := 0; start < len(keys); start += bulkChunkSize {
+		chunk := keys[start:min(start+bulkChunkSize, len(keys))]
+
+		cursor, err := h.mongoDB.Collection(collection).Find(ctx, bson.M{"_id": bson.M{"$in": chunk}})
+		if err != nil {
+			return nil, fmt.Errorf("failed to retrieve documents: %w", err)
+		}
+
+		var docs []bson.M
+		err = cursor.All(ctx, &docs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to retrieve documents: %w", err)
+		}
+
+		for _, doc := range docs {
+			key, ok := doc["_id"].(string)
+			if !ok {
+				continue
+			}
+			jsonData, err := json.Marshal(doc)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal document: %w", err)
+			}
+			found[key] = jsonData
+			retrieved = append(retrieved, BulkItem{Key: key, Data: json.RawMessage(jsonData)})
+		}
+	}
+	return retrieved, nil
+}
@@ -0,0 +1,224 @@
+package databases
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"handbook-scraper/utils/log"
+)
+
+const (
+	writeBehindQueueSize   = 1000
+	writeBehindMaxAttempts = 5
+	writeBehindBaseBackoff = 500 * time.Millisecond
+)
+
+// mongoWrite is a queued write of a document to MongoDB
+type mongoWrite struct {
+	collection string
+	key        string
+	data       interface{}
+	attempts   int       // Failed attempts so far
+	notBefore  time.Time // When a failed write is next attempted
+}
+
+// id identifies the document a write replaces
+func (w *mongoWrite) id() string {
+	return w.collection + "\x00" + w.key
+}
+
+// writeBehind writes documents to MongoDB from a background worker, retrying failed writes.
+// Writes of the same document are coalesced, so only the latest is written and an older one can never land after it.
+type writeBehind struct {
+	mu       sync.Mutex
+	pending  map[string]*mongoWrite // Queued writes by document
+	order    []string               // Documents of pending, in the order they were first queued
+	inFlight string                 // Document being written by the worker
+	wake     chan struct{}          // Signalled when a write is queued or the queue is closed
+	closed   bool
+	aborted  bool // Set once a flush runs out of time, so the worker stops retrying
+	done     chan struct{}
+}
+
+// writeBehindEnabled reports whether HANDBOOK_WRITE_BEHIND enables write-behind for handbook documents
+func writeBehindEnabled() bool {
+	enabled, _ := strconv.ParseBool(os.Getenv("HANDBOOK_WRITE_BEHIND"))
+	return enabled
+}
+
+// startWriteBehind starts the background worker writing queued documents with the handler
+func (h *DatabaseHandler) startWriteBehind() {
+	h.writeBehind = &writeBehind{
+		pending: map[string]*mongoWrite{},
+		wake:    make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+
+	go func() {
+		defer close(h.writeBehind.done)
+		for {
+			write, ok := h.writeBehind.next()
+			if !ok {
+				return
+			}
+			err := h.storeMongo(context.Background(), write.collection, write.key, write.data)
+			h.writeBehind.finish(write, err)
+		}
+	}()
+	log.Infof("Handbook write-behind enabled")
+}
+
+// enqueueMongo queues a write to MongoDB, replacing any queued write of the same document.
+// If the queue is full or flushed, the document is written synchronously instead.
+func (h *DatabaseHandler) enqueueMongo(ctx context.Context, collection string, key string, data interface{}) error {
+	wb := h.writeBehind
+	write := &mongoWrite{collection: collection, key: key, data: data}
+	id := write.id()
+
+	wb.mu.Lock()
+	if queued, ok := wb.pending[id]; ok {
+		queued.data, queued.attempts, queued.notBefore = data, 0, time.Time{}
+		wb.mu.Unlock()
+		return nil
+	}
+	// A document being written is queued again even when the queue is full, so this newer write lands after it
+	if !wb.closed && (len(wb.pending) < writeBehindQueueSize || wb.inFlight == id) {
+		wb.pending[id] = write
+		wb.order = append(wb.order, id)
+		wb.mu.Unlock()
+		wb.signal()
+		return nil
+	}
+	wb.mu.Unlock()
+	return h.storeMongo(ctx, collection, key, data)
+}
+
+// dropMongo discards a queued write of a document, so a deleted document is not written back
+func (wb *writeBehind) dropMongo(collection string, key string) {
+	id := (&mongoWrite{collection: collection, key: key}).id()
+	wb.mu.Lock()
+	defer wb.mu.Unlock()
+	if _, ok := wb.pending[id]; ok {
+		delete(wb.pending, id)
+		wb.removeOrder(id)
+	}
+}
+
+// signal wakes the worker without blocking
+func (wb *writeBehind) signal() {
+	select {
+	case wb.wake <- struct{}{}:
+	default:
+	}
+}
+
+// next waits for the first queued write which is due, and marks it in flight.
+// It returns false once the queue is closed and empty, or a flush has run out of time.
+func (wb *writeBehind) next() (*mongoWrite, bool) {
+	for {
+		wb.mu.Lock()
+		if wb.aborted || (wb.closed && len(wb.pending) == 0) {
+			wb.mu.Unlock()
+			return nil, false
+		}
+
+		now := time.Now()
+		var wait time.Duration
+		for _, id := range wb.order {
+			write := wb.pending[id]
+			if !write.notBefore.After(now) {
+				delete(wb.pending, id)
+				wb.removeOrder(id)
+				wb.inFlight = id
+				wb.mu.Unlock()
+				return write, true
+			}
+			if until := write.notBefore.Sub(now); wait == 0 || until < wait {
+				wait = until
+			}
+		}
+		wb.mu.Unlock()
+
+		// Nothing is due, so wait for a new write or the earliest retry
+		if wait == 0 {
+			<-wb.wake
+			continue
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-wb.wake:
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+}
+
+// finish records the result of a write. A failed write is retried later with exponential backoff,
+// unless a newer write of the document was queued meanwhile, without holding up the writes behind it.
+func (wb *writeBehind) finish(write *mongoWrite, err error) {
+	wb.mu.Lock()
+	defer wb.mu.Unlock()
+	id := write.id()
+	wb.inFlight = ""
+	if err == nil {
+		return
+	}
+
+	write.attempts++
+	if _, superseded := wb.pending[id]; superseded {
+		log.Errorf("[WRITE BEHIND] Attempt %d for %s failed, superseded by a newer write: %v", write.attempts, write.key, err)
+		return
+	}
+	if write.attempts == writeBehindMaxAttempts {
+		log.Errorf("[WRITE BEHIND] Giving up on %s after %d attempts: %v", write.key, write.attempts, err)
+		return
+	}
+
+	backoff := writeBehindBaseBackoff << (write.attempts - 1)
+	log.Errorf("[WRITE BEHIND] Attempt %d for %s failed, retrying in %s: %v", write.attempts, write.key, backoff, err)
+	write.notBefore = time.Now().Add(backoff)
+	wb.pending[id] = write
+	wb.order = append(wb.order, id)
+}
+
+// removeOrder removes a document from the queue order. The lock must be held.
+func (wb *writeBehind) removeOrder(id string) {
+	for i, queued := range wb.order {
+		if queued == id {
+			wb.order = append(wb.order[:i], wb.order[i+1:]...)
+			return
+		}
+	}
+}
+
+// flushWriteBehind stops accepting writes and waits for the queued writes to finish, for at most the flush budget.
+// Writes still queued after it are logged and dropped.
+func (h *DatabaseHandler) flushWriteBehind() {
+	wb := h.writeBehind
+	if wb == nil {
+		return
+	}
+	wb.mu.Lock()
+	wb.closed = true
+	wb.mu.Unlock()
+	wb.signal()
+
+	timer := time.NewTimer(h.timeouts.Flush)
+	defer timer.Stop()
+	select {
+	case <-wb.done:
+		return
+	case <-timer.C:
+	}
+
+	wb.mu.Lock()
+	wb.aborted = true
+	dropped := len(wb.pending)
+	wb.mu.Unlock()
+	wb.signal()
+	log.Errorf("[WRITE BEHIND] Dropping %d queued writes after waiting %s to flush them", dropped, h.timeouts.Flush)
+	<-wb.done
+}
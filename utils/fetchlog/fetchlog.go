@@ -0,0 +1,143 @@
+// Package fetchlog records every request made to upstream sites, such as the handbook, for the fetch audit trail
+package fetchlog
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"handbook-scraper/utils/log"
+)
+
+// queueSize is how many fetches can wait to be recorded before new ones are dropped
+const queueSize = 1000
+
+// Fetch is a request made to an upstream site
+type Fetch struct {
+	URL        string    `json:"url" bson:"url"`
+	Method     string    `json:"method" bson:"method"`
+	Status     int       `json:"status" bson:"status"` // 0 if no response was received
+	DurationMs int64     `json:"duration_ms" bson:"duration_ms"`
+	Bytes      int64     `json:"bytes" bson:"bytes"` // Size of the response body
+	RequestID  string    `json:"request_id,omitempty" bson:"request_id,omitempty"`
+	Error      string    `json:"error,omitempty" bson:"error,omitempty"`
+	FetchedAt  time.Time `json:"fetched_at" bson:"fetched_at"`
+}
+
+// Recorder stores a fetch
+type Recorder func(fetch Fetch) error
+
+// queued is a fetch waiting to be stored by its recorder
+type queued struct {
+	fetch  Fetch
+	record Recorder
+}
+
+var (
+	queue     = make(chan queued, queueSize)
+	startOnce sync.Once
+)
+
+type recorderKey struct{}
+
+// WithRecorder returns a context whose upstream fetches are stored by r
+func WithRecorder(ctx context.Context, r Recorder) context.Context {
+	return context.WithValue(ctx, recorderKey{}, r)
+}
+
+// RecorderOf returns the recorder of a context, or nil if its fetches are not recorded
+func RecorderOf(ctx context.Context) Recorder {
+	r, _ := ctx.Value(recorderKey{}).(Recorder)
+	return r
+}
+
+// Record queues a fetch to be stored by r in the background, so fetches are never slowed down by the audit trail.
+// Fetches are dropped if the queue is full, or if r is nil.
+func (r Recorder) Record(fetch Fetch) {
+	if r == nil {
+		return
+	}
+	startOnce.Do(func() {
+		go func() {
+			for q := range queue {
+				if err := q.record(q.fetch); err != nil {
+					log.Errorf("[FETCH LOG] Failed to record fetch of %s: %v", q.fetch.URL, err)
+				}
+			}
+		}()
+	})
+
+	if fetch.FetchedAt.IsZero() {
+		fetch.FetchedAt = time.Now()
+	}
+	select {
+	case queue <- queued{fetch: fetch, record: r}:
+	default:
+		log.Errorf("[FETCH LOG] Queue is full, dropping fetch of %s", fetch.URL)
+	}
+}
+
+type requestIDKey struct{}
+
+// WithRequestID returns a context whose upstream fetches are attributed to a request ID
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestID returns the request ID of a context, or an empty string if it has none
+func RequestID(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+// Transport records the requests of an http.Client, attributed to the request ID of their context
+type Transport struct {
+	Base http.RoundTripper // http.DefaultTransport if nil
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	fetch := Fetch{URL: req.URL.String(), Method: req.Method, RequestID: RequestID(req.Context()), FetchedAt: time.Now()}
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		fetch.DurationMs = time.Since(fetch.FetchedAt).Milliseconds()
+		fetch.Error = err.Error()
+		RecorderOf(req.Context()).Record(fetch)
+		return nil, err
+	}
+
+	// The fetch is recorded once the body is read, so its duration and size are known
+	fetch.Status = resp.StatusCode
+	resp.Body = &recordingBody{ReadCloser: resp.Body, fetch: fetch, record: RecorderOf(req.Context())}
+	return resp, nil
+}
+
+// recordingBody records its fetch when it is closed
+type recordingBody struct {
+	io.ReadCloser
+	fetch  Fetch
+	record Recorder
+	once   sync.Once
+}
+
+func (b *recordingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.fetch.Bytes += int64(n)
+	return n, err
+}
+
+func (b *recordingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(func() {
+		b.fetch.DurationMs = time.Since(b.fetch.FetchedAt).Milliseconds()
+		b.record.Record(b.fetch)
+	})
+	return err
+}
@@ -0,0 +1,123 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// FieldMask selects which fields of a response to keep.
+// Each key maps to a nested mask, or nil to keep the whole field.
+type FieldMask map[string]FieldMask
+
+// ParseFieldMask parses a mask such as "common(code,title),assessments(assessment_name,weight)"
+func ParseFieldMask(s string) (FieldMask, error) {
+	mask, rest, err := parseFields(s)
+	if err != nil {
+		return nil, err
+	}
+	if rest != "" {
+		return nil, fmt.Errorf("unexpected %q in fields", rest)
+	}
+	return mask, nil
+}
+
+// parseFields parses a comma separated list of fields up to an unmatched closing bracket,
+// returning the unparsed remainder
+func parseFields(s string) (FieldMask, string, error) {
+	mask := FieldMask{}
+	for {
+		end := strings.IndexAny(s, ",()")
+		if end == -1 {
+			end = len(s)
+		}
+
+		name := strings.TrimSpace(s[:end])
+		if name == "" {
+			return nil, s, fmt.Errorf("empty field name in fields")
+		}
+		s = s[end:]
+
+		var nested FieldMask
+		if strings.HasPrefix(s, "(") {
+			var err error
+			nested, s, err = parseFields(s[1:])
+			if err != nil {
+				return nil, s, err
+			}
+			if !strings.HasPrefix(s, ")") {
+				return nil, s, fmt.Errorf("missing ) after %s in fields", name)
+			}
+			s = s[1:]
+		}
+		mask[name] = mergeMasks(mask[name], nested, mask.has(name))
+
+		if !strings.HasPrefix(s, ",") {
+			return mask, s, nil
+		}
+		s = s[1:]
+	}
+}
+
+// has reports whether the mask contains a field
+func (m FieldMask) has(name string) bool {
+	_, ok := m[name]
+	return ok
+}
+
+// mergeMasks combines two masks of a field listed more than once.
+// A nil mask keeps the whole field, so it takes precedence.
+func mergeMasks(existing FieldMask, nested FieldMask, seen bool) FieldMask {
+	if !seen {
+		return nested
+	}
+	if existing == nil || nested == nil {
+		return nil
+	}
+	for name, child := range nested {
+		existing[name] = mergeMasks(existing[name], child, existing.has(name))
+	}
+	return existing
+}
+
+// Apply returns the data with only the masked fields kept.
+// The data is converted through its JSON representation, so the mask uses the JSON field names.
+// Masks apply to every element of arrays, and unknown fields are ignored.
+func (m FieldMask) Apply(data interface{}) (interface{}, error) {
+	marshalled, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var generic interface{}
+	if err := json.Unmarshal(marshalled, &generic); err != nil {
+		return nil, err
+	}
+	return m.prune(generic), nil
+}
+
+// prune removes the fields not in the mask from generic JSON data
+func (m FieldMask) prune(value interface{}) interface{} {
+	if m == nil {
+		return value
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		pruned := map[string]interface{}{}
+		for name, nested := range m {
+			if field, ok := v[name]; ok {
+				pruned[name] = nested.prune(field)
+			}
+		}
+		return pruned
+	case []interface{}:
+		pruned := make([]interface{}, len(v))
+		for i, element := range v {
+			pruned[i] = m.prune(element)
+		}
+		return pruned
+	default:
+		return value
+	}
+}
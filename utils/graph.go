@@ -0,0 +1,165 @@
+package utils
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// Graph is a directed graph of handbook items, such as units and the units they require
+type Graph struct {
+	Nodes []GraphNode `json:"nodes"`
+	Edges []GraphEdge `json:"edges"`
+}
+
+// GraphNode is a handbook item in a graph
+type GraphNode struct {
+	ID    string `json:"id"` // Item code
+	Label string `json:"label"`
+	Type  string `json:"type"` // units, courses, or aos
+}
+
+// GraphEdge connects two items of a graph
+type GraphEdge struct {
+	Source       string `json:"source"`
+	Target       string `json:"target"`
+	Relationship string `json:"relationship"` // e.g. prerequisite, prohibition, curriculum
+}
+
+// HasNode reports whether the graph contains a node
+func (g *Graph) HasNode(id string) bool {
+	for _, node := range g.Nodes {
+		if node.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// AddNode adds a node unless the graph already contains it
+func (g *Graph) AddNode(node GraphNode) {
+	if !g.HasNode(node.ID) {
+		g.Nodes = append(g.Nodes, node)
+	}
+}
+
+// AddEdge adds an edge unless the graph already contains it
+func (g *Graph) AddEdge(edge GraphEdge) {
+	for _, existing := range g.Edges {
+		if existing == edge {
+			return
+		}
+	}
+	g.Edges = append(g.Edges, edge)
+}
+
+// graphML is the GraphML document of a graph
+type graphML struct {
+	XMLName xml.Name     `xml:"graphml"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   struct {
+		ID          string        `xml:"id,attr"`
+		EdgeDefault string        `xml:"edgedefault,attr"`
+		Nodes       []graphMLNode `xml:"node"`
+		Edges       []graphMLEdge `xml:"edge"`
+	} `xml:"graph"`
+}
+
+type graphMLKey struct {
+	ID       string `xml:"id,attr"`
+	For      string `xml:"for,attr"`
+	AttrName string `xml:"attr.name,attr"`
+	AttrType string `xml:"attr.type,attr"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+type graphMLNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+type graphMLEdge struct {
+	ID     string        `xml:"id,attr"`
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphMLData `xml:"data"`
+}
+
+// GraphML renders the graph as GraphML, as read by Gephi and yEd
+func (g *Graph) GraphML() (string, error) {
+	doc := graphML{
+		Xmlns: "http://graphml.graphdrawing.org/xmlns",
+		Keys: []graphMLKey{
+			{ID: "label", For: "node", AttrName: "label", AttrType: "string"},
+			{ID: "type", For: "node", AttrName: "type", AttrType: "string"},
+			{ID: "relationship", For: "edge", AttrName: "relationship", AttrType: "string"},
+		},
+	}
+	doc.Graph.ID = "G"
+	doc.Graph.EdgeDefault = "directed"
+	for _, node := range g.Nodes {
+		doc.Graph.Nodes = append(doc.Graph.Nodes, graphMLNode{
+			ID:   node.ID,
+			Data: []graphMLData{{Key: "label", Value: node.Label}, {Key: "type", Value: node.Type}},
+		})
+	}
+	for i, edge := range g.Edges {
+		doc.Graph.Edges = append(doc.Graph.Edges, graphMLEdge{
+			ID:     fmt.Sprintf("e%d", i),
+			Source: edge.Source,
+			Target: edge.Target,
+			Data:   []graphMLData{{Key: "relationship", Value: edge.Relationship}},
+		})
+	}
+
+	marshalled, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return xml.Header + string(marshalled), nil
+}
+
+// DOT renders the graph in the Graphviz DOT language
+func (g *Graph) DOT() string {
+	quote := func(s string) string {
+		return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+	}
+
+	var sb strings.Builder
+	sb.WriteString("digraph G {\n")
+	for _, node := range g.Nodes {
+		fmt.Fprintf(&sb, "  %s [label=%s, type=%s];\n", quote(node.ID), quote(node.Label), quote(node.Type))
+	}
+	for _, edge := range g.Edges {
+		fmt.Fprintf(&sb, "  %s -> %s [label=%s];\n", quote(edge.Source), quote(edge.Target), quote(edge.Relationship))
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+// CytoscapeJSON returns the graph in the Cytoscape.js elements JSON format
+func (g *Graph) CytoscapeJSON() map[string]interface{} {
+	nodes := make([]map[string]interface{}, 0, len(g.Nodes))
+	for _, node := range g.Nodes {
+		nodes = append(nodes, map[string]interface{}{
+			"data": map[string]string{"id": node.ID, "label": node.Label, "type": node.Type},
+		})
+	}
+	edges := make([]map[string]interface{}, 0, len(g.Edges))
+	for i, edge := range g.Edges {
+		edges = append(edges, map[string]interface{}{
+			"data": map[string]string{
+				"id":           fmt.Sprintf("e%d", i),
+				"source":       edge.Source,
+				"target":       edge.Target,
+				"relationship": edge.Relationship,
+			},
+		})
+	}
+	return map[string]interface{}{"elements": map[string]interface{}{"nodes": nodes, "edges": edges}}
+}
@@ -0,0 +1,139 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+var (
+	// snakeCaseKey matches field names such as "credit_points", so data keys such as unit codes or URLs are left alone
+	snakeCaseKey = regexp.MustCompile(`^[a-z][a-z0-9]*(_[a-z0-9]+)+$`)
+	// camelCaseKey matches field names such as "creditPoints"
+	camelCaseKey = regexp.MustCompile(`^[a-z][a-z0-9]*([A-Z][a-z0-9]*)+$`)
+)
+
+// ToCamelCase converts a snake_case field name to camelCase, e.g. credit_points to creditPoints.
+// Names which are not snake_case are returned as they are.
+func ToCamelCase(name string) string {
+	if !snakeCaseKey.MatchString(name) {
+		return name
+	}
+
+	parts := strings.Split(name, "_")
+	for i := 1; i < len(parts); i++ {
+		parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+	}
+	return strings.Join(parts, "")
+}
+
+// ToSnakeCase converts a camelCase field name to snake_case, e.g. creditPoints to credit_points.
+// Names which are not camelCase are returned as they are.
+func ToSnakeCase(name string) string {
+	if !camelCaseKey.MatchString(name) {
+		return name
+	}
+
+	var b strings.Builder
+	for _, r := range name {
+		if unicode.IsUpper(r) {
+			b.WriteByte('_')
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// RenameJSONKeys rewrites the object keys of a JSON document with rename, keeping their order and every value as it is.
+// Since the keys come from the json struct tags of the serialised types, this renames the fields without
+// needing a second set of types per casing.
+func RenameJSONKeys(data []byte, rename func(string) string) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var out bytes.Buffer
+	// Each open container records whether it is an object, and whether the next token in it is a key
+	type container struct {
+		object    bool
+		expectKey bool
+		count     int
+	}
+	var stack []container
+
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		isKey := false
+		if len(stack) > 0 {
+			top := &stack[len(stack)-1]
+			if delim, ok := token.(json.Delim); !ok || (delim != '}' && delim != ']') {
+				if top.object && top.expectKey {
+					isKey = true
+					if top.count > 0 {
+						out.WriteByte(',')
+					}
+				} else if top.object {
+					out.WriteByte(':')
+				} else if top.count > 0 {
+					out.WriteByte(',')
+				}
+			}
+		}
+
+		switch value := token.(type) {
+		case json.Delim:
+			out.WriteRune(rune(value))
+			switch value {
+			case '{', '[':
+				stack = append(stack, container{object: value == '{', expectKey: true})
+				continue
+			case '}', ']':
+				stack = stack[:len(stack)-1]
+			}
+		case string:
+			if isKey {
+				value = rename(value)
+			}
+			encoded, err := json.Marshal(value)
+			if err != nil {
+				return nil, err
+			}
+			out.Write(encoded)
+		case json.Number:
+			out.WriteString(value.String())
+		case bool:
+			if value {
+				out.WriteString("true")
+			} else {
+				out.WriteString("false")
+			}
+		case nil:
+			out.WriteString("null")
+		}
+
+		// A completed key is followed by its value, a completed value by the next key
+		if len(stack) > 0 {
+			top := &stack[len(stack)-1]
+			if top.object {
+				if !isKey {
+					top.count++
+				}
+				top.expectKey = !isKey
+			} else {
+				top.count++
+			}
+		}
+	}
+	return out.Bytes(), nil
+}
@@ -5,6 +5,8 @@ import (
 	"handbook-scraper/utils/log"
 	"reflect"
 	"strconv"
+	"strings"
+	"sync"
 )
 
 // GetTypedValue retrieves a value from a JSON map and attempts to cast it to the specified type.
@@ -25,6 +27,75 @@ func GetTypedValue[T any](data map[string]interface{}, path string) T {
 		return zero
 	}
 
+	typed, _ := typedValue[T](value, path, log.Warnf)
+	return typed
+}
+
+// GetFirstTypedValue tries the paths of a field in order, for fields which move between handbook revisions,
+// and returns the first value which exists, casts to the specified type, and is not empty, along with its path.
+// If no path has a value, it returns the default value of type T and an empty path.
+// The matching path is recorded, so moved fields show up in PathMatches.
+func GetFirstTypedValue[T any](data map[string]interface{}, paths ...string) (T, string) {
+	var zero T
+	for _, path := range paths {
+		value, ok := LookupValue(data, path)
+		if !ok {
+			continue
+		}
+		typed, ok := typedValue[T](value, path, func(string, ...interface{}) {})
+		if !ok || reflect.ValueOf(&typed).Elem().IsZero() {
+			continue
+		}
+		recordPathMatch(paths, path)
+		return typed, path
+	}
+
+	if len(paths) > 0 {
+		recordPathMatch(paths, "")
+		log.Errorf("No value at any of the paths %s\n", strings.Join(paths, ", "))
+	}
+	return zero, ""
+}
+
+var (
+	pathMatchesMu sync.Mutex
+	pathMatches   = map[string]map[string]int{}
+)
+
+// recordPathMatch counts the path matched for a field, keyed by the field's first path. An empty path counts misses.
+func recordPathMatch(paths []string, matched string) {
+	pathMatchesMu.Lock()
+	defer pathMatchesMu.Unlock()
+
+	counts, ok := pathMatches[paths[0]]
+	if !ok {
+		counts = map[string]int{}
+		pathMatches[paths[0]] = counts
+	}
+	counts[matched]++
+}
+
+// PathMatches returns how often each path of the fields read with GetFirstTypedValue matched since the process started,
+// keyed by the field's first path. Misses are counted under an empty path.
+func PathMatches() map[string]map[string]int {
+	pathMatchesMu.Lock()
+	defer pathMatchesMu.Unlock()
+
+	snapshot := make(map[string]map[string]int, len(pathMatches))
+	for field, counts := range pathMatches {
+		snapshot[field] = make(map[string]int, len(counts))
+		for path, count := range counts {
+			snapshot[field][path] = count
+		}
+	}
+	return snapshot
+}
+
+// typedValue casts a JSON value to the specified type, reporting whether it could.
+// Failed conversions are reported with warnf.
+func typedValue[T any](value interface{}, path string, warnf func(format string, args ...interface{})) (T, bool) {
+	var zero T
+
 	// Handle []map[string]interface{} specifically
 	if _, isTargetType := any(zero).([]map[string]interface{}); isTargetType {
 		if slice, ok := value.([]interface{}); ok {
@@ -33,13 +104,13 @@ func GetTypedValue[T any](data map[string]interface{}, path string) T {
 				if itemMap, ok := item.(map[string]interface{}); ok {
 					result = append(result, itemMap)
 				} else {
-					log.Warnf("Item in slice at path '%s' is not a map[string]interface{}\n", path)
+					warnf("Item in slice at path '%s' is not a map[string]interface{}\n", path)
 				}
 			}
-			return any(result).(T)
+			return any(result).(T), true
 		}
-		log.Warnf("Value at path '%s' is not a slice of interface{} for []map[string]interface{}\n", path)
-		return zero
+		warnf("Value at path '%s' is not a slice of interface{} for []map[string]interface{}\n", path)
+		return zero, false
 	}
 
 	// Handle string to numeric type conversion
@@ -47,67 +118,74 @@ func GetTypedValue[T any](data map[string]interface{}, path string) T {
 	case int:
 		if str, ok := value.(string); ok {
 			if intValue, err := strconv.Atoi(str); err == nil {
-				return any(intValue).(T)
+				return any(intValue).(T), true
 			} else {
-				log.Warnf("Failed to convert string '%s' to int at path '%s': %v\n", str, path, err)
+				warnf("Failed to convert string '%s' to int at path '%s': %v\n", str, path, err)
 			}
 		} else {
-			log.Warnf("Value at path '%s' is not a string for int conversion\n", path)
+			warnf("Value at path '%s' is not a string for int conversion\n", path)
 		}
 	case float32:
 		if str, ok := value.(string); ok {
 			if floatValue, err := strconv.ParseFloat(str, 32); err == nil {
-				return any(float32(floatValue)).(T)
+				return any(float32(floatValue)).(T), true
 			} else {
-				log.Warnf("Failed to convert string '%s' to float32 at path '%s': %v\n", str, path, err)
+				warnf("Failed to convert string '%s' to float32 at path '%s': %v\n", str, path, err)
 			}
 		} else {
-			log.Warnf("Value at path '%s' is not a string for float32 conversion\n", path)
+			warnf("Value at path '%s' is not a string for float32 conversion\n", path)
 		}
 	case float64:
 		if str, ok := value.(string); ok {
 			if floatValue, err := strconv.ParseFloat(str, 64); err == nil {
-				return any(floatValue).(T)
+				return any(floatValue).(T), true
 			} else {
-				log.Warnf("Failed to convert string '%s' to float64 at path '%s': %v\n", str, path, err)
+				warnf("Failed to convert string '%s' to float64 at path '%s': %v\n", str, path, err)
 			}
 		} else {
-			log.Warnf("Value at path '%s' is not a string for float64 conversion\n", path)
+			warnf("Value at path '%s' is not a string for float64 conversion\n", path)
 		}
 	case bool:
 		if str, ok := value.(string); ok {
 			if boolValue, err := strconv.ParseBool(str); err == nil {
-				return any(boolValue).(T)
+				return any(boolValue).(T), true
 			} else {
-				log.Warnf("Failed to convert string '%s' to bool at path '%s': %v\n", str, path, err)
+				warnf("Failed to convert string '%s' to bool at path '%s': %v\n", str, path, err)
 			}
 		} else {
-			log.Warnf("Value at path '%s' is not a string for bool conversion\n", path)
+			warnf("Value at path '%s' is not a string for bool conversion\n", path)
 		}
 	}
 
 	// Attempt to assert the value to the desired type T
 	if typedValue, ok := value.(T); ok {
-		return typedValue
+		return typedValue, true
 	}
 
 	// Handle custom types with type conversion using reflection
 	val := reflect.ValueOf(value)
 	if !val.IsValid() {
-		log.Warnf("Value at path '%s' is invalid for reflection\n", path)
-		return zero
+		warnf("Value at path '%s' is invalid for reflection\n", path)
+		return zero, false
 	}
 
 	zeroType := reflect.TypeOf(zero)
 	if val.Type().ConvertibleTo(zeroType) {
 		convertedValue := val.Convert(zeroType).Interface()
 		if convertedTypedValue, ok := convertedValue.(T); ok {
-			return convertedTypedValue
+			return convertedTypedValue, true
 		}
 	}
 
-	log.Warnf("Value at path '%s' is not of type %T\n", path, zero)
-	return zero
+	warnf("Value at path '%s' is not of type %T\n", path, zero)
+	return zero, false
+}
+
+// LookupValue returns the value at a path of a JSON map, and whether the path exists with a non-null value.
+// Unlike GetTypedValue, a missing path is not logged, so paths can be probed.
+func LookupValue(data map[string]interface{}, path string) (interface{}, bool) {
+	value, err := findInterface(data, path)
+	return value, err == nil && value != nil
 }
 
 // findInterface navigates the JSON map using the provided path and returns the value as an interface{}.
@@ -0,0 +1,77 @@
+package utils
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// loadPages reads the raw pages in testdata, saved as .json or gzipped .json.gz files, such as the page JSON of
+// real handbook pages. large_unit.json.gz is a generated unit with more deeply nested requisites than any real unit.
+func loadPages(b *testing.B) map[string]map[string]interface{} {
+	paths, err := filepath.Glob(filepath.Join("testdata", "*.json*"))
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	pages := map[string]map[string]interface{}{}
+	for _, path := range paths {
+		file, err := os.Open(path)
+		if err != nil {
+			b.Fatal(err)
+		}
+		var reader io.Reader = file
+		if strings.HasSuffix(path, ".gz") {
+			if reader, err = gzip.NewReader(file); err != nil {
+				b.Fatalf("failed to decompress %s: %v", path, err)
+			}
+		}
+		var data map[string]interface{}
+		err = json.NewDecoder(reader).Decode(&data)
+		file.Close()
+		if err != nil {
+			b.Fatalf("failed to decode %s: %v", path, err)
+		}
+		pages[strings.TrimSuffix(strings.TrimSuffix(filepath.Base(path), ".gz"), ".json")] = data
+	}
+	return pages
+}
+
+// typedValuePaths are the paths GetTypedValue is benchmarked on, by the type they are read as
+var typedValuePaths = []struct {
+	name string
+	path string
+	get  func(map[string]interface{}, string)
+}{
+	{"string", "props.pageProps.pageContent.title", func(data map[string]interface{}, path string) {
+		GetTypedValue[string](data, path)
+	}},
+	{"nested", "props.pageProps.pageContent.level.label", func(data map[string]interface{}, path string) {
+		GetTypedValue[string](data, path)
+	}},
+	{"slice", "props.pageProps.pageContent.requisites", func(data map[string]interface{}, path string) {
+		GetTypedValue[[]map[string]interface{}](data, path)
+	}},
+}
+
+// BenchmarkGetTypedValue reads each of typedValuePaths from each page which has it
+func BenchmarkGetTypedValue(b *testing.B) {
+	pages := loadPages(b)
+	for _, typed := range typedValuePaths {
+		for name, page := range pages {
+			if _, ok := LookupValue(page, typed.path); !ok {
+				continue
+			}
+			b.Run(typed.name+"/"+name, func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					typed.get(page, typed.path)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,94 @@
+// Package notifier posts human-readable alerts to Discord and Slack webhooks
+package notifier
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"handbook-scraper/utils/log"
+	"handbook-scraper/utils/signing"
+)
+
+const (
+	// queueSize is how many alerts can wait to be posted before new ones are dropped
+	queueSize = 100
+	// discordLimit is the most characters Discord accepts in a message
+	discordLimit = 2000
+)
+
+// webhook is a Discord or Slack incoming webhook
+type webhook struct {
+	signing.Subscription
+	discord bool // Discord webhooks take the message as content, Slack webhooks as text
+}
+
+var (
+	loadOnce sync.Once
+	webhooks []webhook
+	queue    = make(chan string, queueSize)
+	client   = &http.Client{Timeout: 10 * time.Second}
+)
+
+// load reads the webhooks of DISCORD_WEBHOOK_URLS and SLACK_WEBHOOK_URLS, and starts posting alerts if any are set.
+// Webhooks can have their own signing secret, as url#secret.
+func load() {
+	loadOnce.Do(func() {
+		for _, env := range []string{"DISCORD_WEBHOOK_URLS", "SLACK_WEBHOOK_URLS"} {
+			for _, subscription := range signing.ParseSubscriptions(os.Getenv(env)) {
+				webhooks = append(webhooks, webhook{Subscription: subscription, discord: env == "DISCORD_WEBHOOK_URLS"})
+			}
+		}
+		if len(webhooks) == 0 {
+			return
+		}
+
+		log.Infof("[NOTIFIER] Posting alerts to %d webhooks", len(webhooks))
+		go func() {
+			for message := range queue {
+				for _, hook := range webhooks {
+					if err := hook.post(message); err != nil {
+						log.Errorf("[NOTIFIER] Failed to post alert: %v", err)
+					}
+				}
+			}
+		}()
+	})
+}
+
+// Enabled reports whether any webhook is configured, so callers can skip building alerts nobody receives
+func Enabled() bool {
+	load()
+	return len(webhooks) > 0
+}
+
+// Notify queues an alert to be posted to every webhook in the background.
+// Alerts are dropped if the queue is full.
+func Notify(message string) {
+	if !Enabled() {
+		return
+	}
+	select {
+	case queue <- message:
+	default:
+		log.Errorf("[NOTIFIER] Queue is full, dropping alert")
+	}
+}
+
+// post sends a message to the webhook, signed as every webhook payload is
+func (w webhook) post(message string) error {
+	payload := map[string]string{"text": message}
+	if w.discord {
+		if runes := []rune(message); len(runes) > discordLimit {
+			message = string(runes[:discordLimit-1]) + "…"
+		}
+		payload = map[string]string{"content": message}
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return w.Post(client, body)
+}
@@ -0,0 +1,199 @@
+// Package secrets resolves credentials, such as the database URIs and API keys, from a secrets provider, files or
+// the environment, and reloads them in the background so that rotated credentials apply without a restart
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"handbook-scraper/utils/log"
+)
+
+const (
+	// defaultReloadInterval is how often secrets are resolved again, unless SECRETS_RELOAD_INTERVAL is set
+	defaultReloadInterval = time.Minute
+	// resolveTimeout is the time budget of resolving one secret from the provider
+	resolveTimeout = 10 * time.Second
+)
+
+// Provider is a store of secrets, such as Vault or a cloud KMS or secrets manager
+type Provider interface {
+	// Secret returns the value of a secret by name, and false if the provider does not have it
+	Secret(ctx context.Context, name string) (string, bool, error)
+}
+
+// subscriber is called when any of its secrets change
+type subscriber struct {
+	names  []string
+	notify func()
+}
+
+var (
+	mu          sync.RWMutex
+	provider    Provider
+	values      = map[string]string{}
+	subscribers []subscriber
+	loadOnce    sync.Once
+)
+
+// load sets up the provider of SECRETS_PROVIDER and starts reloading secrets
+func load() {
+	loadOnce.Do(func() {
+		switch name := strings.ToLower(os.Getenv("SECRETS_PROVIDER")); name {
+		case "", "env":
+		case "vault":
+			vault, err := VaultFromEnv()
+			if err != nil {
+				log.Errorf("[SECRETS] Ignoring the Vault provider: %v", err)
+				break
+			}
+			mu.Lock()
+			if provider == nil {
+				provider = vault
+			}
+			mu.Unlock()
+		default:
+			log.Warnf("[SECRETS] Ignoring unknown SECRETS_PROVIDER %q", name)
+		}
+
+		interval := defaultReloadInterval
+		if raw := os.Getenv("SECRETS_RELOAD_INTERVAL"); raw != "" {
+			parsed, err := time.ParseDuration(raw)
+			if err != nil || parsed < 0 {
+				log.Warnf("Ignoring invalid SECRETS_RELOAD_INTERVAL %q", raw)
+			} else {
+				interval = parsed
+			}
+		}
+		if interval == 0 {
+			return
+		}
+		go func() {
+			for range time.Tick(interval) {
+				Reload()
+			}
+		}()
+	})
+}
+
+// SetProvider makes secrets resolve from a provider before files and the environment, such as a KMS
+// the server is embedded with. It replaces the provider of SECRETS_PROVIDER, and the secrets in use are resolved again.
+func SetProvider(p Provider) {
+	load()
+	mu.Lock()
+	provider = p
+	mu.Unlock()
+	Reload()
+}
+
+// Get returns a secret from the provider if it has it, otherwise from the file named by the NAME_FILE environment
+// variable, such as a mounted Kubernetes secret, otherwise from the NAME environment variable.
+// Values are cached, and resolved again every SECRETS_RELOAD_INTERVAL.
+func Get(name string) string {
+	load()
+	mu.RLock()
+	value, ok := values[name]
+	mu.RUnlock()
+	if ok {
+		return value
+	}
+
+	value, err := resolve(name)
+	if err != nil {
+		log.Errorf("[SECRETS] Failed to resolve %s, falling back to the environment: %v", name, err)
+		value = os.Getenv(name)
+	}
+	mu.Lock()
+	if cached, ok := values[name]; ok {
+		value = cached
+	} else {
+		values[name] = value
+	}
+	mu.Unlock()
+	return value
+}
+
+// OnChange calls notify whenever any of the named secrets is rotated, such as to reconnect with new credentials.
+// A reload which changes several of them calls it once.
+func OnChange(notify func(), names ...string) {
+	load()
+	for _, name := range names {
+		Get(name)
+	}
+	mu.Lock()
+	subscribers = append(subscribers, subscriber{names: names, notify: notify})
+	mu.Unlock()
+}
+
+// Reload resolves the secrets in use again, and notifies the subscribers of those which changed.
+// Secrets which fail to resolve keep their previous value.
+func Reload() {
+	mu.RLock()
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	mu.RUnlock()
+
+	var changed []string
+	for _, name := range names {
+		value, err := resolve(name)
+		if err != nil {
+			log.Errorf("[SECRETS] Failed to reload %s, keeping its previous value: %v", name, err)
+			continue
+		}
+		mu.Lock()
+		if values[name] != value {
+			values[name] = value
+			changed = append(changed, name)
+		}
+		mu.Unlock()
+	}
+	if len(changed) == 0 {
+		return
+	}
+
+	// Values are never logged, only which secrets were rotated
+	log.Infof("[SECRETS] Rotated %s", strings.Join(changed, ", "))
+	mu.RLock()
+	notified := slices.Clone(subscribers)
+	mu.RUnlock()
+	for _, sub := range notified {
+		if slices.ContainsFunc(sub.names, func(name string) bool { return slices.Contains(changed, name) }) {
+			sub.notify()
+		}
+	}
+}
+
+// resolve reads a secret from the provider, the file of NAME_FILE or the environment, in that order
+func resolve(name string) (string, error) {
+	mu.RLock()
+	p := provider
+	mu.RUnlock()
+	if p != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), resolveTimeout)
+		value, ok, err := p.Secret(ctx, name)
+		cancel()
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			return value, nil
+		}
+	}
+
+	if path := os.Getenv(name + "_FILE"); path != "" {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s_FILE: %w", name, err)
+		}
+		// Files written by editors and secret mounts often end with a newline
+		return strings.TrimSpace(string(content)), nil
+	}
+	return os.Getenv(name), nil
+}
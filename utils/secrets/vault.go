@@ -0,0 +1,85 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Vault reads secrets from a key/value version 2 secret of HashiCorp Vault, whose keys are the names of the secrets,
+// e.g. MONGO_URI. The token is read on every request, so a token file renewed by the Vault agent is picked up.
+type Vault struct {
+	Addr      string // Address of Vault, e.g. https://vault.example.edu:8200
+	Path      string // Path of the secret, with the data/ segment of the mount, e.g. secret/data/handbook
+	Token     string // Token of the server, unless TokenFile is set
+	TokenFile string // File holding the token
+	Client    *http.Client
+}
+
+// VaultFromEnv configures Vault from VAULT_ADDR, VAULT_SECRET_PATH, and VAULT_TOKEN or VAULT_TOKEN_FILE
+func VaultFromEnv() (*Vault, error) {
+	vault := &Vault{
+		Addr:      strings.TrimSuffix(os.Getenv("VAULT_ADDR"), "/"),
+		Path:      strings.Trim(os.Getenv("VAULT_SECRET_PATH"), "/"),
+		Token:     os.Getenv("VAULT_TOKEN"),
+		TokenFile: os.Getenv("VAULT_TOKEN_FILE"),
+		Client:    &http.Client{Timeout: 10 * time.Second},
+	}
+	if vault.Addr == "" || vault.Path == "" {
+		return nil, errors.New("VAULT_ADDR and VAULT_SECRET_PATH are required")
+	}
+	if vault.Token == "" && vault.TokenFile == "" {
+		return nil, errors.New("VAULT_TOKEN or VAULT_TOKEN_FILE is required")
+	}
+	return vault, nil
+}
+
+// Secret reads a key of the Vault secret
+func (v *Vault) Secret(ctx context.Context, name string) (string, bool, error) {
+	token := v.Token
+	if v.TokenFile != "" {
+		content, err := os.ReadFile(v.TokenFile)
+		if err != nil {
+			return "", false, fmt.Errorf("failed to read the Vault token: %w", err)
+		}
+		token = strings.TrimSpace(string(content))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.Addr+"/v1/"+v.Path, nil)
+	if err != nil {
+		return "", false, err
+	}
+	req.Header.Set("X-Vault-Token", token)
+
+	client := v.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to reach Vault: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return "", false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", false, fmt.Errorf("vault responded with %s", resp.Status)
+	}
+
+	var body struct {
+		Data struct {
+			Data map[string]interface{} `json:"data"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", false, fmt.Errorf("failed to decode the Vault secret: %w", err)
+	}
+	value, ok := body.Data.Data[name].(string)
+	return value, ok, nil
+}
@@ -0,0 +1,178 @@
+// Package signing signs the payloads the API posts to webhooks, so receivers can check they were sent by the API,
+// were not modified, and are not replayed. Receivers written in Go can check them with a Verifier.
+//
+// Each payload is sent with a Unix timestamp, a random nonce, and an HMAC-SHA256 signature of
+// "timestamp.nonce.body" with the secret of the webhook, as hex in the form "v1=<signature>".
+package signing
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"handbook-scraper/utils/secrets"
+)
+
+const (
+	TimestampHeader = "X-Handbook-Timestamp"
+	NonceHeader     = "X-Handbook-Nonce"
+	SignatureHeader = "X-Handbook-Signature"
+
+	// DefaultTolerance is how old a payload a Verifier accepts, and how long it remembers nonces
+	DefaultTolerance = 5 * time.Minute
+	// signatureVersion prefixes signatures, so the scheme can change without breaking receivers
+	signatureVersion = "v1="
+)
+
+// Subscription is a webhook and the secret its payloads are signed with
+type Subscription struct {
+	URL    string
+	Secret string // Signs the payloads, WEBHOOK_SIGNING_SECRET if empty, and unsigned if both are empty
+}
+
+// ParseSubscriptions parses a comma-separated list of webhooks, each a URL optionally followed by #secret,
+// e.g. "https://example.edu/hook#s3cret". Fragments are never sent to webhooks, so they are free to carry the secret.
+func ParseSubscriptions(list string) []Subscription {
+	var subscriptions []Subscription
+	for _, entry := range strings.Split(list, ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		url, secret, _ := strings.Cut(entry, "#")
+		subscriptions = append(subscriptions, Subscription{URL: url, Secret: secret})
+	}
+	return subscriptions
+}
+
+// Post sends a JSON payload to the webhook, signed with its secret
+func (s Subscription) Post(client *http.Client, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, s.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	secret := s.Secret
+	if secret == "" {
+		secret = secrets.Get("WEBHOOK_SIGNING_SECRET")
+	}
+	if secret != "" {
+		if err := Sign(req.Header, body, secret); err != nil {
+			return err
+		}
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Sign sets the timestamp, nonce and signature headers of a payload
+func Sign(header http.Header, body []byte, secret string) error {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("failed to generate a nonce: %w", err)
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	header.Set(TimestampHeader, timestamp)
+	header.Set(NonceHeader, hex.EncodeToString(nonce))
+	header.Set(SignatureHeader, signatureVersion+Signature(secret, timestamp, hex.EncodeToString(nonce), body))
+	return nil
+}
+
+// Signature is the hex HMAC-SHA256 of a payload with its timestamp and nonce
+func Signature(secret string, timestamp string, nonce string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "." + nonce + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Errors of payloads which fail verification
+var (
+	ErrMissingSignature = errors.New("the payload is not signed")
+	ErrInvalidSignature = errors.New("the signature does not match the payload")
+	ErrExpired          = errors.New("the payload is too old, or its timestamp is invalid")
+	ErrReplayed         = errors.New("the payload was already received")
+)
+
+// Verifier checks the signatures of the payloads a webhook receives, and rejects payloads outside the tolerance
+// or whose nonce it has already seen. Several secrets can be accepted, such as the old and new secret during a rotation.
+type Verifier struct {
+	Secrets   []string
+	Tolerance time.Duration // DefaultTolerance if zero
+
+	mu   sync.Mutex
+	seen map[string]time.Time
+}
+
+// NewVerifier creates a verifier of the payloads signed with any of the secrets
+func NewVerifier(secrets ...string) *Verifier {
+	return &Verifier{Secrets: secrets}
+}
+
+// Verify checks the signature headers of a payload, and records its nonce
+func (v *Verifier) Verify(header http.Header, body []byte) error {
+	timestamp, nonce := header.Get(TimestampHeader), header.Get(NonceHeader)
+	signature, ok := strings.CutPrefix(header.Get(SignatureHeader), signatureVersion)
+	if !ok || timestamp == "" || nonce == "" {
+		return ErrMissingSignature
+	}
+
+	tolerance := v.Tolerance
+	if tolerance <= 0 {
+		tolerance = DefaultTolerance
+	}
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrExpired
+	}
+	sent := time.Unix(seconds, 0)
+	if age := time.Since(sent); age > tolerance || age < -tolerance {
+		return ErrExpired
+	}
+
+	matched := false
+	for _, secret := range v.Secrets {
+		if hmac.Equal([]byte(signature), []byte(Signature(secret, timestamp, nonce, body))) {
+			matched = true
+			break
+		}
+	}
+	if !matched {
+		return ErrInvalidSignature
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	// Nonces are only remembered within the tolerance, as older payloads are rejected by their timestamp
+	now := time.Now()
+	for seenNonce, at := range v.seen {
+		if now.Sub(at) > 2*tolerance {
+			delete(v.seen, seenNonce)
+		}
+	}
+	if v.seen == nil {
+		v.seen = map[string]time.Time{}
+	}
+	if _, replayed := v.seen[nonce]; replayed {
+		return ErrReplayed
+	}
+	v.seen[nonce] = sent
+	return nil
+}
@@ -0,0 +1,49 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/event"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// MongoMonitor returns a command monitor creating a client span for every command MongoDB runs, such as find or
+// update, as children of the span of the context the command was run with
+func MongoMonitor() *event.CommandMonitor {
+	var spans sync.Map // Request ID to the span of a running command
+
+	finish := func(requestID int64, err error) {
+		if span, ok := spans.LoadAndDelete(requestID); ok {
+			End(span.(trace.Span), err)
+		}
+	}
+
+	return &event.CommandMonitor{
+		Started: func(ctx context.Context, started *event.CommandStartedEvent) {
+			attrs := []attribute.KeyValue{
+				attribute.String("db.system", "mongodb"),
+				attribute.String("db.name", started.DatabaseName),
+				attribute.String("db.operation", started.CommandName),
+			}
+			// The first element of a command names the collection it runs against
+			if element, err := started.Command.IndexErr(0); err == nil {
+				if collection, ok := element.Value().StringValueOK(); ok {
+					attrs = append(attrs, attribute.String("db.mongodb.collection", collection))
+				}
+			}
+			_, span := otel.Tracer(tracerName).Start(ctx, "mongo."+started.CommandName,
+				trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+			spans.Store(started.RequestID, span)
+		},
+		Succeeded: func(_ context.Context, succeeded *event.CommandSucceededEvent) {
+			finish(succeeded.RequestID, nil)
+		},
+		Failed: func(_ context.Context, failed *event.CommandFailedEvent) {
+			finish(failed.RequestID, errors.New(failed.Failure))
+		},
+	}
+}